@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// readAddresses collects the wallets from a comma-separated -address list
+// and an -addresses-file with one address per line, where blank lines and
+// lines starting with # are ignored. Duplicates are dropped.
+func readAddresses(list, path string) ([]string, error) {
+	fields := strings.Split(list, ",")
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read addresses: %w", err)
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields = append(fields, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read addresses: %w", err)
+		}
+	}
+
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" || strings.HasPrefix(field, "#") {
+			continue
+		}
+		address, err := normalizeAddress(field)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
+
+// lookupAddresses finds every address in one pass over the leaderboard
+// rather than one request per address. missing lists the addresses that
+// aren't ranked, in input order.
+func lookupAddresses(season int, addresses []string) (positions []walletPosition, missing []string, err error) {
+	users, board, err := fetchAllUsers(season)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byAddress := make(map[string]User, len(users))
+	for _, user := range users {
+		byAddress[strings.ToLower(user.Address)] = user
+	}
+	for _, address := range addresses {
+		user, ok := byAddress[address]
+		if !ok {
+			missing = append(missing, address)
+			continue
+		}
+		position := newWalletPosition(user, len(users), percentilePrecision)
+		if normalizeByDays {
+			position.normalize(season, board.LastUpdated)
+		}
+		positions = append(positions, position)
+	}
+	return positions, missing, nil
+}
+
+// enrichPositions annotates positions with their -enrich-rpc activity.
+func enrichPositions(e *rpcEnricher, positions []walletPosition) {
+	addresses := make([]string, len(positions))
+	for i, position := range positions {
+		addresses[i] = position.Address
+	}
+	activities := e.enrich(addresses)
+	for i := range positions {
+		activity := activities[strings.ToLower(positions[i].Address)]
+		positions[i].addressActivity = &activity
+	}
+}
+
+type addressReport struct {
+	Wallets  []walletPosition `json:"wallets"`
+	NotFound []string         `json:"notFound"`
+}
+
+func writeAddressTable(out io.Writer, format string, positions []walletPosition, missing []string) error {
+	report := addressReport{Wallets: make([]walletPosition, len(positions)), NotFound: make([]string, len(missing))}
+	for i, position := range positions {
+		position.Address = redact.address(position.Address)
+		report.Wallets[i] = position
+	}
+	for i, address := range missing {
+		report.NotFound[i] = redact.address(address)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	enriched := enricher != nil
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "address\trank\ttotal score\ttop")
+	if normalizeByDays {
+		fmt.Fprint(w, "\tper day")
+	}
+	if enriched {
+		fmt.Fprint(w, "\tcontract\ttxs")
+	}
+	fmt.Fprintln(w)
+	for _, position := range report.Wallets {
+		fmt.Fprintf(w, "%s\t%s\t%.0f\t%s", position.Address, formatThousands(position.Rank), position.TotalScore,
+			localizeDecimal(position.Percentile))
+		if normalizeByDays {
+			perDay := ""
+			if position.ActiveDays > 0 {
+				perDay = formatPerDay(position.PointsPerDay)
+			}
+			fmt.Fprintf(w, "\t%s", perDay)
+		}
+		if enriched && position.addressActivity != nil {
+			fmt.Fprintf(w, "\t%s\t%s", position.contractText(), position.txCountText())
+		}
+		fmt.Fprintln(w)
+	}
+	for _, address := range report.NotFound {
+		fmt.Fprintf(w, "%s\tnot ranked\t\t\n", address)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testAddress(rank int) string {
+	return fmt.Sprintf("0x%040x", rank)
+}
+
+func TestReadAddresses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallets.txt")
+	file := "# portfolio\n" + testAddress(2) + "\n\n0x" + strings.ToUpper(testAddress(3)[2:]) + "\n"
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	addresses, err := readAddresses(testAddress(1)+", "+testAddress(2), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{testAddress(1), testAddress(2), testAddress(3)}; !reflect.DeepEqual(addresses, want) {
+		t.Errorf("addresses = %v, want %v", addresses, want)
+	}
+
+	if _, err := readAddresses(testAddress(1)+",0x12", ""); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("malformed address: err = %v, want ErrInvalidAddress", err)
+	}
+}
+
+func TestLookupAddressesSinglePass(t *testing.T) {
+	var requests int
+	useFakeUpstream(t, countRequests(&requests, rankedUpstream(250)))
+	unknown := "0x" + strings.Repeat("f", 40)
+
+	positions, missing, err := lookupAddresses(defaultSeason, []string{testAddress(150), unknown, testAddress(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One board request and three pages of 100, however many addresses.
+	if requests != 4 {
+		t.Errorf("made %d requests, want 4", requests)
+	}
+	if len(positions) != 2 || positions[0].Rank != 150 || positions[1].Rank != 3 {
+		t.Fatalf("positions = %+v, want ranks 150 and 3", positions)
+	}
+	if !reflect.DeepEqual(missing, []string{unknown}) {
+		t.Errorf("missing = %v, want %v", missing, []string{unknown})
+	}
+
+	var out bytes.Buffer
+	writeAddressTable(&out, "text", positions, missing)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[3], unknown) || !strings.Contains(lines[3], "not ranked") {
+		t.Errorf("table =\n%s", out.String())
+	}
+	if !strings.Contains(lines[1], testAddress(150)) || !strings.Contains(lines[1], "1010") || !strings.Contains(lines[1], "60.00%") {
+		t.Errorf("row for rank 150 = %q", lines[1])
+	}
+}
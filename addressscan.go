@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// scanAddresses makes single address lookups scan the leaderboard's pages
+// with -scan-address, for an upstream whose address filter is missing or
+// broken.
+var scanAddresses bool
+
+// scanConcurrency bounds the pages fetched at once by a scan.
+const scanConcurrency = 4
+
+// errAddressFound stops the other fetches of a scan once one page has the
+// address.
+var errAddressFound = errors.New("address found")
+
+// findUserByAddress looks for the normalized address on the pages of
+// season, fetching scanConcurrency of them at once in rank order so a
+// high-ranked wallet is found after the first few. As soon as a page has it
+// the pages still in flight are cancelled and the rest never fetched; it
+// returns only once every fetch has stopped, so nothing of the scan
+// outlives it. response is the page the user was found on.
+func findUserByAddress(ctx context.Context, season int, address string) (user User, response Response, found bool, err error) {
+	board, err := defaultClient.FetchBoard(ctx, season)
+	if err != nil {
+		return User{}, board, false, err
+	}
+	pages := (board.Data.Total + dumpPageSize - 1) / dumpPageSize
+	defaultClient.progress.AddTotal(pages)
+	defer defaultClient.progress.Finish()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var (
+		next  atomic.Int64
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	for range min(scanConcurrency, pages) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				page := int(next.Add(1)) - 1
+				if page >= pages {
+					return
+				}
+				rank := page*dumpPageSize + 1
+				r, err := defaultClient.fetchResponse(ctx, defaultClient.pageURL(season, page+defaultClient.pageBase(), dumpPageSize), timeoutForRank(rank))
+				mu.Lock()
+				switch u, ok := findAddress(r, address); {
+				case err != nil && ctx.Err() == nil:
+					first = fmt.Errorf("failed to fetch page %d: %w", page+defaultClient.pageBase(), err)
+					cancel(first)
+				case err == nil && ok && !found:
+					user, response, found = u, r, true
+					cancel(errAddressFound)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if found {
+		return user, response, true, nil
+	}
+	if first != nil {
+		return User{}, board, false, first
+	}
+	if err := context.Cause(ctx); err != nil {
+		return User{}, board, false, err
+	}
+	return User{}, board, false, nil
+}
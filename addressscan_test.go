@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFindUserByAddressStopsEarly(t *testing.T) {
+	var fetched atomic.Int32
+	board := rankedUpstream(10000)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page > 0 {
+			fetched.Add(1)
+		}
+		if page > 2 {
+			// Pages past the match only answer once given up on.
+			<-r.Context().Done()
+			return
+		}
+		board.ServeHTTP(w, r)
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		user, response, found, err := findUserByAddress(t.Context(), defaultSeason, testAddress(150))
+		if err != nil || !found || user.Rank != 150 || response.Data.Total != 10000 {
+			t.Errorf("findUserByAddress = %+v, %d users, %v, %v", user, response.Data.Total, found, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scan kept waiting for pages after the match")
+	}
+	if n := fetched.Load(); n > 2+scanConcurrency {
+		t.Errorf("fetched %d of 100 pages, want the scan stopped at the match", n)
+	}
+}
+
+// TestFindUserByAddressLeavesBreakerClosed checks the fetches a scan cancels
+// once it has the address don't count as upstream failures.
+func TestFindUserByAddressLeavesBreakerClosed(t *testing.T) {
+	board := rankedUpstream(10000)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if page, _ := strconv.Atoi(r.URL.Query().Get("page")); page > 1 {
+			<-r.Context().Done()
+			return
+		}
+		board.ServeHTTP(w, r)
+	}))
+	defaultClient.breaker.threshold = 1
+	defaultClient.breaker.cooldown = time.Hour
+
+	for range 3 {
+		if _, _, found, err := findUserByAddress(t.Context(), defaultSeason, testAddress(50)); err != nil || !found {
+			t.Fatalf("findUserByAddress = %v, %v", found, err)
+		}
+	}
+	if state := defaultClient.breaker.State(); state != breakerClosed {
+		t.Fatalf("breaker %s after successful scans, want closed", state)
+	}
+	if user, err := defaultClient.UserAtRank(t.Context(), defaultSeason, 1); err != nil || user.Rank != 1 {
+		t.Errorf("UserAtRank after the scans = %+v, %v", user, err)
+	}
+}
+
+func TestFindUserByAddressMissing(t *testing.T) {
+	var fetched atomic.Int32
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "" {
+			fetched.Add(1)
+		}
+		rankedUpstream(250).ServeHTTP(w, r)
+	}))
+	_, _, found, err := findUserByAddress(t.Context(), defaultSeason, testAddress(999))
+	if err != nil || found || fetched.Load() != 3 {
+		t.Errorf("found %v, %v after %d pages, want every page scanned", found, err, fetched.Load())
+	}
+
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, "boom", http.StatusBadRequest)
+			return
+		}
+		rankedUpstream(250).ServeHTTP(w, r)
+	}))
+	if _, _, found, err := findUserByAddress(t.Context(), defaultSeason, testAddress(999)); err == nil || found {
+		t.Errorf("found %v, %v, want the failed page reported", found, err)
+	}
+}
+
+func TestLookupAddressScans(t *testing.T) {
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("address") != "" {
+			t.Error("the address filter was used with -scan-address")
+		}
+		rankedUpstream(250).ServeHTTP(w, r)
+	}))
+	scanAddresses = true
+	t.Cleanup(func() { scanAddresses = false })
+	user, total, found, err := userByAddress(t.Context(), defaultSeason, testAddress(201))
+	if err != nil || !found || user.Rank != 201 || total != 250 {
+		t.Errorf("userByAddress = %+v, %d, %v, %v", user, total, found, err)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// auditVersion is the layout of an -audit directory that audit verify
+// reads.
+const auditVersion = 1
+
+const auditManifestFile = "manifest.json"
+
+// sensitiveHeader matches the response headers an audit leaves out, which
+// could carry credentials or session state, on top of secretKey.
+var sensitiveHeader = regexp.MustCompile(`(?i)cookie|auth|session|csrf`)
+
+// auditor saves every API call of a wallet lookup to a directory with
+// -audit, as evidence for disputes over a score: each call's raw body,
+// next to its URL, status, response headers and timestamps. finish adds a
+// manifest linking the calls to what was printed, which audit verify
+// renders again from the saved bodies alone.
+type auditor struct {
+	dir   string
+	mu    sync.Mutex
+	calls []string
+}
+
+// auditCall describes a saved API call, in the file next to its body.
+type auditCall struct {
+	URL         string      `json:"url"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	RequestedAt time.Time   `json:"requestedAt"`
+	ReceivedAt  time.Time   `json:"receivedAt"`
+	Body        string      `json:"body"`
+	BodySHA256  string      `json:"bodySHA256"`
+}
+
+// auditRender is what the printed output of a lookup depends on besides
+// the API's responses.
+type auditRender struct {
+	Season              int    `json:"season"`
+	Address             string `json:"address"`
+	Format              string `json:"format"`
+	PercentilePrecision int    `json:"percentilePrecision"`
+	NormalizeByDays     bool   `json:"normalizeByDays,omitempty"`
+	// SeasonStart is the start -normalize-by-days counted days from.
+	SeasonStart string `json:"seasonStart,omitempty"`
+	DecimalSep  string `json:"decimalSep"`
+	CompactJSON bool   `json:"compactJSON,omitempty"`
+}
+
+type auditManifest struct {
+	Version   int       `json:"version"`
+	Tool      string    `json:"tool"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Calls are the files describing each call, in the order they were
+	// made.
+	Calls  []string    `json:"calls"`
+	Render auditRender `json:"render"`
+	// Summary is the position the lookup found, and Output what was
+	// printed for it.
+	Summary      walletPosition `json:"summary"`
+	Output       string         `json:"output"`
+	OutputSHA256 string         `json:"outputSHA256"`
+}
+
+// newAuditor creates dir for an audit, refusing one that already holds
+// another so their calls don't mix.
+func newAuditor(dir string) (*auditor, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, auditManifestFile)); err == nil {
+		return nil, fmt.Errorf("%s already holds an audit", dir)
+	}
+	return &auditor{dir: dir}, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditHeader is header without the sensitive ones.
+func auditHeader(header http.Header) http.Header {
+	kept := http.Header{}
+	for name, values := range header {
+		if sensitiveHeader.MatchString(name) || secretKey.MatchString(name) {
+			continue
+		}
+		kept[name] = values
+	}
+	return kept
+}
+
+// writeJSONFile writes v to path through a temporary file, so a file is
+// either whole or missing.
+func writeJSONFile(path string, v any) error {
+	tmp, err := writeTemp(filepath.Dir(path), func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	})
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// record saves a call to url answered by resp with body, as it is made, so
+// an audit of a lookup that fails part way still has the calls before.
+// Credentials and secret query parameters are stripped from url.
+func (a *auditor) record(url string, resp *http.Response, requestedAt time.Time, body []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := len(a.calls) + 1
+	call := auditCall{
+		Status:      resp.StatusCode,
+		Header:      auditHeader(resp.Header),
+		RequestedAt: requestedAt.UTC(),
+		ReceivedAt:  time.Now().UTC(),
+		Body:        fmt.Sprintf("%03d.body", n),
+		BodySHA256:  sha256Hex(body),
+	}
+	call.URL, _ = stripURLSecrets(url)
+	if err := os.WriteFile(filepath.Join(a.dir, call.Body), body, 0o644); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%03d.json", n)
+	if err := writeJSONFile(filepath.Join(a.dir, name), call); err != nil {
+		return err
+	}
+	a.calls = append(a.calls, name)
+	return nil
+}
+
+// currentAuditRender is the render settings of this run for a lookup of
+// address in season.
+func currentAuditRender(season int, address string) auditRender {
+	render := auditRender{
+		Season:              season,
+		Address:             address,
+		Format:              outputFormat,
+		PercentilePrecision: percentilePrecision,
+		NormalizeByDays:     normalizeByDays,
+		DecimalSep:          decimalSep,
+		CompactJSON:         compactJSON,
+	}
+	if start, ok := seasonStart(season); ok && normalizeByDays {
+		render.SeasonStart = start.Format(time.DateOnly)
+	}
+	return render
+}
+
+// finish writes the manifest, linking the calls recorded to the summary of
+// the lookup and the output printed for it.
+func (a *auditor) finish(render auditRender, summary walletPosition, output []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	manifest := auditManifest{
+		Version:      auditVersion,
+		Tool:         defaultUserAgent(),
+		CreatedAt:    time.Now().UTC(),
+		Calls:        a.calls,
+		Render:       render,
+		Summary:      summary,
+		Output:       string(output),
+		OutputSHA256: sha256Hex(output),
+	}
+	return writeJSONFile(filepath.Join(a.dir, auditManifestFile), manifest)
+}
+
+// renderAudit looks the wallet of manifest up again in the saved bodies and
+// renders it with the recorded settings, returning the position and the
+// output. The settings are applied to the globals the renderers read, as
+// audit verify is a run of its own.
+func renderAudit(dir string, manifest auditManifest) (walletPosition, []byte, error) {
+	render := manifest.Render
+	address, err := normalizeAddress(render.Address)
+	if err != nil {
+		return walletPosition{}, nil, err
+	}
+	var user User
+	var board Response
+	found := false
+	for _, name := range manifest.Calls {
+		var call auditCall
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			err = json.Unmarshal(data, &call)
+		}
+		if err != nil {
+			return walletPosition{}, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		body, err := os.ReadFile(filepath.Join(dir, call.Body))
+		if err != nil {
+			return walletPosition{}, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if sum := sha256Hex(body); sum != call.BodySHA256 {
+			return walletPosition{}, nil, fmt.Errorf("%s: body %s has SHA-256 %s, want %s", name, call.Body, sum, call.BodySHA256)
+		}
+		var response Response
+		if err := defaultClient.parseJSONResponse(bytes.NewReader(body), &response); err != nil {
+			return walletPosition{}, nil, fmt.Errorf("%s: %w", call.Body, err)
+		}
+		if u, ok := findAddress(response, address); ok {
+			user, board, found = u, response, true
+		}
+	}
+	if !found {
+		return walletPosition{}, nil, fmt.Errorf("%s is in none of the saved responses", address)
+	}
+
+	percentilePrecision = render.PercentilePrecision
+	normalizeByDays = render.NormalizeByDays
+	decimalSep = render.DecimalSep
+	compactJSON = render.CompactJSON
+	if render.SeasonStart != "" {
+		start, err := time.Parse(time.DateOnly, render.SeasonStart)
+		if err != nil {
+			return walletPosition{}, nil, fmt.Errorf("seasonStart: %w", err)
+		}
+		seasonStarts[render.Season] = start
+	}
+	position := newWalletPosition(user, board.Data.Total, render.PercentilePrecision)
+	if render.NormalizeByDays {
+		position.normalize(render.Season, board.LastUpdated)
+	}
+	var output bytes.Buffer
+	if err := writeWalletPosition(&output, render.Format, position); err != nil {
+		return walletPosition{}, nil, err
+	}
+	return position, output.Bytes(), nil
+}
+
+// verifyAudit checks that the bodies saved in dir render to the summary
+// and output its manifest recorded.
+func verifyAudit(dir string) (auditManifest, error) {
+	var manifest auditManifest
+	data, err := os.ReadFile(filepath.Join(dir, auditManifestFile))
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("%s: %w", auditManifestFile, err)
+	}
+	if manifest.Version != auditVersion {
+		return manifest, fmt.Errorf("%s: version %d, want %d", auditManifestFile, manifest.Version, auditVersion)
+	}
+	position, output, err := renderAudit(dir, manifest)
+	if err != nil {
+		return manifest, err
+	}
+	if !reflect.DeepEqual(position, manifest.Summary) {
+		return manifest, fmt.Errorf("the saved responses give %+v, but the manifest records %+v", position, manifest.Summary)
+	}
+	if sum := sha256Hex(output); sum != manifest.OutputSHA256 || string(output) != manifest.Output {
+		return manifest, fmt.Errorf("the saved responses render to %q, but the manifest records %q", output, manifest.Output)
+	}
+	return manifest, nil
+}
+
+func runAudit(args []string) error {
+	const usage = "usage: audit verify <dir>"
+	if len(args) == 0 || args[0] != "verify" {
+		return errors.New(usage)
+	}
+	flags := newCommandFlags("audit verify")
+	if err := parseCommandFlags(flags, args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(usage)
+	}
+	manifest, err := verifyAudit(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("audit verify: %w", err)
+	}
+	fmt.Fprintf(stdout, "OK: %d saved responses render to the recorded output for %s in season %d (rank %s of %s, %.0f points)\n",
+		len(manifest.Calls), manifest.Render.Address, manifest.Render.Season,
+		formatThousands(manifest.Summary.Rank), formatThousands(manifest.Summary.TotalUsers), manifest.Summary.TotalScore)
+	return nil
+}
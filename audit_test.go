@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// auditLookup looks address up in a board of total with -audit dir, the way
+// main does, and returns what was printed.
+func auditLookup(t *testing.T, dir, address string, total int) string {
+	t.Helper()
+	upstream := rankedUpstream(total)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=hunter2")
+		w.Header().Set("X-Api-Token", "hunter2")
+		w.Header().Set("X-Cache", "HIT")
+		upstream.ServeHTTP(w, r)
+	}))
+	var err error
+	if defaultClient.audit, err = newAuditor(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { defaultClient.audit = nil })
+
+	user, board, found, err := lookupAddress(t.Context(), 2, address)
+	if err != nil || !found {
+		t.Fatalf("lookupAddress = %v, %v", found, err)
+	}
+	position := newWalletPosition(user, board.Data.Total, percentilePrecision)
+	var rendered bytes.Buffer
+	if err := writeWalletPosition(&rendered, outputFormat, position); err != nil {
+		t.Fatal(err)
+	}
+	if err := defaultClient.audit.finish(currentAuditRender(2, address), position, rendered.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	return rendered.String()
+}
+
+func TestAudit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "audit")
+	address := fmt.Sprintf("0x%040x", 25)
+	printed := auditLookup(t, dir, address, 1000)
+
+	manifest, err := verifyAudit(dir)
+	if err != nil {
+		t.Fatalf("verifyAudit: %v", err)
+	}
+	if manifest.Output != printed || manifest.Summary.Rank != 25 || len(manifest.Calls) != 1 {
+		t.Errorf("manifest = %+v, want the one call and what was printed", manifest)
+	}
+
+	call, err := os.ReadFile(filepath.Join(dir, "001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(call), "hunter2") || !strings.Contains(string(call), `"X-Cache"`) || !strings.Contains(string(call), "address="+address) {
+		t.Errorf("call = %s, want the URL and headers without the sensitive ones", call)
+	}
+
+	if _, err := newAuditor(dir); err == nil {
+		t.Error("newAuditor reused a directory holding an audit")
+	}
+}
+
+func TestAuditVerifyCatchesTampering(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		tamper func(dir string) error
+		want   string
+	}{
+		{"body", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "001.body"), []byte(`{"data":{"items":[]}}`), 0o644)
+		}, "SHA-256"},
+		{"output", func(dir string) error {
+			path := filepath.Join(dir, auditManifestFile)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(path, bytes.Replace(data, []byte("rank 25 of"), []byte("rank 24 of"), 1), 0o644)
+		}, "render to"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			auditLookup(t, dir, fmt.Sprintf("0x%040x", 25), 1000)
+			if err := tc.tamper(dir); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := verifyAudit(dir); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("verifyAudit = %v, want an error mentioning %q", err, tc.want)
+			}
+		})
+	}
+}
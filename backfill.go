@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+var (
+	archiveURL = "https://web.archive.org"
+	// archiveDelay is the pause between requests to archive.org, which
+	// throttles clients that hit it hard.
+	archiveDelay = 2 * time.Second
+)
+
+type archiveCapture struct {
+	Timestamp time.Time
+	Original  string
+}
+
+type backfillSummary struct {
+	Captures int
+	Usable   int
+	Imported int
+	Skipped  int
+}
+
+// archiveGet fetches url from archive.org, backing off and retrying when
+// it asks us to slow down.
+func archiveGet(ctx context.Context, url string) ([]byte, error) {
+	for attempt := 0; attempt < retryLimit; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := defaultClient.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			time.Sleep(10 * archiveDelay * time.Duration(attempt+1))
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, &StatusError{StatusCode: resp.StatusCode, Body: body}
+		}
+		return body, err
+	}
+	return nil, errors.New("archive.org kept throttling requests")
+}
+
+// listCaptures asks the Wayback Machine's CDX API for the distinct
+// successful captures of the season's leaderboard endpoint.
+func listCaptures(ctx context.Context, season int) ([]archiveCapture, error) {
+	target := strings.TrimPrefix(strings.TrimPrefix(defaultClient.leaderboardURL(season), "https://"), "http://")
+	query := neturl.Values{
+		"url":       {target},
+		"matchType": {"prefix"},
+		"output":    {"json"},
+		"fl":        {"timestamp,original"},
+		"filter":    {"statuscode:200"},
+		"collapse":  {"digest"},
+	}
+	body, err := archiveGet(ctx, archiveURL+"/cdx/search/cdx?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list captures: %w", err)
+	}
+
+	var rows [][]string
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse capture list: %w", err)
+		}
+	}
+	var captures []archiveCapture
+	// The first row is the header.
+	for i, row := range rows {
+		if i == 0 || len(row) < 2 {
+			continue
+		}
+		timestamp, err := time.Parse("20060102150405", row[0])
+		if err != nil {
+			log.Printf("Skipping capture with invalid timestamp %q", row[0])
+			continue
+		}
+		captures = append(captures, archiveCapture{Timestamp: timestamp, Original: row[1]})
+	}
+	return captures, nil
+}
+
+// legacyResponse is the flat layout some older captures use, with the items
+// and totals at the top level.
+type legacyResponse struct {
+	Items       []User `json:"items"`
+	Total       int    `json:"total"`
+	LastUpdated int64  `json:"last_updated"`
+}
+
+// parseArchivedResponse decodes a capture with the regular Response decoder,
+// falling back to the legacy layout.
+func parseArchivedResponse(data []byte) (Response, error) {
+	var response Response
+	if err := defaultClient.parseJSONResponse(bytes.NewReader(data), &response); err != nil {
+		return response, err
+	}
+	if response.Data.Total > 0 {
+		return response, nil
+	}
+
+	var legacy legacyResponse
+	if err := json.Unmarshal(data, &legacy); err == nil && legacy.Total > 0 {
+		return Response{Data: Data{Users: legacy.Items, Total: legacy.Total}, LastUpdated: legacy.LastUpdated}, nil
+	}
+	return response, errors.New("no leaderboard total in capture")
+}
+
+// snapshotFromCapture computes what cutoffs it can from the users in a
+// captured page; cutoffs whose rank isn't on the page are skipped. Captures
+// without a lastUpdated are keyed by capture time.
+func snapshotFromCapture(season int, response Response, captured time.Time) Snapshot {
+	lastUpdated := response.LastUpdated
+	if lastUpdated == 0 {
+		lastUpdated = captured.Unix()
+	}
+	total := response.Data.Total
+	snapshot := Snapshot{Season: season, TotalUsers: total, LastUpdated: lastUpdated, RefreshedAt: captured}
+	for _, percentage := range defaultClient.Percentages {
+		rank := rankForPercentage(total, percentage)
+		result := Result{Season: season, Percentage: percentage, TotalUsers: total, LastUpdated: lastUpdated, Rank: rank, Skipped: true}
+		for _, user := range response.Data.Users {
+			if defaultClient.fromAPIRank(user.Rank) == rank {
+				result.Points = int(user.TotalScore)
+				result.Skipped = false
+				break
+			}
+		}
+		snapshot.Results = append(snapshot.Results, result)
+	}
+	return snapshot
+}
+
+// backfillHistory imports the season's Wayback Machine captures into store.
+// Captures that can't be fetched or parsed are logged and skipped.
+func backfillHistory(ctx context.Context, store SnapshotStore, season int) (backfillSummary, error) {
+	var summary backfillSummary
+	captures, err := listCaptures(ctx, season)
+	if err != nil {
+		return summary, err
+	}
+	summary.Captures = len(captures)
+
+	for _, capture := range captures {
+		time.Sleep(archiveDelay)
+		stamp := capture.Timestamp.Format("20060102150405")
+		// The id_ suffix asks for the capture as archived, without the
+		// Wayback Machine's rewriting.
+		body, err := archiveGet(ctx, fmt.Sprintf("%s/web/%sid_/%s", archiveURL, stamp, capture.Original))
+		if err != nil {
+			log.Printf("Skipping capture %s: %v", stamp, err)
+			continue
+		}
+		response, err := parseArchivedResponse(body)
+		if err != nil {
+			log.Printf("Skipping capture %s: %v", stamp, err)
+			continue
+		}
+		summary.Usable++
+
+		snapshot := snapshotFromCapture(season, response, capture.Timestamp)
+		exists, err := store.Has(season, snapshot.LastUpdated)
+		if err != nil {
+			return summary, err
+		}
+		if exists {
+			summary.Skipped++
+			continue
+		}
+		if err := store.Add(snapshot); err != nil {
+			return summary, err
+		}
+		summary.Imported++
+	}
+	return summary, nil
+}
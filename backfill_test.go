@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeArchive serves a CDX listing of captures and the captures themselves.
+func fakeArchive(t *testing.T, captures map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cdx/search/cdx" {
+			if !strings.HasSuffix(r.URL.Query().Get("url"), fmt.Sprintf(leaderboardPath, defaultSeason)) {
+				t.Errorf("CDX query for %q", r.URL.Query().Get("url"))
+			}
+			rows := []string{`["timestamp","original"]`}
+			for stamp := range captures {
+				rows = append(rows, fmt.Sprintf(`[%q,"https://trailblazer.mainnet.taiko.xyz/s2/leaderboard"]`, stamp))
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(rows, ","))
+			return
+		}
+		stamp, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/web/"), "id_/")
+		body, ok := captures[stamp]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	oldURL, oldDelay := archiveURL, archiveDelay
+	archiveURL, archiveDelay = server.URL, 0
+	t.Cleanup(func() {
+		server.Close()
+		archiveURL, archiveDelay = oldURL, oldDelay
+	})
+}
+
+func TestBackfillHistory(t *testing.T) {
+	old := defaultClient.Percentages
+	defaultClient.Percentages = []float64{0.01, 0.5}
+	t.Cleanup(func() { defaultClient.Percentages = old })
+	fakeArchive(t, map[string]string{
+		// Current schema, with rank 1 (top 1% of 100) on the page.
+		"20240601120000": `{"data":{"items":[{"rank":1,"address":"0x01","totalScore":5000}],"page":1,"size":1,"total":100},"lastUpdated":1717200000}`,
+		// The same data captured again later.
+		"20240601130000": `{"data":{"items":[{"rank":1,"address":"0x01","totalScore":5000}],"page":1,"size":1,"total":100},"lastUpdated":1717200000}`,
+		// Older flat schema without lastUpdated, keyed by capture time.
+		"20240515000000": `{"items":[{"rank":50,"address":"0x32","totalScore":70}],"total":100}`,
+		"20240520000000": `<html>Bad gateway</html>`,
+		"20240521000000": `{"data":{"items":[]}}`,
+	})
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	store, err := openSnapshotStore(Config{History: filepath.Join(t.TempDir(), "history.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := backfillHistory(context.Background(), store, defaultSeason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := backfillSummary{Captures: 5, Usable: 3, Imported: 2, Skipped: 1}
+	if summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+	if n := strings.Count(logs.String(), "Skipping capture"); n != 2 {
+		t.Errorf("logged %d skipped captures, want 2:\n%s", n, logs.String())
+	}
+
+	snapshots, err := store.List(defaultSeason)
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("history has %d snapshots, %v; want 2", len(snapshots), err)
+	}
+	legacy, current := snapshots[0], snapshots[1]
+	captured := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	if legacy.LastUpdated != captured.Unix() || !legacy.RefreshedAt.Equal(captured) {
+		t.Errorf("legacy capture keyed at %d, want capture time %d", legacy.LastUpdated, captured.Unix())
+	}
+	if r := legacy.Results; !r[0].Skipped || r[1].Skipped || r[1].Points != 70 {
+		t.Errorf("legacy results = %+v, want only the top 50%% cutoff", r)
+	}
+	if r := current.Results; r[0].Skipped || r[0].Points != 5000 || !r[1].Skipped {
+		t.Errorf("current results = %+v, want only the top 1%% cutoff", r)
+	}
+}
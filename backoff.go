@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// backoffJitter spreads retry waits so that workers failing together don't
+// retry in lockstep.
+type backoffJitter struct {
+	// fraction is how far a wait may move either way: 0.2 turns a 1s wait
+	// into one between 0.8s and 1.2s.
+	fraction float64
+
+	mu sync.Mutex
+	// rng is time-seeded; tests swap in a seeded source to get exact waits.
+	rng *rand.Rand
+}
+
+// backoffMax caps every retry wait with -backoff-max, however many retries
+// came before (0 = no cap).
+var backoffMax time.Duration
+
+var retryJitter = &backoffJitter{fraction: 0.2, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// apply returns d moved by a random amount within fraction.
+func (j *backoffJitter) apply(d time.Duration) time.Duration {
+	if j.fraction <= 0 || d <= 0 {
+		return d
+	}
+	j.mu.Lock()
+	r := j.rng.Float64()
+	j.mu.Unlock()
+	return time.Duration(float64(d) * (1 + j.fraction*(2*r-1)))
+}
+
+// capBackoff returns d, or backoffMax if that is shorter.
+func capBackoff(d time.Duration) time.Duration {
+	if backoffMax > 0 && d > backoffMax {
+		return backoffMax
+	}
+	return d
+}
+
+// retryWait is the wait before the given retry of a request, counting from
+// 1: retryBackoff times the retry number, jittered and capped at
+// backoffMax.
+func retryWait(retry int) time.Duration {
+	return capBackoff(retryJitter.apply(retryBackoff * time.Duration(retry)))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// seedJitter makes retry waits reproducible for the rest of the test.
+func seedJitter(t *testing.T, seed int64) {
+	t.Helper()
+	old := retryJitter
+	retryJitter = &backoffJitter{fraction: old.fraction, rng: rand.New(rand.NewSource(seed))}
+	t.Cleanup(func() { retryJitter = old })
+}
+
+func TestRetryWaitIsReproducible(t *testing.T) {
+	seedJitter(t, 42)
+	oldBackoff := retryBackoff
+	retryBackoff = time.Second
+	t.Cleanup(func() { retryBackoff = oldBackoff })
+
+	want := []time.Duration{949211344, 1652800397, 3124912621, 3534109924}
+	for i, want := range want {
+		retry := i + 1
+		got := retryWait(retry)
+		if got != want {
+			t.Errorf("retry %d: waited %v, want %v", retry, got, want)
+		}
+		if base := time.Duration(retry) * time.Second; got < base*8/10 || got > base*12/10 {
+			t.Errorf("retry %d: %v is outside 20%% of %v", retry, got, base)
+		}
+	}
+
+	seedJitter(t, 42)
+	first := retryWait(1)
+	seedJitter(t, 42)
+	if again := retryWait(1); again != first {
+		t.Errorf("same seed waited %v then %v", first, again)
+	}
+}
+
+func TestBackoffMax(t *testing.T) {
+	seedJitter(t, 42)
+	oldBackoff, oldMax := retryBackoff, backoffMax
+	retryBackoff, backoffMax = time.Second, 2*time.Second
+	t.Cleanup(func() { retryBackoff, backoffMax = oldBackoff, oldMax })
+
+	if got := retryWait(1); got != 949211344 {
+		t.Errorf("retry 1: waited %v, want it untouched below the cap", got)
+	}
+	// Retry 2 may be jittered either side of the cap.
+	retryWait(2)
+	for retry := 3; retry <= 20; retry++ {
+		if got := retryWait(retry); got != 2*time.Second {
+			t.Errorf("retry %d: waited %v, want the 2s cap", retry, got)
+		}
+	}
+}
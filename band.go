@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// rankBand is the users between two cutoffs: below the top From and down to
+// the top To.
+type rankBand struct {
+	From       float64 `json:"from"`
+	To         float64 `json:"to"`
+	TotalUsers int     `json:"totalUsers"`
+	// FirstRank and LastRank are the ranks in the band; with no users
+	// FirstRank is past LastRank.
+	FirstRank  int     `json:"firstRank"`
+	LastRank   int     `json:"lastRank"`
+	Users      int     `json:"users"`
+	TotalScore float64 `json:"totalScore"`
+}
+
+// parseBand parses -band: two percentages, the smaller first.
+func parseBand(s string) (from, to float64, err error) {
+	fromField, toField, ok := strings.Cut(s, ",")
+	if !ok || strings.Contains(toField, ",") {
+		return 0, 0, fmt.Errorf("-band %q: want two percentages, e.g. 0.01,0.05", s)
+	}
+	if from, err = parsePercentage(fromField); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parsePercentage(toField); err != nil {
+		return 0, 0, err
+	}
+	if from >= to {
+		return 0, 0, fmt.Errorf("-band %q: the first percentage must be the smaller", s)
+	}
+	return from, to, nil
+}
+
+// computeBand counts the users ranked after the cutoff for the top from and
+// up to the cutoff for the top to, out of totalUsers, and sums their
+// TotalScore. users are in rank order.
+func computeBand(users []User, totalUsers int, from, to float64) rankBand {
+	band := rankBand{
+		From:       from,
+		To:         to,
+		TotalUsers: totalUsers,
+		FirstRank:  rankForPercentage(totalUsers, from) + 1,
+		LastRank:   min(rankForPercentage(totalUsers, to), len(users)),
+	}
+	for _, user := range users[min(band.FirstRank-1, len(users)):band.LastRank] {
+		band.Users++
+		band.TotalScore += user.TotalScore
+	}
+	return band
+}
+
+func writeBand(w io.Writer, format string, band rankBand) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(band)
+	}
+	if band.Users == 0 {
+		_, err := fmt.Fprintf(w, "top %s to %s: no users between the cutoffs\n", formatPercentage(band.From), formatPercentage(band.To))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "top %s to %s: ranks %s–%s, %s users, %.0f total points\n",
+		formatPercentage(band.From), formatPercentage(band.To), formatThousands(band.FirstRank),
+		formatThousands(band.LastRank), formatThousands(band.Users), band.TotalScore)
+	return err
+}
+
+func printBand(from, to float64) error {
+	users, board, err := fetchAllUsers(seasons[0])
+	if err != nil {
+		return err
+	}
+	return writeBand(stdout, outputFormat, computeBand(users, board.Data.Total, from, to))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBand(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	setPageSize(t, 100)
+
+	users, board, err := fetchAllUsers(defaultSeason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	band := computeBand(users, board.Data.Total, 0.01, 0.05)
+	// Ranks 11 to 50 hold 10*(1000-r+1) points each.
+	if band.FirstRank != 11 || band.LastRank != 50 || band.Users != 40 || band.TotalScore != 388200 {
+		t.Fatalf("band = %+v, want ranks 11–50 with 40 users and 388200 points", band)
+	}
+
+	var out bytes.Buffer
+	if err := writeBand(&out, "text", band); err != nil {
+		t.Fatal(err)
+	}
+	if want := "top 1% to 5%: ranks 11–50, 40 users, 388200 total points\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	// Cutoffs that round to the same rank leave nobody between them.
+	if band := computeBand(users, board.Data.Total, 0.01, 0.0105); band.Users != 0 || band.TotalScore != 0 {
+		t.Errorf("band = %+v, want it empty", band)
+	}
+}
+
+func TestParseBand(t *testing.T) {
+	if from, to, err := parseBand("0.01, 0.05"); err != nil || from != 0.01 || to != 0.05 {
+		t.Errorf("parseBand = %v, %v, %v", from, to, err)
+	}
+	for _, bad := range []string{"0.01", "0.05,0.01", "0.01,0.01", "0.01,0.05,0.1", "0,0.05"} {
+		if _, _, err := parseBand(bad); err == nil {
+			t.Errorf("parseBand(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// baseline is the -baseline percentage every cutoff is compared against, or
+// 0 for none.
+var baseline float64
+
+// checkBaseline makes sure -baseline is one of the computed percentages.
+func checkBaseline(percentages []float64) error {
+	if baseline != 0 && !slices.Contains(percentages, baseline) {
+		return fmt.Errorf("-baseline %s isn't among the computed percentages", formatPercentage(baseline))
+	}
+	return nil
+}
+
+// baselineRatio is result's points as a multiple of the points of the
+// baseline cutoff among results. ok is false without -baseline, or if
+// either cutoff was skipped or the baseline has no points.
+func baselineRatio(results []Result, result Result) (ratio float64, ok bool) {
+	if baseline == 0 || result.Skipped {
+		return 0, false
+	}
+	i := slices.IndexFunc(results, func(r Result) bool { return r.Percentage == baseline })
+	if i < 0 || results[i].Skipped || results[i].Points == 0 {
+		return 0, false
+	}
+	return float64(result.Points) / float64(results[i].Points), true
+}
+
+// formatBaselineRatio formats the ratio of result, or "" if it has none.
+func formatBaselineRatio(results []Result, result Result) string {
+	ratio, ok := baselineRatio(results, result)
+	if !ok {
+		return ""
+	}
+	return formatFloat(ratio, 2) + "x"
+}
+
+// withBaseline fills in Result.BaselineRatio for JSON output with
+// -baseline, leaving all untouched.
+func withBaseline(all []seasonResults) []seasonResults {
+	if baseline == 0 {
+		return all
+	}
+	converted := make([]seasonResults, len(all))
+	for i, season := range all {
+		season.Results = slices.Clone(season.Results)
+		for j := range season.Results {
+			season.Results[j].BaselineRatio, _ = baselineRatio(season.Results, season.Results[j])
+		}
+		converted[i] = season
+	}
+	return converted
+}
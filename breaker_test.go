@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useFakeUpstream points the package at handler for the duration of the test
+// and resets the request budget and circuit breaker around it.
+func useFakeUpstream(t *testing.T, handler http.Handler) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	oldBaseURL, oldBreaker, oldMaxRequests := defaultClient.BaseURL, defaultClient.breaker, defaultClient.MaxRequests
+	defaultClient.BaseURL = server.URL
+	defaultClient.breaker = &circuitBreaker{}
+	defaultClient.MaxRequests = 0
+	defaultClient.resetBudget()
+
+	t.Cleanup(func() {
+		server.Close()
+		defaultClient.BaseURL, defaultClient.breaker, defaultClient.MaxRequests = oldBaseURL, oldBreaker, oldMaxRequests
+		defaultClient.resetBudget()
+	})
+}
+
+// scriptedUpstream answers each request with the next status in the script
+// and with 200 once the script is used up.
+type scriptedUpstream struct {
+	mu       sync.Mutex
+	statuses []int
+	hits     atomic.Int64
+}
+
+func (s *scriptedUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.hits.Add(1)
+
+	s.mu.Lock()
+	status := http.StatusOK
+	if len(s.statuses) > 0 {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	s.mu.Unlock()
+
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	fmt.Fprint(w, `{"data":{"items":[],"page":1,"size":1,"total":100,"total_pages":100},"lastUpdated":1}`)
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	upstream := &scriptedUpstream{statuses: []int{500, 500, 503}}
+	useFakeUpstream(t, upstream)
+	defaultClient.breaker.threshold = 2
+	defaultClient.breaker.cooldown = 20 * time.Millisecond
+	url := defaultClient.leaderboardURL(defaultSeason)
+
+	for i := 0; i < 2; i++ {
+		if _, err := fetchResponse(url); err == nil {
+			t.Fatalf("request %d: expected upstream error", i+1)
+		}
+	}
+	if state := defaultClient.breaker.State(); state != breakerOpen {
+		t.Fatalf("state after %d failures = %s, want open", defaultClient.breaker.threshold, state)
+	}
+
+	if _, err := fetchResponse(url); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("request while open: err = %v, want ErrCircuitOpen", err)
+	}
+	if hits := upstream.hits.Load(); hits != 2 {
+		t.Fatalf("open breaker let a request through: %d hits, want 2", hits)
+	}
+
+	time.Sleep(defaultClient.breaker.cooldown)
+	if _, err := fetchResponse(url); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("failed probe: err = %v, want upstream error", err)
+	}
+	if state := defaultClient.breaker.State(); state != breakerOpen {
+		t.Fatalf("state after failed probe = %s, want open", state)
+	}
+
+	time.Sleep(defaultClient.breaker.cooldown)
+	if _, err := fetchResponse(url); err != nil {
+		t.Fatalf("successful probe: %v", err)
+	}
+	if state := defaultClient.breaker.State(); state != breakerClosed {
+		t.Fatalf("state after successful probe = %s, want closed", state)
+	}
+	if hits := upstream.hits.Load(); hits != 4 {
+		t.Fatalf("upstream saw %d requests, want 4", hits)
+	}
+}
+
+func TestCircuitBreakerHalfOpenRejectsConcurrentRequests(t *testing.T) {
+	breaker := &circuitBreaker{threshold: 1, cooldown: time.Millisecond}
+	breaker.failure()
+	time.Sleep(2 * breaker.cooldown)
+
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if state := breaker.State(); state != breakerHalfOpen {
+		t.Fatalf("state during probe = %s, want half-open", state)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second request during probe: err = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreakerReleasesProbeWhenBudgetExhausted(t *testing.T) {
+	upstream := &scriptedUpstream{statuses: []int{500}}
+	useFakeUpstream(t, upstream)
+	defaultClient.breaker.threshold = 1
+	defaultClient.breaker.cooldown = 10 * time.Millisecond
+	url := defaultClient.leaderboardURL(defaultSeason)
+
+	if _, err := fetchResponse(url); err == nil {
+		t.Fatal("expected upstream error")
+	}
+	time.Sleep(defaultClient.breaker.cooldown)
+
+	defaultClient.MaxRequests = defaultClient.requestCount()
+	if _, err := fetchResponse(url); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("err = %v, want ErrBudgetExhausted", err)
+	}
+
+	defaultClient.MaxRequests = 0
+	if _, err := fetchResponse(url); err != nil {
+		t.Fatalf("probe after budget rejection: %v", err)
+	}
+	if state := defaultClient.breaker.State(); state != breakerClosed {
+		t.Fatalf("state = %s, want closed", state)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// budgetPageSizes are the coarser page sizes planPages may switch to, up to
+// the largest page the API serves.
+var budgetPageSizes = []int{10, 20, 50, dumpPageSize}
+
+// pagePlan is how the ranks of a run are fetched.
+type pagePlan struct {
+	// size is the page size to fetch with; ranks sharing a page share one
+	// request.
+	size int
+	// skip marks the ranks left out because even the coarsest pages need
+	// more requests than the budget has.
+	skip []bool
+
+	requested int // the page size asked for
+	needed    int // requests needed at the requested page size
+	requests  int // requests the plan spends, without retries
+	budget    int
+	ranks     []int
+}
+
+// pagesFor counts the distinct pages of size holding ranks.
+func pagesFor(ranks []int, size int) int {
+	pages := make(map[int]bool)
+	for _, rank := range ranks {
+		pages[(rank-1)/size] = true
+	}
+	return len(pages)
+}
+
+// planPages fits fetching ranks into budget requests (-1 = unlimited). If
+// pages of size need too many, it switches to the first coarser size that
+// fits, so more ranks share a page. If none does, it fetches the first
+// budget pages at the coarsest size and skips the ranks on the others.
+func planPages(ranks []int, size, budget int) pagePlan {
+	plan := pagePlan{size: size, skip: make([]bool, len(ranks)), requested: size, budget: budget, ranks: ranks}
+	plan.needed = pagesFor(ranks, size)
+	plan.requests = plan.needed
+	if budget < 0 || plan.needed <= budget {
+		return plan
+	}
+
+	for _, coarser := range budgetPageSizes {
+		if coarser <= size {
+			continue
+		}
+		plan.size, plan.requests = coarser, pagesFor(ranks, coarser)
+		if plan.requests <= budget {
+			return plan
+		}
+	}
+
+	kept := make(map[int]bool)
+	for i, rank := range ranks {
+		page := (rank - 1) / plan.size
+		if !kept[page] && len(kept) < budget {
+			kept[page] = true
+		}
+		plan.skip[i] = !kept[page]
+	}
+	plan.requests = len(kept)
+	return plan
+}
+
+// log says what the plan reduced to fit the budget, if anything.
+func (p pagePlan) log(season int) {
+	if p.size != p.requested {
+		log.Printf("Season %d: %d ranks need %d requests with -page-size %d but the budget allows %d; fetching pages of %d instead (%d requests)",
+			season, len(p.ranks), p.needed, p.requested, p.budget, p.size, p.requests)
+	}
+	var skipped []string
+	for i, skip := range p.skip {
+		if skip {
+			skipped = append(skipped, fmt.Sprint(p.ranks[i]))
+		}
+	}
+	if len(skipped) > 0 {
+		log.Printf("Season %d: skipping ranks %s to stay within the budget", season, strings.Join(skipped, ", "))
+	}
+}
+
+// pageMemo shares page fetches between the workers of one run. A nil
+// pageMemo fetches every time.
+type pageMemo struct {
+	mu    sync.Mutex
+	pages map[string]*memoPage
+}
+
+type memoPage struct {
+	done     chan struct{}
+	response Response
+	err      error
+}
+
+func (m *pageMemo) fetch(ctx context.Context, c *Client, base, url string, perRequest time.Duration) (Response, error) {
+	if m == nil {
+		return c.fetchCachedResponse(ctx, base, url, perRequest)
+	}
+	m.mu.Lock()
+	page, ok := m.pages[url]
+	if !ok {
+		page = &memoPage{done: make(chan struct{})}
+		if m.pages == nil {
+			m.pages = make(map[string]*memoPage)
+		}
+		m.pages[url] = page
+		m.mu.Unlock()
+		page.response, page.err = c.fetchCachedResponse(ctx, base, url, perRequest)
+		close(page.done)
+		return page.response, page.err
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-page.done:
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+	c.progress.Done()
+	return page.response, page.err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPlanPages(t *testing.T) {
+	ranks := []int{1, 2, 3, 10, 500}
+	tests := []struct {
+		budget   int
+		size     int
+		requests int
+		skip     []bool
+	}{
+		{-1, 1, 5, []bool{false, false, false, false, false}},
+		{5, 1, 5, []bool{false, false, false, false, false}},
+		// Pages of 10 put ranks 1-10 on one page.
+		{2, 10, 2, []bool{false, false, false, false, false}},
+		// Not even pages of 100 fit: the last rank is dropped.
+		{1, 100, 1, []bool{false, false, false, false, true}},
+		{0, 100, 0, []bool{true, true, true, true, true}},
+	}
+	for _, tt := range tests {
+		plan := planPages(ranks, 1, tt.budget)
+		if plan.size != tt.size || plan.requests != tt.requests || !slices.Equal(plan.skip, tt.skip) {
+			t.Errorf("budget %d: size %d, %d requests, skip %v; want size %d, %d requests, skip %v",
+				tt.budget, plan.size, plan.requests, plan.skip, tt.size, tt.requests, tt.skip)
+		}
+	}
+}
+
+func setBudgetPercentages(t *testing.T) {
+	t.Helper()
+	old := defaultClient.Percentages
+	// Ranks 1, 2, 3, 10 and 500 of 1000.
+	defaultClient.Percentages = []float64{0.001, 0.002, 0.003, 0.01, 0.5}
+	t.Cleanup(func() { defaultClient.Percentages = old })
+}
+
+func TestBudgetCoarsensPages(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	setBudgetPercentages(t)
+	defaultClient.MaxRequests = 3
+
+	results, err := calculatePointsForTopUsers(defaultSeason)
+	if err != nil {
+		t.Fatalf("calculatePointsForTopUsers: %v", err)
+	}
+	if got := defaultClient.requestCount(); got != 3 {
+		t.Errorf("spent %d requests, want 3", got)
+	}
+	for _, result := range results {
+		if want := 10 * (1000 - result.Rank + 1); result.Skipped || result.Points != want {
+			t.Errorf("rank %d: points %d, skipped %v; want %d", result.Rank, result.Points, result.Skipped, want)
+		}
+	}
+}
+
+func TestBudgetSkipsWhatDoesNotFit(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	setBudgetPercentages(t)
+	defaultClient.MaxRequests = 2
+
+	results, err := calculatePointsForTopUsers(defaultSeason)
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("err = %v, want ErrBudgetExhausted", err)
+	}
+	if got := defaultClient.requestCount(); got != 2 {
+		t.Errorf("spent %d requests, want 2", got)
+	}
+	for _, result := range results {
+		if result.Skipped != (result.Rank == 500) {
+			t.Errorf("rank %d: skipped %v", result.Rank, result.Skipped)
+		}
+	}
+
+	all := []seasonResults{{Results: results}}
+	markBudgetLimited(all)
+	if !all[0].BudgetLimited || !slices.Equal(skippedCutoffs(all), []string{"s2 top 50%"}) {
+		t.Errorf("budgetLimited %v, skipped %q", all[0].BudgetLimited, skippedCutoffs(all))
+	}
+}
+
+func TestBudgetExhaustionCancelsOutstandingWork(t *testing.T) {
+	board := rankedUpstream(1000)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "1":
+			// Cut off mid-body, so it is retried until the budget runs out.
+			w.Header().Set("Content-Length", "1000")
+			fmt.Fprint(w, `{"data":{"items":[{"rank":1,`)
+		case "500":
+			// Hangs until the run gives up on it.
+			<-r.Context().Done()
+		default:
+			board.ServeHTTP(w, r)
+		}
+	}))
+	old, oldBackoff := defaultClient.Percentages, retryBackoff
+	defaultClient.Percentages, retryBackoff = []float64{0.001, 0.5}, 0
+	t.Cleanup(func() { defaultClient.Percentages, retryBackoff = old, oldBackoff })
+	// The board, both pages and one retry.
+	defaultClient.MaxRequests = 4
+
+	start := time.Now()
+	results, err := calculatePointsForTopUsers(defaultSeason)
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("err = %v, want ErrBudgetExhausted", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v: the hanging fetch wasn't cancelled", elapsed)
+	}
+	if len(results) != 2 || !results[0].Skipped || !results[1].Skipped {
+		t.Errorf("results = %+v, want both skipped", results)
+	}
+}
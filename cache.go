@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	base      string
+	response  Response
+	fetchedAt time.Time
+}
+
+// pageCache keeps fetched pages between watch cycles. Entries expire after
+// ttl and all entries of a leaderboard are dropped as soon as it reports a
+// newer lastUpdated than the one the entries were fetched under.
+type pageCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	lastUpdated map[string]int64
+	entries     map[string]cacheEntry
+}
+
+func (c *pageCache) get(url string) (Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[url]
+	if !ok {
+		return Response{}, false
+	}
+	if time.Since(entry.fetchedAt) > c.ttl {
+		delete(c.entries, url)
+		return Response{}, false
+	}
+	return entry.response, true
+}
+
+func (c *pageCache) put(base, url string, response Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if response.LastUpdated < c.lastUpdated[base] {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+	c.entries[url] = cacheEntry{base: base, response: response, fetchedAt: time.Now()}
+}
+
+func (c *pageCache) observe(base string, lastUpdated int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if lastUpdated <= c.lastUpdated[base] {
+		return
+	}
+	if c.lastUpdated == nil {
+		c.lastUpdated = make(map[string]int64)
+	}
+	c.lastUpdated[base] = lastUpdated
+	for url, entry := range c.entries {
+		if entry.base == base {
+			delete(c.entries, url)
+		}
+	}
+}
+
+func (c *Client) fetchCachedResponse(ctx context.Context, base, url string, perRequest time.Duration) (Response, error) {
+	if c.cache.ttl <= 0 {
+		return c.fetchResponse(ctx, url, perRequest)
+	}
+	if response, ok := c.cache.get(url); ok {
+		c.progress.Done()
+		return response, nil
+	}
+	response, err := c.fetchResponse(ctx, url, perRequest)
+	if err != nil {
+		return response, err
+	}
+	// With -retry-empty an empty page is a transient answer, not one to
+	// keep serving.
+	if c.RetryEmpty && len(response.Data.Users) == 0 {
+		return response, nil
+	}
+	c.cache.put(base, url, response)
+	return response, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/HeuDeaI/taikoPointsByLevel/internal/progress"
+)
+
+// defaultBaseURL is the API queried without -base-url.
+const defaultBaseURL = "https://trailblazer.mainnet.taiko.xyz"
+
+// Client queries one deployment of the leaderboard API. It holds everything
+// a request depends on: where the API is and how its pages and ranks are
+// numbered, the HTTP client, the cutoffs to compute and the request budget,
+// along with the mirror failover, circuit breaker and page cache of that
+// upstream. Clients share none of it, so two of them can query different
+// deployments side by side. Set the exported fields before first use; after
+// that a Client is safe for concurrent use.
+type Client struct {
+	// BaseURL is the API's base URL, used unless mirrors are set.
+	BaseURL    string
+	HTTPClient *http.Client
+	// Percentages are the cutoffs Cutoffs computes.
+	Percentages []float64
+
+	// MaxRequests caps the requests sent, retries included, until the
+	// budget is reset (0 = no cap).
+	MaxRequests int64
+	// PageSize is the page size used to locate a user by rank.
+	PageSize int
+	// PageBase is the index of the first page, 0 or 1, unless calibration
+	// detects otherwise; RankBase the rank the API reports for the top
+	// user, 0 or 1.
+	PageBase int
+	RankBase int
+	// RetryEmpty refetches pages that come back with no users.
+	RetryEmpty bool
+	// StrictSchema fails on responses that don't match responseSchema
+	// instead of logging a warning.
+	StrictSchema bool
+
+	requests    atomic.Int64
+	baseURLs    stringList
+	mirrors     *mirrorSet
+	breaker     *circuitBreaker
+	cache       *pageCache
+	calibration *calibration
+	// audit is nil unless -audit is set, and progress unless stderr is a
+	// terminal and -no-progress is unset.
+	audit    *auditor
+	progress *progress.Bar
+}
+
+// NewClient returns a Client for the API at baseURL computing the default
+// cutoffs one rank per page, with its own HTTP client, breaker and cache.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		HTTPClient:  &http.Client{},
+		Percentages: slices.Clone(defaultPercentages),
+		PageSize:    1,
+		PageBase:    1,
+		RankBase:    1,
+		mirrors:     &mirrorSet{},
+		breaker:     &circuitBreaker{threshold: 5, cooldown: 30 * time.Second},
+		cache:       &pageCache{},
+		calibration: &calibration{},
+	}
+}
+
+// defaultClient is the Client the command line configures.
+var defaultClient = NewClient(defaultBaseURL)
+
+// base returns the base URL requests should go to: the current mirror, or
+// BaseURL without any.
+func (c *Client) base() string {
+	if base := c.mirrors.base(); base != "" {
+		return base
+	}
+	return c.BaseURL
+}
+
+// useBaseURLs points c at the -base-url list, the first URL and then the
+// others as mirrors to fail over to.
+func (c *Client) useBaseURLs() {
+	if len(c.baseURLs) == 0 {
+		return
+	}
+	for i, u := range c.baseURLs {
+		c.baseURLs[i] = strings.TrimSuffix(u, "/")
+	}
+	c.BaseURL = c.baseURLs[0]
+	c.mirrors.set(c.baseURLs)
+}
+
+// acquireRequest takes one request from the budget, reporting false once
+// MaxRequests have been sent.
+func (c *Client) acquireRequest() bool {
+	for {
+		n := c.requests.Load()
+		if c.MaxRequests > 0 && n >= c.MaxRequests {
+			return false
+		}
+		if c.requests.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// requestCount is how many requests were sent since the budget was last
+// reset.
+func (c *Client) requestCount() int64 {
+	return c.requests.Load()
+}
+
+// resetBudget starts a new request budget, as each watch cycle does.
+func (c *Client) resetBudget() {
+	c.requests.Store(0)
+}
+
+// requestsLeft is this caller's share of what is left of MaxRequests, or
+// -1 without a budget.
+func (c *Client) requestsLeft(shares int) int {
+	if c.MaxRequests <= 0 {
+		return -1
+	}
+	return max(int(c.MaxRequests-c.requests.Load()), 0) / max(shares, 1)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestClientsDoNotShareState runs two differently configured Clients against
+// separate upstreams at once; run it with -race.
+func TestClientsDoNotShareState(t *testing.T) {
+	type upstream struct {
+		client *Client
+		hits   atomic.Int64
+		// sizes holds every page size the upstream was asked for.
+		mu    sync.Mutex
+		sizes map[string]bool
+		want  []Result
+	}
+	newUpstream := func(total int, percentages []float64, pageSize int, maxRequests int64) *upstream {
+		u := &upstream{sizes: make(map[string]bool)}
+		ranked := rankedUpstream(total)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u.hits.Add(1)
+			if size := r.URL.Query().Get("size"); size != "" {
+				u.mu.Lock()
+				u.sizes[size] = true
+				u.mu.Unlock()
+			}
+			ranked.ServeHTTP(w, r)
+		}))
+		t.Cleanup(server.Close)
+		u.client = NewClient(server.URL)
+		u.client.Percentages = percentages
+		u.client.PageSize = pageSize
+		u.client.MaxRequests = maxRequests
+		for _, percentage := range percentages {
+			rank := rankForPercentage(total, percentage)
+			u.want = append(u.want, Result{Season: defaultSeason, Percentage: percentage, TotalUsers: total,
+				LastUpdated: 1718000000, Rank: rank, Points: 10 * (total - rank + 1)})
+		}
+		return u
+	}
+	// Each Cutoffs call is the board then one page per cutoff: 3 requests
+	// for a, 2 for b, whose budget fits exactly the 10 calls made on it.
+	a := newUpstream(1000, []float64{0.01, 0.1}, 1, 0)
+	b := newUpstream(5000, []float64{0.5}, 100, 10*2)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		u := a
+		if i%2 == 1 {
+			u = b
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := u.client.Cutoffs(context.Background(), defaultSeason)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !slices.Equal(results, u.want) {
+				t.Errorf("%s: results = %+v, want %+v", u.client.BaseURL, results, u.want)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got, want := a.hits.Load(), int64(10*3); got != want || a.client.requestCount() != want {
+		t.Errorf("first upstream served %d requests and its client counted %d, want %d", got, a.client.requestCount(), want)
+	}
+	if got, want := b.hits.Load(), int64(10*2); got != want || b.client.requestCount() != want {
+		t.Errorf("second upstream served %d requests and its client counted %d, want %d", got, b.client.requestCount(), want)
+	}
+	if !a.sizes["1"] || len(a.sizes) != 1 || !b.sizes["100"] || len(b.sizes) != 1 {
+		t.Errorf("page sizes asked for: %v and %v, want only 1 and only 100", a.sizes, b.sizes)
+	}
+
+	// b's budget is spent; a's, unlimited, is not touched by it.
+	if _, err := b.client.Cutoffs(context.Background(), defaultSeason); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("second client past its budget: err = %v, want ErrBudgetExhausted", err)
+	}
+	if _, err := a.client.Cutoffs(context.Background(), defaultSeason); err != nil {
+		t.Errorf("first client after the second ran out: %v", err)
+	}
+	if !slices.Equal(a.client.Percentages, []float64{0.01, 0.1}) || !slices.Equal(b.client.Percentages, []float64{0.5}) {
+		t.Errorf("percentages changed: %v and %v", a.client.Percentages, b.client.Percentages)
+	}
+	if a.client.base() == b.client.base() {
+		t.Errorf("both clients query %s", a.client.base())
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// command is a subcommand of the tool.
+type command struct {
+	name string
+	// args is the synopsis after the name.
+	args    string
+	summary string
+	// run is nil for the modes of the default run, cutoffs, user and
+	// watch, which main parses with its own flags.
+	run func(config Config, args []string) error
+	// actions are the words run expects first, for commands that take
+	// their flags after one.
+	actions []string
+	// hidden commands work but are left out of the usage.
+	hidden bool
+}
+
+// commands are listed in the usage in this order. They are set in init, as
+// help refers back to them.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "cutoffs", args: "[flags]", summary: "print the points needed to be in each top percentage; the default without a command"},
+		{name: "user", args: "<address>... [flags]", summary: "look up the rank, points and percentile of wallets"},
+		{name: "watch", args: "<interval> [flags]", summary: "recompute the cutoffs every interval, notifying the sinks of changes"},
+		{name: "export", args: "-o file [flags]", summary: "write the leaderboard, or its top, to a JSON or Parquet file",
+			run: func(_ Config, args []string) error { return runExport(args) }},
+		{name: "diff", args: "[flags] <before.json> <after.json>", summary: "count the wallets entering and leaving the top tiers between two JSON exports",
+			run: func(_ Config, args []string) error { return runDiff(args) }},
+		{name: "top", args: "[flags]", summary: "list the users at the top of the leaderboard",
+			run: func(_ Config, args []string) error { return runTop(args) }},
+		{name: "scan", args: "-from-rank n -to-rank n [flags]", summary: "sample the points at every step of a range of ranks",
+			run: func(_ Config, args []string) error { return runScan(args) }},
+		{name: "plan", args: "-tier percentage -current-score n -daily-points n [flags]", summary: "estimate when a wallet reaches a tier",
+			run: runPlan},
+		{name: "multiplier-impact", args: "[flags]", summary: "estimate how the multipliers reorder the leaderboard",
+			run: func(_ Config, args []string) error { return runMultiplierImpact(args) }},
+		{name: "history", args: "import <dir> | list | backfill | address <address>", summary: "import, list and backfill the history of cutoffs, and follow a wallet through it",
+			run: runHistory, actions: []string{"import", "list", "backfill", "address"}},
+		{name: "report", args: "weekly [flags]", summary: "summarize a week of cutoffs, wallets and churn in Markdown or HTML",
+			run: runReport, actions: []string{"weekly"}},
+		{name: "watchlist", args: "report [flags]", summary: "report the positions of the wallets of a watchlist",
+			run: runWatchlist, actions: []string{"report"}},
+		{name: "serve", args: "[flags]", summary: "serve the cutoffs over HTTP and gRPC, refreshing them in the background",
+			run: func(_ Config, args []string) error { return runServe(args) }},
+		{name: "daemon", args: "[flags]", summary: "keep the cutoffs fresh for -get queries on a Unix socket",
+			run: func(_ Config, args []string) error { return runDaemon(args) }},
+		{name: "state", args: "export <bundle.tar.gz> | import <bundle.tar.gz>", summary: "move the -state-file and history to another machine",
+			run: func(_ Config, args []string) error { return runState(reloader.path, args) }, actions: []string{"export", "import"}},
+		{name: "audit", args: "verify <dir>", summary: "check that an -audit directory renders to the output it recorded",
+			run: func(_ Config, args []string) error { return runAudit(args) }, actions: []string{"verify"}},
+		{name: "debug", args: "fetch [flags]", summary: "fetch a raw leaderboard page",
+			run: func(_ Config, args []string) error { return runDebug(args) }, actions: []string{"fetch"}},
+		{name: "self-update", args: "[flags]", summary: "replace this binary with the latest release",
+			run: func(_ Config, args []string) error { return runSelfUpdate(args) }},
+		{name: "help", args: "[command]", summary: "show the usage of the tool or of a command",
+			run: func(_ Config, args []string) error { return runHelp(args) }},
+		{name: "record-fixtures", args: "[flags]", summary: "record live responses into testdata for the tests", hidden: true,
+			run: func(_ Config, args []string) error { return runRecordFixtures(args) }},
+	}
+}
+
+// programName is the name the usage calls the tool by.
+var programName = filepath.Base(os.Args[0])
+
+// usageOutput is where the usage of commands is written.
+var usageOutput io.Writer = os.Stderr
+
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// seasonsValue is the -season flag, parsed straight into seasons so it
+// takes effect wherever it is given.
+type seasonsValue struct{ seasons *[]int }
+
+func (v seasonsValue) String() string {
+	if v.seasons == nil {
+		return ""
+	}
+	fields := make([]string, len(*v.seasons))
+	for i, season := range *v.seasons {
+		fields[i] = fmt.Sprint(season)
+	}
+	return strings.Join(fields, ",")
+}
+
+func (v seasonsValue) Set(list string) error {
+	parsed, err := parseSeasons(list)
+	if err == nil {
+		*v.seasons = parsed
+	}
+	return err
+}
+
+// formatValue is the -format flag, checked as it is set.
+type formatValue struct{ format *string }
+
+func (v formatValue) String() string {
+	if v.format == nil {
+		return ""
+	}
+	return *v.format
+}
+
+func (v formatValue) Set(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("unknown format %q, want one of %s", format, strings.Join(outputFormats, ", "))
+	}
+	*v.format = format
+	return nil
+}
+
+// globalFlags are the flags commands share. They are defined once, here,
+// and may be given before the command, or after it among its own flags if
+// it reads them.
+var globalFlags = newGlobalFlags()
+
+func newGlobalFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("global", flag.ContinueOnError)
+	flags.Var(seasonsValue{&seasons}, "season", "comma-separated list of `seasons` to compute, e.g. 2,3")
+	flags.Var(&defaultClient.baseURLs, "base-url", "API base URL; repeat to list mirrors to fail over to, in order (default "+defaultBaseURL+")")
+	flags.Var(formatValue{&outputFormat}, "format", fmt.Sprintf("output `format`: %s", strings.Join(outputFormats, ", ")))
+	flags.BoolVar(&verbose, "verbose", false, "log the URL, status, size and user count of every request")
+	return flags
+}
+
+// newCommandFlags is the flag set of a command or of one of its actions,
+// named like "history import", whose usage shows the command's synopsis.
+func newCommandFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(usageOutput)
+	flags.Usage = func() { writeCommandUsage(flags.Output(), name, flags) }
+	return flags
+}
+
+// parseCommandFlags parses args into the flags of a command, taking the
+// named global flags, the ones the command reads, too.
+func parseCommandFlags(flags *flag.FlagSet, args []string, globals ...string) error {
+	for _, name := range globals {
+		global := globalFlags.Lookup(name)
+		flags.Var(global.Value, global.Name, global.Usage)
+		flags.Lookup(global.Name).DefValue = global.DefValue
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "base-url" && f.Value == globalFlags.Lookup("base-url").Value {
+			defaultClient.useBaseURLs()
+		}
+	})
+	return nil
+}
+
+// writeCommandUsage writes the usage of the command or action name with
+// flags.
+func writeCommandUsage(w io.Writer, name string, flags *flag.FlagSet) {
+	commandName, action, _ := strings.Cut(name, " ")
+	c, _ := lookupCommand(commandName)
+	synopsis := c.args
+	if action != "" {
+		synopsis = action + " [flags]"
+	}
+	fmt.Fprintf(w, "usage: %s %s %s\n\n%s.\n", programName, commandName, synopsis, capitalize(c.summary))
+	if c.actions != nil && action == "" {
+		fmt.Fprintf(w, "\nRun %s %s <action> -h for the flags of an action.\n", programName, commandName)
+		return
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// writeUsage writes the usage of the tool: its commands and the flags of
+// the default run.
+func writeUsage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintf(w, "usage: %s [flags] [command] [command flags]\n\n", programName)
+	fmt.Fprintln(w, "Without a command, prints the cutoffs of the top percentages.")
+	fmt.Fprintln(w, "\nCommands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		if !c.hidden {
+			fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun %s help <command> for the flags of a command. -season, -base-url, -format and -verbose\n", programName)
+	fmt.Fprintln(w, "go before the command, or after it where it lists them; the flags below go before it, or after")
+	fmt.Fprintln(w, "cutoffs, user or watch.")
+	fmt.Fprintln(w, "\nFlags:")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+// parseModeCommand parses the arguments of cutoffs, user and watch, the
+// modes of the default run, into flags, the flags of the default run:
+// flags and arguments may be mixed. It reports false for other commands,
+// which runCommand runs. Afterwards flags has no arguments left.
+func parseModeCommand(flags *flag.FlagSet, args []string) (bool, error) {
+	c, ok := lookupCommand(args[0])
+	if !ok || c.run != nil {
+		return false, nil
+	}
+	flags.Usage = func() { writeCommandUsage(flags.Output(), c.name, flags) }
+	var positional []string
+	for rest := args[1:]; len(rest) > 0; {
+		if err := flags.Parse(rest); err != nil {
+			return true, err
+		}
+		parsed := rest[:len(rest)-flags.NArg()]
+		rest = flags.Args()
+		if len(parsed) > 0 && parsed[len(parsed)-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		if len(rest) > 0 {
+			positional = append(positional, rest[0])
+			rest = rest[1:]
+		}
+	}
+	defer flags.Parse(nil)
+
+	switch c.name {
+	case "cutoffs":
+		if len(positional) > 0 {
+			return true, fmt.Errorf("cutoffs takes no arguments, got %q", positional)
+		}
+	case "user":
+		if len(positional) == 0 {
+			return true, errors.New("usage: user <address>... [flags]")
+		}
+		if flags.Lookup("address").Value.String() != "" {
+			return true, errors.New("user: give the addresses as arguments or with -address, not both")
+		}
+		return true, flags.Set("address", strings.Join(positional, ","))
+	case "watch":
+		if len(positional) > 1 {
+			return true, errors.New("usage: watch <interval> [flags]")
+		}
+		if len(positional) == 1 {
+			if err := flags.Set("watch", positional[0]); err != nil {
+				return true, fmt.Errorf("watch: invalid interval %q", positional[0])
+			}
+		}
+		if interval, _ := time.ParseDuration(flags.Lookup("watch").Value.String()); interval <= 0 {
+			return true, errors.New("usage: watch <interval> [flags]")
+		}
+	}
+	return true, nil
+}
+
+// runHelp prints the usage of the tool, or of the command args names, to
+// stdout.
+func runHelp(args []string) error {
+	old := usageOutput
+	usageOutput = stdout
+	defer func() { usageOutput = old }()
+	if len(args) == 0 {
+		writeUsage(stdout, flag.CommandLine)
+		return nil
+	}
+	c, ok := lookupCommand(args[0])
+	if !ok {
+		return fmt.Errorf("help: unknown command %q", args[0])
+	}
+	if c.run == nil {
+		writeCommandUsage(stdout, c.name, flag.CommandLine)
+		return nil
+	}
+	if err := runCommand(Config{}, []string{c.name, "-h"}); !errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useUsageOutput captures the usage of commands in a buffer, under a fixed
+// program name.
+func useUsageOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	oldOutput, oldName := usageOutput, programName
+	usageOutput, programName = &out, "taikopoints"
+	t.Cleanup(func() { usageOutput, programName = oldOutput, oldName })
+	return &out
+}
+
+// TestCommandHelp parses -h with every command, catching flags that
+// change without their help changing along in testdata/help.golden.
+func TestCommandHelp(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	t.Setenv("TAIKO_RELOAD_TOKEN", "")
+	out := useUsageOutput(t)
+
+	var all bytes.Buffer
+	for _, c := range commands {
+		if c.run == nil || c.name == "help" {
+			continue
+		}
+		out.Reset()
+		if err := runCommand(Config{}, []string{c.name, "-h"}); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("%s -h = %v, want flag.ErrHelp", c.name, err)
+		}
+		if !strings.HasPrefix(out.String(), "usage: taikopoints "+c.name+" ") || !strings.Contains(out.String(), capitalize(c.summary)) {
+			t.Errorf("%s -h printed %q, want its synopsis and summary", c.name, out.String())
+		}
+		all.WriteString(out.String() + "\n")
+	}
+	checkGolden(t, "help", all.Bytes())
+}
+
+func TestGlobalFlagsAfterCommand(t *testing.T) {
+	useUsageOutput(t)
+	setSeasons(t, 2)
+	oldFormat := outputFormat
+	t.Cleanup(func() { outputFormat = oldFormat })
+
+	if err := runCommand(Config{}, []string{"plan", "-season", "3,4", "-format", "json", "-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Fatal(err)
+	}
+	if len(seasons) != 2 || seasons[0] != 3 || outputFormat != "json" {
+		t.Errorf("seasons %v, -format %q, want the global flags given after plan", seasons, outputFormat)
+	}
+	// export has a -format of its own.
+	if err := runCommand(Config{}, []string{"export", "-format", "parquet", "-h"}); !errors.Is(err, flag.ErrHelp) || outputFormat != "json" {
+		t.Errorf("export -format parquet: %v, global -format %q, want export's own", err, outputFormat)
+	}
+	if err := runCommand(Config{}, []string{"diff", "-format", "yaml"}); err == nil || !strings.Contains(err.Error(), `unknown format "yaml"`) {
+		t.Errorf("diff -format yaml: %v", err)
+	}
+	// Commands take only the global flags they read.
+	for _, args := range [][]string{{"top", "-format", "json"}, {"self-update", "-season", "3"}, {"audit", "verify", "-base-url", "http://x", "dir"}} {
+		if err := runCommand(Config{}, args); err == nil || !strings.Contains(err.Error(), "flag provided but not defined") {
+			t.Errorf("%s: %v, want the flag rejected", strings.Join(args, " "), err)
+		}
+	}
+	if err := runCommand(Config{}, []string{"bogus"}); err == nil || !strings.Contains(err.Error(), "help") {
+		t.Errorf("bogus: %v", err)
+	}
+}
+
+func TestModeCommands(t *testing.T) {
+	useUsageOutput(t)
+	parse := func(args ...string) (*flag.FlagSet, bool, error) {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetOutput(usageOutput)
+		flags.String("address", "", "")
+		flags.Duration("watch", 0, "")
+		flags.Bool("tui", false, "")
+		mode, err := parseModeCommand(flags, args)
+		return flags, mode, err
+	}
+	value := func(flags *flag.FlagSet, name string) string { return flags.Lookup(name).Value.String() }
+
+	flags, mode, err := parse("user", "0xA", "-tui", "0xB")
+	if !mode || err != nil || value(flags, "address") != "0xA,0xB" || value(flags, "tui") != "true" || flags.NArg() != 0 {
+		t.Errorf("user 0xA -tui 0xB: %v, -address %q, -tui %s, %d args left", err, value(flags, "address"), value(flags, "tui"), flags.NArg())
+	}
+	flags, _, err = parse("user", "--", "-0xA")
+	if err != nil || value(flags, "address") != "-0xA" {
+		t.Errorf("user -- -0xA: %v, -address %q", err, value(flags, "address"))
+	}
+	if _, _, err := parse("user", "-address", "0xA", "0xB"); err == nil {
+		t.Error("user with -address and arguments succeeded")
+	}
+	flags, _, err = parse("watch", "-tui", "1h")
+	if err != nil || value(flags, "watch") != time.Hour.String() {
+		t.Errorf("watch -tui 1h: %v, -watch %s", err, value(flags, "watch"))
+	}
+	if _, _, err := parse("watch"); err == nil {
+		t.Error("watch without an interval succeeded")
+	}
+	if _, _, err := parse("cutoffs", "extra"); err == nil {
+		t.Error("cutoffs with an argument succeeded")
+	}
+	if _, mode, err := parse("cutoffs", "-h"); !mode || !errors.Is(err, flag.ErrHelp) || !strings.HasPrefix(usageOutput.(*bytes.Buffer).String(), "usage: taikopoints cutoffs [flags]") {
+		t.Errorf("cutoffs -h: %v, printed %q", err, usageOutput)
+	}
+	if _, mode, _ := parse("export", "-o", "x"); mode {
+		t.Error("export was parsed as a mode of the default run")
+	}
+}
+
+func TestUsageListsCommands(t *testing.T) {
+	var out bytes.Buffer
+	writeUsage(&out, flag.NewFlagSet("test", flag.ContinueOnError))
+	for _, c := range commands {
+		if listed := strings.Contains(out.String(), "\n  "+c.name+" "); listed == c.hidden {
+			t.Errorf("%s listed %v, hidden %v", c.name, listed, c.hidden)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+// compactJSON makes JSON output lean with -compact-json: unindented, and
+// without zero or empty fields or verboseJSONFields.
+var compactJSON bool
+
+// verboseJSONFields are left out with -compact-json. The address of a
+// position is the one the caller asked about.
+var verboseJSONFields = []string{"address"}
+
+// encodeJSON writes v as one JSON value, indented if indent is set, or
+// compacted with -compact-json.
+func encodeJSON(w io.Writer, v any, indent bool) error {
+	encoder := json.NewEncoder(w)
+	if !compactJSON {
+		if indent {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(v)
+	}
+	compacted, err := compactValue(v)
+	if err != nil {
+		return err
+	}
+	return encoder.Encode(compacted)
+}
+
+// compactValue is v as generic JSON with the fields -compact-json drops
+// removed. Numbers are kept as written so that nothing is rounded.
+func compactValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return pruneJSON(generic), nil
+}
+
+func pruneJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			value = pruneJSON(value)
+			if slices.Contains(verboseJSONFields, key) || emptyJSON(value) {
+				delete(v, key)
+				continue
+			}
+			v[key] = value
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = pruneJSON(value)
+		}
+	}
+	return v
+}
+
+// emptyJSON reports whether v is what omitempty would leave out.
+func emptyJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// gzipOutput makes output files gzip-compressed, with ".gz" appended to
+// their names.
+var gzipOutput bool
+
+func outputPath(path string) string {
+	if gzipOutput {
+		return path + ".gz"
+	}
+	return path
+}
+
+// writeOutput runs write against w, compressing on the fly with
+// -gzip-output so the output is never held in memory.
+func writeOutput(w io.Writer, write func(io.Writer) error) error {
+	if !gzipOutput {
+		return write(w)
+	}
+	gz := gzip.NewWriter(w)
+	if err := write(gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Watchlist string `yaml:"watchlist"`
+	History   string `yaml:"history"`
+	UserAgent string `yaml:"userAgent"`
+	// BaseURLs lists the API and its mirrors, in failover order.
+	BaseURLs []string `yaml:"baseURLs"`
+	// Percentages are used when neither -percentages nor -percentages-file
+	// is given.
+	Percentages []float64 `yaml:"percentages"`
+	// Timezone is the IANA time zone of -quiet-hours and digests, unless
+	// -timezone is given. Empty means local time.
+	Timezone string `yaml:"timezone"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "taikoPointsByLevel", "config.yaml")
+}
+
+// loadConfig reads the YAML config at path. A missing file at the default
+// location is not an error; an explicitly requested one is.
+func loadConfig(path string, explicit bool) (Config, error) {
+	var config Config
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	for _, p := range []*string{&config.Watchlist, &config.History} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(filepath.Dir(path), *p)
+		}
+	}
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (c Config) validate() error {
+	for _, percentage := range c.Percentages {
+		if percentage <= 0 || percentage > 1 {
+			return fmt.Errorf("percentages: %v out of range (0, 1]", percentage)
+		}
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("timezone: %w", err)
+	}
+	for _, base := range c.BaseURLs {
+		u, err := url.Parse(base)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("baseURLs: %q is not an http(s) URL", base)
+		}
+	}
+	return nil
+}
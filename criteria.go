@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// Criterion is one official tier from a published criteria file: users with
+// at least MinScore points qualify for Tier.
+type Criterion struct {
+	Tier     string `json:"tier"`
+	MinScore int    `json:"minScore"`
+}
+
+// criterionEstimate is where an official threshold falls on the current
+// leaderboard. Rank is 0 when nobody reaches the threshold.
+type criterionEstimate struct {
+	Criterion
+	Rank       int     `json:"rank"`
+	Percentile float64 `json:"percentile"`
+}
+
+// criteria holds the tiers from -criteria-file, highest threshold first.
+var criteria []Criterion
+
+// readCriteriaFile reads a JSON array of tiers and rejects files that list a
+// tier twice or give two tiers the same threshold.
+func readCriteriaFile(path string) ([]Criterion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read criteria file: %w", err)
+	}
+	var list []Criterion
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse criteria file %s: %w", path, err)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("criteria file %s lists no tiers", path)
+	}
+
+	tiers := make(map[string]int)
+	thresholds := make(map[int]string)
+	for i, criterion := range list {
+		if criterion.Tier == "" {
+			return nil, fmt.Errorf("%s: entry %d: missing tier", path, i+1)
+		}
+		if criterion.MinScore <= 0 {
+			return nil, fmt.Errorf("%s: tier %q: minScore must be positive, got %d", path, criterion.Tier, criterion.MinScore)
+		}
+		if previous, ok := tiers[criterion.Tier]; ok {
+			return nil, fmt.Errorf("%s: tier %q is listed twice (minScore %d and %d)", path, criterion.Tier, previous, criterion.MinScore)
+		}
+		if other, ok := thresholds[criterion.MinScore]; ok {
+			return nil, fmt.Errorf("%s: tiers %q and %q overlap at minScore %d", path, other, criterion.Tier, criterion.MinScore)
+		}
+		tiers[criterion.Tier] = criterion.MinScore
+		thresholds[criterion.MinScore] = criterion.Tier
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].MinScore > list[j].MinScore })
+	return list, nil
+}
+
+// tierForPoints returns the highest official tier that points qualify for,
+// or "" if they qualify for none.
+func tierForPoints(points int) string {
+	for _, criterion := range criteria {
+		if points >= criterion.MinScore {
+			return criterion.Tier
+		}
+	}
+	return ""
+}
+
+// estimateCriteria finds the rank of the last user reaching each official
+// threshold with findRankForPoints.
+func estimateCriteria(season, totalUsers int) ([]criterionEstimate, error) {
+	estimates := make([]criterionEstimate, 0, len(criteria))
+	for _, criterion := range criteria {
+		estimate := criterionEstimate{Criterion: criterion}
+		rank, _, err := findRankForPoints(season, criterion.MinScore)
+		if err != nil && !errors.Is(err, ErrAboveTopScore) {
+			return estimates, fmt.Errorf("failed to locate tier %q: %w", criterion.Tier, err)
+		}
+		if err == nil {
+			estimate.Rank = rank
+			estimate.Percentile = topPercentile(rank, totalUsers)
+		}
+		estimates = append(estimates, estimate)
+	}
+	return estimates, nil
+}
+
+// applyCriteria labels every cutoff with its official tier and adds the
+// reverse view of where each official threshold falls.
+func applyCriteria(all []seasonResults) error {
+	if criteria == nil {
+		return nil
+	}
+	for i := range all {
+		if len(all[i].Results) == 0 {
+			continue
+		}
+		for j := range all[i].Results {
+			if result := &all[i].Results[j]; !result.Skipped {
+				result.Tier = tierForPoints(result.Points)
+			}
+		}
+		estimates, err := estimateCriteria(seasons[i], all[i].Results[0].TotalUsers)
+		all[i].Criteria = estimates
+		if err != nil {
+			return fmt.Errorf("season %d: %w", seasons[i], err)
+		}
+	}
+	return nil
+}
+
+func writeCriteria(out io.Writer, season int, estimates []criterionEstimate) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "s%d tier\tmin score\trank\ttop\n", season)
+	for _, estimate := range estimates {
+		if estimate.Rank == 0 {
+			fmt.Fprintf(w, "%s\t%d\t-\tnobody\n", estimate.Tier, estimate.MinScore)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", estimate.Tier, estimate.MinScore, estimate.Rank, formatPercentage(estimate.Percentile))
+	}
+	w.Flush()
+}
+
+func tierOrNone(tier string) string {
+	if tier == "" {
+		return "none"
+	}
+	return tier
+}
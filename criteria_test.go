@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func useCriteria(t *testing.T, path string) {
+	t.Helper()
+	list, err := readCriteriaFile(path)
+	if err != nil {
+		t.Fatalf("readCriteriaFile: %v", err)
+	}
+	old := criteria
+	criteria = list
+	t.Cleanup(func() { criteria = old })
+}
+
+func TestReadCriteriaFileRejectsBadTiers(t *testing.T) {
+	for name, tc := range map[string]struct{ body, want string }{
+		"overlap":   {`[{"tier": "Gold", "minScore": 100}, {"tier": "Silver", "minScore": 100}]`, `"Gold" and "Silver" overlap at minScore 100`},
+		"duplicate": {`[{"tier": "Gold", "minScore": 100}, {"tier": "Gold", "minScore": 50}]`, `tier "Gold" is listed twice`},
+		"negative":  {`[{"tier": "Gold", "minScore": -1}]`, "must be positive"},
+		"empty":     {`[]`, "lists no tiers"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "criteria.json")
+			os.WriteFile(path, []byte(tc.body), 0o644)
+			if _, err := readCriteriaFile(path); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("readCriteriaFile = %v, want error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyCriteria(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	useCriteria(t, "testdata/criteria.json")
+	setSeasons(t, defaultSeason)
+
+	all := []seasonResults{{Results: []Result{
+		{Season: 2, Percentage: 0.01, TotalUsers: 1000, Rank: 10, Points: 9910},
+		{Season: 2, Percentage: 0.1, TotalUsers: 1000, Rank: 100, Points: 9010},
+		{Season: 2, Percentage: 0.9, TotalUsers: 1000, Rank: 900, Points: 1010},
+	}}}
+	if err := applyCriteria(all); err != nil {
+		t.Fatalf("applyCriteria: %v", err)
+	}
+
+	var tiers []string
+	for _, result := range all[0].Results {
+		tiers = append(tiers, result.Tier)
+	}
+	if strings.Join(tiers, ",") != "Gold,Silver," {
+		t.Fatalf("tiers = %q", tiers)
+	}
+
+	want := []criterionEstimate{
+		{Criterion: Criterion{"Diamond", 20000}},
+		{Criterion: Criterion{"Gold", 9900}, Rank: 11, Percentile: 0.011},
+		{Criterion: Criterion{"Silver", 5000}, Rank: 501, Percentile: 0.501},
+	}
+	for i, got := range all[0].Criteria {
+		if got.Criterion != want[i].Criterion || got.Rank != want[i].Rank || !closeTo(got.Percentile, want[i].Percentile) {
+			t.Errorf("criteria[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeJSON(&out, all, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var envelope snapshotEnvelope
+	if err := json.Unmarshal(out.Bytes(), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	season := envelope.Seasons["2"]
+	if season.Results[0].Tier != "Gold" || len(season.Criteria) != 3 || season.Criteria[1].Rank != 11 {
+		t.Fatalf("JSON is missing the criteria mappings:\n%s", out.String())
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// daemonSocket is the Unix socket -get asks a running daemon first; empty
+// to always fetch.
+var daemonSocket string
+
+const (
+	// daemonDialTimeout and daemonQueryTimeout keep a hung daemon from
+	// slowing -get down more than going to the API would.
+	daemonDialTimeout  = 100 * time.Millisecond
+	daemonQueryTimeout = time.Second
+)
+
+// defaultDaemonSocket is $XDG_RUNTIME_DIR/taikopoints.sock, or a per-user
+// socket in the temporary directory without XDG_RUNTIME_DIR.
+func defaultDaemonSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "taikopoints.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("taikopoints-%d.sock", os.Getuid()))
+}
+
+// daemonPIDPath is where the daemon listening on socket writes its PID.
+func daemonPIDPath(socket string) string {
+	return socket + ".pid"
+}
+
+// processAlive reports whether a process with pid exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// daemonAnswer answers one line of the protocol:
+//
+//	ping                  -> ok <pid>
+//	get <season> <key>    -> ok <value>, or miss <reason> to fetch instead
+//
+// and err <message> for anything else.
+func daemonAnswer(store *snapshotStore, season int, line string) string {
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 1 && fields[0] == "ping":
+		return "ok " + strconv.Itoa(os.Getpid())
+	case len(fields) == 3 && fields[0] == "get":
+		if fields[1] != strconv.Itoa(season) {
+			return fmt.Sprintf("miss serving season %d", season)
+		}
+		health, snapshot := store.health(time.Now())
+		if health.RefreshedAt == nil || health.State == stateUnavailable {
+			return "miss no fresh snapshot"
+		}
+		value, ok := snapshotValue(snapshot, fields[2])
+		if !ok {
+			return "miss not in the snapshot"
+		}
+		return "ok " + value
+	}
+	return fmt.Sprintf("err unknown request %q", line)
+}
+
+func handleDaemonConn(conn net.Conn, store *snapshotStore, season int) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for {
+		conn.SetDeadline(time.Now().Add(daemonQueryTimeout))
+		if !scanner.Scan() {
+			return
+		}
+		if _, err := fmt.Fprintln(conn, daemonAnswer(store, season, scanner.Text())); err != nil {
+			return
+		}
+	}
+}
+
+// serveDaemon answers queries on listener until ctx is done.
+func serveDaemon(ctx context.Context, listener net.Listener, store *snapshotStore, season int) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go handleDaemonConn(conn, store, season)
+	}
+}
+
+// daemonQuery sends one request line to the daemon on socket and returns
+// its answer.
+func daemonQuery(socket, request string) (string, error) {
+	conn, err := net.DialTimeout("unix", socket, daemonDialTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(daemonQueryTimeout))
+	if _, err := fmt.Fprintln(conn, request); err != nil {
+		return "", err
+	}
+	answer, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(answer, "\n"), nil
+}
+
+// daemonGet asks the daemon on socket for key of season. ok is false when
+// there is no daemon or it can't answer, and the value should be fetched.
+func daemonGet(socket string, season int, key string) (value string, ok bool) {
+	if socket == "" {
+		return "", false
+	}
+	if _, err := os.Stat(socket); err != nil {
+		return "", false
+	}
+	answer, err := daemonQuery(socket, fmt.Sprintf("get %d %s", season, key))
+	if err != nil {
+		if verbose {
+			log.Printf("Daemon at %s didn't answer, fetching instead: %v", socket, err)
+		}
+		return "", false
+	}
+	value, ok = strings.CutPrefix(answer, "ok ")
+	if !ok && verbose {
+		log.Printf("Daemon at %s can't answer %s, fetching instead: %s", socket, key, answer)
+	}
+	return value, ok
+}
+
+// claimDaemonSocket checks that no other daemon answers on socket, removing
+// the socket and PID file a dead one left behind, and listens on it. The
+// socket is only accessible to the current user.
+func claimDaemonSocket(socket string) (net.Listener, error) {
+	if answer, err := daemonQuery(socket, "ping"); err == nil {
+		pid, _ := strings.CutPrefix(answer, "ok ")
+		return nil, fmt.Errorf("daemon: already running on %s (pid %s)", socket, pid)
+	}
+	pidPath := daemonPIDPath(socket)
+	if data, err := os.ReadFile(pidPath); err == nil {
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		if processAlive(pid) {
+			log.Printf("Daemon pid %d is running but not answering on %s; taking over the socket", pid, socket)
+		} else {
+			log.Printf("Removing the socket of daemon pid %d, which is no longer running", pid)
+		}
+	}
+	if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("daemon: failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	if err := os.Chmod(socket, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("daemon: failed to write PID file: %w", err)
+	}
+	return listener, nil
+}
+
+func runDaemon(args []string) error {
+	flags := newCommandFlags("daemon")
+	socket := flags.String("socket", defaultDaemonSocket(), "Unix socket to answer queries on")
+	interval := flags.Duration("refresh", 5*time.Minute, "how often the cutoffs are recomputed")
+	maxStale := flags.Duration("max-stale", time.Hour, "stop answering once the last good snapshot is this old, so clients fetch instead (0 = answer forever)")
+	if err := parseCommandFlags(flags, args, "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+
+	listener, err := claimDaemonSocket(*socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(daemonPIDPath(*socket))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	store := &snapshotStore{maxStale: *maxStale}
+	season := seasons[0]
+	go refreshSnapshots(ctx, store, season, *interval, nil)
+
+	log.Printf("Answering queries on %s", *socket)
+	return serveDaemon(ctx, listener, store, season)
+}
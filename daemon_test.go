@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startDaemon serves store on a socket in a fresh directory for the test and
+// points -get at it.
+func startDaemon(t *testing.T, store *snapshotStore) string {
+	t.Helper()
+	// Unix socket paths are short, so avoid the long t.TempDir.
+	dir, err := os.MkdirTemp("", "taikod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "taikopoints.sock")
+
+	listener, err := claimDaemonSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- serveDaemon(ctx, listener, store, defaultSeason) }()
+	oldSocket := daemonSocket
+	daemonSocket = socket
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serveDaemon: %v", err)
+		}
+		daemonSocket = oldSocket
+	})
+	return socket
+}
+
+func TestGetFromDaemon(t *testing.T) {
+	requests := 0
+	useFakeUpstream(t, countRequests(&requests, rankedUpstream(1000)))
+	store := &snapshotStore{}
+	store.set(Snapshot{Season: defaultSeason, TotalUsers: 900, LastUpdated: 1717000000, RefreshedAt: time.Now(),
+		Results: []Result{{Percentage: 0.01, Rank: 9, Points: 1234}}})
+	socket := startDaemon(t, store)
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket permissions %v, want only the owner", perm)
+	}
+
+	for key, want := range map[string]string{"total-wallets": "900", "cutoff:0.01": "1234", "rank:0.01": "9"} {
+		var stdout, stderr bytes.Buffer
+		if code := runGet(key, nil, &stdout, &stderr); code != 0 || stdout.String() != want {
+			t.Errorf("runGet(%q) = %d, %q; want %q from the daemon", key, code, stdout.String(), want)
+		}
+	}
+	if requests != 0 {
+		t.Fatalf("answering from the daemon made %d requests", requests)
+	}
+
+	// What the snapshot doesn't hold is fetched.
+	var stdout, stderr bytes.Buffer
+	if code := runGet("cutoff:0.05", nil, &stdout, &stderr); code != 0 || stdout.String() != "9510" || requests == 0 {
+		t.Errorf("runGet(cutoff:0.05) = %d, %q after %d requests; want 9510 fetched", code, stdout.String(), requests)
+	}
+	if answer, ok := daemonGet(socket, defaultSeason+1, "total-wallets"); ok {
+		t.Errorf("the daemon answered %q for another season", answer)
+	}
+}
+
+func TestDaemonStaleSnapshot(t *testing.T) {
+	store := &snapshotStore{maxStale: time.Minute}
+	store.set(Snapshot{Season: defaultSeason, TotalUsers: 900, RefreshedAt: time.Now().Add(-time.Hour)})
+	socket := startDaemon(t, store)
+
+	if answer, ok := daemonGet(socket, defaultSeason, "total-wallets"); ok {
+		t.Errorf("the daemon answered %q from a snapshot past -max-stale", answer)
+	}
+}
+
+func TestClaimDaemonSocket(t *testing.T) {
+	socket := startDaemon(t, &snapshotStore{})
+	if _, err := claimDaemonSocket(socket); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("claiming a served socket: err = %v, want already running", err)
+	}
+
+	// A daemon that died left its socket and PID file behind.
+	dir := filepath.Dir(socket)
+	stale := filepath.Join(dir, "stale.sock")
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(daemonPIDPath(stale), []byte("999999999\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := claimDaemonSocket(stale)
+	if err != nil {
+		t.Fatalf("claiming a stale socket: %v", err)
+	}
+	listener.Close()
+	if pid, _ := os.ReadFile(daemonPIDPath(stale)); strings.TrimSpace(string(pid)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("PID file = %q, want this process", pid)
+	}
+}
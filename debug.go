@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// debugFetch is one request of debug fetch.
+type debugFetch struct {
+	url     string
+	timeout time.Duration
+	// retries is how many times a failed or 5xx request is repeated.
+	retries int
+	// noThrottle skips the circuit breaker and -max-requests.
+	noThrottle bool
+	pretty     bool
+	asCurl     bool
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// curlCommand is the curl invocation sending the same request as req.
+func curlCommand(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString("curl -sS")
+	if req.Method != "GET" {
+		b.WriteString(" -X " + req.Method)
+	}
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
+			b.WriteString(" -H " + shellQuote(key+": "+value))
+		}
+	}
+	b.WriteString(" " + shellQuote(req.URL.String()))
+	return b.String()
+}
+
+// run sends the request through the same request building as every other
+// API call and writes what came back to out: the status, response headers,
+// timing and raw body.
+func (d debugFetch) run(ctx context.Context, out io.Writer) error {
+	var lastErr error
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("Attempt %d failed (%v), retrying", attempt, lastErr)
+			if err := sleepCtx(ctx, retryWait(attempt)); err != nil {
+				return err
+			}
+		}
+		if !d.noThrottle {
+			if err := defaultClient.breaker.allow(); err != nil {
+				return err
+			}
+			if !defaultClient.acquireRequest() {
+				defaultClient.breaker.release()
+				return ErrBudgetExhausted
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+		req, _, err := newAPIRequest(attemptCtx, d.url)
+		if err != nil {
+			return err
+		}
+		if d.asCurl && attempt == 0 {
+			fmt.Fprintln(out, curlCommand(req))
+		}
+
+		start := time.Now()
+		resp, err := defaultClient.HTTPClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				if !d.noThrottle {
+					defaultClient.breaker.release()
+				}
+				return ctx.Err()
+			}
+			if !d.noThrottle {
+				defaultClient.breaker.failure()
+			}
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+		resp.Body.Close()
+		elapsed := time.Since(start)
+		if !d.noThrottle {
+			if resp.StatusCode >= http.StatusInternalServerError {
+				defaultClient.breaker.failure()
+			} else {
+				defaultClient.breaker.success()
+			}
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode >= http.StatusInternalServerError && attempt < d.retries {
+			lastErr = fmt.Errorf("status %d", resp.StatusCode)
+			continue
+		}
+		return d.write(out, resp, body, elapsed)
+	}
+	return fmt.Errorf("failed to send request: %w", lastErr)
+}
+
+func (d debugFetch) write(out io.Writer, resp *http.Response, body []byte, elapsed time.Duration) error {
+	fmt.Fprintf(out, "GET %s\n%s %s\n", d.url, resp.Proto, resp.Status)
+	keys := make([]string, 0, len(resp.Header))
+	for key := range resp.Header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, value := range resp.Header[key] {
+			fmt.Fprintf(out, "%s: %s\n", key, value)
+		}
+	}
+	fmt.Fprintf(out, "time: %s, %d bytes\n\n", elapsed.Round(time.Millisecond), len(body))
+
+	if d.pretty {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err == nil {
+			body = indented.Bytes()
+		} else {
+			log.Printf("Warning: -pretty: the body is not JSON: %v", err)
+		}
+	}
+	out.Write(body)
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+func runDebug(args []string) error {
+	if len(args) == 0 || args[0] != "fetch" {
+		return errors.New("usage: debug fetch [-page n] [-size n] [-pretty] [-as-curl]")
+	}
+	flags := newCommandFlags("debug fetch")
+	page := flags.Int("page", defaultClient.pageBase(), "leaderboard page to fetch")
+	size := flags.Int("size", defaultClient.PageSize, "page size to ask for")
+	fetch := debugFetch{}
+	flags.DurationVar(&fetch.timeout, "timeout", timeout, "request timeout")
+	flags.IntVar(&fetch.retries, "retries", 0, "retry failed and 5xx requests this many times")
+	flags.BoolVar(&fetch.noThrottle, "no-throttle", false, "bypass the circuit breaker and -max-requests")
+	flags.BoolVar(&fetch.pretty, "pretty", false, "indent a JSON body")
+	flags.BoolVar(&fetch.asCurl, "as-curl", false, "also print the equivalent curl command first")
+	if err := parseCommandFlags(flags, args[1:], "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+	if *size < 1 {
+		return errors.New("debug fetch: -size must be at least 1")
+	}
+	if fetch.retries < 0 {
+		return errors.New("debug fetch: -retries must not be negative")
+	}
+	fetch.url = defaultClient.pageURL(seasons[0], *page, *size)
+	return fetch.run(context.Background(), stdout)
+}
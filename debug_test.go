@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebugFetch(t *testing.T) {
+	var userAgents []string
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		w.Header().Set("X-Served-By", "edge-1")
+		fmt.Fprint(w, `{"data":{"items":[],"page":3,"size":50,"total":0}}`)
+	}))
+
+	fetch := debugFetch{url: defaultClient.pageURL(defaultSeason, 3, 50), timeout: time.Second, pretty: true, asCurl: true}
+	var out bytes.Buffer
+	if err := fetch.run(context.Background(), &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	curl := fmt.Sprintf("curl -sS -H 'User-Agent: %s' '%s'\n", userAgent, fetch.url)
+	if !strings.HasPrefix(got, curl) {
+		t.Errorf("want the curl command first:\n%s", got)
+	}
+	for _, want := range []string{"HTTP/1.1 200 OK\n", "X-Served-By: edge-1\n", "\n{\n  \"data\": {\n    \"items\": [],"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output is missing %q:\n%s", want, got)
+		}
+	}
+	if len(userAgents) != 1 || userAgents[0] != userAgent {
+		t.Errorf("User-Agent headers = %q, want one %q like every API request", userAgents, userAgent)
+	}
+}
+
+func TestDebugFetchRetries(t *testing.T) {
+	var calls atomic.Int32
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	oldBackoff := retryBackoff
+	retryBackoff = 0
+	t.Cleanup(func() { retryBackoff = oldBackoff })
+
+	var out bytes.Buffer
+	fetch := debugFetch{url: defaultClient.pageURL(defaultSeason, 1, 10), timeout: time.Second}
+	if err := fetch.run(context.Background(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "503 Service Unavailable") || calls.Load() != 1 {
+		t.Errorf("without -retries the 503 should be shown as is:\n%s", out.String())
+	}
+
+	out.Reset()
+	fetch.retries = 1
+	if err := fetch.run(context.Background(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "200 OK") || calls.Load() != 3 {
+		t.Errorf("want the 503 retried:\n%s", out.String())
+	}
+}
+
+func TestDebugFetchNoThrottle(t *testing.T) {
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, `{}`) }))
+	defaultClient.MaxRequests = 1
+	defaultClient.requests.Store(1)
+
+	fetch := debugFetch{url: defaultClient.pageURL(defaultSeason, 1, 10), timeout: time.Second}
+	if err := fetch.run(context.Background(), &bytes.Buffer{}); err != ErrBudgetExhausted {
+		t.Fatalf("err = %v, want %v", err, ErrBudgetExhausted)
+	}
+	fetch.noThrottle = true
+	if err := fetch.run(context.Background(), &bytes.Buffer{}); err != nil {
+		t.Fatalf("-no-throttle should bypass the budget: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"text/tabwriter"
+)
+
+// diffReport is what diff prints with -format json.
+type diffReport struct {
+	Before dumpSummary `json:"before"`
+	After  dumpSummary `json:"after"`
+	Churn  []churnRow  `json:"churn"`
+	Notes  []string    `json:"notes,omitempty"`
+}
+
+type dumpSummary struct {
+	TotalUsers  int   `json:"totalUsers"`
+	LastUpdated int64 `json:"lastUpdated"`
+	dumpCoverage
+}
+
+func summarizeDump(dump leaderboardDump) dumpSummary {
+	return dumpSummary{TotalUsers: dump.TotalUsers, LastUpdated: dump.LastUpdated, dumpCoverage: dump.dumpCoverage}
+}
+
+// runDiff compares two JSON exports: the wallets entering and leaving each
+// top tier between them, like the churn of report weekly.
+func runDiff(args []string) error {
+	flags := newCommandFlags("diff")
+	tiers := flags.String("tiers", "0.01,0.1", "comma-separated top percentages to compare")
+	allowPartial := flags.Bool("allow-partial", false, "compare exports even if they cover different prefixes of the board")
+	if err := parseCommandFlags(flags, args, "format"); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: diff [-tiers percentages] [-allow-partial] <before.json> <after.json>")
+	}
+	percentages, err := parsePercentages(*tiers)
+	if err != nil {
+		return fmt.Errorf("diff: -tiers: %w", err)
+	}
+	before, err := readDump(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := readDump(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	if err := checkCoverage(before, after, *allowPartial); err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+
+	report := diffReport{Before: summarizeDump(before), After: summarizeDump(after)}
+	report.Churn, report.Notes = churnBetween(before, after, percentages)
+	if outputFormat == "json" {
+		return encodeJSON(stdout, report, true)
+	}
+	for _, note := range report.Notes {
+		log.Print(note)
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Top\tEntered\tLeft\t")
+	for _, row := range report.Churn {
+		fmt.Fprintf(w, "%s\t%d\t%d\t\n", formatPercentage(row.Percentage), row.Entered, row.Exited)
+	}
+	w.Flush()
+	fmt.Fprintf(stdout, "%s → %s wallets\n", formatThousands(before.TotalUsers), formatThousands(after.TotalUsers))
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestDump writes an export of a board of total users whose top ranks
+// are held by addresses, in order, and returns its path.
+func writeTestDump(t *testing.T, name string, total int, coverage dumpCoverage, addresses ...string) string {
+	t.Helper()
+	board := Response{LastUpdated: 1718000000}
+	board.Data.Total = total
+	users := make([]User, len(addresses))
+	for i, address := range addresses {
+		users[i] = User{Rank: i + 1, Address: address}
+	}
+	var out bytes.Buffer
+	if err := writeDump(&out, board, coverage, users, false); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiff(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+	oldFormat := outputFormat
+	t.Cleanup(func() { outputFormat = oldFormat })
+
+	before := writeTestDump(t, "before.json", 200, dumpCoverage{TopPercent: 0.02, TopRanks: 4}, "0xa", "0xb", "0xc", "0xd")
+	after := writeTestDump(t, "after.json", 300, dumpCoverage{TopPercent: 0.02, TopRanks: 6}, "0xc", "0xe", "0xa", "0xf", "0xb", "0xd")
+	if err := runDiff([]string{"-format", "json", before, after}); err != nil {
+		t.Fatal(err)
+	}
+	var report diffReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	// The top 1% is ranks 1-2 before and 1-3 after: 0xc and 0xe entered,
+	// 0xb left. Neither export holds the whole top 10%.
+	if len(report.Churn) != 1 || report.Churn[0] != (churnRow{Percentage: 0.01, Entered: 2, Exited: 1}) {
+		t.Errorf("churn = %+v", report.Churn)
+	}
+	if len(report.Notes) != 1 || !strings.Contains(report.Notes[0], "top 10%") || report.After.TopRanks != 6 {
+		t.Errorf("report = %+v", report)
+	}
+
+	full := writeTestDump(t, "full.json", 200, dumpCoverage{}, "0xa", "0xb")
+	if err := runDiff([]string{full, after}); err == nil || !strings.Contains(err.Error(), "-allow-partial") {
+		t.Errorf("diff of the whole board and its top: %v", err)
+	}
+	out.Reset()
+	outputFormat = "text"
+	if err := runDiff([]string{"-allow-partial", "-tiers", "0.01", full, after}); err != nil || !strings.Contains(out.String(), "200 → 300 wallets") {
+		t.Errorf("diff -allow-partial: %v, printed %q", err, out.String())
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+)
+
+// dumpPageSize is the page size used to walk the whole leaderboard.
+const dumpPageSize = 100
+
+type leaderboardDump struct {
+	TotalUsers  int   `json:"totalUsers"`
+	LastUpdated int64 `json:"lastUpdated"`
+	dumpCoverage
+	Users []User `json:"users"`
+}
+
+// dumpCoverage is the prefix of the board an export holds: the top
+// TopPercent, if set, or the top TopRanks ranks. Both are zero for the
+// whole board. Once an export is taken TopRanks is the effective boundary,
+// the last rank it holds.
+type dumpCoverage struct {
+	TopPercent float64 `json:"topPercent,omitempty"`
+	TopRanks   int     `json:"topRanks,omitempty"`
+}
+
+func (c dumpCoverage) partial() bool {
+	return c.TopPercent > 0 || c.TopRanks > 0
+}
+
+// resolve is c on a board of total users, with TopRanks set to its
+// boundary.
+func (c dumpCoverage) resolve(total int) (dumpCoverage, error) {
+	if c.TopPercent > 0 {
+		rank, err := defaultClient.cutoffRank(total, c.TopPercent, dumpPageSize)
+		if err != nil {
+			return c, err
+		}
+		c.TopRanks = rank
+	}
+	c.TopRanks = min(c.TopRanks, total)
+	return c, nil
+}
+
+// matches reports whether c and other were asked for the same prefix:
+// the same top percentage, whose boundaries differ as the board grows, or
+// else the same ranks.
+func (c dumpCoverage) matches(other dumpCoverage) bool {
+	if c.TopPercent > 0 || other.TopPercent > 0 {
+		return c.TopPercent == other.TopPercent
+	}
+	return c.TopRanks == other.TopRanks
+}
+
+// covers reports whether a board of total users taken with c holds every
+// rank in its top percentage.
+func (c dumpCoverage) covers(total int, percentage float64) bool {
+	return !c.partial() || rankForPercentage(total, percentage) <= c.TopRanks
+}
+
+func (c dumpCoverage) String() string {
+	switch {
+	case c.TopPercent > 0:
+		return fmt.Sprintf("the top %s (%s ranks)", formatPercentage(c.TopPercent), formatThousands(c.TopRanks))
+	case c.TopRanks > 0:
+		return fmt.Sprintf("the top %s ranks", formatThousands(c.TopRanks))
+	}
+	return "the whole board"
+}
+
+// fetchAllUsers walks every page of the leaderboard in rank order.
+func fetchAllUsers(season int) ([]User, Response, error) {
+	users, board, _, err := fetchTopUsers(season, dumpCoverage{})
+	return users, board, err
+}
+
+// fetchTopUsers walks the pages of the leaderboard in rank order up to the
+// boundary of top, which it returns.
+func fetchTopUsers(season int, top dumpCoverage) ([]User, Response, dumpCoverage, error) {
+	// users grows with the pages rather than being sized up front from the
+	// total the API reports, which a partial dump doesn't reach and which
+	// nothing bounds.
+	var users []User
+	board, coverage, err := walkTopUsers(season, top, func(board Response, page []User) error {
+		users = append(users, page...)
+		return nil
+	})
+	return users, board, coverage, err
+}
+
+// walkUsers fetches every page of the leaderboard in rank order and hands
+// each one to visit, so callers that stream the users never hold more than a
+// page of them. The page slice is reused between calls.
+func walkUsers(season int, visit func(board Response, page []User) error) (Response, error) {
+	board, _, err := walkTopUsers(season, dumpCoverage{}, visit)
+	return board, err
+}
+
+// walkTopUsers is walkUsers stopping at the boundary of top on the board
+// fetched, which it returns: a boundary of n ranks takes exactly
+// ceil(n/dumpPageSize) pages, the last one cut at the boundary.
+func walkTopUsers(season int, top dumpCoverage, visit func(board Response, page []User) error) (Response, dumpCoverage, error) {
+	defaultClient.calibratePages(season)
+	board, err := defaultClient.FetchBoard(context.Background(), season)
+	if err != nil {
+		return board, top, err
+	}
+	limit := board.Data.Total
+	if top.partial() {
+		if top, err = top.resolve(board.Data.Total); err != nil {
+			return board, top, err
+		}
+		limit = top.TopRanks
+	}
+
+	defaultClient.progress.AddTotal((limit + dumpPageSize - 1) / dumpPageSize)
+	defer defaultClient.progress.Finish()
+
+	users := make([]User, 0, dumpPageSize)
+	for page, seen := defaultClient.pageBase(), 0; seen < limit; page++ {
+		url := defaultClient.pageURL(season, page, dumpPageSize)
+		size := 0
+		err := defaultClient.fetchDecode(context.Background(), url, timeoutForRank(seen+1), maxExportResponseBytes, func(body io.Reader) (int, error) {
+			users = users[:0]
+			var err error
+			size, err = decodeUsers(body, func(user User) error {
+				checkLeaderboardAddress(user)
+				user.Rank = defaultClient.fromAPIRank(user.Rank)
+				if want := seen + len(users) + 1; user.Rank != want {
+					return fmt.Errorf("%w: expected rank %d on page %d but API returned rank %d", ErrRankMismatch, want, page, user.Rank)
+				}
+				users = append(users, user)
+				return nil
+			})
+			return len(users), err
+		})
+		if err != nil {
+			return board, top, fmt.Errorf("failed to fetch page %d: %w", page, err)
+		}
+		checkPageFill(season, page, dumpPageSize, size, len(users), seen+len(users) >= board.Data.Total)
+		if len(users) == 0 {
+			return board, top, fmt.Errorf("page %d is empty after %d of %d users", page, seen, board.Data.Total)
+		}
+		if over := seen + len(users) - limit; over > 0 && top.partial() {
+			users = users[:len(users)-over]
+		}
+		if err := visit(board, users); err != nil {
+			return board, top, err
+		}
+		seen += len(users)
+	}
+	return board, top, nil
+}
+
+// checkPageFill logs the size the API reported for a fetched page and the
+// users it actually held with -verbose, and warns whenever a page other than
+// the last one holds a different number of users than requested: the API is
+// then paging differently than asked, for instance by capping the page
+// size, and a walk of the board will under- or over-collect.
+func checkPageFill(season, page, requested, reported, users int, final bool) {
+	if verbose {
+		log.Printf("Season %d page %d: size %d (requested %d), %d users", season, page, reported, requested, users)
+	}
+	if users != requested && !final {
+		log.Printf("Warning: season %d page %d has %d users, not the %d requested (API size %d), and it isn't the last page", season, page, users, requested, reported)
+	}
+}
+
+// decodeUsers reads a leaderboard response token by token, calling visit for
+// each user in data.items as it is decoded instead of buffering the page. It
+// returns data.size, or 0 if the response has none.
+func decodeUsers(body io.Reader, visit func(User) error) (size int, err error) {
+	dec := json.NewDecoder(body)
+	if defaultClient.StrictSchema {
+		dec.DisallowUnknownFields()
+	}
+	err = decodeObject(dec, func(key string) error {
+		if key != "data" {
+			return skipValue(dec)
+		}
+		return decodeObject(dec, func(key string) error {
+			if key == "size" {
+				return dec.Decode(&size)
+			}
+			if key != "items" {
+				return skipValue(dec)
+			}
+			if err := expectDelim(dec, '['); err != nil {
+				return err
+			}
+			for i := 0; dec.More(); i++ {
+				var user User
+				if err := dec.Decode(&user); err != nil {
+					return err
+				}
+				if err := defaultClient.reportSchemaViolations(defaultClient.checkUser(fmt.Sprintf("data.items[%d]", i), user)); err != nil {
+					return err
+				}
+				if err := visit(user); err != nil {
+					return err
+				}
+			}
+			return expectDelim(dec, ']')
+		})
+	})
+	return size, err
+}
+
+// decodeObject calls field for every key of the JSON object at the decoder's
+// position; field must consume the value.
+func decodeObject(dec *json.Decoder, field func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected an object key, got %v", token)
+		}
+		if err := field(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != want {
+		return fmt.Errorf("expected %v, got %v", want, token)
+	}
+	return nil
+}
+
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+	return dec.Decode(&raw)
+}
+
+// writeDump encodes the dump one user at a time rather than marshalling the
+// whole leaderboard into memory first. The output decodes as a
+// leaderboardDump.
+func writeDump(out io.Writer, board Response, coverage dumpCoverage, users []User, pretty bool) error {
+	w := bufio.NewWriter(out)
+	indent, nl := "", ""
+	if pretty {
+		indent, nl = "  ", "\n"
+	}
+
+	fmt.Fprintf(w, "{%s%s\"totalUsers\":%s%d,%s%s\"lastUpdated\":%s%d,",
+		nl, indent, space(pretty), board.Data.Total, nl, indent, space(pretty), board.LastUpdated)
+	if coverage.TopPercent > 0 {
+		fmt.Fprintf(w, "%s%s\"topPercent\":%s%s,", nl, indent, space(pretty), strconv.FormatFloat(coverage.TopPercent, 'g', -1, 64))
+	}
+	if coverage.TopRanks > 0 {
+		fmt.Fprintf(w, "%s%s\"topRanks\":%s%d,", nl, indent, space(pretty), coverage.TopRanks)
+	}
+	fmt.Fprintf(w, "%s%s\"users\":%s[", nl, indent, space(pretty))
+	for i, user := range users {
+		var data []byte
+		var err error
+		if pretty {
+			data, err = json.MarshalIndent(user, indent+indent, indent)
+		} else {
+			data, err = json.Marshal(user)
+		}
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, "%s%s%s", nl, indent+indent, data)
+	}
+	if len(users) > 0 {
+		fmt.Fprintf(w, "%s%s", nl, indent)
+	}
+	fmt.Fprintf(w, "]%s}\n", nl)
+	return w.Flush()
+}
+
+func space(pretty bool) string {
+	if pretty {
+		return " "
+	}
+	return ""
+}
+
+func dumpJSON(path string, pretty bool) error {
+	users, board, err := fetchAllUsers(seasons[0])
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(outputPath(path))
+	if err != nil {
+		return fmt.Errorf("failed to create dump: %w", err)
+	}
+	err = writeOutput(file, func(w io.Writer) error { return writeDump(w, board, dumpCoverage{}, users, pretty) })
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write dump: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDumpRoundTrips(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(250))
+
+	users, board, err := fetchAllUsers(defaultSeason)
+	if err != nil {
+		t.Fatalf("fetchAllUsers: %v", err)
+	}
+	if len(users) != 250 || users[249].Rank != 250 {
+		t.Fatalf("got %d users, want 250 in rank order", len(users))
+	}
+
+	for _, pretty := range []bool{false, true} {
+		t.Run(fmt.Sprint("pretty=", pretty), func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeDump(&out, board, dumpCoverage{}, users, pretty); err != nil {
+				t.Fatalf("writeDump: %v", err)
+			}
+			var dump leaderboardDump
+			if err := json.Unmarshal(out.Bytes(), &dump); err != nil {
+				t.Fatalf("dump is not valid JSON: %v\n%s", err, out.String()[:200])
+			}
+			if dump.TotalUsers != 250 || dump.LastUpdated != 1718000000 || len(dump.Users) != 250 || dump.Users[9] != users[9] {
+				t.Fatalf("dump = %d users, total %d, lastUpdated %d", len(dump.Users), dump.TotalUsers, dump.LastUpdated)
+			}
+			if pretty != bytes.Contains(out.Bytes(), []byte("\n  ")) {
+				t.Fatalf("pretty=%v but output is\n%s", pretty, out.String()[:200])
+			}
+		})
+	}
+
+	var empty bytes.Buffer
+	if err := writeDump(&empty, Response{}, dumpCoverage{}, nil, true); err != nil || !json.Valid(empty.Bytes()) {
+		t.Fatalf("empty dump is invalid: %v\n%s", err, empty.String())
+	}
+}
+
+func TestFetchAllUsersChecksRanks(t *testing.T) {
+	// Serving page p+1 for page p shifts every rank by one page, as a
+	// 0-based API would look to 1-based requests.
+	board := rankedUpstream(250)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if page := r.URL.Query().Get("page"); page != "" {
+			n, _ := strconv.Atoi(page)
+			query := r.URL.Query()
+			query.Set("page", strconv.Itoa(n+1))
+			r.URL.RawQuery = query.Encode()
+		}
+		board.ServeHTTP(w, r)
+	}))
+
+	users, _, err := fetchAllUsers(defaultSeason)
+	if !errors.Is(err, ErrRankMismatch) {
+		t.Fatalf("fetchAllUsers err = %v, want ErrRankMismatch", err)
+	}
+	if len(users) != 0 {
+		t.Fatalf("kept %d users from a shifted page", len(users))
+	}
+}
+
+func TestFetchTopUsersOfHugeBoard(t *testing.T) {
+	// A top-ranks dump of a board claiming more users than fit in memory
+	// holds only the ranks asked for.
+	useFakeUpstream(t, rankedUpstream(math.MaxInt/16))
+	users, _, _, err := fetchTopUsers(defaultSeason, dumpCoverage{TopRanks: 150})
+	if err != nil || len(users) != 150 || users[149].Rank != 150 {
+		t.Fatalf("fetchTopUsers = %d users, %v, want the top 150", len(users), err)
+	}
+}
+
+func TestDumpJSONGzip(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(250))
+	gzipOutput = true
+	t.Cleanup(func() { gzipOutput = false })
+
+	path := filepath.Join(t.TempDir(), "dump.json")
+	if err := dumpJSON(path, false); err != nil {
+		t.Fatalf("dumpJSON: %v", err)
+	}
+	file, err := os.Open(path + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("dump is not gzip: %v", err)
+	}
+	var dump leaderboardDump
+	if err := json.NewDecoder(gz).Decode(&dump); err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Users) != 250 {
+		t.Fatalf("decompressed dump has %d users", len(dump.Users))
+	}
+}
+
+func TestWalkUsersWarnsAboutShortPages(t *testing.T) {
+	// The API caps pages at 60 users whatever size is asked for.
+	board := rankedUpstream(250)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if size := r.URL.Query().Get("size"); size != "" && size != "1" {
+			query := r.URL.Query()
+			query.Set("size", "60")
+			r.URL.RawQuery = query.Encode()
+		}
+		board.ServeHTTP(w, r)
+	}))
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	verbose = true
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		verbose = false
+	})
+
+	users, _, err := fetchAllUsers(defaultSeason)
+	if err != nil || len(users) != 250 {
+		t.Fatalf("fetchAllUsers = %d users, %v", len(users), err)
+	}
+	if strings.Contains(logs.String(), "page 5 has") {
+		t.Errorf("warned about the last page:\n%s", logs.String())
+	}
+	for _, want := range []string{
+		"Season 2 page 1: size 60 (requested 100), 60 users",
+		"Season 2 page 5: size 60 (requested 100), 10 users",
+		"Warning: season 2 page 1 has 60 users, not the 100 requested (API size 60), and it isn't the last page",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs lack %q:\n%s", want, logs.String())
+		}
+	}
+}
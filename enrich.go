@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// enrichConcurrency bounds the RPC lookups in flight at once.
+const enrichConcurrency = 4
+
+// enricher annotates wallet lookups and top with on-chain activity from
+// -enrich-rpc; nil when that isn't set.
+var enricher *rpcEnricher
+
+// addressActivity is what the chain says about an address. A nil field is
+// unknown because the RPC call for it failed.
+type addressActivity struct {
+	IsContract *bool   `json:"is_contract"`
+	TxCount    *uint64 `json:"tx_count"`
+}
+
+func (a addressActivity) contractCSV() string {
+	if a.IsContract == nil {
+		return ""
+	}
+	return strconv.FormatBool(*a.IsContract)
+}
+
+func (a addressActivity) contractText() string {
+	switch {
+	case a.IsContract == nil:
+		return "unknown"
+	case *a.IsContract:
+		return "yes"
+	}
+	return "no"
+}
+
+func (a addressActivity) txCountCSV() string {
+	if a.TxCount == nil {
+		return ""
+	}
+	return strconv.FormatUint(*a.TxCount, 10)
+}
+
+func (a addressActivity) txCountText() string {
+	if a.TxCount == nil {
+		return "unknown"
+	}
+	return formatThousands(int(*a.TxCount))
+}
+
+// rateLimiter spaces calls at least interval apart.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := now
+	if l.next.After(now) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	return sleepCtx(ctx, at.Sub(now))
+}
+
+// rpcEnricher looks up addresses over Ethereum JSON-RPC, caching each
+// address for the rest of the run. It has its own rate limit and doesn't
+// count against -max-requests, which budgets the leaderboard API.
+type rpcEnricher struct {
+	url     string
+	client  *http.Client
+	limiter *rateLimiter
+
+	mu    sync.Mutex
+	cache map[string]addressActivity
+}
+
+func newRPCEnricher(url string, perSecond float64) *rpcEnricher {
+	return &rpcEnricher{
+		url:     url,
+		client:  &http.Client{Timeout: timeout},
+		limiter: &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)},
+		cache:   make(map[string]addressActivity),
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	Result string `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// call runs method on address at the latest block and returns its hex
+// string result.
+func (e *rpcEnricher) call(ctx context.Context, method, address string) (string, error) {
+	if err := e.limiter.wait(ctx); err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: []any{address, "latest"}})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: unexpected status code: %d", method, resp.StatusCode)
+	}
+
+	var response rpcResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&response); err != nil {
+		return "", fmt.Errorf("%s: %w", method, err)
+	}
+	if response.Error != nil {
+		return "", fmt.Errorf("%s: RPC error %d: %s", method, response.Error.Code, response.Error.Message)
+	}
+	if !strings.HasPrefix(response.Result, "0x") {
+		return "", fmt.Errorf("%s: malformed result %q", method, response.Result)
+	}
+	return response.Result, nil
+}
+
+// lookup returns the activity of address. A failed call leaves its field
+// unknown rather than failing the run.
+func (e *rpcEnricher) lookup(ctx context.Context, address string) addressActivity {
+	address = strings.ToLower(address)
+	e.mu.Lock()
+	activity, ok := e.cache[address]
+	e.mu.Unlock()
+	if ok {
+		return activity
+	}
+
+	if code, err := e.call(ctx, "eth_getCode", address); err != nil {
+		log.Printf("Warning: -enrich-rpc: %s: %v", address, err)
+	} else {
+		isContract := code != "0x"
+		activity.IsContract = &isContract
+	}
+	if nonce, err := e.call(ctx, "eth_getTransactionCount", address); err != nil {
+		log.Printf("Warning: -enrich-rpc: %s: %v", address, err)
+	} else if count, err := strconv.ParseUint(strings.TrimPrefix(nonce, "0x"), 16, 64); err != nil {
+		log.Printf("Warning: -enrich-rpc: %s: eth_getTransactionCount: malformed result %q", address, nonce)
+	} else {
+		activity.TxCount = &count
+	}
+
+	e.mu.Lock()
+	e.cache[address] = activity
+	e.mu.Unlock()
+	return activity
+}
+
+// enrich looks up every address with at most enrichConcurrency lookups at
+// once. The result is keyed by lowercase address.
+func (e *rpcEnricher) enrich(addresses []string) map[string]addressActivity {
+	activities := make([]addressActivity, len(addresses))
+	sem := make(chan struct{}, enrichConcurrency)
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			activities[i] = e.lookup(context.Background(), address)
+		}()
+	}
+	wg.Wait()
+
+	byAddress := make(map[string]addressActivity, len(addresses))
+	for i, address := range addresses {
+		byAddress[strings.ToLower(address)] = activities[i]
+	}
+	return byAddress
+}
+
+// activityColumns are the -enrich-rpc columns for users, looked up by rank
+// since the addresses may be redacted by the time they are written.
+func activityColumns(byRank map[int]addressActivity) []userColumn {
+	return []userColumn{
+		{"is_contract", "is_contract", "contract",
+			func(u User) string { return byRank[u.Rank].contractCSV() },
+			func(u User) string { return byRank[u.Rank].contractText() }},
+		{"tx_count", "tx_count", "txs",
+			func(u User) string { return byRank[u.Rank].txCountCSV() },
+			func(u User) string { return byRank[u.Rank].txCountText() }},
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	contractAddress = "0x1111111111111111111111111111111111111111"
+	walletAddress   = "0x2222222222222222222222222222222222222222"
+	brokenAddress   = "0x3333333333333333333333333333333333333333"
+)
+
+// fakeRPC answers eth_getCode and eth_getTransactionCount, failing every
+// call for brokenAddress. It counts calls per address and the most calls
+// in flight at once.
+type fakeRPC struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	inFlight int
+	peak     int
+}
+
+func (f *fakeRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request rpcRequest
+	json.NewDecoder(r.Body).Decode(&request)
+	address := request.Params[0].(string)
+
+	f.mu.Lock()
+	f.calls[address]++
+	f.inFlight++
+	f.peak = max(f.peak, f.inFlight)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	switch {
+	case address == brokenAddress:
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"header not found"}}`)
+	case request.Method == "eth_getCode" && address == contractAddress:
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x6080604052"}`)
+	case request.Method == "eth_getCode":
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x"}`)
+	case address == contractAddress:
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+	default:
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x4d2"}`)
+	}
+}
+
+func useFakeRPC(t *testing.T) *fakeRPC {
+	t.Helper()
+	rpc := &fakeRPC{calls: make(map[string]int)}
+	server := httptest.NewServer(rpc)
+	t.Cleanup(server.Close)
+	enricher = newRPCEnricher(server.URL, 1000)
+	t.Cleanup(func() { enricher = nil })
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return rpc
+}
+
+func TestEnrichAddresses(t *testing.T) {
+	rpc := useFakeRPC(t)
+
+	var addresses []string
+	for i := range 10 {
+		addresses = append(addresses, fmt.Sprintf("0x%040x", i+16))
+	}
+	addresses = append(addresses, contractAddress, walletAddress, brokenAddress)
+
+	enricher.enrich(addresses)
+	activity := enricher.enrich(addresses)
+
+	contract := activity[contractAddress]
+	if contract.IsContract == nil || !*contract.IsContract || contract.TxCount == nil || *contract.TxCount != 1 {
+		t.Errorf("contract = %s/%s, want yes/1", contract.contractText(), contract.txCountText())
+	}
+	wallet := activity[walletAddress]
+	if wallet.IsContract == nil || *wallet.IsContract || wallet.TxCount == nil || *wallet.TxCount != 1234 {
+		t.Errorf("wallet = %s/%s, want no/1234", wallet.contractText(), wallet.txCountText())
+	}
+	if broken := activity[brokenAddress]; broken.IsContract != nil || broken.TxCount != nil {
+		t.Errorf("broken = %s/%s, want unknown/unknown", broken.contractText(), broken.txCountText())
+	}
+
+	for address, calls := range rpc.calls {
+		if calls != 2 {
+			t.Errorf("%s looked up with %d calls, want 2 (cached after the first enrich)", address, calls)
+		}
+	}
+	if rpc.peak > enrichConcurrency {
+		t.Errorf("%d calls in flight at once, want at most %d", rpc.peak, enrichConcurrency)
+	}
+}
+
+func TestWriteTopEnriched(t *testing.T) {
+	useFakeRPC(t)
+	users := []User{
+		{Rank: 1, Address: contractAddress, TotalScore: 30},
+		{Rank: 2, Address: walletAddress, TotalScore: 20},
+		{Rank: 3, Address: brokenAddress, TotalScore: 10},
+	}
+	activity := enricher.enrich([]string{contractAddress, walletAddress, brokenAddress})
+
+	var csv bytes.Buffer
+	if err := writeTop(&csv, "csv", users, activity); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if want := "rank,address,score,multiplier,total_score,is_contract,tx_count"; lines[0] != want {
+		t.Errorf("header = %q, want %q", lines[0], want)
+	}
+	for i, suffix := range []string{",true,1", ",false,1234", ",,"} {
+		if !strings.HasSuffix(lines[i+1], suffix) {
+			t.Errorf("row %d = %q, want suffix %q", i+1, lines[i+1], suffix)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeTop(&out, "json", users, activity); err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows[0]["is_contract"] != true || rows[1]["tx_count"] != 1234.0 || rows[2]["is_contract"] != nil {
+		t.Errorf("JSON rows = %v", rows)
+	}
+
+	out.Reset()
+	writeTop(&out, "json", users, nil)
+	if strings.Contains(out.String(), "is_contract") {
+		t.Errorf("JSON without enrichment has the enrichment columns:\n%s", out.String())
+	}
+}
+
+func TestWalletPositionEnriched(t *testing.T) {
+	useFakeRPC(t)
+	position := newWalletPosition(User{Rank: 7, Address: walletAddress, TotalScore: 100}, 100, 2)
+
+	var out bytes.Buffer
+	writeWalletPosition(&out, "json", position)
+	if strings.Contains(out.String(), "tx_count") {
+		t.Errorf("JSON without enrichment has tx_count:\n%s", out.String())
+	}
+
+	activity := enricher.lookup(t.Context(), walletAddress)
+	position.addressActivity = &activity
+	out.Reset()
+	writeWalletPosition(&out, "json", position)
+	if !strings.Contains(out.String(), `"is_contract": false`) || !strings.Contains(out.String(), `"tx_count": 1234`) {
+		t.Errorf("JSON is missing the enrichment:\n%s", out.String())
+	}
+	out.Reset()
+	writeWalletPosition(&out, "text", position)
+	if !strings.Contains(out.String(), "contract: no, transactions: 1,234") {
+		t.Errorf("text is missing the enrichment:\n%s", out.String())
+	}
+}
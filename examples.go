@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"text/tabwriter"
+)
+
+// maxExampleRequests caps the extra requests -examples may spend, on top of
+// whatever is left of -max-requests.
+const maxExampleRequests = 200
+
+// sampleRequestLimit is how many requests sampling may spend: at most
+// maxExampleRequests, and no more than is left of -max-requests.
+func sampleRequestLimit() int {
+	limit := maxExampleRequests
+	if defaultClient.MaxRequests > 0 {
+		limit = min(limit, int(defaultClient.MaxRequests-defaultClient.requestCount()))
+	}
+	return max(limit, 0)
+}
+
+// band is the range of ranks between two consecutive cutoffs, with the
+// example users sampled from it.
+type band struct {
+	Percentage    float64
+	FromRank      int
+	ToRank        int
+	Users         []User
+	AvgMultiplier float64
+}
+
+// sampleBands fetches n users at uniformly random ranks within each band
+// between the cutoffs in results. Bands are sampled in order with rng, so the
+// same seed gives the same ranks.
+func sampleBands(season int, results []Result, n int, rng *rand.Rand) ([]band, error) {
+	limit := sampleRequestLimit()
+	if n*len(results) > limit {
+		capped := limit / len(results)
+		if capped == 0 {
+			return nil, fmt.Errorf("%w: %d requests left, not enough for one example per band", ErrBudgetExhausted, limit)
+		}
+		log.Printf("Warning: -examples %d across %d bands would take %d requests, sampling %d per band instead",
+			n, len(results), n*len(results), capped)
+		n = capped
+	}
+
+	var bands []band
+	from := 1
+	for _, result := range results {
+		if result.Skipped || result.Rank < from {
+			continue
+		}
+		b := band{Percentage: result.Percentage, FromRank: from, ToRank: result.Rank}
+		from = result.Rank + 1
+
+		multipliers := 0
+		for range n {
+			rank := b.FromRank + rng.Intn(b.ToRank-b.FromRank+1)
+			user, err := defaultClient.UserAtRank(context.Background(), season, rank)
+			if err != nil {
+				return bands, fmt.Errorf("failed to sample rank %d: %w", rank, err)
+			}
+			b.Users = append(b.Users, user)
+			multipliers += user.Multiplier
+		}
+		if len(b.Users) > 0 {
+			b.AvgMultiplier = float64(multipliers) / float64(len(b.Users))
+		}
+		bands = append(bands, b)
+	}
+	return bands, nil
+}
+
+// writeExamples prints the sampled users per band. Addresses are always
+// redacted, with -redact-secret when it is set and a per-run key otherwise.
+func writeExamples(out io.Writer, bands []band) {
+	redactor := redact
+	if redactor == nil {
+		redactor = newRedactor("")
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "top\tranks\tavg multiplier\trank\taddress\ttotal score\tmultiplier")
+	for _, b := range bands {
+		fmt.Fprintf(w, "%s\t%d-%d\t%s\t\t\t\t\n", formatPercentage(b.Percentage), b.FromRank, b.ToRank, formatFloat(b.AvgMultiplier, 2))
+		for _, user := range b.Users {
+			fmt.Fprintf(w, "\t\t\t%d\t%s\t%.0f\t%dx\n", user.Rank, redactor.address(user.Address), user.TotalScore, user.Multiplier)
+		}
+	}
+	w.Flush()
+}
+
+func printExamples(n int, seed int64) error {
+	results, err := calculatePointsForTopUsers(seasons[0])
+	if err != nil {
+		return err
+	}
+	log.Printf("Sampling examples with -seed %d", seed)
+	bands, err := sampleBands(seasons[0], results, n, rand.New(rand.NewSource(seed)))
+	writeExamples(stdout, bands)
+	return err
+}
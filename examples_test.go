@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSampleBands(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	results := []Result{{Percentage: 0.01, Rank: 10}, {Percentage: 0.1, Rank: 100}}
+
+	bands, err := sampleBands(defaultSeason, results, 5, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Fatalf("sampleBands: %v", err)
+	}
+	if len(bands) != 2 || bands[0].FromRank != 1 || bands[0].ToRank != 10 || bands[1].FromRank != 11 || bands[1].ToRank != 100 {
+		t.Fatalf("bands = %+v", bands)
+	}
+	for _, b := range bands {
+		if len(b.Users) != 5 || b.AvgMultiplier != 1 {
+			t.Errorf("band %d-%d has %d users and avg multiplier %v", b.FromRank, b.ToRank, len(b.Users), b.AvgMultiplier)
+		}
+		for _, user := range b.Users {
+			if user.Rank < b.FromRank || user.Rank > b.ToRank {
+				t.Errorf("rank %d sampled outside band %d-%d", user.Rank, b.FromRank, b.ToRank)
+			}
+		}
+	}
+
+	again, err := sampleBands(defaultSeason, results, 5, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range bands {
+		for j := range bands[i].Users {
+			if bands[i].Users[j].Rank != again[i].Users[j].Rank {
+				t.Fatal("the same seed should sample the same ranks")
+			}
+		}
+	}
+}
+
+func TestSampleBandsRespectsBudget(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	defaultClient.MaxRequests = 7
+	results := []Result{{Percentage: 0.01, Rank: 10}, {Percentage: 0.1, Rank: 100}}
+
+	bands, err := sampleBands(defaultSeason, results, 50, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("sampleBands: %v", err)
+	}
+	if len(bands[0].Users) != 3 || len(bands[1].Users) != 3 {
+		t.Fatalf("sampled %d and %d users, want 3 per band within a budget of 7", len(bands[0].Users), len(bands[1].Users))
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+var exportFormats = []string{"json", "parquet"}
+
+var parquetCodecs = map[string]compress.Codec{
+	"snappy": &parquet.Snappy,
+	"zstd":   &parquet.Zstd,
+	"gzip":   &parquet.Gzip,
+	"none":   &parquet.Uncompressed,
+}
+
+// parquetUser is a User row in a Parquet export. The types are narrowed to
+// what the API actually returns so analytics tools don't have to guess.
+type parquetUser struct {
+	Rank       int64   `parquet:"rank"`
+	Address    string  `parquet:"address"`
+	Score      float64 `parquet:"score"`
+	Multiplier int32   `parquet:"multiplier"`
+	TotalScore float64 `parquet:"totalScore"`
+}
+
+// Keys of the file-level metadata in a Parquet export.
+const (
+	parquetSeasonKey      = "taiko.season"
+	parquetTotalUsersKey  = "taiko.totalUsers"
+	parquetLastUpdatedKey = "taiko.lastUpdated"
+	parquetExportedAtKey  = "taiko.exportedAt"
+	// The coverage of an export of the top of the board, left out of
+	// one of the whole board.
+	parquetTopPercentKey = "taiko.topPercent"
+	parquetTopRanksKey   = "taiko.topRanks"
+)
+
+// writeParquet streams the users of season within top to out as Parquet. Rows are
+// written page by page and the writer flushes a row group every
+// rowGroupSize rows, so memory stays bounded by one row group however large
+// the leaderboard is.
+func writeParquet(out io.Writer, season int, top dumpCoverage, rowGroupSize int64, codec compress.Codec, now time.Time) (int, error) {
+	writer := parquet.NewGenericWriter[parquetUser](out,
+		parquet.MaxRowsPerRowGroup(rowGroupSize),
+		parquet.Compression(codec),
+		parquet.CreatedBy("taikoPointsByLevel", buildVersion(), ""),
+	)
+
+	rows := make([]parquetUser, 0, dumpPageSize)
+	written := 0
+	board, coverage, err := walkTopUsers(season, top, func(_ Response, page []User) error {
+		rows = rows[:0]
+		for _, user := range page {
+			rows = append(rows, parquetUser{
+				Rank:       int64(user.Rank),
+				Address:    user.Address,
+				Score:      user.Score,
+				Multiplier: int32(user.Multiplier),
+				TotalScore: user.TotalScore,
+			})
+		}
+		n, err := writer.Write(rows)
+		written += n
+		return err
+	})
+	if err != nil {
+		return written, err
+	}
+
+	writer.SetKeyValueMetadata(parquetSeasonKey, strconv.Itoa(season))
+	writer.SetKeyValueMetadata(parquetTotalUsersKey, strconv.Itoa(board.Data.Total))
+	writer.SetKeyValueMetadata(parquetLastUpdatedKey, strconv.FormatInt(board.LastUpdated, 10))
+	writer.SetKeyValueMetadata(parquetExportedAtKey, now.UTC().Format(time.RFC3339))
+	if coverage.TopPercent > 0 {
+		writer.SetKeyValueMetadata(parquetTopPercentKey, strconv.FormatFloat(coverage.TopPercent, 'g', -1, 64))
+	}
+	if coverage.TopRanks > 0 {
+		writer.SetKeyValueMetadata(parquetTopRanksKey, strconv.Itoa(coverage.TopRanks))
+	}
+	return written, writer.Close()
+}
+
+// exportUsers writes the users of season within top, every user if it is
+// zero, to path in format. The file is written under a temporary name and
+// renamed into place, so a failed export never leaves a truncated file
+// behind.
+func exportUsers(path, format string, season int, top dumpCoverage, rowGroupSize int64, codec compress.Codec) error {
+	tmp, err := writeTemp(filepath.Dir(path), func(w io.Writer) error {
+		if format == "json" {
+			users, board, coverage, err := fetchTopUsers(season, top)
+			if err != nil {
+				return err
+			}
+			return writeDump(w, board, coverage, users, false)
+		}
+		n, err := writeParquet(w, season, top, rowGroupSize, codec, time.Now())
+		if err == nil {
+			log.Printf("Exported %d users to %s", n, path)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	flags := newCommandFlags("export")
+	format := flags.String("format", "json", fmt.Sprintf("output format: %s", strings.Join(exportFormats, ", ")))
+	path := flags.String("o", "", "file to write the export to")
+	rowGroupSize := flags.Int64("row-group-size", 50000, "rows per Parquet row group")
+	compression := flags.String("compression", "snappy", "Parquet compression: snappy, zstd, gzip or none")
+	topPercent := flags.Float64("top-percent", 0, "export only the top `percent` of the board, such as 2 for the top 2%, with the cutoff rank taken from the current total")
+	topRanks := flags.Int("top-ranks", 0, "export only the top `n` ranks")
+	if err := parseCommandFlags(flags, args, "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("export: -o is required")
+	}
+	if !slices.Contains(exportFormats, *format) {
+		return fmt.Errorf("export: unknown -format %q, want one of %s", *format, strings.Join(exportFormats, ", "))
+	}
+	codec, ok := parquetCodecs[*compression]
+	if !ok {
+		return fmt.Errorf("export: unknown -compression %q", *compression)
+	}
+	if *rowGroupSize < 1 {
+		return errors.New("export: -row-group-size must be at least 1")
+	}
+	if *topPercent != 0 && *topRanks != 0 {
+		return errors.New("export: -top-percent and -top-ranks are exclusive")
+	}
+	if *topPercent < 0 || *topPercent > 100 {
+		return fmt.Errorf("export: -top-percent %v out of range (0, 100]", *topPercent)
+	}
+	if *topRanks < 0 {
+		return errors.New("export: -top-ranks must be at least 1")
+	}
+	top := dumpCoverage{TopPercent: *topPercent / 100, TopRanks: *topRanks}
+	return exportUsers(*path, *format, seasons[0], top, *rowGroupSize, codec)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestExportParquetRoundTrips(t *testing.T) {
+	users := make([]User, 250)
+	for i := range users {
+		users[i] = User{
+			Rank:       i + 1,
+			Address:    testAddress(i + 1),
+			Score:      float64(5000-i) + 0.25,
+			Multiplier: 100 + i%3*50,
+			TotalScore: float64(10000 - i*2),
+		}
+	}
+	useFakeUpstream(t, usersUpstream(users))
+
+	path := filepath.Join(t.TempDir(), "users.parquet")
+	for name, codec := range parquetCodecs {
+		if err := exportUsers(path, "parquet", defaultSeason, dumpCoverage{}, 100, codec); err != nil {
+			t.Fatalf("%s: exportUsers: %v", name, err)
+		}
+
+		rows, err := parquet.ReadFile[parquetUser](path)
+		if err != nil {
+			t.Fatalf("%s: reading back: %v", name, err)
+		}
+		if len(rows) != len(users) {
+			t.Fatalf("%s: read %d rows, want %d", name, len(rows), len(users))
+		}
+		for i, row := range rows {
+			user := users[i]
+			want := parquetUser{int64(user.Rank), user.Address, user.Score, int32(user.Multiplier), user.TotalScore}
+			if row != want {
+				t.Fatalf("%s: row %d = %+v, want %+v", name, i, row, want)
+			}
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, _ := file.Stat()
+		pf, err := parquet.OpenFile(file, info.Size())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if groups := len(pf.RowGroups()); groups != 3 {
+			t.Errorf("%s: %d row groups, want 3 of at most 100 rows", name, groups)
+		}
+		for key, want := range map[string]string{
+			parquetSeasonKey:      "2",
+			parquetTotalUsersKey:  "250",
+			parquetLastUpdatedKey: "1718000000",
+		} {
+			if got, _ := pf.Lookup(key); got != want {
+				t.Errorf("%s: metadata %s = %q, want %q", name, key, got, want)
+			}
+		}
+		if _, ok := pf.Lookup(parquetExportedAtKey); !ok {
+			t.Errorf("%s: missing %s", name, parquetExportedAtKey)
+		}
+		file.Close()
+	}
+}
+
+func TestExportFailureLeavesNoFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.parquet")
+
+	// Serve the board but fail the second page.
+	board := rankedUpstream(250)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, "boom", http.StatusBadRequest)
+			return
+		}
+		board.ServeHTTP(w, r)
+	}))
+
+	if err := exportUsers(path, "parquet", defaultSeason, dumpCoverage{}, 100, &parquet.Snappy); err == nil {
+		t.Fatal("export succeeded despite a failed page")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("failed export left %s behind", entries[0].Name())
+	}
+}
+
+func TestExportTopPrefix(t *testing.T) {
+	pages := 0
+	board := rankedUpstream(30000)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("size") == strconv.Itoa(dumpPageSize) {
+			pages++
+		}
+		board.ServeHTTP(w, r)
+	}))
+	dir := t.TempDir()
+
+	for _, tc := range []struct {
+		top        dumpCoverage
+		ranks      int
+		pages      int
+		topPercent string
+	}{
+		{dumpCoverage{TopPercent: 0.02}, 600, 6, "0.02"},
+		{dumpCoverage{TopRanks: 250}, 250, 3, ""},
+	} {
+		pages = 0
+		path := filepath.Join(dir, "top.json")
+		if err := exportUsers(path, "json", defaultSeason, tc.top, 100, &parquet.Snappy); err != nil {
+			t.Fatal(err)
+		}
+		dump, err := readDump(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages != tc.pages || len(dump.Users) != tc.ranks || dump.Users[tc.ranks-1].Rank != tc.ranks {
+			t.Errorf("%+v: fetched %d pages for %d users, want %d pages for %d", tc.top, pages, len(dump.Users), tc.pages, tc.ranks)
+		}
+		if dump.TotalUsers != 30000 || dump.TopRanks != tc.ranks || dump.TopPercent != tc.top.TopPercent {
+			t.Errorf("%+v: dump coverage %+v of %d users", tc.top, dump.dumpCoverage, dump.TotalUsers)
+		}
+
+		path = filepath.Join(dir, "top.parquet")
+		if err := exportUsers(path, "parquet", defaultSeason, tc.top, 100, &parquet.Snappy); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, _ := file.Stat()
+		pf, err := parquet.OpenFile(file, info.Size())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := pf.Lookup(parquetTopRanksKey); got != strconv.Itoa(tc.ranks) || pf.NumRows() != int64(tc.ranks) {
+			t.Errorf("%+v: %s = %q with %d rows, want %d", tc.top, parquetTopRanksKey, got, pf.NumRows(), tc.ranks)
+		}
+		if got, _ := pf.Lookup(parquetTopPercentKey); got != tc.topPercent {
+			t.Errorf("%+v: %s = %q, want %q", tc.top, parquetTopPercentKey, got, tc.topPercent)
+		}
+		file.Close()
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// userColumn is how a User field is written to CSV and table output. field
+// is the field's JSON name, which -fields selects by.
+type userColumn struct {
+	field       string
+	csvHeader   string
+	tableHeader string
+	csv         func(User) string
+	table       func(User) string
+}
+
+var userColumns = []userColumn{
+	{"rank", "rank", "rank",
+		func(u User) string { return strconv.Itoa(u.Rank) },
+		func(u User) string { return formatThousands(u.Rank) }},
+	{"address", "address", "address",
+		func(u User) string { return u.Address },
+		func(u User) string { return u.Address }},
+	{"score", "score", "score",
+		func(u User) string { return formatFloat(u.Score, -1) },
+		func(u User) string { return formatFloat(u.Score, 0) }},
+	{"multiplier", "multiplier", "multiplier",
+		func(u User) string { return strconv.Itoa(u.Multiplier) },
+		func(u User) string { return strconv.Itoa(u.Multiplier) }},
+	{"totalScore", "total_score", "total score",
+		func(u User) string { return formatFloat(u.TotalScore, -1) },
+		func(u User) string { return formatFloat(u.TotalScore, 0) }},
+}
+
+// userFields holds the columns selected with -fields, in the order given;
+// nil means all of them.
+var userFields []userColumn
+
+// parseFields selects columns by User field name, e.g.
+// "rank,address,totalScore".
+func parseFields(list string) ([]userColumn, error) {
+	var columns []userColumn
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		column, ok := findUserColumn(name)
+		if !ok {
+			names := make([]string, len(userColumns))
+			for i, column := range userColumns {
+				names[i] = column.field
+			}
+			return nil, fmt.Errorf("unknown field %q, want one of %s", name, strings.Join(names, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("field %q is listed twice", name)
+		}
+		seen[name] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func findUserColumn(field string) (userColumn, bool) {
+	for _, column := range userColumns {
+		if column.field == field {
+			return column, true
+		}
+	}
+	return userColumn{}, false
+}
+
+func selectedUserColumns() []userColumn {
+	if userFields != nil {
+		return userFields
+	}
+	return userColumns
+}
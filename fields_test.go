@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUserColumnsCoverUser(t *testing.T) {
+	userType := reflect.TypeFor[User]()
+	if userType.NumField() != len(userColumns) {
+		t.Fatalf("User has %d fields but there are %d columns", userType.NumField(), len(userColumns))
+	}
+	for i := range userType.NumField() {
+		name := strings.Split(userType.Field(i).Tag.Get("json"), ",")[0]
+		if _, ok := findUserColumn(name); !ok {
+			t.Errorf("User field %s has no column", name)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	columns, err := parseFields("totalScore, rank,address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userFields = columns
+	t.Cleanup(func() { userFields = nil })
+
+	users := []User{{Rank: 1234, Address: "0xabc", Score: 10, Multiplier: 2, TotalScore: 20.5}}
+	var out bytes.Buffer
+	writeUsersCSV(&out, users, selectedUserColumns())
+	if want := "total_score,rank,address\n20.5,1234,0xabc\n"; out.String() != want {
+		t.Errorf("csv = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	writeUsersTable(&out, users, selectedUserColumns())
+	if want := "  total score   rank  address\n           20  1,234  0xabc  \n"; out.String() != want {
+		t.Errorf("table = %q, want %q", out.String(), want)
+	}
+
+	for _, list := range []string{"rank,points", "rank,rank", ""} {
+		if _, err := parseFields(list); err == nil {
+			t.Errorf("parseFields(%q) succeeded", list)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Fill modes for history -fill.
+const (
+	fillNone        = "none"
+	fillPrevious    = "previous"
+	fillInterpolate = "interpolate"
+)
+
+func parseFillMode(s string) (string, error) {
+	switch s {
+	case fillNone, fillPrevious, fillInterpolate:
+		return s, nil
+	}
+	return "", fmt.Errorf("-fill %q: want interpolate, previous or none", s)
+}
+
+// fillSnapshots returns the snapshots of all, oldest first, updated in
+// [from, to], with a synthesized one at the start of every step-long
+// interval of the range that has no real snapshot. previous repeats the last
+// real snapshot before the interval; interpolate draws a line between it and
+// the first real snapshot after, so it leaves the intervals past the newest
+// snapshot empty. Snapshots before from still serve as the previous one, so
+// a gap at the start of the range is filled when the store goes back
+// further. A zero from starts the range at the oldest snapshot.
+func fillSnapshots(all []Snapshot, from, to time.Time, step time.Duration, mode string) []Snapshot {
+	if len(all) == 0 {
+		return nil
+	}
+	if from.IsZero() {
+		from = time.Unix(all[0].LastUpdated, 0)
+	}
+	var filled []Snapshot
+	for _, snapshot := range all {
+		at := time.Unix(snapshot.LastUpdated, 0)
+		if !at.Before(from) && !at.After(to) {
+			filled = append(filled, snapshot)
+		}
+	}
+	if mode == fillNone || step <= 0 {
+		return filled
+	}
+
+	for start := from; !start.After(to); start = start.Add(step) {
+		end := start.Add(step)
+		// The index of the first real snapshot at or after start.
+		i, _ := slices.BinarySearchFunc(all, start.Unix(), func(s Snapshot, t int64) int {
+			return cmp.Compare(s.LastUpdated, t)
+		})
+		if i < len(all) && all[i].LastUpdated < end.Unix() {
+			continue
+		}
+		if i == 0 {
+			continue
+		}
+		before := all[i-1]
+		switch {
+		case mode == fillPrevious:
+			filled = append(filled, carrySnapshot(before, start.Unix()))
+		case i < len(all):
+			filled = append(filled, interpolateSnapshots(before, all[i], start.Unix()))
+		}
+	}
+	slices.SortStableFunc(filled, func(a, b Snapshot) int { return cmp.Compare(a.LastUpdated, b.LastUpdated) })
+	return filled
+}
+
+// carrySnapshot is snapshot repeated at lastUpdated.
+func carrySnapshot(snapshot Snapshot, lastUpdated int64) Snapshot {
+	snapshot.LastUpdated, snapshot.Synthesized = lastUpdated, true
+	snapshot.Results = slices.Clone(snapshot.Results)
+	for i := range snapshot.Results {
+		snapshot.Results[i].LastUpdated = lastUpdated
+	}
+	return snapshot
+}
+
+// interpolateSnapshots is the snapshot at lastUpdated on the line from
+// before to after. Only the cutoffs and tracked addresses both have are
+// kept.
+func interpolateSnapshots(before, after Snapshot, lastUpdated int64) Snapshot {
+	f := float64(lastUpdated-before.LastUpdated) / float64(after.LastUpdated-before.LastUpdated)
+	lerp := func(a, b int) int { return int(math.Round(float64(a) + f*float64(b-a))) }
+
+	snapshot := Snapshot{
+		Season:      before.Season,
+		TotalUsers:  lerp(before.TotalUsers, after.TotalUsers),
+		LastUpdated: lastUpdated,
+		Synthesized: true,
+	}
+	for _, result := range before.Results {
+		j := slices.IndexFunc(after.Results, func(r Result) bool { return r.Percentage == result.Percentage })
+		if result.Skipped || j < 0 || after.Results[j].Skipped {
+			continue
+		}
+		result.LastUpdated, result.TotalUsers = lastUpdated, snapshot.TotalUsers
+		result.Rank = lerp(result.Rank, after.Results[j].Rank)
+		result.Points = lerp(result.Points, after.Results[j].Points)
+		snapshot.Results = append(snapshot.Results, result)
+	}
+	for _, address := range before.Tracked {
+		if slices.Contains(after.Tracked, address) {
+			snapshot.Tracked = append(snapshot.Tracked, address)
+		}
+	}
+	for _, user := range before.Users {
+		j := slices.IndexFunc(after.Users, func(u User) bool { return u.Address == user.Address })
+		if j < 0 {
+			continue
+		}
+		user.Rank = lerp(user.Rank, after.Users[j].Rank)
+		user.Score += f * (after.Users[j].Score - user.Score)
+		user.TotalScore += f * (after.Users[j].TotalScore - user.TotalScore)
+		snapshot.Users = append(snapshot.Users, user)
+	}
+	return snapshot
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFillSnapshots(t *testing.T) {
+	day := int64(24 * 60 * 60)
+	base := int64(1717200000)
+	snapshot := func(d int64, users, points int) Snapshot {
+		return Snapshot{LastUpdated: base + d*day, TotalUsers: users,
+			Results: []Result{{Percentage: 0.1, LastUpdated: base + d*day, Points: points}}}
+	}
+	// Days 0, 2.5 and 5 are recorded and the range is days 1 to 7: day 1
+	// is missing at the start, filled from day 0 before the range, days 3
+	// and 4 in the middle and days 6 and 7 at the end.
+	all := []Snapshot{snapshot(0, 100, 1000), snapshot(2, 120, 1200), snapshot(5, 150, 1500)}
+	all[1].LastUpdated += day / 2
+	all[1].Results[0].LastUpdated = all[1].LastUpdated
+	from, to := time.Unix(base+day, 0), time.Unix(base+7*day, 0)
+
+	type point struct {
+		day         float64
+		users       int
+		points      int
+		synthesized bool
+	}
+	points := func(snapshots []Snapshot) []point {
+		var got []point
+		for _, s := range snapshots {
+			got = append(got, point{float64(s.LastUpdated-base) / float64(day), s.TotalUsers, s.Results[0].Points, s.Synthesized})
+		}
+		return got
+	}
+	for _, tt := range []struct {
+		mode string
+		want []point
+	}{
+		{fillNone, []point{{2.5, 120, 1200, false}, {5, 150, 1500, false}}},
+		{fillPrevious, []point{
+			{1, 100, 1000, true}, {2.5, 120, 1200, false}, {3, 120, 1200, true}, {4, 120, 1200, true},
+			{5, 150, 1500, false}, {6, 150, 1500, true}, {7, 150, 1500, true},
+		}},
+		// Interpolation can't reach past the newest snapshot, so the end
+		// of the range stays empty.
+		{fillInterpolate, []point{
+			{1, 108, 1080, true}, {2.5, 120, 1200, false}, {3, 126, 1260, true}, {4, 138, 1380, true},
+			{5, 150, 1500, false},
+		}},
+	} {
+		got := points(fillSnapshots(all, from, to, 24*time.Hour, tt.mode))
+		if len(got) != len(tt.want) {
+			t.Errorf("-fill %s: got %v, want %v", tt.mode, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("-fill %s: got %v, want %v", tt.mode, got, tt.want)
+				break
+			}
+		}
+	}
+
+	// Nothing before the range, nothing to fill its start with.
+	if got := points(fillSnapshots(all[1:], from, to, 24*time.Hour, fillPrevious)); got[0].day != 2.5 {
+		t.Errorf("-fill previous without an earlier snapshot starts at day %v, want the first real one", got[0].day)
+	}
+}
+
+func TestAddressHistoryMarksSynthesized(t *testing.T) {
+	address := "0x00000000000000000000000000000000000000aa"
+	tracked := []string{address}
+	snapshots := fillSnapshots([]Snapshot{
+		{LastUpdated: 1717200000, TotalUsers: 1000, Tracked: tracked, Users: []User{{Rank: 120, Address: address, TotalScore: 1000}}},
+		{LastUpdated: 1717200000 + 2*86400, TotalUsers: 1000, Tracked: tracked, Users: []User{{Rank: 100, Address: address, TotalScore: 1600}}},
+	}, time.Time{}, time.Unix(1717200000+2*86400, 0), 24*time.Hour, fillInterpolate)
+
+	var out bytes.Buffer
+	if err := writeAddressHistory(&out, defaultSeason, address, addressHistory(snapshots, address)); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"2024-06-02T00:00:00Z*  110",
+		"* synthesized by -fill",
+		"rank 120 → 100 (up 20), total score 1000 → 1600 (+600)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output is missing %q:\n%s", want, got)
+		}
+	}
+}
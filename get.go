@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var getKeys = []string{"total-wallets", "last-updated", "cutoff:<pct>", "rank:<pct>", "user-rank:<address>"}
+
+var errUnknownGetKey = errors.New("unknown key")
+
+// freshSnapshot returns the newest snapshot in the history store if it was
+// taken within -cache-ttl.
+func freshSnapshot(store SnapshotStore, now time.Time) (Snapshot, bool) {
+	if store == nil || defaultClient.cache.ttl <= 0 {
+		return Snapshot{}, false
+	}
+	snapshots, err := store.List(seasons[0])
+	if err != nil || len(snapshots) == 0 {
+		return Snapshot{}, false
+	}
+	latest := snapshots[len(snapshots)-1]
+	return latest, now.Sub(latest.RefreshedAt) < defaultClient.cache.ttl
+}
+
+// snapshotValue answers key from snapshot, if the snapshot holds its value.
+func snapshotValue(snapshot Snapshot, key string) (string, bool) {
+	name, arg, _ := strings.Cut(key, ":")
+	switch name {
+	case "total-wallets":
+		return strconv.Itoa(snapshot.TotalUsers), arg == ""
+	case "last-updated":
+		return strconv.FormatInt(snapshot.LastUpdated, 10), arg == ""
+	case "cutoff", "rank":
+		percentage, err := parsePercentage(arg)
+		if err != nil {
+			return "", false
+		}
+		for _, result := range snapshot.Results {
+			if result.Percentage != percentage || result.Skipped {
+				continue
+			}
+			if name == "rank" {
+				return strconv.Itoa(result.Rank), true
+			}
+			return strconv.Itoa(result.Points), true
+		}
+	}
+	return "", false
+}
+
+// getValue looks up a single value for -get, answering from the daemon or
+// a fresh history snapshot when it can.
+func getValue(key string, store SnapshotStore) (string, error) {
+	if value, ok := daemonGet(daemonSocket, seasons[0], key); ok {
+		return value, nil
+	}
+	if snapshot, fresh := freshSnapshot(store, time.Now()); fresh {
+		if value, ok := snapshotValue(snapshot, key); ok {
+			return value, nil
+		}
+	}
+
+	name, arg, _ := strings.Cut(key, ":")
+	switch name {
+	case "total-wallets", "last-updated":
+		if arg != "" {
+			break
+		}
+		board, err := defaultClient.FetchBoard(context.Background(), seasons[0])
+		if err != nil {
+			return "", err
+		}
+		if name == "total-wallets" {
+			return strconv.Itoa(board.Data.Total), nil
+		}
+		return strconv.FormatInt(board.LastUpdated, 10), nil
+	case "cutoff", "rank":
+		percentage, err := parsePercentage(arg)
+		if err != nil {
+			return "", err
+		}
+		total, err := getTotalWallets(seasons[0])
+		if err != nil {
+			return "", err
+		}
+		rank := rankForPercentage(total, percentage)
+		if name == "rank" {
+			return strconv.Itoa(rank), nil
+		}
+		user, err := defaultClient.UserAtRank(context.Background(), seasons[0], rank)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(user.TotalScore)), nil
+	case "user-rank":
+		user, _, found, err := userByAddress(context.Background(), seasons[0], arg)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return "", fmt.Errorf("%s is not ranked", arg)
+		}
+		return strconv.Itoa(user.Rank), nil
+	}
+	return "", fmt.Errorf("%w %q", errUnknownGetKey, key)
+}
+
+// runGet prints the value for key to stdout with nothing else, not even a
+// newline, and everything else to stderr. It returns the exit code: 2 for an
+// unknown key, 1 for any other failure.
+func runGet(key string, store SnapshotStore, stdout, stderr io.Writer) int {
+	value, err := getValue(key, store)
+	if errors.Is(err, errUnknownGetKey) {
+		fmt.Fprintf(stderr, "Error: %v\nvalid keys: %s\n", err, strings.Join(getKeys, ", "))
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(stdout, value)
+	return 0
+}
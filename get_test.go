@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunGet(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+
+	for key, want := range map[string]string{
+		"total-wallets": "1000",
+		"last-updated":  "1718000000",
+		"cutoff:0.01":   "9910",
+		"rank:0.01":     "10",
+		"user-rank:0x0000000000000000000000000000000000000032": "50",
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := runGet(key, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("runGet(%q) = %d, stderr: %s", key, code, stderr.String())
+		}
+		if stdout.String() != want {
+			t.Errorf("runGet(%q) stdout = %q, want exactly %q", key, stdout.String(), want)
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("runGet(%q) wrote to stderr: %q", key, stderr.String())
+		}
+	}
+}
+
+func TestRunGetErrors(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+
+	for key, wantCode := range map[string]int{
+		"points":                                 2,
+		"total-wallets:1":                        2,
+		"cutoff:abc":                             1,
+		"user-rank:0x1234":                       1,
+		"user-rank:0x" + strings.Repeat("f", 40): 1,
+	} {
+		var stdout, stderr bytes.Buffer
+		code := runGet(key, nil, &stdout, &stderr)
+		if code != wantCode {
+			t.Errorf("runGet(%q) = %d, want %d", key, code, wantCode)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("runGet(%q) wrote %q to stdout on failure", key, stdout.String())
+		}
+		if wantCode == 2 && !strings.Contains(stderr.String(), "valid keys: total-wallets") {
+			t.Errorf("runGet(%q) stderr does not list the valid keys: %q", key, stderr.String())
+		}
+	}
+}
+
+func TestRunGetFromFreshSnapshot(t *testing.T) {
+	requests := 0
+	useFakeUpstream(t, countRequests(&requests, rankedUpstream(1000)))
+	store, err := openSnapshotStore(Config{History: filepath.Join(t.TempDir(), "history.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Add(Snapshot{Season: defaultSeason, TotalUsers: 900, LastUpdated: 1717000000, RefreshedAt: time.Now(),
+		Results: []Result{{Percentage: 0.01, Rank: 9, Points: 1234}}})
+
+	oldTTL := defaultClient.cache.ttl
+	defaultClient.cache.ttl = time.Hour
+	t.Cleanup(func() { defaultClient.cache.ttl = oldTTL })
+
+	for key, want := range map[string]string{"total-wallets": "900", "last-updated": "1717000000", "cutoff:0.01": "1234", "rank:0.01": "9"} {
+		var stdout, stderr bytes.Buffer
+		if code := runGet(key, store, &stdout, &stderr); code != 0 || stdout.String() != want {
+			t.Errorf("runGet(%q) = %d, %q; want %q", key, code, stdout.String(), want)
+		}
+	}
+	if requests != 0 {
+		t.Fatalf("answering from a fresh snapshot made %d requests", requests)
+	}
+
+	defaultClient.cache.ttl = time.Nanosecond
+	var stdout, stderr bytes.Buffer
+	if runGet("total-wallets", store, &stdout, &stderr); stdout.String() != "1000" {
+		t.Fatalf("a stale snapshot was used: %q", stdout.String())
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+//go:generate protoc --proto_path=proto --go_out=. --go_opt=module=github.com/HeuDeaI/taikoPointsByLevel --go-grpc_out=. --go-grpc_opt=module=github.com/HeuDeaI/taikoPointsByLevel taikopoints/v1/cutoffs.proto
+
+import (
+	"context"
+	"errors"
+
+	"github.com/HeuDeaI/taikoPointsByLevel/taikopointspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+type cutoffsServer struct {
+	taikopointspb.UnimplementedCutoffsServer
+	store  *snapshotStore
+	season int
+}
+
+func newGRPCServer(store *snapshotStore, season int) *grpc.Server {
+	server := grpc.NewServer()
+	taikopointspb.RegisterCutoffsServer(server, &cutoffsServer{store: store, season: season})
+	reflection.Register(server)
+	return server
+}
+
+func (s *cutoffsServer) GetCutoffs(ctx context.Context, req *taikopointspb.CutoffsRequest) (*taikopointspb.CutoffsResponse, error) {
+	snapshot, ok := s.store.get()
+	if !ok {
+		return nil, status.Error(codes.Unavailable, "no snapshot yet")
+	}
+	return snapshotToProto(snapshot), nil
+}
+
+func (s *cutoffsServer) GetUser(ctx context.Context, req *taikopointspb.UserRequest) (*taikopointspb.UserResponse, error) {
+	if req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing address")
+	}
+	user, total, found, err := userByAddress(ctx, s.season, req.GetAddress())
+	if errors.Is(err, ErrInvalidAddress) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if !found {
+		return &taikopointspb.UserResponse{Address: redact.address(req.GetAddress()), TotalUsers: int64(total)}, nil
+	}
+	return &taikopointspb.UserResponse{
+		Found:      true,
+		Rank:       int64(user.Rank),
+		Address:    redact.address(user.Address),
+		Score:      user.Score,
+		Multiplier: int32(user.Multiplier),
+		TotalScore: user.TotalScore,
+		TotalUsers: int64(total),
+	}, nil
+}
+
+func (s *cutoffsServer) WatchCutoffs(req *taikopointspb.CutoffsRequest, stream grpc.ServerStreamingServer[taikopointspb.CutoffsResponse]) error {
+	updates, unsubscribe := s.store.subscribe()
+	defer unsubscribe()
+
+	if snapshot, ok := s.store.get(); ok {
+		if err := stream.Send(snapshotToProto(snapshot)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case snapshot := <-updates:
+			if err := stream.Send(snapshotToProto(snapshot)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func snapshotToProto(snapshot Snapshot) *taikopointspb.CutoffsResponse {
+	response := &taikopointspb.CutoffsResponse{
+		Season:      int32(snapshot.Season),
+		TotalUsers:  int64(snapshot.TotalUsers),
+		LastUpdated: snapshot.LastUpdated,
+		RefreshedAt: snapshot.RefreshedAt.Unix(),
+	}
+	for _, result := range snapshot.Results {
+		response.Cutoffs = append(response.Cutoffs, &taikopointspb.Cutoff{
+			Percentage: result.Percentage,
+			Rank:       int64(result.Rank),
+			Points:     int64(result.Points),
+			Skipped:    result.Skipped,
+		})
+	}
+	return response
+}
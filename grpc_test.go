@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/HeuDeaI/taikoPointsByLevel/taikopointspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newBufconnClient(t *testing.T, store *snapshotStore) taikopointspb.CutoffsClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer(store, defaultSeason)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dialing bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return taikopointspb.NewCutoffsClient(conn)
+}
+
+func testSnapshot(lastUpdated int64, points int) Snapshot {
+	return Snapshot{
+		Season:      defaultSeason,
+		TotalUsers:  1000,
+		LastUpdated: lastUpdated,
+		RefreshedAt: time.Unix(lastUpdated, 0),
+		Results:     []Result{{Season: defaultSeason, Percentage: 0.01, TotalUsers: 1000, LastUpdated: lastUpdated, Rank: 10, Points: points}},
+	}
+}
+
+func TestGRPCGetCutoffs(t *testing.T) {
+	store := &snapshotStore{}
+	client := newBufconnClient(t, store)
+	ctx := context.Background()
+
+	_, err := client.GetCutoffs(ctx, &taikopointspb.CutoffsRequest{})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("GetCutoffs before first refresh: err = %v, want Unavailable", err)
+	}
+
+	store.set(testSnapshot(100, 9910))
+	response, err := client.GetCutoffs(ctx, &taikopointspb.CutoffsRequest{})
+	if err != nil {
+		t.Fatalf("GetCutoffs: %v", err)
+	}
+	if response.GetLastUpdated() != 100 || len(response.GetCutoffs()) != 1 || response.GetCutoffs()[0].GetPoints() != 9910 {
+		t.Fatalf("GetCutoffs = %v", response)
+	}
+}
+
+func TestGRPCGetUser(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	client := newBufconnClient(t, &snapshotStore{})
+	ctx := context.Background()
+
+	response, err := client.GetUser(ctx, &taikopointspb.UserRequest{Address: "0x0000000000000000000000000000000000000007"})
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if !response.GetFound() || response.GetRank() != 7 || response.GetTotalScore() != 9940 || response.GetTotalUsers() != 1000 {
+		t.Fatalf("GetUser = %v", response)
+	}
+
+	response, err = client.GetUser(ctx, &taikopointspb.UserRequest{Address: "0x00000000000000000000000000000000000fffff"})
+	if err != nil {
+		t.Fatalf("GetUser for an unranked address: %v", err)
+	}
+	if response.GetFound() {
+		t.Fatalf("unranked address reported as found: %v", response)
+	}
+
+	_, err = client.GetUser(ctx, &taikopointspb.UserRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("GetUser without address: err = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPCGetUserStopsWithTheCall(t *testing.T) {
+	// The upstream only answers once the lookup is given up on, which
+	// without the call's deadline is -timeout later.
+	released := make(chan struct{})
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(released)
+	}))
+	client := newBufconnClient(t, &snapshotStore{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.GetUser(ctx, &taikopointspb.UserRequest{Address: "0x0000000000000000000000000000000000000007"}); err == nil {
+		t.Fatal("GetUser past its deadline succeeded")
+	}
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("the upstream request outlived the call")
+	}
+}
+
+func TestGRPCWatchCutoffs(t *testing.T) {
+	store := &snapshotStore{}
+	store.set(testSnapshot(100, 9910))
+	client := newBufconnClient(t, store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchCutoffs(ctx, &taikopointspb.CutoffsRequest{})
+	if err != nil {
+		t.Fatalf("WatchCutoffs: %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("receiving current snapshot: %v", err)
+	}
+	if first.GetLastUpdated() != 100 {
+		t.Fatalf("first message lastUpdated = %d, want 100", first.GetLastUpdated())
+	}
+
+	// The server subscribes before sending the current snapshot, so the
+	// unchanged refresh below would be delivered first if it were broadcast.
+	store.set(testSnapshot(100, 9910))
+	store.set(testSnapshot(200, 9950))
+	update, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("receiving update: %v", err)
+	}
+	if update.GetLastUpdated() != 200 || update.GetCutoffs()[0].GetPoints() != 9950 {
+		t.Fatalf("update = %v, want lastUpdated 200 with 9950 points", update)
+	}
+}
+
+func TestSnapshotStoreNotifiesOnlyOnChange(t *testing.T) {
+	store := &snapshotStore{}
+	updates, unsubscribe := store.subscribe()
+	defer unsubscribe()
+
+	if !store.set(testSnapshot(100, 9910)) {
+		t.Fatal("first snapshot not reported as a change")
+	}
+	<-updates
+	if store.set(testSnapshot(100, 9910)) {
+		t.Fatal("identical snapshot reported as a change")
+	}
+	select {
+	case snapshot := <-updates:
+		t.Fatalf("unexpected notification: %+v", snapshot)
+	default:
+	}
+}
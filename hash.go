@@ -0,0 +1,47 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+var (
+	// hashResults prints a fingerprint of the results instead of the
+	// results themselves; see resultsHash.
+	hashResults bool
+	// hashTimestamps includes each result's lastUpdated in the fingerprint.
+	hashTimestamps bool
+)
+
+// resultsHash is a hex SHA-256 of the results in a canonical form: one line
+// per result, sorted by season and percentage, holding only the fields
+// that identify a cutoff. The order of -percentages and the rank and user
+// count, which move on every leaderboard update, don't affect it, and
+// neither does lastUpdated unless includeTimestamp is set.
+func resultsHash(all []seasonResults, includeTimestamp bool) string {
+	var results []Result
+	for _, season := range all {
+		results = append(results, season.Results...)
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(cmp.Compare(a.Season, b.Season), cmp.Compare(a.Percentage, b.Percentage))
+	})
+
+	hash := sha256.New()
+	for _, result := range results {
+		points := "-"
+		if !result.Skipped {
+			points = strconv.Itoa(result.Points)
+		}
+		fmt.Fprintf(hash, "%d\t%s\t%s", result.Season, strconv.FormatFloat(result.Percentage, 'g', -1, 64), points)
+		if includeTimestamp {
+			fmt.Fprintf(hash, "\t%d", result.LastUpdated)
+		}
+		fmt.Fprintln(hash)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
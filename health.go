@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Serving states of /cutoffs.
+const (
+	stateFresh       = "fresh"
+	stateStale       = "stale"
+	stateUnavailable = "unavailable"
+)
+
+var serveStates = []string{stateFresh, stateStale, stateUnavailable}
+
+// serveHealth is the refresh health reported at /status.
+type serveHealth struct {
+	State string `json:"state"`
+	// RefreshedAt and AgeSeconds describe the snapshot being served.
+	RefreshedAt *time.Time `json:"refreshedAt,omitempty"`
+	AgeSeconds  int64      `json:"ageSeconds"`
+	// LastError is the error of the latest refresh, cleared by the next
+	// successful one.
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorAt         *time.Time `json:"lastErrorAt,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	MaxStaleSeconds     int64      `json:"maxStaleSeconds,omitempty"`
+}
+
+func (s *snapshotStore) refreshFailed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError, s.lastErrorAt = err, time.Now()
+	s.failures++
+	s.failuresTotal++
+}
+
+func (s *snapshotStore) refreshSucceeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = nil
+	s.failures = 0
+}
+
+// health reports how the snapshot should be served at now. The last good
+// snapshot is served as stale while refreshes fail, and not at all once it
+// is older than maxStale.
+func (s *snapshotStore) health(now time.Time) (serveHealth, Snapshot) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	health := serveHealth{State: stateFresh, ConsecutiveFailures: s.failures, MaxStaleSeconds: int64(s.maxStale / time.Second)}
+	if s.lastError != nil {
+		health.State = stateStale
+		health.LastError = s.lastError.Error()
+		lastErrorAt := s.lastErrorAt
+		health.LastErrorAt = &lastErrorAt
+	}
+	if s.snapshot == nil {
+		health.State = stateUnavailable
+		return health, Snapshot{}
+	}
+	snapshot := *s.snapshot
+	health.RefreshedAt = &snapshot.RefreshedAt
+	age := now.Sub(snapshot.RefreshedAt)
+	health.AgeSeconds = int64(max(age, 0) / time.Second)
+	if s.maxStale > 0 && age > s.maxStale {
+		health.State = stateUnavailable
+	}
+	return health, snapshot
+}
+
+// cutoffsResponse is a snapshot as served at /cutoffs.
+type cutoffsResponse struct {
+	Snapshot
+	Stale bool `json:"stale,omitempty"`
+}
+
+func handleCutoffs(store *snapshotStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health, snapshot := store.health(time.Now())
+		if health.RefreshedAt == nil {
+			writeJSONError(w, http.StatusServiceUnavailable, "no snapshot yet")
+			return
+		}
+		w.Header().Set("Age", strconv.FormatInt(health.AgeSeconds, 10))
+		if health.State == stateUnavailable {
+			message := fmt.Sprintf("the last good data is %s old, past the %s staleness limit", time.Duration(health.AgeSeconds)*time.Second, store.maxStale)
+			if health.LastError != "" {
+				message = fmt.Sprintf("upstream unavailable (%s); %s", health.LastError, message)
+			}
+			writeJSONResponse(w, http.StatusServiceUnavailable, map[string]any{
+				"error":       message,
+				"ageSeconds":  health.AgeSeconds,
+				"refreshedAt": health.RefreshedAt,
+			})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, cutoffsResponse{Snapshot: snapshot, Stale: health.State == stateStale})
+	}
+}
+
+func handleStatus(store *snapshotStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health, _ := store.health(time.Now())
+		writeJSONResponse(w, http.StatusOK, health)
+	}
+}
+
+// handleMetrics serves the refresh health in the Prometheus text format.
+func handleMetrics(store *snapshotStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health, _ := store.health(time.Now())
+		store.mu.RLock()
+		failuresTotal := store.failuresTotal
+		store.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintln(w, "# HELP taiko_cutoffs_serving_state Whether /cutoffs is serving fresh data, stale data, or nothing.")
+		fmt.Fprintln(w, "# TYPE taiko_cutoffs_serving_state gauge")
+		for _, state := range serveStates {
+			value := 0
+			if state == health.State {
+				value = 1
+			}
+			fmt.Fprintf(w, "taiko_cutoffs_serving_state{state=%q} %d\n", state, value)
+		}
+		fmt.Fprintln(w, "# HELP taiko_cutoffs_snapshot_age_seconds Age of the snapshot being served.")
+		fmt.Fprintln(w, "# TYPE taiko_cutoffs_snapshot_age_seconds gauge")
+		fmt.Fprintf(w, "taiko_cutoffs_snapshot_age_seconds %d\n", health.AgeSeconds)
+		fmt.Fprintln(w, "# HELP taiko_cutoffs_refresh_failures_total Failed snapshot refreshes.")
+		fmt.Fprintln(w, "# TYPE taiko_cutoffs_refresh_failures_total counter")
+		fmt.Fprintf(w, "taiko_cutoffs_refresh_failures_total %d\n", failuresTotal)
+		fmt.Fprintln(w, "# HELP taiko_cutoffs_schema_violations_total Upstream response fields that didn't match the expected schema.")
+		fmt.Fprintln(w, "# TYPE taiko_cutoffs_schema_violations_total counter")
+		fmt.Fprintf(w, "taiko_cutoffs_schema_violations_total %d\n", schemaViolations.Load())
+		fmt.Fprintln(w, "# HELP taiko_cutoffs_breaker_state State of the circuit breaker in front of the upstream API.")
+		fmt.Fprintln(w, "# TYPE taiko_cutoffs_breaker_state gauge")
+		breaker := defaultClient.breaker.State()
+		for _, state := range []breakerState{breakerClosed, breakerOpen, breakerHalfOpen} {
+			value := 0
+			if state == breaker {
+				value = 1
+			}
+			fmt.Fprintf(w, "taiko_cutoffs_breaker_state{state=%q} %d\n", state, value)
+		}
+	}
+}
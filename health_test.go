@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServeDegradesToStaleThenUnavailable(t *testing.T) {
+	var down atomic.Bool
+	board := rankedUpstream(1000)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "upstream down", http.StatusBadRequest)
+			return
+		}
+		board.ServeHTTP(w, r)
+	}))
+	old := defaultClient.Percentages
+	defaultClient.Percentages = []float64{0.01}
+	t.Cleanup(func() { defaultClient.Percentages = old })
+
+	store := &snapshotStore{maxStale: time.Hour}
+	server := httptest.NewServer(newHTTPHandler(store, defaultSeason))
+	defer server.Close()
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		return response, string(body)
+	}
+	status := func() serveHealth {
+		t.Helper()
+		_, body := get("/status")
+		var health serveHealth
+		if err := json.Unmarshal([]byte(body), &health); err != nil {
+			t.Fatalf("/status: %v\n%s", err, body)
+		}
+		return health
+	}
+	metricState := func() string {
+		t.Helper()
+		_, body := get("/metrics")
+		for _, state := range serveStates {
+			if strings.Contains(body, `taiko_cutoffs_serving_state{state="`+state+`"} 1`) {
+				return state
+			}
+		}
+		t.Fatalf("no serving state set in\n%s", body)
+		return ""
+	}
+
+	if response, _ := get("/cutoffs"); response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("/cutoffs before any refresh: status %d, want 503", response.StatusCode)
+	}
+
+	// Fresh.
+	refreshOnce(context.Background(), store, defaultSeason, nil, 0)
+	response, body := get("/cutoffs")
+	if response.StatusCode != http.StatusOK || strings.Contains(body, `"stale"`) || response.Header.Get("Age") == "" {
+		t.Fatalf("fresh /cutoffs: status %d, Age %q, body %s", response.StatusCode, response.Header.Get("Age"), body)
+	}
+	if got := status(); got.State != stateFresh || got.LastError != "" {
+		t.Errorf("fresh /status = %+v", got)
+	}
+	if got := metricState(); got != stateFresh {
+		t.Errorf("fresh metrics state = %s", got)
+	}
+
+	// Stale: the refresh fails and the last good snapshot is kept.
+	down.Store(true)
+	refreshOnce(context.Background(), store, defaultSeason, nil, 0)
+	response, body = get("/cutoffs")
+	var served cutoffsResponse
+	json.Unmarshal([]byte(body), &served)
+	if response.StatusCode != http.StatusOK || !served.Stale || len(served.Results) != 1 || served.Results[0].Points != 9910 {
+		t.Fatalf("stale /cutoffs: status %d, body %s", response.StatusCode, body)
+	}
+	if got := status(); got.State != stateStale || !strings.Contains(got.LastError, "upstream down") || got.ConsecutiveFailures != 1 {
+		t.Errorf("stale /status = %+v", got)
+	}
+	if got := metricState(); got != stateStale {
+		t.Errorf("stale metrics state = %s", got)
+	}
+
+	// Unavailable: the last good data is past the staleness limit.
+	store.mu.Lock()
+	store.snapshot.RefreshedAt = time.Now().Add(-2 * time.Hour)
+	store.mu.Unlock()
+	response, body = get("/cutoffs")
+	if response.StatusCode != http.StatusServiceUnavailable || !strings.Contains(body, "upstream unavailable") || !strings.Contains(body, `"ageSeconds":7200`) {
+		t.Fatalf("unavailable /cutoffs: status %d, body %s", response.StatusCode, body)
+	}
+	if got := status(); got.State != stateUnavailable {
+		t.Errorf("unavailable /status = %+v", got)
+	}
+	if got := metricState(); got != stateUnavailable {
+		t.Errorf("unavailable metrics state = %s", got)
+	}
+
+	// Recovery.
+	down.Store(false)
+	refreshOnce(context.Background(), store, defaultSeason, nil, 0)
+	if got := status(); got.State != stateFresh || got.ConsecutiveFailures != 0 {
+		t.Errorf("recovered /status = %+v", got)
+	}
+	if _, body := get("/metrics"); !strings.Contains(body, "taiko_cutoffs_refresh_failures_total 1") {
+		t.Errorf("metrics lost the failure count:\n%s", body)
+	}
+	if _, body := get("/metrics"); !strings.Contains(body, `taiko_cutoffs_breaker_state{state="closed"} 1`) {
+		t.Errorf("metrics lack the breaker state:\n%s", body)
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// snapshotVersion is the version written into the JSON output envelope.
+// Bump it when the envelope changes shape.
+const snapshotVersion = 1
+
+type snapshotEnvelope struct {
+	Version     int                      `json:"version"`
+	GeneratedAt time.Time                `json:"generatedAt"`
+	Seasons     map[string]seasonResults `json:"seasons"`
+	// RankRounding is the -rank-rounding the ranks were derived with.
+	RankRounding string    `json:"rankRounding,omitempty"`
+	Stats        *runStats `json:"stats,omitempty"`
+}
+
+// runStats describes how a run's data was fetched.
+type runStats struct {
+	// BaseURL is the API or mirror that served the data.
+	BaseURL  string `json:"baseURL"`
+	Requests int64  `json:"requests"`
+}
+
+type SnapshotStore interface {
+	Has(season int, lastUpdated int64) (bool, error)
+	Add(snapshot Snapshot) error
+	List(season int) ([]Snapshot, error)
+}
+
+// fileSnapshotStore keeps one JSON snapshot per line in an append-only file.
+type fileSnapshotStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func defaultHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "history.jsonl"
+	}
+	return filepath.Join(dir, "taikoPointsByLevel", "history.jsonl")
+}
+
+func openSnapshotStore(config Config) (SnapshotStore, error) {
+	path := config.History
+	if path == "" {
+		path = defaultHistoryPath()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &fileSnapshotStore{path: path}, nil
+}
+
+func (s *fileSnapshotStore) readAll() ([]Snapshot, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var snapshots []Snapshot
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var snapshot Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.path, line, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return snapshots, nil
+}
+
+func (s *fileSnapshotStore) Has(season int, lastUpdated int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshots, err := s.readAll()
+	if err != nil {
+		return false, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Season == season && snapshot.LastUpdated == lastUpdated {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *fileSnapshotStore) Add(snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to history: %w", err)
+	}
+	return file.Close()
+}
+
+func (s *fileSnapshotStore) List(season int) ([]Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, snapshot := range all {
+		if snapshot.Season == season {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].LastUpdated < snapshots[j].LastUpdated })
+	return snapshots, nil
+}
+
+// parseSnapshotFile accepts the versioned envelope written by -format json
+// as well as the legacy bare array of results.
+func parseSnapshotFile(data []byte, modTime time.Time) ([]Snapshot, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty file")
+	}
+
+	if data[0] == '[' {
+		var results []Result
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, fmt.Errorf("invalid legacy snapshot: %w", err)
+		}
+		bySeason := make(map[int][]Result)
+		for _, result := range results {
+			if result.Season == 0 {
+				result.Season = defaultSeason
+			}
+			bySeason[result.Season] = append(bySeason[result.Season], result)
+		}
+		var snapshots []Snapshot
+		for _, results := range bySeason {
+			snapshot, err := snapshotFromResults(results, modTime)
+			if err != nil {
+				return nil, err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return snapshots, nil
+	}
+
+	var envelope snapshotEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid snapshot envelope: %w", err)
+	}
+	if envelope.Version < 1 || envelope.Version > snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", envelope.Version)
+	}
+	if envelope.GeneratedAt.IsZero() {
+		envelope.GeneratedAt = modTime
+	}
+
+	var snapshots []Snapshot
+	for key, season := range envelope.Seasons {
+		if season.Unavailable || len(season.Results) == 0 {
+			continue
+		}
+		number, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid season key %q", key)
+		}
+		for i := range season.Results {
+			season.Results[i].Season = number
+		}
+		snapshot, err := snapshotFromResults(season.Results, envelope.GeneratedAt)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func snapshotFromResults(results []Result, refreshedAt time.Time) (Snapshot, error) {
+	first := results[0]
+	if first.LastUpdated == 0 {
+		return Snapshot{}, fmt.Errorf("season %d: results have no lastUpdated", first.Season)
+	}
+	return Snapshot{
+		Season:      first.Season,
+		TotalUsers:  first.TotalUsers,
+		LastUpdated: first.LastUpdated,
+		RefreshedAt: refreshedAt,
+		Results:     results,
+	}, nil
+}
+
+type importSummary struct {
+	Files    int
+	Imported int
+	Skipped  int
+	Failed   map[string]error
+}
+
+func importHistory(store SnapshotStore, dir string) (importSummary, error) {
+	summary := importSummary{Failed: make(map[string]error)}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		summary.Files++
+
+		info, err := entry.Info()
+		if err != nil {
+			summary.Failed[path] = err
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			summary.Failed[path] = err
+			return nil
+		}
+		snapshots, err := parseSnapshotFile(data, info.ModTime())
+		if err != nil {
+			summary.Failed[path] = err
+			return nil
+		}
+
+		for _, snapshot := range snapshots {
+			exists, err := store.Has(snapshot.Season, snapshot.LastUpdated)
+			if err != nil {
+				return err
+			}
+			if exists {
+				summary.Skipped++
+				continue
+			}
+			if err := store.Add(snapshot); err != nil {
+				return err
+			}
+			summary.Imported++
+		}
+		return nil
+	})
+	return summary, err
+}
+
+func runHistory(config Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: history import <dir> | history list [-since window] [-fill mode] | history backfill -from wayback | history address [-since window] [-fill mode] <address>")
+	}
+	store, err := openSnapshotStore(config)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "import":
+		flags := newCommandFlags("history import")
+		if err := parseCommandFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: history import <dir>")
+		}
+		summary, err := importHistory(store, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%d files: %d snapshots imported, %d duplicates skipped, %d files failed\n",
+			summary.Files, summary.Imported, summary.Skipped, len(summary.Failed))
+		paths := make([]string, 0, len(summary.Failed))
+		for path := range summary.Failed {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Fprintf(stdout, "failed: %s: %v\n", path, summary.Failed[path])
+		}
+		return nil
+	case "backfill":
+		flags := newCommandFlags("history backfill")
+		from := flags.String("from", "", "where to backfill from; only wayback (the Internet Archive) is supported")
+		if err := parseCommandFlags(flags, args[1:], "season", "base-url", "verbose"); err != nil {
+			return err
+		}
+		if *from != "wayback" {
+			return errors.New("usage: history backfill -from wayback")
+		}
+		summary, err := backfillHistory(context.Background(), store, seasons[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%d captures: %d usable, %d snapshots imported, %d duplicates skipped\n",
+			summary.Captures, summary.Usable, summary.Imported, summary.Skipped)
+		return nil
+	case "address":
+		flags := newCommandFlags("history address")
+		since := flags.String("since", "", `only show snapshots from this window, as a duration back from now ("720h") or an RFC3339 time`)
+		fill := addFillFlags(flags)
+		if err := parseCommandFlags(flags, args[1:], "season"); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: history address [-since window] [-fill mode] <address>")
+		}
+		return runAddressHistory(store, flags.Arg(0), *since, *fill, stdout)
+	case "list":
+		flags := newCommandFlags("history list")
+		since := flags.String("since", "", `only list snapshots from this window, as a duration back from now ("720h") or an RFC3339 time`)
+		fill := addFillFlags(flags)
+		if err := parseCommandFlags(flags, args[1:], "season", "format"); err != nil {
+			return err
+		}
+		snapshots, err := queryHistory(store, *since, *fill)
+		if err != nil {
+			return err
+		}
+		if outputFormat == "json" {
+			return encodeJSON(stdout, snapshots, true)
+		}
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "lastUpdated\ttotal users\tcutoffs")
+		synthesized := false
+		for _, snapshot := range snapshots {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", formatSnapshotTime(snapshot), snapshot.TotalUsers, len(snapshot.Results))
+			synthesized = synthesized || snapshot.Synthesized
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if synthesized {
+			fmt.Fprintf(stdout, "\n* synthesized by -fill %s, not recorded\n", fill.mode)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown history command %q", args[0])
+}
+
+// parseSince accepts a duration back from now, an RFC3339 time or a plain
+// date for the flag called name.
+func parseSince(name, s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("%s %q: duration must not be negative", name, s)
+		}
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(`%s %q: want a duration such as "720h" or an RFC3339 time`, name, s)
+}
+
+// snapshotsSince keeps the snapshots whose leaderboard was updated at or
+// after from.
+func snapshotsSince(snapshots []Snapshot, from time.Time) []Snapshot {
+	var kept []Snapshot
+	for _, snapshot := range snapshots {
+		if !time.Unix(snapshot.LastUpdated, 0).Before(from) {
+			kept = append(kept, snapshot)
+		}
+	}
+	return kept
+}
+
+// historyFill is how history list and history address fill the intervals
+// with no snapshot, from -fill and -fill-step.
+type historyFill struct {
+	mode string
+	step time.Duration
+}
+
+func addFillFlags(flags *flag.FlagSet) *historyFill {
+	fill := &historyFill{mode: fillNone}
+	flags.Func("fill", "fill intervals with no snapshot: interpolate between the snapshots around them, carry the previous one forward, or none", func(s string) error {
+		mode, err := parseFillMode(s)
+		fill.mode = mode
+		return err
+	})
+	flags.DurationVar(&fill.step, "fill-step", 24*time.Hour, "the length of the intervals -fill checks for a snapshot")
+	return fill
+}
+
+// queryHistory returns the snapshots of the first season from the -since
+// window on, filled as asked.
+func queryHistory(store SnapshotStore, since string, fill historyFill) ([]Snapshot, error) {
+	snapshots, err := store.List(seasons[0])
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var from time.Time
+	if since != "" {
+		if from, err = parseSince("-since", since, now); err != nil {
+			return nil, err
+		}
+	}
+	if fill.mode == fillNone {
+		return snapshotsSince(snapshots, from), nil
+	}
+	return fillSnapshots(snapshots, from, now, fill.step, fill.mode), nil
+}
+
+// formatSnapshotTime is when snapshot's leaderboard was updated, marked
+// with an asterisk if -fill synthesized it.
+func formatSnapshotTime(snapshot Snapshot) string {
+	when := time.Unix(snapshot.LastUpdated, 0).UTC().Format(time.RFC3339)
+	if snapshot.Synthesized {
+		when += "*"
+	}
+	return when
+}
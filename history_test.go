@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImportHistory(t *testing.T) {
+	store, err := openSnapshotStore(Config{History: filepath.Join(t.TempDir(), "history.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := importHistory(store, "testdata/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Files != 4 || summary.Imported != 2 || summary.Skipped != 1 {
+		t.Fatalf("summary = %+v, want 4 files, 2 imported, 1 skipped", summary)
+	}
+	if _, ok := summary.Failed[filepath.Join("testdata", "history", "old", "truncated.json")]; !ok || len(summary.Failed) != 1 {
+		t.Fatalf("failed = %v, want only old/truncated.json", summary.Failed)
+	}
+
+	snapshots, err := store.List(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].LastUpdated != 1714000000 || snapshots[1].LastUpdated != 1714600000 {
+		t.Fatalf("snapshots = %+v", snapshots)
+	}
+	if snapshots[0].Results[0].Season != defaultSeason {
+		t.Fatalf("legacy results should default to season %d, got %d", defaultSeason, snapshots[0].Results[0].Season)
+	}
+
+	again, err := importHistory(store, "testdata/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Imported != 0 || again.Skipped != 3 {
+		t.Fatalf("re-import summary = %+v, want everything skipped", again)
+	}
+}
+
+func TestParseSnapshotFileRejectsUnknownVersion(t *testing.T) {
+	if _, err := parseSnapshotFile([]byte(`{"version": 99, "seasons": {}}`), time.Time{}); err == nil {
+		t.Fatal("expected an error for an unsupported version")
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		in   string
+		want time.Time
+	}{
+		{"720h", now.Add(-720 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"2024-05-01T00:00:00Z", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		got, err := parseSince("-since", tc.in, now)
+		if err != nil || !got.Equal(tc.want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+	for _, in := range []string{"yesterday", "-1h", "2024-13-01"} {
+		if _, err := parseSince("-since", in, now); err == nil {
+			t.Errorf("parseSince(%q) succeeded", in)
+		}
+	}
+
+	snapshots := []Snapshot{{LastUpdated: 1714000000}, {LastUpdated: 1714600000}}
+	kept := snapshotsSince(snapshots, time.Unix(1714600000, 0))
+	if len(kept) != 1 || kept[0].LastUpdated != 1714600000 {
+		t.Fatalf("snapshotsSince = %+v", kept)
+	}
+}
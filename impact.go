@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// impactRow compares a cutoff on the real leaderboard, ranked by TotalScore,
+// with an estimate of where it would be if users were ranked by raw Score.
+type impactRow struct {
+	Percentage float64
+	Rank       int
+	// Score and TotalScore are those of the user at the cutoff rank.
+	Score      float64
+	TotalScore float64
+	// RawCutoff is the estimated Score cutoff under raw-score ranking.
+	RawCutoff float64
+}
+
+// sampleUsers fetches n users at distinct, uniformly random ranks.
+func sampleUsers(season, total, n int, rng *rand.Rand) ([]User, error) {
+	n = min(n, total)
+	users := make([]User, 0, n)
+	seen := make(map[int]bool, n)
+	for len(users) < n {
+		rank := 1 + rng.Intn(total)
+		if seen[rank] {
+			continue
+		}
+		seen[rank] = true
+		user, err := defaultClient.UserAtRank(context.Background(), season, rank)
+		if err != nil {
+			return users, fmt.Errorf("failed to sample rank %d: %w", rank, err)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// rawScoreCutoff estimates the Score a user would need to be in the top
+// percentage if the board were ranked by Score, from the sample: the
+// sample is sorted by Score and its element at the percentage's position
+// stands in for the board.
+func rawScoreCutoff(sample []User, percentage float64) float64 {
+	scores := make([]float64, len(sample))
+	for i, user := range sample {
+		scores[i] = user.Score
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(scores)))
+	i := rankForPercentage(len(scores), percentage) - 1
+	return scores[min(max(i, 0), len(scores)-1)]
+}
+
+func multiplierImpact(season, sampleSize int, rng *rand.Rand) ([]impactRow, int, error) {
+	total, err := getTotalWallets(season)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows := make([]impactRow, len(defaultClient.Percentages))
+	for i, percentage := range defaultClient.Percentages {
+		rank := rankForPercentage(total, percentage)
+		user, err := defaultClient.UserAtRank(context.Background(), season, rank)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get the cutoff at rank %d: %w", rank, err)
+		}
+		rows[i] = impactRow{Percentage: percentage, Rank: rank, Score: user.Score, TotalScore: user.TotalScore}
+	}
+
+	if limit := sampleRequestLimit(); sampleSize > limit {
+		if limit == 0 {
+			return nil, 0, fmt.Errorf("%w: no requests left to sample with", ErrBudgetExhausted)
+		}
+		log.Printf("Warning: sampling %d ranks instead of %d to stay within the request budget", limit, sampleSize)
+		sampleSize = limit
+	}
+	sample, err := sampleUsers(season, total, sampleSize, rng)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range rows {
+		rows[i].RawCutoff = rawScoreCutoff(sample, rows[i].Percentage)
+	}
+	return rows, len(sample), nil
+}
+
+func writeMultiplierImpact(out io.Writer, rows []impactRow, sampleSize int) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "top\trank\tscore\ttotal score\test. raw-score cutoff\tdelta\t")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t~%s\t%+.0f\t\n", formatPercentage(row.Percentage), formatThousands(row.Rank),
+			formatFloat(row.Score, 0), formatFloat(row.TotalScore, 0), formatFloat(row.RawCutoff, 0), row.RawCutoff-row.TotalScore)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\nThe raw-score cutoffs are estimates from a sample of %d ranks, not a ranking of the whole board,\n"+
+		"and are least reliable for the smallest percentages. delta is the estimate minus the total score cutoff.\n", sampleSize)
+}
+
+func runMultiplierImpact(args []string) error {
+	flags := newCommandFlags("multiplier-impact")
+	sampleSize := flags.Int("sample", maxExampleRequests, "number of random ranks to sample for the raw-score estimate")
+	seed := flags.Int64("seed", 0, "random seed for the sample (default: time-based)")
+	if err := parseCommandFlags(flags, args, "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+	if *sampleSize < 1 {
+		return errors.New("multiplier-impact: -sample must be at least 1")
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	log.Printf("Sampling with -seed %d", *seed)
+
+	rows, n, err := multiplierImpact(seasons[0], *sampleSize, rand.New(rand.NewSource(*seed)))
+	if err != nil {
+		return err
+	}
+	writeMultiplierImpact(stdout, rows, n)
+	return nil
+}
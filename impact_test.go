@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMultiplierImpact(t *testing.T) {
+	// Ranked by TotalScore, but every other user owes most of it to a 10x
+	// multiplier, so ranking by Score would reorder the board.
+	users := make([]User, 100)
+	for i := range users {
+		users[i] = User{Rank: i + 1, Address: testAddress(i + 1), TotalScore: float64(1000 - i*10), Multiplier: 1}
+		if i%2 == 0 {
+			users[i].Multiplier = 10
+		}
+		users[i].Score = users[i].TotalScore / float64(users[i].Multiplier)
+	}
+	useFakeUpstream(t, usersUpstream(users))
+	old := defaultClient.Percentages
+	defaultClient.Percentages = []float64{0.1, 0.5}
+	t.Cleanup(func() { defaultClient.Percentages = old })
+
+	// Sampling every rank makes the estimate exact.
+	rows, n, err := multiplierImpact(defaultSeason, 100, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 100 {
+		t.Fatalf("sampled %d ranks, want 100", n)
+	}
+	// The top 10 by Score are the 1x users at ranks 2, 4, ..., 20.
+	if row := rows[0]; row.Rank != 10 || row.TotalScore != 910 || row.Score != 910 || row.RawCutoff != 810 {
+		t.Errorf("top 10%% = %+v, want rank 10, total 910, score 910, raw cutoff 810", row)
+	}
+
+	var out bytes.Buffer
+	writeMultiplierImpact(&out, rows, n)
+	if !strings.Contains(out.String(), "~810") || !strings.Contains(out.String(), "-100") || !strings.Contains(out.String(), "sample of 100 ranks") {
+		t.Errorf("report =\n%s", out.String())
+	}
+}
+
+func TestMultiplierImpactRespectsBudget(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	defaultClient.MaxRequests = 20
+	old := defaultClient.Percentages
+	defaultClient.Percentages = []float64{0.1}
+	t.Cleanup(func() { defaultClient.Percentages = old })
+
+	if _, n, err := multiplierImpact(defaultSeason, 500, rand.New(rand.NewSource(1))); err != nil || n != 18 {
+		t.Errorf("sampled %d ranks, %v; want the 18 the budget leaves", n, err)
+	}
+}
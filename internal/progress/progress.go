@@ -0,0 +1,169 @@
+// Package progress draws a single-line request progress display on a
+// terminal, redrawn in place with a carriage return.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const barWidth = 20
+
+// Bar tracks completed requests against an expected total. A nil *Bar is
+// valid and does nothing, so callers don't need to check whether progress
+// is enabled.
+type Bar struct {
+	mu      sync.Mutex
+	out     io.Writer
+	now     func() time.Time
+	start   time.Time
+	total   int
+	done    int
+	retries int
+	drawn   int
+}
+
+// New returns a Bar writing to out. now is the clock used for the rate and
+// ETA; pass time.Now outside of tests.
+func New(out io.Writer, now func() time.Time) *Bar {
+	return &Bar{out: out, now: now, start: now()}
+}
+
+// IsTerminal reports whether f is a character device, which is how the
+// display decides whether to enable itself.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// AddTotal raises the number of requests expected.
+func (b *Bar) AddTotal(n int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total += n
+	b.redraw()
+}
+
+// Done records a completed request.
+func (b *Bar) Done() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done++
+	b.redraw()
+}
+
+// Retry records a retried request.
+func (b *Bar) Retry() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.retries++
+	b.redraw()
+}
+
+// Finish clears the display and resets the counters for the next operation.
+func (b *Bar) Finish() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	b.total, b.done, b.retries = 0, 0, 0
+	b.start = b.now()
+}
+
+// Writer wraps w so that anything written to it, typically log lines, is
+// printed on a cleared line with the display redrawn below it.
+func (b *Bar) Writer(w io.Writer) io.Writer {
+	if b == nil {
+		return w
+	}
+	return writerFunc(func(p []byte) (int, error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.clear()
+		n, err := w.Write(p)
+		b.redraw()
+		return n, err
+	})
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func (b *Bar) clear() {
+	if b.drawn > 0 {
+		fmt.Fprintf(b.out, "\r%s\r", strings.Repeat(" ", b.drawn))
+		b.drawn = 0
+	}
+}
+
+func (b *Bar) redraw() {
+	if b.total == 0 {
+		return
+	}
+	line := Format(b.done, b.total, b.retries, b.now().Sub(b.start))
+	padding := ""
+	if len(line) < b.drawn {
+		padding = strings.Repeat(" ", b.drawn-len(line))
+	}
+	fmt.Fprintf(b.out, "\r%s%s", line, padding)
+	b.drawn = len(line)
+}
+
+// Format renders one progress line.
+func Format(done, total, retries int, elapsed time.Duration) string {
+	filled := 0
+	if total > 0 {
+		filled = min(done*barWidth/total, barWidth)
+	}
+	line := fmt.Sprintf("[%s%s] %d/%d requests  %.1f req/s  ETA %s",
+		strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled),
+		done, total, Rate(done, elapsed), formatETA(done, total, elapsed))
+	if retries > 0 {
+		line += fmt.Sprintf("  %d retries", retries)
+	}
+	return line
+}
+
+// Rate returns completed requests per second.
+func Rate(done int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(done) / elapsed.Seconds()
+}
+
+// ETA extrapolates the time left from the average time per completed
+// request. ok is false until at least one request has completed.
+func ETA(done, total int, elapsed time.Duration) (eta time.Duration, ok bool) {
+	if done == 0 {
+		return 0, false
+	}
+	if done >= total {
+		return 0, true
+	}
+	return elapsed * time.Duration(total-done) / time.Duration(done), true
+}
+
+func formatETA(done, total int, elapsed time.Duration) string {
+	eta, ok := ETA(done, total, elapsed)
+	if !ok {
+		return "?"
+	}
+	return eta.Round(time.Second).String()
+}
@@ -0,0 +1,103 @@
+package progress
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+func newFakeClock() *fakeClock               { return &fakeClock{now: time.Unix(1718000000, 0)} }
+
+func TestETA(t *testing.T) {
+	tests := []struct {
+		done, total int
+		elapsed     time.Duration
+		want        time.Duration
+		ok          bool
+	}{
+		{0, 10, 5 * time.Second, 0, false},
+		{5, 10, 5 * time.Second, 5 * time.Second, true},
+		{1, 4, 2 * time.Second, 6 * time.Second, true},
+		{10, 10, 7 * time.Second, 0, true},
+		{12, 10, 7 * time.Second, 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := ETA(tt.done, tt.total, tt.elapsed)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ETA(%d, %d, %s) = %s, %v; want %s, %v", tt.done, tt.total, tt.elapsed, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		done, total, retries int
+		elapsed              time.Duration
+		want                 string
+	}{
+		{0, 12, 0, 0, "[                    ] 0/12 requests  0.0 req/s  ETA ?"},
+		{6, 12, 0, 3 * time.Second, "[==========          ] 6/12 requests  2.0 req/s  ETA 3s"},
+		{12, 12, 2, 4 * time.Second, "[====================] 12/12 requests  3.0 req/s  ETA 0s  2 retries"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.done, tt.total, tt.retries, tt.elapsed); got != tt.want {
+			t.Errorf("Format(%d, %d, %d, %s) =\n%q\nwant\n%q", tt.done, tt.total, tt.retries, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestBarRedrawsInPlace(t *testing.T) {
+	clock := newFakeClock()
+	var out bytes.Buffer
+	bar := New(&out, clock.Now)
+	bar.AddTotal(2)
+	clock.Advance(time.Second)
+	bar.Done()
+
+	if strings.Contains(out.String(), "\n") {
+		t.Fatalf("progress output contains a newline: %q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "\r"+Format(1, 2, 0, time.Second)) {
+		t.Fatalf("last frame = %q", out.String())
+	}
+
+	bar.Finish()
+	if !strings.HasSuffix(out.String(), "\r") {
+		t.Fatalf("Finish did not clear the line: %q", out.String())
+	}
+}
+
+func TestBarWriterClearsAroundLogLines(t *testing.T) {
+	clock := newFakeClock()
+	var out bytes.Buffer
+	bar := New(&out, clock.Now)
+	bar.AddTotal(3)
+	out.Reset()
+
+	logger := log.New(bar.Writer(&out), "", 0)
+	logger.Print("retrying")
+
+	frame := Format(0, 3, 0, 0)
+	want := "\r" + strings.Repeat(" ", len(frame)) + "\r" + "retrying\n" + "\r" + frame
+	if out.String() != want {
+		t.Fatalf("output =\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestNilBar(t *testing.T) {
+	var bar *Bar
+	bar.AddTotal(1)
+	bar.Done()
+	bar.Retry()
+	bar.Finish()
+	var out bytes.Buffer
+	if w := bar.Writer(&out); w != &out {
+		t.Fatal("nil bar should return the writer unchanged")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// hugeUpstream answers every request with a well-formed page whose items run
+// on for size bytes, generated on the fly.
+func hugeUpstream(size int) http.Handler {
+	item := fmt.Sprintf(`{"rank":1,"address":"0x%040x","score":1,"multiplier":1,"totalScore":1},`, 1)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"items":[`)
+		chunk := strings.Repeat(item, 1000)
+		for written := 0; written < size; written += len(chunk) {
+			if _, err := fmt.Fprint(w, chunk); err != nil {
+				return
+			}
+		}
+		fmt.Fprint(w, item[:len(item)-1]+`],"total":1},"lastUpdated":1718000000}`)
+	})
+}
+
+func setResponseLimits(t *testing.T, page, export int64) {
+	t.Helper()
+	oldPage, oldExport := maxResponseBytes, maxExportResponseBytes
+	maxResponseBytes, maxExportResponseBytes = page, export
+	t.Cleanup(func() { maxResponseBytes, maxExportResponseBytes = oldPage, oldExport })
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	useFakeUpstream(t, hugeUpstream(64<<20))
+	setResponseLimits(t, 1<<20, 1<<20)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	_, err := fetchResponse(defaultClient.leaderboardURL(defaultSeason))
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("fetchResponse err = %v, want ErrResponseTooLarge", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Fatalf("allocated %d MB reading a body capped at 1 MB", allocated>>20)
+	}
+}
+
+func TestExportSizeLimit(t *testing.T) {
+	board := rankedUpstream(250)
+	padding := strings.Repeat("x", 8<<20)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprintf(w, `{"data":{"padding":"%s","items":[]},"lastUpdated":1718000000}`, padding)
+			return
+		}
+		board.ServeHTTP(w, r)
+	}))
+	setResponseLimits(t, 10<<20, 1<<20)
+
+	users, _, err := fetchAllUsers(defaultSeason)
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("fetchAllUsers err = %v, want ErrResponseTooLarge", err)
+	}
+	if len(users) != 100 {
+		t.Fatalf("kept %d users, want the 100 from the first page", len(users))
+	}
+
+	setResponseLimits(t, 10<<20, 64<<20)
+	if _, err := fetchResponse(defaultClient.leaderboardURL(defaultSeason) + "?page=2&size=100"); err != nil {
+		t.Fatalf("an 8 MB page under the 10 MB page limit failed: %v", err)
+	}
+	if _, _, err := fetchAllUsers(defaultSeason); err == nil || !strings.Contains(err.Error(), "page 2 is empty") {
+		t.Fatalf("with a 64 MB export limit the padded page should be read, got %v", err)
+	}
+}
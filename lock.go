@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// refreshLock coordinates background refreshes across serve replicas. Only
+// the replica holding the leader lock talks to the upstream API; the others
+// follow the snapshot it publishes to the shared store.
+type refreshLock interface {
+	// Acquire takes or extends the leader lock for ttl and reports whether
+	// this replica holds it.
+	Acquire(ctx context.Context, ttl time.Duration) (bool, error)
+	Release(ctx context.Context) error
+	// Publish stores snapshot unless the shared store already holds a newer
+	// one, and reports whether it was written.
+	Publish(ctx context.Context, snapshot Snapshot) (bool, error)
+	// Latest returns the last published snapshot.
+	Latest(ctx context.Context) (Snapshot, bool, error)
+	Close() error
+}
+
+// openRefreshLock returns the lock for a -lock URL.
+func openRefreshLock(rawURL string, season int) (refreshLock, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -lock URL: %w", err)
+	}
+	switch u.Scheme {
+	case "redis", "rediss":
+		options, err := redis.ParseURL(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -lock URL: %w", err)
+		}
+		return newRedisLock(redis.NewClient(options), season), nil
+	case "postgres", "postgresql":
+		return nil, errors.New("-lock: postgres is not supported yet, use redis://")
+	}
+	return nil, fmt.Errorf("-lock: unsupported scheme %q", u.Scheme)
+}
+
+type redisLock struct {
+	client      *redis.Client
+	token       string
+	lockKey     string
+	snapshotKey string
+}
+
+func newRedisLock(client *redis.Client, season int) *redisLock {
+	token := make([]byte, 16)
+	rand.Read(token)
+	prefix := fmt.Sprintf("taikopoints:s%d:", season)
+	return &redisLock{
+		client:      client,
+		token:       hex.EncodeToString(token),
+		lockKey:     prefix + "lock",
+		snapshotKey: prefix + "snapshot",
+	}
+}
+
+// acquireScript takes the lock when it is free and extends it when this
+// replica already holds it.
+var acquireScript = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if holder == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if holder == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// publishScript is a compare-and-set on lastUpdated: a leader that lost the
+// lock mid-refresh cannot replace a newer snapshot with its older one. Every
+// write bumps the version.
+var publishScript = redis.NewScript(`
+local current = tonumber(redis.call("HGET", KEYS[1], "lastUpdated"))
+if current ~= nil and current > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("HSET", KEYS[1], "lastUpdated", ARGV[1], "data", ARGV[2])
+redis.call("HINCRBY", KEYS[1], "version", 1)
+return 1
+`)
+
+func (l *redisLock) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	held, err := acquireScript.Run(ctx, l.client, []string{l.lockKey}, l.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire refresh lock: %w", err)
+	}
+	return held == 1, nil
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	if err := releaseScript.Run(ctx, l.client, []string{l.lockKey}, l.token).Err(); err != nil {
+		return fmt.Errorf("failed to release refresh lock: %w", err)
+	}
+	return nil
+}
+
+func (l *redisLock) Publish(ctx context.Context, snapshot Snapshot) (bool, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return false, err
+	}
+	written, err := publishScript.Run(ctx, l.client, []string{l.snapshotKey}, snapshot.LastUpdated, data).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to publish snapshot: %w", err)
+	}
+	return written == 1, nil
+}
+
+func (l *redisLock) Latest(ctx context.Context) (Snapshot, bool, error) {
+	var snapshot Snapshot
+	data, err := l.client.HGet(ctx, l.snapshotKey, "data").Bytes()
+	if errors.Is(err, redis.Nil) {
+		return snapshot, false, nil
+	}
+	if err != nil {
+		return snapshot, false, fmt.Errorf("failed to read shared snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, false, fmt.Errorf("failed to decode shared snapshot: %w", err)
+	}
+	return snapshot, true, nil
+}
+
+func (l *redisLock) Close() error {
+	return l.client.Close()
+}
+
+// refreshCoordinated runs one refresh cycle under lock. The leader refreshes
+// from upstream and publishes; followers pull the published snapshot. If the
+// lock itself is unreachable the replica refreshes on its own rather than
+// serving stale data.
+func refreshCoordinated(ctx context.Context, store *snapshotStore, season int, lock refreshLock, ttl time.Duration) error {
+	leader, err := lock.Acquire(ctx, ttl)
+	if err != nil {
+		log.Printf("%v; refreshing without coordination", err)
+		return refreshLocal(store, season)
+	}
+	if !leader {
+		snapshot, ok, err := lock.Latest(ctx)
+		if err != nil {
+			return err
+		}
+		if ok && store.set(snapshot) {
+			log.Printf("Snapshot updated from leader: lastUpdated %d, %d users", snapshot.LastUpdated, snapshot.TotalUsers)
+		}
+		return nil
+	}
+
+	defaultClient.resetBudget()
+	snapshot, err := buildSnapshot(season)
+	if err != nil {
+		return err
+	}
+	written, err := lock.Publish(ctx, snapshot)
+	if err != nil {
+		log.Printf("%v", err)
+	} else if !written {
+		// Another leader published newer data while this refresh ran.
+		if latest, ok, err := lock.Latest(ctx); err == nil && ok {
+			snapshot = latest
+		}
+	}
+	if store.set(snapshot) {
+		log.Printf("Snapshot updated: lastUpdated %d, %d users", snapshot.LastUpdated, snapshot.TotalUsers)
+	}
+	return nil
+}
+
+func refreshLocal(store *snapshotStore, season int) error {
+	defaultClient.resetBudget()
+	snapshot, err := buildSnapshot(season)
+	if err != nil {
+		return err
+	}
+	if store.set(snapshot) {
+		log.Printf("Snapshot updated: lastUpdated %d, %d users", snapshot.LastUpdated, snapshot.TotalUsers)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestLocks(t *testing.T) (*miniredis.Miniredis, *redisLock, *redisLock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	open := func() *redisLock {
+		lock := newRedisLock(redis.NewClient(&redis.Options{Addr: server.Addr()}), defaultSeason)
+		t.Cleanup(func() { lock.Close() })
+		return lock
+	}
+	return server, open(), open()
+}
+
+func TestRedisLockSingleLeader(t *testing.T) {
+	server, first, second := newTestLocks(t)
+	ctx := context.Background()
+
+	if held, err := first.Acquire(ctx, time.Minute); err != nil || !held {
+		t.Fatalf("first Acquire = %v, %v; want true", held, err)
+	}
+	if held, err := second.Acquire(ctx, time.Minute); err != nil || held {
+		t.Fatalf("second Acquire = %v, %v; want false while first holds the lock", held, err)
+	}
+	if held, _ := first.Acquire(ctx, time.Minute); !held {
+		t.Fatal("leader could not extend its own lock")
+	}
+
+	// Releasing a lock held by someone else is a no-op.
+	second.Release(ctx)
+	if held, _ := second.Acquire(ctx, time.Minute); held {
+		t.Fatal("follower took the lock after releasing a lock it did not hold")
+	}
+
+	server.FastForward(2 * time.Minute)
+	if held, _ := second.Acquire(ctx, time.Minute); !held {
+		t.Fatal("follower could not take an expired lock")
+	}
+	if held, _ := first.Acquire(ctx, time.Minute); held {
+		t.Fatal("former leader still holds the lock after it expired")
+	}
+}
+
+func TestRedisLockPublishCompareAndSet(t *testing.T) {
+	_, first, second := newTestLocks(t)
+	ctx := context.Background()
+
+	if _, ok, err := second.Latest(ctx); err != nil || ok {
+		t.Fatalf("Latest on an empty store = %v, %v; want nothing", ok, err)
+	}
+	if written, err := first.Publish(ctx, testSnapshot(200, 9910)); err != nil || !written {
+		t.Fatalf("Publish = %v, %v", written, err)
+	}
+	// A leader that lost the lock mid-refresh must not overwrite newer data.
+	if written, err := second.Publish(ctx, testSnapshot(100, 1)); err != nil || written {
+		t.Fatalf("Publish of an older snapshot = %v, %v; want rejected", written, err)
+	}
+
+	snapshot, ok, err := second.Latest(ctx)
+	if err != nil || !ok {
+		t.Fatalf("Latest = %v, %v", ok, err)
+	}
+	if snapshot.LastUpdated != 200 || snapshot.Results[0].Points != 9910 {
+		t.Errorf("Latest = lastUpdated %d, points %d; want 200, 9910", snapshot.LastUpdated, snapshot.Results[0].Points)
+	}
+}
+
+func TestRefreshCoordinatedFollowerSkipsUpstream(t *testing.T) {
+	var requests int
+	useFakeUpstream(t, countRequests(&requests, rankedUpstream(1000)))
+	_, leader, follower := newTestLocks(t)
+	ctx := context.Background()
+
+	leaderStore, followerStore := &snapshotStore{}, &snapshotStore{}
+	if err := refreshCoordinated(ctx, leaderStore, defaultSeason, leader, time.Minute); err != nil {
+		t.Fatalf("leader refresh: %v", err)
+	}
+	if requests == 0 {
+		t.Fatal("leader did not fetch from upstream")
+	}
+
+	fetched := requests
+	if err := refreshCoordinated(ctx, followerStore, defaultSeason, follower, time.Minute); err != nil {
+		t.Fatalf("follower refresh: %v", err)
+	}
+	if requests != fetched {
+		t.Errorf("follower made %d upstream requests, want 0", requests-fetched)
+	}
+
+	want, _ := leaderStore.get()
+	got, ok := followerStore.get()
+	if !ok || got.TotalUsers != want.TotalUsers || len(got.Results) != len(want.Results) {
+		t.Errorf("follower snapshot = %+v, want the leader's %+v", got, want)
+	}
+}
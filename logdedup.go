@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dedupWriter is a log output that prints each distinct error or warning
+// once and counts repeats, so an outage doesn't bury the output in identical
+// errors from every goroutine. Flush reports the repeat counts. Other
+// records, such as the breaker's state changes and progress, are printed
+// every time. It adds the timestamp itself, so the logger must not.
+type dedupWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	now    func() time.Time
+	counts map[string]int
+	order  []string
+}
+
+// problemRecord matches the log records dedupWriter collapses. The log
+// has no levels, so they are told by their wording: "Warning: ...",
+// "Error: ...", "Failed to ..." or "Attempt 2 failed (...), retrying".
+var problemRecord = regexp.MustCompile(`(?i)\b(warning|error|failed)\b`)
+
+// logDedup is installed as the log output by main.
+var logDedup *dedupWriter
+
+func newDedupWriter(out io.Writer, now func() time.Time) *dedupWriter {
+	return &dedupWriter{out: out, now: now, counts: make(map[string]int)}
+}
+
+func (d *dedupWriter) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	message := string(p)
+	if !problemRecord.MatchString(message) {
+		if _, err := fmt.Fprintf(d.out, "%s %s", d.now().Format("2006/01/02 15:04:05"), message); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	d.counts[message]++
+	if d.counts[message] > 1 {
+		return len(p), nil
+	}
+	d.order = append(d.order, message)
+	if _, err := fmt.Fprintf(d.out, "%s %s", d.now().Format("2006/01/02 15:04:05"), message); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush prints one line with a count for every message that was repeated
+// since the last Flush and forgets them all.
+func (d *dedupWriter) Flush() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, message := range d.order {
+		if n := d.counts[message]; n > 1 {
+			fmt.Fprintf(d.out, "%s %s (x%d)\n", d.now().Format("2006/01/02 15:04:05"), strings.TrimSuffix(message, "\n"), n)
+		}
+	}
+	clear(d.counts)
+	d.order = d.order[:0]
+}
+
+// fatalErr logs prefix and err and exits with exitCode(err).
+func fatalErr(prefix string, err error) {
+	log.Print(prefix, err)
+	logDedup.Flush()
+	os.Exit(exitCode(err))
+}
+
+// fatalf flushes the deduplicated log before exiting like log.Fatalf.
+func fatalf(format string, v ...any) {
+	logDedup.Flush()
+	log.Fatalf(format, v...)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedupWriter(t *testing.T) {
+	var out bytes.Buffer
+	now := func() time.Time { return time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC) }
+	dedup := newDedupWriter(&out, now)
+	logger := log.New(dedup, "", 0)
+
+	var wg sync.WaitGroup
+	for range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Print("failed to fetch page 3: connection refused")
+		}()
+	}
+	wg.Wait()
+	// Records that aren't errors or warnings print every time.
+	logger.Print("Circuit breaker closed -> open")
+	logger.Print("Circuit breaker closed -> open")
+	logger.Print("Warning: season 2 page 4 has 9 users")
+	logger.Print("Warning: season 2 page 4 has 9 users")
+
+	want := "2024/06/10 12:00:00 failed to fetch page 3: connection refused\n" +
+		"2024/06/10 12:00:00 Circuit breaker closed -> open\n" +
+		"2024/06/10 12:00:00 Circuit breaker closed -> open\n" +
+		"2024/06/10 12:00:00 Warning: season 2 page 4 has 9 users\n"
+	if out.String() != want {
+		t.Fatalf("before flush:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	dedup.Flush()
+	want += "2024/06/10 12:00:00 failed to fetch page 3: connection refused (x12)\n" +
+		"2024/06/10 12:00:00 Warning: season 2 page 4 has 9 users (x2)\n"
+	if out.String() != want {
+		t.Fatalf("after flush:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	logger.Print("failed to fetch page 3: connection refused")
+	dedup.Flush()
+	if out.String() != "2024/06/10 12:00:00 failed to fetch page 3: connection refused\n" {
+		t.Fatalf("a flush should start a fresh run, got:\n%s", out.String())
+	}
+}
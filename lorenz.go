@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// lorenzPoint is the share of all points held by the users down to Rank.
+type lorenzPoint struct {
+	Rank               int
+	UserFraction       float64
+	CumulativePoints   float64
+	CumulativeFraction float64
+}
+
+// lorenzCurve sums TotalScore in rank order. With step 0 there is a point for
+// every rank; otherwise one for every step of the user fraction, e.g. 0.01
+// for each percent, always ending with the last rank.
+func lorenzCurve(users []User, step float64) []lorenzPoint {
+	var grand float64
+	for _, user := range users {
+		grand += user.TotalScore
+	}
+
+	// threshold is the rank at which the user fraction reaches k steps,
+	// rounded per -rank-rounding like any other percentage.
+	threshold := func(k int) int {
+		if step == 0 {
+			return k
+		}
+		return rankForPercentage(len(users), float64(k)*step)
+	}
+
+	var (
+		points []lorenzPoint
+		sum    float64
+		k      = 1
+		next   = threshold(k)
+	)
+	for i, user := range users {
+		sum += user.TotalScore
+		rank := i + 1
+		if rank < next && rank < len(users) {
+			continue
+		}
+		point := lorenzPoint{Rank: rank, UserFraction: float64(rank) / float64(len(users)), CumulativePoints: sum}
+		if grand > 0 {
+			point.CumulativeFraction = sum / grand
+		}
+		points = append(points, point)
+		for next <= rank {
+			k++
+			next = threshold(k)
+		}
+	}
+	return points
+}
+
+// writeLorenzCSV writes the curve with the same separator conventions as
+// writeCSV.
+func writeLorenzCSV(out io.Writer, points []lorenzPoint) error {
+	w := csv.NewWriter(out)
+	if decimalSep == "," {
+		w.Comma = ';'
+	}
+	w.Write([]string{"rank", "user_fraction", "cumulative_points", "cumulative_fraction"})
+	for _, point := range points {
+		w.Write([]string{
+			strconv.Itoa(point.Rank),
+			formatFloat(point.UserFraction, -1),
+			formatFloat(point.CumulativePoints, -1),
+			formatFloat(point.CumulativeFraction, -1),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printLorenz(step float64) error {
+	if step < 0 || step >= 1 {
+		return fmt.Errorf("-lorenz-step must be at least 0 and below 1")
+	}
+	users, _, err := fetchAllUsers(seasons[0])
+	if err != nil {
+		return err
+	}
+	return writeLorenzCSV(stdout, lorenzCurve(users, step))
+}
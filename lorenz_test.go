@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLorenzCurve(t *testing.T) {
+	users := []User{{TotalScore: 50}, {TotalScore: 30}, {TotalScore: 15}, {TotalScore: 5}}
+
+	points := lorenzCurve(users, 0)
+	want := []float64{0.5, 0.8, 0.95, 1}
+	if len(points) != len(want) {
+		t.Fatalf("got %d points, want %d", len(points), len(want))
+	}
+	for i, point := range points {
+		if point.Rank != i+1 || !closeTo(point.CumulativeFraction, want[i]) {
+			t.Errorf("point %d = rank %d, %v; want rank %d, %v", i, point.Rank, point.CumulativeFraction, i+1, want[i])
+		}
+	}
+	if points[3].CumulativePoints != 100 || points[1].UserFraction != 0.5 {
+		t.Errorf("last point = %+v", points[3])
+	}
+}
+
+func TestLorenzCurveSteps(t *testing.T) {
+	users := make([]User, 1000)
+	for i := range users {
+		users[i].TotalScore = float64(1000 - i)
+	}
+
+	points := lorenzCurve(users, 0.1)
+	if len(points) != 10 {
+		t.Fatalf("got %d points for 10%% steps, want 10", len(points))
+	}
+	for i, point := range points {
+		if point.Rank != (i+1)*100 {
+			t.Errorf("point %d at rank %d, want %d", i, point.Rank, (i+1)*100)
+		}
+	}
+	if !closeTo(points[9].CumulativeFraction, 1) {
+		t.Errorf("curve ends at %v, want 1", points[9].CumulativeFraction)
+	}
+
+	// Steps that don't divide the board still end on the last rank, and
+	// the ones in between are rounded per -rank-rounding.
+	t.Cleanup(func() { rankRounding = "floor" })
+	for rounding, want := range map[string]int{"floor": 3, "round": 4, "ceil": 4} {
+		rankRounding = rounding
+		points = lorenzCurve(users[:7], 0.5)
+		if len(points) != 2 || points[0].Rank != want || points[1].Rank != 7 {
+			t.Errorf("%s: points = %+v, want ranks %d and 7", rounding, points, want)
+		}
+	}
+}
+
+func TestWriteLorenzCSV(t *testing.T) {
+	var out bytes.Buffer
+	writeLorenzCSV(&out, lorenzCurve([]User{{TotalScore: 3}, {TotalScore: 1}}, 0))
+	want := "rank,user_fraction,cumulative_points,cumulative_fraction\n1,0.5,3,0.75\n2,1,4,1\n"
+	if out.String() != want {
+		t.Errorf("csv = %q, want %q", out.String(), want)
+	}
+}
@@ -1,13 +1,25 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/HeuDeaI/taikoPointsByLevel/internal/progress"
 )
 
 type User struct {
@@ -31,121 +43,1440 @@ type Response struct {
 	LastUpdated int64 `json:"lastUpdated"`
 }
 
+type Result struct {
+	Season      int     `json:"season"`
+	Percentage  float64 `json:"percentage"`
+	TotalUsers  int     `json:"totalUsers"`
+	LastUpdated int64   `json:"lastUpdated"`
+	Rank        int     `json:"rank"`
+	Points      int     `json:"points"`
+	Skipped     bool    `json:"skipped,omitempty"`
+	Estimated   bool    `json:"estimated,omitempty"`
+	Tier        string  `json:"tier,omitempty"`
+	// BasisPoints is Percentage in basis points, set only in JSON output
+	// with -bps.
+	BasisPoints float64 `json:"bps,omitempty"`
+	// PointsPerDay is Points divided by ActiveDays, the UTC days of the
+	// season begun by LastUpdated, set only in JSON output with
+	// -normalize-by-days.
+	PointsPerDay float64 `json:"pointsPerDay,omitempty"`
+	ActiveDays   int     `json:"activeDays,omitempty"`
+	// BaselineRatio is Points as a multiple of the -baseline cutoff's, set
+	// only in JSON output.
+	BaselineRatio float64 `json:"baselineRatio,omitempty"`
+	// Score and Multiplier are those of the user at Rank, making up
+	// Points, set only with -breakdown.
+	Score      float64 `json:"score,omitempty"`
+	Multiplier int     `json:"multiplier,omitempty"`
+}
+
+// Exit codes of failures that scripts may want to tell apart. Any other
+// failure exits with 1, and -get exits with 2 for an unknown key.
 const (
-	baseURL    = "https://trailblazer.mainnet.taiko.xyz/s2/v2/leaderboard/user"
-	timeout    = 10 * time.Second
-	retryLimit = 3
+	exitNotRanked             = 3
+	exitBeyondPaginationLimit = 4
+	exitStaleData             = 5
 )
 
-var (
-	client         = &http.Client{Timeout: timeout}
-	topPercentages = []float64{
-		0.0001, 0.001, 0.005, 0.01, 0.03, 0.04, 0.06, 0.08, 0.1, 0.18, 0.26,
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrNotRanked):
+		return exitNotRanked
+	case errors.Is(err, ErrBeyondPaginationLimit):
+		return exitBeyondPaginationLimit
+	case errors.Is(err, ErrStaleData):
+		return exitStaleData
 	}
+	return 1
+}
+
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+	RequestID  string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d%s\nResponse body: %s", e.StatusCode, requestIDSuffix(e.RequestID), e.Body)
+}
+
+func requestIDSuffix(id string) string {
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (request %s)", id)
+}
+
+const (
+	leaderboardPath = "/s%d/v2/leaderboard/user"
+	defaultSeason   = 2
+	timeout         = 10 * time.Second
+	retryLimit      = 3
+
+	// maxErrorBodyBytes is how much of a non-200 response is kept for the
+	// error message.
+	maxErrorBodyBytes = 4 << 10
+	maxProbes         = 64
+)
+
+var (
+	seasons       = []int{defaultSeason}
+	explain       bool
+	verbose       bool
+	userAgent     = defaultUserAgent()
+	sendRequestID bool
+	strict        bool
+	fallbackTotal int
+	outputFormat  = "text"
+
+	// maxResponseBytes caps the body of a single page, and
+	// maxExportResponseBytes the pages streamed by -dump-json.
+	maxResponseBytes       int64 = 10 << 20
+	maxExportResponseBytes int64 = 64 << 20
+
+	// deepTimeoutStep is added to the request timeout for deep ranks; see
+	// timeoutForRank.
+	deepTimeoutStep = 5 * time.Second
+
+	// retryBackoff is the first wait before retrying a failed request; later
+	// waits grow linearly. Each is jittered; see retryWait.
+	retryBackoff = time.Second
+
+	// emptyRetryBackoff is the first wait before refetching a page that came
+	// back empty with -retry-empty; later waits grow linearly.
+	emptyRetryBackoff = time.Second
+
+	// deadline bounds each calculatePointsForTopUsers call (0 = none).
+	deadline time.Duration
+
+	// maxAge is the oldest leaderboard -max-age lets a run compute on (0 =
+	// any).
+	maxAge time.Duration
+
+	// redact is nil unless -redact-addresses is set.
+	redact *redactor
 )
 
+var (
+	ErrBudgetExhausted   = errors.New("request budget exhausted")
+	ErrSeasonUnavailable = errors.New("season unavailable")
+	ErrInvalidAddress    = errors.New("invalid address")
+	ErrAboveTopScore     = errors.New("above the top score")
+	ErrRankMismatch      = errors.New("rank mismatch")
+	// ErrRankOutOfRange is a computed rank that doesn't fit the board or
+	// the pages it would be fetched from.
+	ErrRankOutOfRange   = errors.New("rank out of range")
+	ErrResponseTooLarge = errors.New("response too large")
+	// ErrBeyondPaginationLimit is a rank on the board that the API won't
+	// page to.
+	ErrBeyondPaginationLimit = errors.New("beyond the pagination limit")
+	// ErrNotRanked is a well-formed address that isn't on the board.
+	ErrNotRanked = errors.New("not ranked")
+	// ErrSchemaViolation is a response that doesn't match responseSchema,
+	// with -strict-schema.
+	ErrSchemaViolation = errors.New("response doesn't match the expected schema")
+	// ErrStaleData is a leaderboard last updated longer ago than -max-age.
+	ErrStaleData = errors.New("stale data")
+)
+
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
 func fetchResponse(url string) (Response, error) {
+	return defaultClient.fetchResponse(context.Background(), url, timeout)
+}
+
+// timeoutForRank returns the per-request deadline for the page holding rank.
+// Deep pages are slower to serve, so every power of ten from rank 1,000 up
+// adds deepTimeoutStep to the base timeout: rank 500 gets timeout, rank 5,000
+// timeout+step and rank 50,000 timeout+2*step.
+func timeoutForRank(rank int) time.Duration {
+	d := timeout
+	for r := rank / 1000; r > 0; r /= 10 {
+		d += deepTimeoutStep
+	}
+	return d
+}
+
+// fetchResponse fetches url, giving each attempt its own deadline of
+// perRequest on top of ctx.
+func (c *Client) fetchResponse(ctx context.Context, url string, perRequest time.Duration) (Response, error) {
 	var response Response
+	err := c.fetchDecode(ctx, url, perRequest, maxResponseBytes, func(body io.Reader) (int, error) {
+		response = Response{}
+		err := c.parseJSONResponse(body, &response)
+		return len(response.Data.Users), err
+	})
+	return response, err
+}
+
+// fetchDecode fetches url with retries and hands the body of a 200 response
+// to decode, which returns the number of users it read. The body is cut off
+// after limit bytes, failing with ErrResponseTooLarge.
+func (c *Client) fetchDecode(ctx context.Context, url string, perRequest time.Duration, limit int64, decode func(io.Reader) (int, error)) error {
+	defer c.progress.Done()
+
 	for attempt := 0; attempt < retryLimit; attempt++ {
-		req, err := http.NewRequest("GET", url, nil)
+		if attempt > 0 {
+			c.progress.Retry()
+		}
+		if err := c.breaker.allow(); err != nil {
+			return err
+		}
+		url = c.mirrors.rewrite(url)
+		base := c.base()
+
+		attemptCtx, cancel := context.WithTimeout(ctx, perRequest)
+		defer cancel()
+		req, requestID, err := newAPIRequest(attemptCtx, url)
 		if err != nil {
+			c.breaker.release()
 			log.Printf("Failed to create request: %v", err)
 			continue
 		}
-		req.Header.Set("User-Agent", "Mozilla/5.0")
 
-		resp, err := client.Do(req)
+		if !c.acquireRequest() {
+			c.breaker.release()
+			return ErrBudgetExhausted
+		}
+		requestedAt := time.Now()
+		resp, err := c.HTTPClient.Do(req)
+		if errors.Is(err, errTooManyRedirects) {
+			// The API answered, and asking again would redirect the same way.
+			c.breaker.success()
+			return fmt.Errorf("failed to send request%s: %w", requestIDSuffix(requestID), err)
+		}
 		if err != nil {
+			if ctx.Err() != nil {
+				// Cancelled by the caller, not failed by the upstream: a
+				// deadline, a spent budget or a scan that found its address.
+				c.breaker.release()
+				return fmt.Errorf("failed to send request: %w", ctx.Err())
+			}
+			c.breaker.failure()
+			if c.mirrors.connectionFailed(base, err) {
+				continue
+			}
 			if attempt < retryLimit-1 {
-				time.Sleep(time.Second * time.Duration(attempt+1))
+				if err := sleepCtx(ctx, retryWait(attempt+1)); err != nil {
+					return fmt.Errorf("failed to send request: %w", err)
+				}
 				continue
 			}
-			return response, fmt.Errorf("failed to send request after retries: %v", err)
+			return fmt.Errorf("failed to send request%s after retries: %w", requestIDSuffix(requestID), err)
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode >= http.StatusInternalServerError {
+			c.breaker.failure()
+			if c.mirrors.serverError(base) {
+				resp.Body.Close()
+				continue
+			}
+		} else {
+			c.breaker.success()
+		}
+
 		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return response, fmt.Errorf("unexpected status code: %d\nResponse body: %s", resp.StatusCode, body)
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+			logRequest(url, requestID, resp.StatusCode, int64(len(body)), 0)
+			return &StatusError{StatusCode: resp.StatusCode, Body: body, RequestID: requestID}
 		}
 
-		err = parseJSONResponse(resp.Body, &response)
+		var raw bytes.Buffer
+		var reader io.Reader = resp.Body
+		if c.audit != nil {
+			reader = io.TeeReader(resp.Body, &raw)
+		}
+		body := &limitedReader{r: reader, n: limit}
+		users, err := decode(body)
+		logRequest(url, requestID, resp.StatusCode, limit-body.n, users)
 		if err != nil {
-			return response, fmt.Errorf("failed to decode JSON response: %v", err)
+			if body.exceeded {
+				return fmt.Errorf("%w: more than %d bytes%s", ErrResponseTooLarge, limit, requestIDSuffix(requestID))
+			}
+			// A connection dropped mid-body; try again on a new one.
+			if (errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)) && attempt < retryLimit-1 {
+				log.Printf("Response from %s%s was cut off, retrying: %v", url, requestIDSuffix(requestID), err)
+				resp.Body.Close()
+				if err := sleepCtx(ctx, retryWait(attempt+1)); err != nil {
+					return fmt.Errorf("failed to decode JSON response: %w", err)
+				}
+				continue
+			}
+			return fmt.Errorf("failed to decode JSON response%s: %w", requestIDSuffix(requestID), err)
 		}
-		return response, nil
+		c.mirrors.ok(base)
+		if c.audit != nil {
+			if err := c.audit.record(url, resp, requestedAt, raw.Bytes()); err != nil {
+				return fmt.Errorf("-audit: %w", err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("retries exceeded")
+}
+
+// newTransport is the API transport, with dial bounding each attempt to
+// connect and header the wait for a response's headers once the request is
+// sent. The per-request timeout bounds the whole exchange on top of these.
+// network forces "tcp4" or "tcp6" for dual-stack hosts where one is broken;
+// empty dials either.
+func newTransport(dial, header time.Duration, network string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: dial, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	if network != "" {
+		transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	transport.ResponseHeaderTimeout = header
+	return transport
+}
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// redirectPolicy follows at most max redirects, logging each one. The
+// User-Agent and X-Request-ID go along to wherever the API redirects;
+// credentials, whether an Authorization header or the user:password of a
+// -base-url, only within the same host.
+func redirectPolicy(max int) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > max {
+			return fmt.Errorf("%w: not following the one to %s (-max-redirects %d)", errTooManyRedirects, req.URL.Redacted(), max)
+		}
+		first, previous := via[0], via[len(via)-1]
+		log.Printf("Redirected from %s to %s (%d)", previous.URL.Redacted(), req.URL.Redacted(), req.Response.StatusCode)
+		for _, name := range []string{"User-Agent", "X-Request-ID"} {
+			if value := first.Header.Get(name); value != "" {
+				req.Header.Set(name, value)
+			}
+		}
+		if req.URL.Host == first.URL.Host {
+			if auth := first.Header.Get("Authorization"); auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			if req.URL.User == nil {
+				req.URL.User = first.URL.User
+			}
+		}
+		return nil
+	}
+}
+
+// newAPIRequest builds a GET of url with the headers every API request
+// carries. requestID is the X-Request-ID sent with -request-id.
+func newAPIRequest(ctx context.Context, url string) (req *http.Request, requestID string, err error) {
+	req, err = http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	showURL(url)
+	if sendRequestID {
+		requestID = newRequestID()
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	return req, requestID, nil
+}
+
+// sleepCtx waits for d, or returns ctx's error if it is done first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// logRequest logs a completed request with -verbose.
+func logRequest(url, requestID string, status int, bytes int64, users int) {
+	if !verbose {
+		return
+	}
+	log.Printf("GET %s%s: %d, %d bytes, %d users", url, requestIDSuffix(requestID), status, bytes, users)
+}
+
+// limitedReader reads at most n bytes from r and fails with
+// ErrResponseTooLarge if r has more.
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		var probe [1]byte
+		if n, err := l.r.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		l.exceeded = true
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+func parseSeasons(list string) ([]int, error) {
+	var seasons []int
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(list, ",") {
+		season, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || season < 1 {
+			return nil, fmt.Errorf("invalid season %q", field)
+		}
+		if seen[season] {
+			continue
+		}
+		seen[season] = true
+		seasons = append(seasons, season)
+	}
+	return seasons, nil
+}
+
+func (c *Client) leaderboardURL(season int) string {
+	return c.base() + fmt.Sprintf(leaderboardPath, season)
+}
+
+// pageURL returns the URL of a page of the leaderboard split into pages of
+// size users.
+func (c *Client) pageURL(season, page, size int) string {
+	return fmt.Sprintf("%s?page=%d&size=%d", c.leaderboardURL(season), page, size)
+}
+
+// FetchBoard fetches the first page of season's leaderboard, with its total
+// and when it was last updated.
+func (c *Client) FetchBoard(ctx context.Context, season int) (Response, error) {
+	url := c.leaderboardURL(season)
+	response, err := c.fetchResponse(ctx, url, timeout)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return response, fmt.Errorf("%w: season %d: %w", ErrSeasonUnavailable, season, err)
+	}
+	if err != nil {
+		return response, fmt.Errorf("failed to fetch total wallets: %w", err)
 	}
-	return response, fmt.Errorf("retries exceeded")
+	c.cache.observe(url, response.LastUpdated)
+	return response, nil
 }
 
-func parseJSONResponse(body io.Reader, response *Response) error {
-	return json.NewDecoder(body).Decode(response)
+// fetchBoardOrEstimate is fetchBoard, except that when the board can't be
+// fetched and -fallback-total is set the estimate is used instead. The
+// season being unavailable is never papered over.
+func (c *Client) fetchBoardOrEstimate(ctx context.Context, season int) (board Response, estimated bool, err error) {
+	board, err = c.FetchBoard(ctx, season)
+	if err == nil || fallbackTotal <= 0 || errors.Is(err, ErrSeasonUnavailable) {
+		return board, false, err
+	}
+	log.Printf("Season %d: using estimated total of %d users: %v", season, fallbackTotal, err)
+	board = Response{Data: Data{Total: fallbackTotal}}
+	return board, true, nil
 }
 
-func getTotalWallets() (int, error) {
-	response, err := fetchResponse(baseURL)
+// checkDataAge fails with ErrStaleData if season's leaderboard was last
+// updated more than maxAge before now.
+func checkDataAge(season int, maxAge time.Duration, now time.Time) error {
+	board, err := defaultClient.FetchBoard(context.Background(), season)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch total wallets: %v", err)
+		return fmt.Errorf("-max-age: %w", err)
+	}
+	if board.LastUpdated == 0 {
+		return fmt.Errorf("%w: season %d has no lastUpdated to check -max-age against", ErrStaleData, season)
+	}
+	updated := time.Unix(board.LastUpdated, 0)
+	if age := now.Sub(updated); age > maxAge {
+		return fmt.Errorf("%w: season %d was last updated %s ago (%s), past -max-age %s",
+			ErrStaleData, season, age.Round(time.Second), updated.UTC().Format(time.RFC3339), maxAge)
+	}
+	return nil
+}
+
+func getTotalWallets(season int) (int, error) {
+	response, err := defaultClient.FetchBoard(context.Background(), season)
+	if err != nil {
+		return 0, err
 	}
 	return response.Data.Total, nil
 }
 
-func getUserTotalPoints(rank int) (int, error) {
-	url := fmt.Sprintf("%s?page=%d&size=1", baseURL, rank)
-	response, err := fetchResponse(url)
+// UserAtRank returns the user at the 1-based rank. Ranks map to pages of
+// pageSize users: rank r is at offset (r-1)%pageSize on page
+// (r-1)/pageSize+pageBase, so with -page-size 1 and 1-based pages the request
+// is page=r&size=1. The rank the API reports for that user must match, after
+// translating from -rank-base; the returned user's Rank is always 1-based.
+func (c *Client) UserAtRank(ctx context.Context, season, rank int) (User, error) {
+	return c.userAtRankPaged(ctx, season, rank, c.PageSize, nil)
+}
+
+// userAtRankPaged is UserAtRank with pages of size users, fetching the
+// first attempt at each page through pages so that ranks sharing a page
+// share the request.
+func (c *Client) userAtRankPaged(ctx context.Context, season, rank, size int, pages *pageMemo) (User, error) {
+	if rank < 1 {
+		return User{}, fmt.Errorf("invalid rank %d", rank)
+	}
+
+	pageBase := c.pageBase()
+	page := (rank-1)/size + pageBase
+	offset := (rank - 1) % size
+	base := c.leaderboardURL(season)
+	url := c.pageURL(season, page, size)
+	var response Response
+	for attempt := 1; ; attempt++ {
+		var err error
+		if attempt == 1 {
+			response, err = pages.fetch(ctx, c, base, url, timeoutForRank(rank))
+		} else {
+			response, err = c.fetchCachedResponse(ctx, base, url, timeoutForRank(rank))
+		}
+		if err != nil {
+			return User{}, fmt.Errorf("failed to fetch page %d: %w", page, err)
+		}
+		if !c.RetryEmpty || len(response.Data.Users) > 0 || attempt >= retryLimit {
+			break
+		}
+		// The API is eventually consistent and sometimes serves an empty
+		// page for a rank that exists.
+		log.Printf("Season %d: page %d came back empty, retrying (%d/%d)", season, page, attempt, retryLimit-1)
+		c.progress.AddTotal(1)
+		if err := sleepCtx(ctx, capBackoff(emptyRetryBackoff*time.Duration(attempt))); err != nil {
+			return User{}, fmt.Errorf("failed to fetch page %d: %w", page, err)
+		}
+	}
+
+	if len(response.Data.Users) == 0 && rank <= response.Data.Total {
+		// The rank exists, so the API stops paginating somewhere before
+		// this page.
+		return User{}, fmt.Errorf("%w: rank %d of %d is on page %d, which came back empty; the API appears to serve at most %d pages (ranks up to %d) of size %d",
+			ErrBeyondPaginationLimit, rank, response.Data.Total, page, page-pageBase, (page-pageBase)*size, size)
+	}
+	if offset >= len(response.Data.Users) {
+		return User{}, fmt.Errorf("page %d has %d users, no user at offset %d", page, len(response.Data.Users), offset)
+	}
+
+	user := response.Data.Users[offset]
+	checkLeaderboardAddress(user)
+	if want := rank - 1 + c.RankBase; user.Rank != want {
+		return User{}, fmt.Errorf("%w: requested rank %d (page %d, size %d, offset %d, API rank %d) but API returned rank %d", ErrRankMismatch, rank, page, size, offset, want, user.Rank)
+	}
+	user.Rank = rank
+	return user, nil
+}
+
+// fromAPIRank translates a rank reported by the API into a 1-based rank.
+func (c *Client) fromAPIRank(rank int) int {
+	return rank - c.RankBase + 1
+}
+
+// normalizeAddress checks that address is a 0x-prefixed 40 hex digit wallet
+// address and returns it in lowercase.
+func normalizeAddress(address string) (string, error) {
+	if !addressPattern.MatchString(address) {
+		return "", fmt.Errorf("%w %q: want 0x followed by 40 hex digits", ErrInvalidAddress, address)
+	}
+	return strings.ToLower(address), nil
+}
+
+// checkLeaderboardAddress reports a malformed address returned by the API
+// when -strict is set.
+func checkLeaderboardAddress(user User) {
+	if strict && !addressPattern.MatchString(user.Address) {
+		log.Printf("Warning: leaderboard returned malformed address %q at rank %d", user.Address, user.Rank)
+	}
+}
+
+// userByAddress looks up a wallet using the leaderboard's address filter.
+// found is false when the address is not on the board.
+func userByAddress(ctx context.Context, season int, address string) (user User, total int, found bool, err error) {
+	user, board, found, err := lookupAddress(ctx, season, address)
+	return user, board.Data.Total, found, err
+}
+
+// lookupAddress is userByAddress, returning the whole response the user was
+// found in. With -scan-address it scans the pages instead of filtering.
+func lookupAddress(ctx context.Context, season int, address string) (user User, response Response, found bool, err error) {
+	address, err = normalizeAddress(address)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch total points: %v", err)
+		return User{}, response, false, err
+	}
+	if scanAddresses {
+		user, response, found, err = findUserByAddress(ctx, season, address)
+		if err != nil {
+			return User{}, response, false, fmt.Errorf("failed to scan for address %s: %w", address, err)
+		}
+		return user, response, found, nil
 	}
 
-	if len(response.Data.Users) == 0 {
-		return 0, fmt.Errorf("no users found in response")
+	url := fmt.Sprintf("%s?address=%s", defaultClient.leaderboardURL(season), neturl.QueryEscape(address))
+	response, err = defaultClient.fetchResponse(ctx, url, timeout)
+	if err != nil {
+		return User{}, response, false, fmt.Errorf("failed to fetch address %s: %w", address, err)
 	}
 
-	return int(response.Data.Users[0].TotalScore), nil
+	user, found = findAddress(response, address)
+	return user, response, found, nil
+}
+
+// findAddress is the user with the normalized address among the users of
+// response, with its rank made 1-based.
+func findAddress(response Response, address string) (User, bool) {
+	for _, user := range response.Data.Users {
+		checkLeaderboardAddress(user)
+		user.Rank = defaultClient.fromAPIRank(user.Rank)
+		if strings.ToLower(user.Address) == address {
+			return user, true
+		}
+	}
+	return User{}, false
 }
 
-func calculatePointsForTopUsers() ([]int, error) {
-	totalUsers, err := getTotalWallets()
+func findRankForPoints(season, target int) (int, int, error) {
+	totalUsers, err := getTotalWallets(season)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get total wallets: %v", err)
+		return 0, 0, fmt.Errorf("failed to get total wallets: %w", err)
+	}
+	if totalUsers < 1 {
+		return 0, 0, fmt.Errorf("leaderboard is empty")
 	}
 
+	top, err := defaultClient.UserAtRank(context.Background(), season, 1)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get top points: %w", err)
+	}
+	topPoints := int(top.TotalScore)
+	if topPoints < target {
+		return 0, 0, fmt.Errorf("target %d is %w %d", target, ErrAboveTopScore, topPoints)
+	}
+
+	last, err := defaultClient.UserAtRank(context.Background(), season, totalUsers)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get last points: %w", err)
+	}
+	lastPoints := int(last.TotalScore)
+	if lastPoints >= target {
+		log.Printf("Target %d is below the lowest score %d, every ranked user reaches it", target, lastPoints)
+		return totalUsers, lastPoints, nil
+	}
+
+	// Invariant: rank lo has at least target points, rank hi has fewer.
+	lo, hi := 1, totalUsers
+	loPoints := topPoints
+	for probes := 2; hi-lo > 1; probes++ {
+		if probes >= maxProbes {
+			return 0, 0, fmt.Errorf("no boundary found for target %d after %d probes", target, probes)
+		}
+		mid := lo + (hi-lo)/2
+		user, err := defaultClient.UserAtRank(context.Background(), season, mid)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get total points for rank %d: %w", mid, err)
+		}
+		points := int(user.TotalScore)
+		if points >= target {
+			lo, loPoints = mid, points
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, loPoints, nil
+}
+
+// calculatePointsForTopUsers computes the cutoffs of season, bounded by
+// -deadline if set.
+func calculatePointsForTopUsers(season int) ([]Result, error) {
+	return calculateSeasonCutoffs(season, 1)
+}
+
+// calculateSeasonCutoffs is calculatePointsForTopUsers for one of shares
+// seasons computed at once, each planned within its share of what is left
+// of -max-requests.
+func calculateSeasonCutoffs(season, shares int) ([]Result, error) {
+	ctx := context.Background()
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+	return defaultClient.calculateCutoffs(ctx, season, shares)
+}
+
+// planCutoffs is the ranks of the cutoffs of c.Percentages on a board of
+// totalUsers, and how their pages are fetched with a share of the budget.
+func (c *Client) planCutoffs(totalUsers, shares int) ([]int, pagePlan, error) {
+	ranks := make([]int, len(c.Percentages))
+	for i, percentage := range c.Percentages {
+		var err error
+		if ranks[i], err = c.cutoffRank(totalUsers, percentage, c.PageSize); err != nil {
+			return nil, pagePlan{}, err
+		}
+	}
+	return ranks, planPages(ranks, c.PageSize, c.requestsLeft(shares)), nil
+}
+
+// Cutoffs computes the cutoffs of season for c.Percentages with one worker
+// per percentage. Every fetch runs under ctx, so once it is done all
+// outstanding work is abandoned and its error is returned.
+func (c *Client) Cutoffs(ctx context.Context, season int) ([]Result, error) {
+	return c.calculateCutoffs(ctx, season, 1)
+}
+
+// calculateCutoffs does the work of Cutoffs. With -max-requests the fetches
+// are planned up front to fit the season's share of the budget; see
+// planPages. If the budget still runs out mid-run, the remaining fetches
+// are cancelled and the partial results returned.
+func (c *Client) calculateCutoffs(ctx context.Context, season, shares int) ([]Result, error) {
+	c.calibratePages(season)
+
+	board, estimated, err := c.fetchBoardOrEstimate(ctx, season)
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("failed to get total wallets: %w", ctx.Err())
+	}
+	if err != nil {
+		if errors.Is(err, ErrCircuitOpen) {
+			return nil, fmt.Errorf("upstream appears down: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get total wallets: %w", err)
+	}
+
+	totalUsers := board.Data.Total
+	ranks, plan, err := c.planCutoffs(totalUsers, shares)
+	if err != nil {
+		return nil, err
+	}
+	plan.log(season)
+
+	budgetCtx, cancelBudget := context.WithCancel(ctx)
+	defer cancelBudget()
+	pages := &pageMemo{}
 	var wg sync.WaitGroup
-	points := make([]int, len(topPercentages))
-	errs := make([]error, len(topPercentages))
+	results := make([]Result, len(c.Percentages))
+	errs := make([]error, len(c.Percentages))
 
-	for i, percentage := range topPercentages {
+	for i, percentage := range c.Percentages {
+		rank := ranks[i]
+		results[i] = Result{Season: season, Percentage: percentage, TotalUsers: totalUsers, LastUpdated: board.LastUpdated, Rank: rank, Estimated: estimated}
+		if plan.skip[i] {
+			errs[i] = fmt.Errorf("%w: skipped rank %d to stay within the budget", ErrBudgetExhausted, rank)
+			results[i].Skipped = true
+			streamResult(results[i])
+			continue
+		}
 		wg.Add(1)
-		go func(i int, percentage float64) {
+		go func(i int) {
 			defer wg.Done()
-			rank := int(float64(totalUsers) * percentage)
-			totalPoints, err := getUserTotalPoints(rank)
+			defer func() { streamResult(results[i]) }()
+			user, err := c.userAtRankPaged(budgetCtx, season, rank, plan.size, pages)
 			if err != nil {
-				errs[i] = fmt.Errorf("failed to get total points for rank %d: %v", rank, err)
+				if errors.Is(err, ErrBudgetExhausted) {
+					cancelBudget()
+				} else if budgetCtx.Err() != nil && ctx.Err() == nil {
+					err = fmt.Errorf("%w: cancelled", ErrBudgetExhausted)
+				}
+				errs[i] = fmt.Errorf("failed to get total points for rank %d: %w", rank, err)
+				results[i].Skipped = true
 				return
 			}
-			points[i] = totalPoints
-		}(i, percentage)
+			results[i].Points = int(user.TotalScore)
+			if breakdown {
+				results[i].Score, results[i].Multiplier = user.Score, user.Multiplier
+			}
+		}(i)
 	}
 
 	wg.Wait()
 
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("error calculating points: %w", ctx.Err())
+	}
+	for _, err := range errs {
+		if errors.Is(err, ErrCircuitOpen) {
+			return nil, fmt.Errorf("upstream appears down: %w", err)
+		}
+	}
+
+	// Once the budget is exhausted the run is partial anyway, so results are
+	// kept even if other ranks failed for unrelated reasons.
+	exhausted := false
+	var failures []error
 	for _, err := range errs {
+		if errors.Is(err, ErrBudgetExhausted) {
+			exhausted = true
+		} else if err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if exhausted {
+		return results, fmt.Errorf("error calculating points: %w", errors.Join(append([]error{ErrBudgetExhausted}, failures...)...))
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("error calculating points: %w", failures[0])
+	}
+
+	return results, nil
+}
+
+type seasonResults struct {
+	Unavailable bool `json:"unavailable,omitempty"`
+	// BudgetLimited is set when -max-requests ran out before every cutoff
+	// was computed; the skipped ones have no points.
+	BudgetLimited bool                `json:"budgetLimited,omitempty"`
+	Results       []Result            `json:"results,omitempty"`
+	Criteria      []criterionEstimate `json:"criteria,omitempty"`
+}
+
+func calculateSeasons(seasons []int) ([]seasonResults, error) {
+	var wg sync.WaitGroup
+	all := make([]seasonResults, len(seasons))
+	errs := make([]error, len(seasons))
+
+	for i, season := range seasons {
+		wg.Add(1)
+		go func(i, season int) {
+			defer wg.Done()
+			results, err := calculateSeasonCutoffs(season, len(seasons))
+			if errors.Is(err, ErrSeasonUnavailable) {
+				log.Printf("Season %d is unavailable: %v", season, err)
+				all[i].Unavailable = true
+				return
+			}
+			all[i].Results = results
+			errs[i] = err
+		}(i, season)
+	}
+
+	wg.Wait()
+
+	exhausted := false
+	var failures []error
+	for i, err := range errs {
+		if errors.Is(err, ErrBudgetExhausted) {
+			exhausted = true
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error calculating points: %v", err)
+			failures = append(failures, fmt.Errorf("season %d: %w", seasons[i], err))
 		}
 	}
+	if exhausted {
+		return all, errors.Join(failures...)
+	}
+	if len(failures) > 0 {
+		return nil, failures[0]
+	}
+	return all, nil
+}
+
+func runCommand(config Config, args []string) error {
+	c, ok := lookupCommand(args[0])
+	if !ok || c.run == nil {
+		return fmt.Errorf("unknown command %q; run %s help for the commands", args[0], programName)
+	}
+	if c.actions != nil && len(args) > 1 && slices.Contains([]string{"-h", "-help", "--help"}, args[1]) {
+		writeCommandUsage(usageOutput, c.name, flag.NewFlagSet(c.name, flag.ContinueOnError))
+		return flag.ErrHelp
+	}
+	return c.run(config, args[1:])
+}
 
-	return points, nil
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
 func main() {
-	points, err := calculatePointsForTopUsers()
+	flag.Float64Var(&baseline, "baseline", 0, "also show each cutoff as a multiple of this percentage's cutoff, e.g. 0.1")
+	flag.BoolVar(&normalizeByDays, "normalize-by-days", false, "also show scores divided by the UTC days since the season started")
+	seasonStartFlag := flag.String("season-start", "", "first day of the season for -normalize-by-days, as YYYY-MM-DD or season:YYYY-MM-DD pairs (default the known start)")
+	flag.DurationVar(&maxAge, "max-age", 0, "fail before computing if the leaderboard was last updated longer ago than this (0 = no limit)")
+	dialTimeout := flag.Duration("dial-timeout", 5*time.Second, "give up connecting to the API after this long")
+	maxRedirects := flag.Int("max-redirects", 10, "follow at most this many redirects from the API (0 = none)")
+	forceIPv4 := flag.Bool("force-ipv4", false, "connect to the API over IPv4 only")
+	forceIPv6 := flag.Bool("force-ipv6", false, "connect to the API over IPv6 only")
+	headerTimeout := flag.Duration("response-header-timeout", 30*time.Second, "give up waiting for the API to start responding after this long; the request timeout still bounds reading the body")
+	flag.DurationVar(&deadline, "deadline", 0, "give up on computing the cutoffs of a season after this long, abandoning outstanding requests (0 = no limit)")
+	flag.DurationVar(&backoffMax, "backoff-max", 0, "cap every wait before a retry at this, however many retries came before (0 = no cap)")
+	flag.Int64Var(&defaultClient.MaxRequests, "max-requests", 0, "maximum number of HTTP requests per run or watch cycle, including retries (0 = unlimited)")
+	flag.IntVar(&defaultClient.breaker.threshold, "breaker-threshold", defaultClient.breaker.threshold, "consecutive upstream failures before failing fast (0 = disabled)")
+	flag.DurationVar(&defaultClient.breaker.cooldown, "breaker-cooldown", defaultClient.breaker.cooldown, "time before an open circuit breaker lets a probe request through")
+	percentagesList := flag.String("percentages", "", "comma-separated list of top percentages to compute, e.g. 0.01,0.05")
+	percentagesFile := flag.String("percentages-file", "", "file with one top percentage per line (blank lines and # comments ignored)")
+	preset := flag.String("preset", "", "compute a named list of percentages, merged with -percentages, -percentages-file or -percentile-step if given; see -list-presets")
+	listPresets := flag.Bool("list-presets", false, "print the percentages of every -preset and exit")
+	percentileStep := flag.Float64("percentile-step", 0, "compute every multiple of this many percent up to 100%, e.g. 5 for 5%, 10%, ... 100% (ignored with -percentages or -percentages-file)")
+	flag.IntVar(&densifyFactor, "densify", 1, "split the gap between each pair of consecutive percentages into this many equal parts, e.g. 2 adds every midpoint")
+	targetPoints := flag.Int("target-points", 0, "find the lowest rank that still has at least this many points")
+	address := flag.String("address", "", "look up the rank and points of this wallet address, or of a comma-separated list of them")
+	track := flag.String("track", "", "record the rows of these comma-separated wallet addresses to the history with each run's cutoffs, for history address")
+	addressesFile := flag.String("addresses-file", "", "look up every wallet address in this file, one per line")
+	flag.BoolVar(&scanAddresses, "scan-address", false, fmt.Sprintf("look a single address up by scanning the leaderboard's pages, %d at once, instead of with the API's address filter", scanConcurrency))
+	flag.IntVar(&percentilePrecision, "percentile-precision", percentilePrecision, fmt.Sprintf("decimals of the percentile shown for -address (0-%d)", maxPercentilePrecision))
+	flag.BoolVar(&strict, "strict", false, "warn about malformed addresses in the leaderboard data")
+	flag.IntVar(&defaultClient.PageSize, "page-size", defaultClient.PageSize, "page size used to locate a user by rank")
+	align := flag.Bool("align", false, "with -watch, refresh on wall-clock multiples of the interval, e.g. on the hour for -watch 1h")
+	watchInterval := flag.Duration("watch", 0, "recompute the cutoffs at this interval instead of exiting (0 = run once)")
+	discordWebhook := flag.String("discord-webhook", "", "with -watch, post cutoff changes to this Discord webhook")
+	slackWebhook := flag.String("slack-webhook", "", "with -watch, post cutoff changes to this Slack incoming webhook")
+	slackChannel := flag.String("slack-channel-override", "", "post to this Slack channel instead of the webhook's, where the webhook allows it")
+	alertThreshold := flag.Int("alert-threshold", 0, "mark notifications of cutoffs moving by at least this many points as alerts (0 = never)")
+	notifyInterval := flag.Duration("notify-interval", defaultNotifyInterval, "send to each notification sink at most this often, queueing what comes faster")
+	notifyQueueDepth := flag.Int("notify-queue", defaultNotifyQueue, "notifications queued per sink before the oldest is dropped")
+	notifyCoalesce := flag.Int("notify-coalesce", defaultNotifyCoalesce, "combine a sink's queued notifications into one once more than this many wait (0 = never)")
+	metricsAddr := flag.String("metrics-addr", "", "with a notification sink, serve the notification queues at /metrics on this address, e.g. :9090")
+	quietHoursFlag := flag.String("quiet-hours", "", "hold notifications during this daily window, e.g. 23:00-07:00, and send what changed as one digest when it ends")
+	digest := flag.Duration("digest", 0, "batch notifications into a digest at most this often, e.g. 6h (quiet hours take precedence)")
+	timezone := flag.String("timezone", "", "IANA time zone of -quiet-hours and digests (default the config's timezone, else local time)")
+	statePath := flag.String("state-file", "", "keep the last cutoffs and held notifications in this file across restarts")
+	tui := flag.Bool("tui", false, "with -watch, show the cutoffs, their change since the last cycle and the data age as a dashboard redrawn in place, q to quit (plain output when stdout is not a terminal)")
+	flag.StringVar(&daemonSocket, "daemon-socket", defaultDaemonSocket(), "ask the daemon on this Unix socket before fetching for -get (empty to always fetch)")
+	flag.DurationVar(&defaultClient.cache.ttl, "cache-ttl", 0, "reuse fetched pages across watch cycles, and history snapshots for -get, for up to this long (0 = no caching)")
+	flag.BoolVar(&explain, "explain", false, "print how each number was computed")
+	flag.BoolVar(&hashResults, "hash", false, "print a SHA-256 of the results (season, percentage and points) instead of the results, for change detection")
+	flag.BoolVar(&hashTimestamps, "include-timestamp", false, "include lastUpdated in the -hash fingerprint")
+	fields := flag.String("fields", "", "comma-separated User fields for CSV and table output of users, in order, e.g. rank,address,totalScore (default all)")
+	stream := flag.Bool("stream", false, "print each result as soon as it is computed, before the final summary (on stderr unless -format is text)")
+	flag.StringVar(&rankRounding, "rank-rounding", rankRounding, fmt.Sprintf("how totalUsers*percentage is rounded to a rank: %s; floor is the original truncation less float error (100*0.29 is rank 29, not 28), and round breaks a tie at .5 upward", strings.Join(rankRoundings, ", ")))
+	flag.IntVar(&fallbackTotal, "fallback-total", 0, "user count to assume if the total can't be fetched; results are marked as estimated (0 = fail)")
+	redactAddresses := flag.Bool("redact-addresses", false, "replace wallet addresses in the output with stable pseudonyms")
+	enrichRPC := flag.String("enrich-rpc", "", "annotate -address lookups and top with is_contract and tx_count from this Ethereum JSON-RPC endpoint, e.g. https://rpc.mainnet.taiko.xyz")
+	enrichRate := flag.Float64("enrich-rpc-rate", 10, "maximum -enrich-rpc requests per second")
+	redactSecret := flag.String("redact-secret", os.Getenv("TAIKO_REDACT_SECRET"), "key for -redact-addresses pseudonyms, so they match across runs (default random per run)")
+	flag.BoolVar(&defaultClient.RetryEmpty, "retry-empty", false, "retry pages that come back with no users, which the API sometimes serves for ranks that exist")
+	flag.DurationVar(&deepTimeoutStep, "deep-timeout-step", deepTimeoutStep, "extra request timeout for every power of ten from rank 1000 up")
+	examples := flag.Int("examples", 0, "show this many example users sampled at random ranks within each band")
+	seed := flag.Int64("seed", 0, "random seed for -examples (0 = pick one and log it)")
+	dumpPath := flag.String("dump-json", "", "write every user on the leaderboard to this JSON file")
+	pretty := flag.Bool("pretty", false, "indent the -dump-json output")
+	statsFlag := flag.Bool("stats", false, "print the count, total, range, mean, variance and standard deviation of every user's points")
+	lorenz := flag.Bool("lorenz", false, "print the cumulative share of points by rank as CSV, for plotting a Lorenz curve")
+	scoresFlag := flag.String("scores", "", "find the rank, percentile and tier of each of these comma-separated scores, e.g. 250000,500000")
+	bandFlag := flag.String("band", "", "count the users between two top percentages, e.g. 0.01,0.05, and sum their points")
+	lorenzStep := flag.Float64("lorenz-step", 0, "with -lorenz, print a row per this fraction of users, e.g. 0.01 for every percent (0 = every rank)")
+	flag.IntVar(&defaultClient.RankBase, "rank-base", defaultClient.RankBase, "rank the API reports for the top user, 0 or 1")
+	flag.IntVar(&defaultClient.PageBase, "page-base", defaultClient.PageBase, "index of the first leaderboard page, 0 or 1")
+	flag.BoolVar(&defaultClient.calibration.enabled, "calibrate-pages", false, "detect whether pages start at 0 or 1 before fetching ranks")
+	flag.DurationVar(&defaultClient.calibration.ttl, "calibrate-ttl", time.Hour, "how long a -calibrate-pages result is trusted")
+	flag.BoolVar(&compactJSON, "compact-json", false, "leave zero and empty fields and addresses out of -format json and ndjson output, unindented")
+	flag.BoolVar(&breakdown, "breakdown", false, "also show the score and multiplier making up the points at each cutoff")
+	flag.BoolVar(&basisPoints, "bps", false, "show percentages as basis points (0.01 = 100 bps) in every output format")
+	flag.StringVar(&decimalSep, "decimal-sep", decimalSep, `decimal separator for text output, "." or ","`)
+	flag.StringVar(&userAgent, "user-agent", userAgent, "User-Agent header sent to the API")
+	flag.BoolVar(&sendRequestID, "request-id", false, "send a unique X-Request-ID header with each request and include it in errors")
+	criteriaFile := flag.String("criteria-file", "", "JSON file of official tiers ([{\"tier\": ..., \"minScore\": ...}]) to compare the cutoffs against")
+	auditDir := flag.String("audit", "", "with -address, save the raw API responses of the lookup and a manifest to this directory, for `audit verify`")
+	teePath := flag.String("tee", "", "also write what is printed to stdout to this file, like tee(1)")
+	flag.StringVar(&outDir, "out-dir", "", "write the cutoffs in every -output format to files in this directory instead of stdout")
+	flag.Var(&outDirFormats, "output", fmt.Sprintf("format to write with -out-dir, repeatable or comma-separated: %s (default json,csv,md)", strings.Join(outputFormats, ", ")))
+	flag.StringVar(&snapshotDir, "snapshot-dir", "", "also write the cutoffs of every run or -watch cycle to timestamped files in this directory (formats from -output, default json,csv)")
+	flag.IntVar(&snapshotKeep, "keep", 0, "keep only the newest this many snapshots in -snapshot-dir (0 = keep all)")
+	flag.StringVar(&outDirLayout, "out-dir-layout", outDirLayout, `"flat", or "date" to write into a dated subdirectory`)
+	flag.BoolVar(&gzipOutput, "gzip-output", false, "gzip-compress -dump-json and -out-dir files and append .gz to their names")
+	flag.Int64Var(&maxResponseBytes, "max-response-bytes", maxResponseBytes, "largest response body accepted for a single page")
+	flag.Int64Var(&maxExportResponseBytes, "max-export-response-bytes", maxExportResponseBytes, "largest response body accepted for a -dump-json page")
+	checkSchema := flag.Bool("schema-check", false, "log response keys that no field of the decoded response takes, as early warning of API changes")
+	flag.BoolVar(&defaultClient.StrictSchema, "strict-schema", false, "fail on responses that don't match the expected schema instead of logging a warning")
+	flag.BoolVar(&showURLs, "show-urls", false, "print every API URL requested to stderr, once, with secret query parameters redacted")
+	flag.BoolVar(&dryRun, "dry-run", false, "fetch only the totals and print the page URLs the cutoffs would be fetched from, without fetching them")
+	getKey := flag.String("get", "", fmt.Sprintf("print just this value for scripts: %s", strings.Join(getKeys, ", ")))
+	flag.StringVar(&splitOutput, "split-output", "", "also write each percentage's result to its own JSON file in this directory")
+	flag.IntVar(&splitNeighbors, "split-neighbors", 0, "with -split-output, include the users this many ranks above and below each cutoff")
+	noProgress := flag.Bool("no-progress", false, "never show the progress display on stderr")
+	configPath := flag.String("config", "", fmt.Sprintf("YAML config file (default %s)", defaultConfigPath()))
+	globalFlags.VisitAll(func(f *flag.Flag) { flag.Var(f.Value, f.Name, f.Usage) })
+	flag.Usage = func() { writeUsage(flag.CommandLine.Output(), flag.CommandLine) }
+	log.SetFlags(0)
+	flag.Parse()
+	if flag.NArg() > 0 {
+		if _, err := parseModeCommand(flag.CommandLine, flag.Args()); err != nil {
+			fatalErr("Error: ", err)
+		}
+	}
+
+	if *checkSchema {
+		schemaCheck = newUnmappedKeys()
+	}
+	if *tui && *watchInterval > 0 {
+		dashboardView = openDashboard(os.Stdout)
+	}
+	if !*noProgress && dashboardView == nil && progress.IsTerminal(os.Stderr) {
+		defaultClient.progress = progress.New(os.Stderr, time.Now)
+	}
+	var logOut io.Writer = defaultClient.progress.Writer(os.Stderr)
+	if dashboardView != nil {
+		logOut = dashboardView
+	}
+	logDedup = newDedupWriter(logOut, time.Now)
+	log.SetFlags(0)
+	log.SetOutput(logDedup)
+	defer logDedup.Flush()
+	if *align && *watchInterval <= 0 {
+		fatalf("Error: -align needs -watch")
+	}
+	if *tui && dashboardView == nil {
+		if *watchInterval <= 0 {
+			fatalf("Error: -tui needs -watch")
+		}
+		log.Printf("-tui: stdout is not a terminal, printing plainly")
+	}
+
+	if *listPresets {
+		writePresets(os.Stdout)
+		return
+	}
+	if *redactAddresses {
+		redact = newRedactor(*redactSecret)
+	}
+	if *enrichRPC != "" {
+		if *enrichRate <= 0 {
+			fatalf("Error: -enrich-rpc-rate must be positive")
+		}
+		enricher = newRPCEnricher(*enrichRPC, *enrichRate)
+	}
+
+	if percentilePrecision < 0 || percentilePrecision > maxPercentilePrecision {
+		fatalf("Error: -percentile-precision must be between 0 and %d", maxPercentilePrecision)
+	}
+	if *fields != "" {
+		var err error
+		if userFields, err = parseFields(*fields); err != nil {
+			fatalf("Error: -fields: %v", err)
+		}
+	}
+	if !slices.Contains(rankRoundings, rankRounding) {
+		fatalf("Error: unknown -rank-rounding %q, want one of %s", rankRounding, strings.Join(rankRoundings, ", "))
+	}
+
+	if *teePath != "" {
+		if dashboardView != nil {
+			fatalf("Error: -tee can't record the -tui dashboard")
+		}
+		file, err := openTee(*teePath)
+		if err != nil {
+			fatalf("Error: -tee: %v", err)
+		}
+		defer file.Close()
+	}
+
+	if *stream {
+		streamTo = defaultClient.progress.Writer(stdout)
+		if outputFormat != "text" {
+			streamTo = defaultClient.progress.Writer(os.Stderr)
+		}
+	}
+
+	if outDir != "" {
+		if err := checkOutDir(outDir); err != nil {
+			fatalf("Error: -out-dir: %v", err)
+		}
+	}
+
+	if snapshotDir != "" {
+		if snapshotKeep < 0 {
+			fatalf("Error: -keep must not be negative")
+		}
+		for _, format := range snapshotFormats() {
+			if !slices.Contains(outputFormats, format) {
+				fatalf("Error: unknown -output %q, want one of %s", format, strings.Join(outputFormats, ", "))
+			}
+		}
+	}
+
+	if decimalSep != "." && decimalSep != "," {
+		fatalf("Error: -decimal-sep must be \".\" or \",\"")
+	}
+
+	if defaultClient.RankBase != 0 && defaultClient.RankBase != 1 {
+		fatalf("Error: -rank-base must be 0 or 1")
+	}
+
+	if defaultClient.pageBase() != 0 && defaultClient.pageBase() != 1 {
+		fatalf("Error: -page-base must be 0 or 1")
+	}
+
+	if defaultClient.PageSize < 1 {
+		fatalf("Error: -page-size must be at least 1")
+	}
+
+	if *dialTimeout <= 0 || *headerTimeout <= 0 {
+		fatalf("Error: -dial-timeout and -response-header-timeout must be positive")
+	}
+	network := ""
+	switch {
+	case *forceIPv4 && *forceIPv6:
+		fatalf("Error: -force-ipv4 and -force-ipv6 are mutually exclusive")
+	case *forceIPv4:
+		network = "tcp4"
+	case *forceIPv6:
+		network = "tcp6"
+	}
+	defaultClient.HTTPClient.Transport = newTransport(*dialTimeout, *headerTimeout, network)
+	if backoffMax < 0 {
+		fatalf("Error: -backoff-max must not be negative")
+	}
+	if *maxRedirects < 0 {
+		fatalf("Error: -max-redirects must not be negative")
+	}
+	defaultClient.HTTPClient.CheckRedirect = redirectPolicy(*maxRedirects)
+
+	var err error
+	if *seasonStartFlag != "" {
+		if seasonStarts, err = parseSeasonStarts(*seasonStartFlag, seasons); err != nil {
+			fatalf("Error: -season-start: %v", err)
+		}
+	}
+	if normalizeByDays {
+		if err := checkSeasonStarts(seasons); err != nil {
+			fatalf("Error: %v", err)
+		}
+	}
+
+	switch {
+	case *percentagesList != "" && *percentagesFile != "":
+		fatalf("Error: -percentages and -percentages-file are mutually exclusive")
+	case *percentagesList != "":
+		percentages, err := parsePercentages(*percentagesList)
+		if err != nil {
+			fatalf("Error: -percentages: %v", err)
+		}
+		defaultClient.Percentages = percentages
+	case *percentagesFile != "":
+		percentages, err := readPercentagesFile(*percentagesFile)
+		if err != nil {
+			fatalf("Error: %v", err)
+		}
+		defaultClient.Percentages = percentages
+	case flagSet("percentile-step"):
+		percentages, err := stepPercentages(*percentileStep)
+		if err != nil {
+			fatalf("Error: -percentile-step: %v", err)
+		}
+		defaultClient.Percentages = percentages
+	}
+	if *preset != "" {
+		percentages, err := findPreset(*preset)
+		if err != nil {
+			fatalf("Error: -preset: %v", err)
+		}
+		if *percentagesList != "" || *percentagesFile != "" || flagSet("percentile-step") {
+			percentages = unionPercentages(defaultClient.Percentages, percentages)
+		}
+		defaultClient.Percentages = percentages
+	}
+
+	if *criteriaFile != "" {
+		criteria, err = readCriteriaFile(*criteriaFile)
+		if err != nil {
+			fatalf("Error: %v", err)
+		}
+	}
+
+	path, explicit := *configPath, true
+	if path == "" {
+		path, explicit = defaultConfigPath(), false
+	}
+	config, err := loadConfig(path, explicit)
 	if err != nil {
-		log.Fatalf("Error: %v", err)
+		fatalf("Error: %v", err)
+	}
+
+	percentagesFromFlags := *percentagesList != "" || *percentagesFile != "" || flagSet("percentile-step") || *preset != ""
+	if len(config.Percentages) > 0 && !percentagesFromFlags {
+		defaultClient.Percentages = config.Percentages
+	}
+	reloader = &configReloader{path: path, explicit: explicit, current: config, percentagesFromFlags: percentagesFromFlags}
+	if densifyFactor < 1 {
+		fatalf("Error: -densify %d: want a factor of at least 1", densifyFactor)
+	}
+	if densifyFactor > 1 {
+		defaultClient.Percentages = densifyPercentages(defaultClient.Percentages, densifyFactor)
+	}
+	if err := checkBaseline(defaultClient.Percentages); err != nil {
+		fatalf("Error: %v", err)
+	}
+
+	if config.UserAgent != "" && !flagSet("user-agent") {
+		userAgent = config.UserAgent
+	}
+
+	if len(defaultClient.baseURLs) == 0 {
+		defaultClient.baseURLs = config.BaseURLs
+	}
+	defaultClient.useBaseURLs()
+
+	if flag.NArg() > 0 {
+		if err := runCommand(config, flag.Args()); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fatalErr("Error: ", err)
+		}
+		return
+	}
+
+	if dryRun {
+		for _, name := range []string{"watch", "address", "addresses-file", "get", "dump-json", "stats", "lorenz", "scores", "band", "examples", "target-points"} {
+			if flagSet(name) {
+				fatalf("Error: -dry-run plans the cutoffs only, not -%s", name)
+			}
+		}
+		urls, err := plannedURLs(context.Background(), seasons)
+		if err != nil {
+			fatalErr("Error: ", err)
+		}
+		for _, url := range urls {
+			fmt.Fprintln(stdout, redactURL(url))
+		}
+		return
+	}
+
+	if *auditDir != "" {
+		switch {
+		case *address == "" || strings.Contains(*address, ",") || *addressesFile != "":
+			fatalf("Error: -audit needs a single -address")
+		case redact != nil || enricher != nil:
+			fatalf("Error: -audit can't be reproduced from the API's responses with -redact-addresses or -enrich-rpc")
+		}
+		var err error
+		if defaultClient.audit, err = newAuditor(*auditDir); err != nil {
+			fatalf("Error: -audit: %v", err)
+		}
+	}
+
+	if *address != "" && !strings.Contains(*address, ",") && *addressesFile == "" {
+		user, board, found, err := lookupAddress(context.Background(), seasons[0], *address)
+		if err != nil {
+			fatalf("Error: -address: %v", err)
+		}
+		if !found {
+			fatalErr("Error: -address: ", fmt.Errorf("%s is %w in season %d", *address, ErrNotRanked, seasons[0]))
+		}
+		position := newWalletPosition(user, board.Data.Total, percentilePrecision)
+		if normalizeByDays {
+			position.normalize(seasons[0], board.LastUpdated)
+		}
+		if enricher != nil {
+			activity := enricher.lookup(context.Background(), user.Address)
+			position.addressActivity = &activity
+		}
+		var rendered bytes.Buffer
+		if err := writeWalletPosition(io.MultiWriter(stdout, &rendered), outputFormat, position); err != nil {
+			fatalf("Error: %v", err)
+		}
+		if defaultClient.audit != nil {
+			if err := defaultClient.audit.finish(currentAuditRender(seasons[0], *address), position, rendered.Bytes()); err != nil {
+				fatalf("Error: -audit: %v", err)
+			}
+			log.Printf("Saved the audit of %s to %s; check it with `audit verify %s`", *address, *auditDir, *auditDir)
+		}
+		return
+	}
+
+	if *address != "" || *addressesFile != "" {
+		addresses, err := readAddresses(*address, *addressesFile)
+		if err != nil {
+			fatalf("Error: %v", err)
+		}
+		positions, missing, err := lookupAddresses(seasons[0], addresses)
+		if err != nil {
+			fatalf("Error: %v", err)
+		}
+		if len(missing) > 0 {
+			log.Printf("%d of %d addresses are not ranked in season %d", len(missing), len(addresses), seasons[0])
+		}
+		if enricher != nil {
+			enrichPositions(enricher, positions)
+		}
+		if err := writeAddressTable(stdout, outputFormat, positions, missing); err != nil {
+			fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if *getKey != "" {
+		store, err := openSnapshotStore(config)
+		if err != nil {
+			fatalf("Error: %v", err)
+		}
+		code := runGet(*getKey, store, stdout, os.Stderr)
+		logDedup.Flush()
+		os.Exit(code)
+	}
+
+	if *dumpPath != "" {
+		if err := dumpJSON(*dumpPath, *pretty); err != nil {
+			fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if *scoresFlag != "" {
+		scores, err := parseScores(*scoresFlag)
+		if err != nil {
+			fatalf("Error: -scores: %v", err)
+		}
+		if err := printScores(scores); err != nil {
+			fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if *bandFlag != "" {
+		from, to, err := parseBand(*bandFlag)
+		if err != nil {
+			fatalf("Error: %v", err)
+		}
+		if err := printBand(from, to); err != nil {
+			fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if *statsFlag {
+		if err := printStats(); err != nil {
+			fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if *lorenz {
+		if err := printLorenz(*lorenzStep); err != nil {
+			fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if *examples > 0 {
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		if err := printExamples(*examples, *seed); err != nil {
+			fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	if *targetPoints > 0 {
+		rank, points, err := findRankForPoints(seasons[0], *targetPoints)
+		if err != nil {
+			fatalErr("Error: ", err)
+		}
+		fmt.Fprintf(stdout, "rank %d: %d points\n", rank, points)
+		return
+	}
+
+	var sinks []Notifier
+	var sinkNames []string
+	if *discordWebhook != "" {
+		sinks = append(sinks, newDiscordNotifier(*discordWebhook))
+		sinkNames = append(sinkNames, "discord")
+	}
+	if *slackWebhook != "" {
+		sinks = append(sinks, newSlackNotifier(*slackWebhook, *slackChannel))
+		sinkNames = append(sinkNames, "slack")
+	} else if *slackChannel != "" {
+		fatalf("Error: -slack-channel-override needs -slack-webhook")
+	}
+	if len(sinks) == 0 && (*quietHoursFlag != "" || *digest != 0 || *alertThreshold != 0) {
+		fatalf("Error: -quiet-hours, -digest and -alert-threshold need -discord-webhook or -slack-webhook")
+	}
+	if len(sinks) > 0 {
+		if *watchInterval <= 0 {
+			fatalf("Error: -discord-webhook and -slack-webhook need -watch")
+		}
+		if *digest < 0 || *alertThreshold < 0 || *notifyInterval < 0 || *notifyCoalesce < 0 {
+			fatalf("Error: -digest, -alert-threshold, -notify-interval and -notify-coalesce must not be negative")
+		}
+		if *notifyQueueDepth < 1 {
+			fatalf("Error: -notify-queue must be at least 1")
+		}
+		for i, sink := range sinks {
+			queue := newNotifyQueue(sinkNames[i], sink, *notifyInterval, *notifyQueueDepth, *notifyCoalesce)
+			go queue.run(context.Background())
+			notifyQueues = append(notifyQueues, queue)
+			sinks[i] = queue
+		}
+		var quiet *quietHours
+		if *quietHoursFlag != "" {
+			var err error
+			if quiet, err = parseQuietHours(*quietHoursFlag); err != nil {
+				fatalf("Error: -quiet-hours: %v", err)
+			}
+		}
+		if *timezone == "" {
+			*timezone = config.Timezone
+		}
+		location, err := time.LoadLocation(*timezone)
+		if err != nil {
+			fatalf("Error: -timezone: %v", err)
+		}
+		notifications, err = newWatchNotifications(sinks, quiet, *digest, location, *statePath)
+		if err != nil {
+			fatalf("Error: %v", err)
+		}
+		notifications.alertThreshold = *alertThreshold
+	}
+	if *metricsAddr != "" {
+		if len(notifyQueues) == 0 {
+			fatalf("Error: -metrics-addr needs -discord-webhook or -slack-webhook")
+		}
+		if err := serveWatchMetrics(*metricsAddr); err != nil {
+			fatalf("Error: -metrics-addr: %v", err)
+		}
+	}
+
+	if *track != "" {
+		addresses, err := readAddresses(*track, "")
+		if err != nil {
+			fatalf("Error: -track: %v", err)
+		}
+		store, err := openSnapshotStore(config)
+		if err != nil {
+			fatalf("Error: %v", err)
+		}
+		tracker = &historyTracker{store: store, addresses: addresses}
+	}
+
+	if *watchInterval > 0 {
+		stop := reloader.watchSignals()
+		defer stop()
+		var clock watchClock = realClock{}
+		if dashboardView != nil {
+			clock = dashboardClock{view: dashboardView}
+			go func() {
+				err := dashboardView.run()
+				logDedup.Flush()
+				if err != nil {
+					fatalf("Error: -tui: %v", err)
+				}
+				os.Exit(0)
+			}()
+		}
+		schedule := newWatchScheduler(clock, *watchInterval, *align)
+		for {
+			schedule.wait(context.Background())
+			reloader.apply()
+			defaultClient.resetBudget()
+			err := printCutoffs()
+			if err != nil {
+				log.Printf("Error: %v", err)
+			}
+			logDedup.Flush()
+			if dashboardView != nil {
+				dashboardView.cycleDone(err, schedule.upcoming())
+			}
+		}
 	}
 
-	for _, pointsForLevel := range points {
-		fmt.Println(pointsForLevel)
+	if err := printCutoffs(); err != nil {
+		fatalErr("Error: ", err)
 	}
 }
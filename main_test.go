@@ -0,0 +1,571 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fixtureUpstream serves testdata files keyed by the page and size query
+// parameters, e.g. "2/3" for page=2&size=3.
+func fixtureUpstream(t *testing.T, fixtures map[string]string) http.Handler {
+	t.Helper()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("page") + "/" + r.URL.Query().Get("size")
+		name, ok := fixtures[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Errorf("reading fixture: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	})
+}
+
+// countRequests wraps handler, counting the requests it serves.
+func countRequests(n *int, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*n++
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// rankedUpstream serves a synthetic 1-based leaderboard of total users where
+// rank r has 10*(total-r+1) points and the address 0x%040x of r. The address
+// filter returns that single user, or no items for an unknown address.
+func rankedUpstream(total int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if address := r.URL.Query().Get("address"); address != "" {
+			var items string
+			rank, err := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(address), "0x"), 16, 64)
+			if err == nil && rank >= 1 && int(rank) <= total {
+				points := 10 * (total - int(rank) + 1)
+				items = fmt.Sprintf(`{"rank":%d,"address":"0x%040x","score":%d,"multiplier":1,"totalScore":%d}`, rank, rank, points, points)
+			}
+			fmt.Fprintf(w, `{"data":{"items":[%s],"page":1,"size":10,"total":%d,"total_pages":1},"lastUpdated":1718000000}`, items, total)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		if page < 1 {
+			page = 1
+		}
+		if size < 1 {
+			size = 10
+		}
+
+		var items []string
+		for rank := (page-1)*size + 1; rank <= page*size && rank <= total; rank++ {
+			points := 10 * (total - rank + 1)
+			items = append(items, fmt.Sprintf(`{"rank":%d,"address":"0x%040x","score":%d,"multiplier":1,"totalScore":%d}`, rank, rank, points, points))
+		}
+		fmt.Fprintf(w, `{"data":{"items":[%s],"page":%d,"size":%d,"total":%d,"total_pages":%d},"lastUpdated":1718000000}`,
+			strings.Join(items, ","), page, size, total, (total+size-1)/size)
+	})
+}
+
+func setPageSize(t *testing.T, size int) {
+	t.Helper()
+	old := defaultClient.PageSize
+	defaultClient.PageSize = size
+	t.Cleanup(func() { defaultClient.PageSize = old })
+}
+
+func TestUserAtRankOneBasedPages(t *testing.T) {
+	useFakeUpstream(t, fixtureUpstream(t, map[string]string{"2/3": "page2_size3_one_based.json"}))
+	setPageSize(t, 3)
+
+	user, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 5)
+	if err != nil {
+		t.Fatalf("userAtRank(5): %v", err)
+	}
+	if user.Rank != 5 || user.TotalScore != 950 || user.Address != "0x00000000000000000000000000000000000000a5" {
+		t.Fatalf("userAtRank(5) = %+v, want rank 5 with 950 points", user)
+	}
+}
+
+func TestUserAtRankRejectsZeroBasedPages(t *testing.T) {
+	useFakeUpstream(t, fixtureUpstream(t, map[string]string{"2/3": "page2_size3_zero_based.json"}))
+	setPageSize(t, 3)
+
+	_, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 5)
+	if !errors.Is(err, ErrRankMismatch) {
+		t.Fatalf("userAtRank(5) err = %v, want rank mismatch", err)
+	}
+	if !strings.Contains(err.Error(), "API returned rank 8") {
+		t.Fatalf("mismatch error does not name the returned rank: %v", err)
+	}
+}
+
+func TestUserAtRankPageAndOffset(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(100))
+
+	for _, size := range []int{1, 3, 7, 50} {
+		setPageSize(t, size)
+		for _, rank := range []int{1, 2, size, size + 1, 99, 100} {
+			user, err := defaultClient.UserAtRank(context.Background(), defaultSeason, rank)
+			if err != nil {
+				t.Fatalf("size %d: userAtRank(%d): %v", size, rank, err)
+			}
+			if user.Rank != rank {
+				t.Fatalf("size %d: userAtRank(%d) returned rank %d", size, rank, user.Rank)
+			}
+		}
+	}
+}
+
+func TestUserAtRankBeyondBoard(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(100))
+	setPageSize(t, 10)
+
+	if _, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 101); err == nil {
+		t.Fatal("userAtRank(101) on a 100-user board succeeded")
+	}
+}
+
+func TestCalculatePointsClampsRankToOne(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(100))
+	old := defaultClient.Percentages
+	defaultClient.Percentages = []float64{0.001, 0.5}
+	t.Cleanup(func() { defaultClient.Percentages = old })
+
+	results, err := calculatePointsForTopUsers(defaultSeason)
+	if err != nil {
+		t.Fatalf("calculatePointsForTopUsers: %v", err)
+	}
+	if results[0].Rank != 1 || results[0].Points != 1000 {
+		t.Fatalf("top 0.1%% of 100 users = rank %d, %d points; want rank 1, 1000 points", results[0].Rank, results[0].Points)
+	}
+	if results[1].Rank != 50 || results[1].Points != 510 {
+		t.Fatalf("top 50%% of 100 users = rank %d, %d points; want rank 50, 510 points", results[1].Rank, results[1].Points)
+	}
+}
+
+func TestUserByAddressValidatesAddress(t *testing.T) {
+	requests := 0
+	useFakeUpstream(t, countRequests(&requests, rankedUpstream(100)))
+
+	for _, address := range []string{"", "0x123", "000000000000000000000000000000000000000a0a", "0x00000000000000000000000000000000000000zz"} {
+		if _, _, _, err := userByAddress(t.Context(), defaultSeason, address); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("userByAddress(t.Context(), %q) = %v, want ErrInvalidAddress", address, err)
+		}
+	}
+	if requests != 0 {
+		t.Fatalf("malformed addresses caused %d requests", requests)
+	}
+
+	user, _, found, err := userByAddress(t.Context(), defaultSeason, "0x000000000000000000000000000000000000000A")
+	if err != nil || !found || user.Rank != 10 {
+		t.Fatalf("mixed-case lookup = %+v, %v, %v; want rank 10", user, found, err)
+	}
+}
+
+func TestFallbackTotalWhenBoardFails(t *testing.T) {
+	board := rankedUpstream(1000)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			http.Error(w, "upstream timeout", http.StatusGatewayTimeout)
+			return
+		}
+		board.ServeHTTP(w, r)
+	}))
+	oldPercentages, oldFallback := defaultClient.Percentages, fallbackTotal
+	defaultClient.Percentages = []float64{0.01}
+	t.Cleanup(func() { defaultClient.Percentages, fallbackTotal = oldPercentages, oldFallback })
+
+	if _, err := calculatePointsForTopUsers(defaultSeason); err == nil {
+		t.Fatal("expected an error without -fallback-total")
+	}
+
+	fallbackTotal = 1000
+	results, err := calculatePointsForTopUsers(defaultSeason)
+	if err != nil {
+		t.Fatalf("calculatePointsForTopUsers: %v", err)
+	}
+	if got := results[0]; !got.Estimated || got.TotalUsers != 1000 || got.Rank != 10 || got.Points != 9910 {
+		t.Fatalf("result = %+v, want an estimated rank 10 with 9910 points", got)
+	}
+	if got := formatPoints(results[0]); got != "~9910" {
+		t.Fatalf("formatPoints = %q, want ~9910", got)
+	}
+}
+
+func TestTimeoutForRank(t *testing.T) {
+	for _, tc := range []struct {
+		rank int
+		want time.Duration
+	}{
+		{1, timeout},
+		{999, timeout},
+		{1000, timeout + deepTimeoutStep},
+		{9999, timeout + deepTimeoutStep},
+		{50000, timeout + 2*deepTimeoutStep},
+		{2000000, timeout + 4*deepTimeoutStep},
+	} {
+		if got := timeoutForRank(tc.rank); got != tc.want {
+			t.Errorf("timeoutForRank(%d) = %s, want %s", tc.rank, got, tc.want)
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	var gotAgent, gotID string
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAgent, gotID = r.Header.Get("User-Agent"), r.Header.Get("X-Request-ID")
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	t.Cleanup(func() { sendRequestID = false })
+
+	_, err := fetchResponse(defaultClient.leaderboardURL(defaultSeason))
+	if !strings.HasPrefix(gotAgent, "taikoPointsByLevel/") || !strings.Contains(gotAgent, repoURL) {
+		t.Fatalf("User-Agent = %q", gotAgent)
+	}
+	if gotID != "" || strings.Contains(err.Error(), "request ") {
+		t.Fatalf("X-Request-ID sent without -request-id: %q, %v", gotID, err)
+	}
+
+	sendRequestID = true
+	_, err = fetchResponse(defaultClient.leaderboardURL(defaultSeason))
+	if len(gotID) != 36 || gotID[14] != '4' {
+		t.Fatalf("X-Request-ID = %q, want a v4 UUID", gotID)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.RequestID != gotID || !strings.Contains(err.Error(), gotID) {
+		t.Fatalf("error %v does not carry request id %s", err, gotID)
+	}
+}
+
+// zeroRankedUpstream serves pages of size 1 where page p holds the user the
+// API labels rank p-1, i.e. 1-based pages with 0-based rank labels.
+func zeroRankedUpstream(total int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var items string
+		if page >= 1 && page <= total {
+			points := 10 * (total - page + 1)
+			items = fmt.Sprintf(`{"rank":%d,"address":"0x%040x","score":%d,"multiplier":1,"totalScore":%d}`, page-1, page, points, points)
+		}
+		fmt.Fprintf(w, `{"data":{"items":[%s],"page":%d,"size":1,"total":%d,"total_pages":%d},"lastUpdated":1718000000}`, items, page, total, total)
+	})
+}
+
+func TestUserAtRankRankBase(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(100))
+	for _, rank := range []int{1, 2, 50, 100} {
+		user, err := defaultClient.UserAtRank(context.Background(), defaultSeason, rank)
+		if err != nil || user.Rank != rank || user.TotalScore != float64(10*(101-rank)) {
+			t.Fatalf("page=%d&size=1 returned %+v, %v; want the user at rank %d", rank, user, err, rank)
+		}
+	}
+
+	useFakeUpstream(t, zeroRankedUpstream(100))
+	if _, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 5); err == nil || !strings.Contains(err.Error(), "API returned rank 4") {
+		t.Fatalf("0-based rank labels with -rank-base 1: err = %v, want a mismatch", err)
+	}
+
+	old := defaultClient.RankBase
+	defaultClient.RankBase = 0
+	t.Cleanup(func() { defaultClient.RankBase = old })
+	user, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 5)
+	if err != nil || user.Rank != 5 || user.Address != fmt.Sprintf("0x%040x", 5) {
+		t.Fatalf("-rank-base 0: userAtRank(5) = %+v, %v", user, err)
+	}
+}
+
+func TestVerboseLogsRequests(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(100))
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	verbose = true
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		verbose = false
+	})
+
+	url := defaultClient.leaderboardURL(defaultSeason) + "?page=1&size=3"
+	if _, err := fetchResponse(url); err != nil {
+		t.Fatal(err)
+	}
+	line := logs.String()
+	if !strings.Contains(line, "GET "+url+": 200, ") || !strings.Contains(line, " bytes, 3 users") {
+		t.Fatalf("verbose log = %q", line)
+	}
+	var size int
+	fmt.Sscanf(line[strings.Index(line, ": 200, ")+len(": 200, "):], "%d bytes", &size)
+	if size < 100 {
+		t.Fatalf("logged size %d is too small for a 3-user page", size)
+	}
+}
+
+func TestRetryEmptyPage(t *testing.T) {
+	board := rankedUpstream(100)
+	var requests, empty int
+	useFakeUpstream(t, countRequests(&requests, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if empty > 0 {
+			empty--
+			fmt.Fprint(w, `{"data":{"items":[],"page":5,"size":1,"total":100,"total_pages":100},"lastUpdated":1}`)
+			return
+		}
+		board.ServeHTTP(w, r)
+	})))
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	oldBackoff := emptyRetryBackoff
+	emptyRetryBackoff = 0
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		emptyRetryBackoff = oldBackoff
+		defaultClient.RetryEmpty = false
+	})
+
+	empty = 1
+	if _, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 5); err == nil {
+		t.Fatal("empty page succeeded without -retry-empty")
+	}
+
+	defaultClient.RetryEmpty = true
+	requests, empty = 0, 2
+	user, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 5)
+	if err != nil {
+		t.Fatalf("userAtRank with -retry-empty: %v", err)
+	}
+	if user.Rank != 5 || requests != 3 {
+		t.Errorf("got rank %d after %d requests, want rank 5 after 3", user.Rank, requests)
+	}
+	if n := strings.Count(logs.String(), "came back empty"); n != 2 {
+		t.Errorf("logged %d empty retries, want 2:\n%s", n, logs.String())
+	}
+
+	requests, empty = 0, retryLimit
+	if _, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 5); err == nil {
+		t.Fatal("page that stays empty succeeded")
+	}
+	if requests != retryLimit {
+		t.Errorf("made %d requests for a page that stays empty, want %d", requests, retryLimit)
+	}
+}
+
+func TestRetryTruncatedResponse(t *testing.T) {
+	board := rankedUpstream(100)
+	var requests int
+	useFakeUpstream(t, countRequests(&requests, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests == 1 {
+			// Promise more than is sent, so the connection drops mid-body.
+			w.Header().Set("Content-Length", "1000")
+			fmt.Fprint(w, `{"data":{"items":[{"rank":1,`)
+			return
+		}
+		board.ServeHTTP(w, r)
+	})))
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	oldBackoff := retryBackoff
+	retryBackoff = 0
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		retryBackoff = oldBackoff
+	})
+
+	user, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 1)
+	if err != nil {
+		t.Fatalf("userAtRank after a truncated response: %v", err)
+	}
+	if user.Rank != 1 || requests != 2 {
+		t.Errorf("got rank %d after %d requests, want rank 1 after 2", user.Rank, requests)
+	}
+	if !strings.Contains(logs.String(), "was cut off, retrying") {
+		t.Errorf("retry was not logged: %q", logs.String())
+	}
+}
+
+func TestDeadlineCancelsHangingFetches(t *testing.T) {
+	board := rankedUpstream(1000)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Rank 10 answers; every other rank hangs until the client gives up.
+		if page := r.URL.Query().Get("page"); page != "" && page != "10" {
+			<-r.Context().Done()
+			return
+		}
+		board.ServeHTTP(w, r)
+	}))
+	old, oldDeadline := defaultClient.Percentages, deadline
+	defaultClient.Percentages = []float64{0.001, 0.01, 0.1}
+	deadline = 200 * time.Millisecond
+	t.Cleanup(func() { defaultClient.Percentages, deadline = old, oldDeadline })
+
+	start := time.Now()
+	_, err := calculatePointsForTopUsers(defaultSeason)
+	elapsed := time.Since(start)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("returned after %v, want close to the 200ms deadline", elapsed)
+	}
+}
+
+func TestBeyondPaginationLimit(t *testing.T) {
+	useFakeUpstream(t, fixtureUpstream(t, map[string]string{"34/3": "page34_size3_empty.json"}))
+	setPageSize(t, 3)
+
+	_, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 100)
+	if !errors.Is(err, ErrBeyondPaginationLimit) {
+		t.Fatalf("userAtRank(100) err = %v, want ErrBeyondPaginationLimit", err)
+	}
+	if !strings.Contains(err.Error(), "at most 33 pages (ranks up to 99)") {
+		t.Errorf("error doesn't name the apparent cap: %v", err)
+	}
+	if code := exitCode(fmt.Errorf("wrapped: %w", err)); code != exitBeyondPaginationLimit {
+		t.Errorf("exit code %d, want %d", code, exitBeyondPaginationLimit)
+	}
+
+	// A rank past the end of the board is not a pagination limit.
+	_, err = defaultClient.UserAtRank(context.Background(), defaultSeason, 101)
+	if err == nil || errors.Is(err, ErrBeyondPaginationLimit) {
+		t.Errorf("userAtRank(101) err = %v, want a plain error", err)
+	}
+}
+
+func TestAddressNotRanked(t *testing.T) {
+	useFakeUpstream(t, fixtureUpstream(t, map[string]string{"/": "address_not_ranked.json"}))
+
+	_, total, found, err := userByAddress(t.Context(), defaultSeason, testAddress(500))
+	if err != nil || found || total != 100 {
+		t.Fatalf("userByAddress = total %d, found %v, err %v; want not found on a board of 100", total, found, err)
+	}
+	notRanked := fmt.Errorf("%s is %w in season %d", testAddress(500), ErrNotRanked, defaultSeason)
+	if code := exitCode(notRanked); code != exitNotRanked {
+		t.Errorf("exit code %d, want %d", code, exitNotRanked)
+	}
+	if exitNotRanked == exitBeyondPaginationLimit || exitCode(errors.New("other")) != 1 {
+		t.Error("exit codes don't tell the failures apart")
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	requests := 0
+	useFakeUpstream(t, countRequests(&requests, rankedUpstream(1000)))
+	maxAge = time.Hour
+	t.Cleanup(func() { maxAge = 0 })
+
+	err := printCutoffs()
+	if !errors.Is(err, ErrStaleData) {
+		t.Fatalf("err = %v, want ErrStaleData for a board last updated in 2024", err)
+	}
+	if !strings.Contains(err.Error(), "(2024-06-10T06:13:20Z), past -max-age 1h0m0s") {
+		t.Errorf("error doesn't give the age: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want only the board fetch before giving up", requests)
+	}
+	if code := exitCode(err); code != exitStaleData {
+		t.Errorf("exit code %d, want %d", code, exitStaleData)
+	}
+
+	if err := checkDataAge(defaultSeason, time.Hour, time.Unix(1718000000, 0).Add(59*time.Minute)); err != nil {
+		t.Errorf("data 59m old is within -max-age 1h: %v", err)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	var slowHeaders atomic.Bool
+	slowHeaders.Store(true)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slowHeaders.Load() {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// A slow body is bounded by the request timeout alone.
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, `{"data":{"items":[],"page":1,"size":1,"total":1,"total_pages":1},"lastUpdated":1718000000}`)
+	}))
+	oldTransport, oldBackoff := defaultClient.HTTPClient.Transport, retryBackoff
+	defaultClient.HTTPClient.Transport, retryBackoff = newTransport(time.Second, 50*time.Millisecond, ""), 0
+	t.Cleanup(func() { defaultClient.HTTPClient.Transport, retryBackoff = oldTransport, oldBackoff })
+
+	_, err := fetchResponse(defaultClient.leaderboardURL(defaultSeason))
+	if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Fatalf("err = %v, want a response header timeout", err)
+	}
+
+	slowHeaders.Store(false)
+	if _, err := fetchResponse(defaultClient.leaderboardURL(defaultSeason)); err != nil {
+		t.Errorf("a body slower than -response-header-timeout should still be read: %v", err)
+	}
+}
+
+func TestForceIPVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	for network, ok := range map[string]bool{"": true, "tcp4": true, "tcp6": false} {
+		c := &http.Client{Transport: newTransport(time.Second, time.Second, network)}
+		resp, err := c.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err == nil) != ok {
+			t.Errorf("network %q to the IPv4 test server: err = %v, want success %v", network, err, ok)
+		}
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	type seen struct{ userAgent, requestID string }
+	var got []seen
+	var mu sync.Mutex
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = append(got, seen{r.Header.Get("User-Agent"), r.Header.Get("X-Request-ID")})
+		mu.Unlock()
+		if hops, _ := strconv.Atoi(r.URL.Query().Get("hops")); hops < 2 {
+			http.Redirect(w, r, fmt.Sprintf("%s?hops=%d", r.URL.Path, hops+1), http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"items":[],"page":1,"size":1,"total":1,"total_pages":1},"lastUpdated":1718000000}`)
+	}))
+	oldRedirect, oldSendID := defaultClient.HTTPClient.CheckRedirect, sendRequestID
+	t.Cleanup(func() { defaultClient.HTTPClient.CheckRedirect, sendRequestID = oldRedirect, oldSendID })
+	sendRequestID = true
+
+	defaultClient.HTTPClient.CheckRedirect = redirectPolicy(2)
+	if _, err := fetchResponse(defaultClient.leaderboardURL(defaultSeason)); err != nil {
+		t.Fatalf("two redirects with -max-redirects 2: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("%d requests, want 3", len(got))
+	}
+	for _, s := range got[1:] {
+		if s != got[0] || s.userAgent != userAgent || s.requestID == "" {
+			t.Errorf("redirected request had %+v, want %+v", s, got[0])
+		}
+	}
+
+	got = nil
+	defaultClient.HTTPClient.CheckRedirect = redirectPolicy(1)
+	_, err := fetchResponse(defaultClient.leaderboardURL(defaultSeason))
+	if !errors.Is(err, errTooManyRedirects) {
+		t.Fatalf("err = %v, want errTooManyRedirects", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("%d requests, want 2 without retrying", len(got))
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// mirrorFailoverAfter is how many 5xx responses in a row make a mirror count
+// as down.
+const mirrorFailoverAfter = 3
+
+// mirrorSet is the ordered list of base URLs from -base-url. Requests go to
+// the current mirror; once it fails at the connection level or keeps
+// answering 5xx, the run moves on to the next one and never returns, so a
+// flaky primary can't make requests ping-pong between hosts.
+type mirrorSet struct {
+	mu           sync.Mutex
+	urls         []string
+	current      int
+	serverErrors int
+	served       string
+}
+
+func (m *mirrorSet) set(urls []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.urls = urls
+	m.current = 0
+	m.serverErrors = 0
+	m.served = ""
+}
+
+// base returns the base URL requests should go to, or "" without mirrors.
+func (m *mirrorSet) base() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.urls) == 0 {
+		return ""
+	}
+	return m.urls[m.current]
+}
+
+// rewrite points url, built against any of the mirrors, at the current one.
+func (m *mirrorSet) rewrite(url string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.urls) == 0 {
+		return url
+	}
+	for _, base := range m.urls {
+		if rest, ok := strings.CutPrefix(url, base); ok {
+			return m.urls[m.current] + rest
+		}
+	}
+	return url
+}
+
+// connectionFailed records that base could not be reached and reports
+// whether there is another mirror to try.
+func (m *mirrorSet) connectionFailed(base string, err error) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.failover(base, err.Error())
+}
+
+// serverError records a 5xx from base and reports whether that made the run
+// switch to another mirror.
+func (m *mirrorSet) serverError(base string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.urls) == 0 || m.urls[m.current] != base {
+		return false
+	}
+	m.serverErrors++
+	if m.serverErrors < mirrorFailoverAfter {
+		return false
+	}
+	return m.failover(base, fmt.Sprintf("%d responses with status 5xx in a row", mirrorFailoverAfter))
+}
+
+func (m *mirrorSet) failover(base, reason string) bool {
+	if len(m.urls) == 0 {
+		return false
+	}
+	if m.urls[m.current] != base {
+		// Another request already moved on.
+		return true
+	}
+	if m.current == len(m.urls)-1 {
+		return false
+	}
+	m.current++
+	m.serverErrors = 0
+	log.Printf("Mirror %s failed (%s), switching to %s for the rest of the run", base, reason, m.urls[m.current])
+	return true
+}
+
+// ok records that base served a response.
+func (m *mirrorSet) ok(base string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.served = base
+	if len(m.urls) > 0 && m.urls[m.current] == base {
+		m.serverErrors = 0
+	}
+}
+
+// servedBy returns the base URL that served the last successful response.
+func (m *mirrorSet) servedBy() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.served
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useMirrors points the package at urls as its -base-url list.
+func useMirrors(t *testing.T, urls ...string) {
+	t.Helper()
+	oldBaseURL := defaultClient.BaseURL
+	defaultClient.BaseURL = urls[0]
+	defaultClient.mirrors.set(urls)
+	t.Cleanup(func() {
+		defaultClient.BaseURL = oldBaseURL
+		defaultClient.mirrors.set(nil)
+	})
+}
+
+// refusedURL returns the URL of a server that is no longer listening.
+func refusedURL() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestMirrorFailoverOnConnectionError(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(100))
+	live := defaultClient.BaseURL
+	useMirrors(t, refusedURL(), live)
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	total, err := getTotalWallets(defaultSeason)
+	if err != nil || total != 100 {
+		t.Fatalf("getTotalWallets = %d, %v; want 100 from the mirror", total, err)
+	}
+	if !strings.Contains(logs.String(), "switching to "+live) {
+		t.Errorf("failover was not logged:\n%s", logs.String())
+	}
+	if got := defaultClient.mirrors.servedBy(); got != live {
+		t.Errorf("servedBy = %q, want %q", got, live)
+	}
+
+	// The rest of the run stays on the mirror.
+	logs.Reset()
+	if _, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 5); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 0 || defaultClient.requestCount() != 3 {
+		t.Errorf("second lookup made %d requests in total and logged %q", defaultClient.requestCount(), logs.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeJSON(&out, []seasonResults{{}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var envelope snapshotEnvelope
+	if err := json.Unmarshal(out.Bytes(), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Stats == nil || envelope.Stats.BaseURL != live {
+		t.Errorf("stats = %+v, want baseURL %s", envelope.Stats, live)
+	}
+	// Records written before must keep loading, so the key is pinned.
+	var raw struct {
+		Stats map[string]any `json:"stats"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw.Stats["baseURL"] != live {
+		t.Errorf("stats keys = %v, want baseURL", raw.Stats)
+	}
+}
+
+func TestMirrorFailoverOnRepeatedServerErrors(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(100))
+	live := defaultClient.BaseURL
+	var primaryHits int
+	primary := httptest.NewServer(countRequests(&primaryHits, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})))
+	t.Cleanup(primary.Close)
+	useMirrors(t, primary.URL, live)
+	log.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	for i := 1; i < mirrorFailoverAfter; i++ {
+		if _, err := getTotalWallets(defaultSeason); err == nil {
+			t.Fatalf("request %d succeeded before failover", i)
+		}
+	}
+	if _, err := getTotalWallets(defaultSeason); err != nil {
+		t.Fatalf("request after %d server errors did not fail over: %v", mirrorFailoverAfter, err)
+	}
+	if _, err := getTotalWallets(defaultSeason); err != nil {
+		t.Fatal(err)
+	}
+	if primaryHits != mirrorFailoverAfter {
+		t.Errorf("primary got %d requests, want %d and none after failover", primaryHits, mirrorFailoverAfter)
+	}
+}
+
+func TestMirrorRewrite(t *testing.T) {
+	useMirrors(t, "https://a.example", "https://b.example")
+	defaultClient.mirrors.connectionFailed("https://a.example", os.ErrDeadlineExceeded)
+
+	if got := defaultClient.mirrors.rewrite("https://a.example/s2?page=1"); got != "https://b.example/s2?page=1" {
+		t.Errorf("rewrite = %q", got)
+	}
+	// The last mirror has nowhere to go.
+	if defaultClient.mirrors.connectionFailed("https://b.example", os.ErrDeadlineExceeded) {
+		t.Error("failover past the last mirror")
+	}
+	if got := defaultClient.mirrors.base(); got != "https://b.example" {
+		t.Errorf("base = %q", got)
+	}
+}
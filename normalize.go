@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// normalizeByDays shows scores divided by the active days of the season
+// next to the absolute values with -normalize-by-days.
+var normalizeByDays bool
+
+// knownSeasonStarts are the first days of the seasons, in UTC.
+var knownSeasonStarts = map[int]time.Time{
+	2: time.Date(2024, time.September, 16, 0, 0, 0, 0, time.UTC),
+}
+
+// seasonStarts overrides knownSeasonStarts with -season-start.
+var seasonStarts = map[int]time.Time{}
+
+func seasonStart(season int) (time.Time, bool) {
+	if start, ok := seasonStarts[season]; ok {
+		return start, true
+	}
+	start, ok := knownSeasonStarts[season]
+	return start, ok
+}
+
+// parseSeasonStarts parses -season-start: a date for every season in
+// seasons, or comma-separated season:date pairs. Dates are UTC days.
+func parseSeasonStarts(s string, seasons []int) (map[int]time.Time, error) {
+	starts := make(map[int]time.Time)
+	for _, field := range strings.Split(s, ",") {
+		seasonField, dateField, paired := strings.Cut(strings.TrimSpace(field), ":")
+		if !paired {
+			seasonField, dateField = "", seasonField
+		}
+		date, err := time.Parse(time.DateOnly, dateField)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, want YYYY-MM-DD", dateField)
+		}
+		if !paired {
+			for _, season := range seasons {
+				starts[season] = date
+			}
+			continue
+		}
+		season, err := strconv.Atoi(seasonField)
+		if err != nil || season < 1 {
+			return nil, fmt.Errorf("invalid season %q", seasonField)
+		}
+		starts[season] = date
+	}
+	return starts, nil
+}
+
+// activeDays is how many days of a season starting on the UTC day of start
+// have begun by at. Days are UTC calendar days, whatever the local time
+// zone, and the day at falls in counts in full: a score from any time on
+// the first day is divided by 1, and one from 23:59 UTC on the third day by
+// 3. It is 0 before the season starts.
+func activeDays(start, at time.Time) int {
+	utcDay := func(t time.Time) time.Time {
+		t = t.UTC()
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	days := int(utcDay(at).Sub(utcDay(start))/(24*time.Hour)) + 1
+	return max(days, 0)
+}
+
+// perDay is points per active day of season as of at. ok is false if the
+// season has no known start or hadn't started by at.
+func perDay(season int, at time.Time, points float64) (value float64, days int, ok bool) {
+	start, known := seasonStart(season)
+	if !known {
+		return 0, 0, false
+	}
+	days = activeDays(start, at)
+	if days == 0 {
+		return 0, 0, false
+	}
+	return points / float64(days), days, true
+}
+
+func formatPerDay(value float64) string {
+	return formatFloat(value, 1) + "/day"
+}
+
+// resultPerDay is the cutoff of result per active day as of the update it
+// was computed from, formatted, or "" without -normalize-by-days.
+func resultPerDay(result Result) string {
+	if !normalizeByDays || result.Skipped {
+		return ""
+	}
+	value, _, ok := perDay(result.Season, time.Unix(result.LastUpdated, 0), float64(result.Points))
+	if !ok {
+		return ""
+	}
+	return formatPerDay(value)
+}
+
+// withPerDay fills in Result.PointsPerDay and ActiveDays for JSON output
+// with -normalize-by-days, leaving all untouched.
+func withPerDay(all []seasonResults) []seasonResults {
+	if !normalizeByDays {
+		return all
+	}
+	converted := make([]seasonResults, len(all))
+	for i, season := range all {
+		season.Results = slices.Clone(season.Results)
+		for j := range season.Results {
+			result := &season.Results[j]
+			if result.Skipped {
+				continue
+			}
+			result.PointsPerDay, result.ActiveDays, _ = perDay(result.Season, time.Unix(result.LastUpdated, 0), float64(result.Points))
+		}
+		converted[i] = season
+	}
+	return converted
+}
+
+// checkSeasonStarts makes sure every season has a start to count days from.
+func checkSeasonStarts(seasons []int) error {
+	for _, season := range seasons {
+		if _, ok := seasonStart(season); !ok {
+			return fmt.Errorf("-normalize-by-days: the start of season %d isn't known; pass -season-start %d:YYYY-MM-DD", season, season)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func useNormalizeByDays(t *testing.T) {
+	t.Helper()
+	normalizeByDays = true
+	t.Cleanup(func() { normalizeByDays = false })
+}
+
+func TestActiveDays(t *testing.T) {
+	start := time.Date(2024, time.September, 16, 0, 0, 0, 0, time.UTC)
+	plus3 := time.FixedZone("UTC+3", 3*60*60)
+	for _, tc := range []struct {
+		at   time.Time
+		want int
+	}{
+		{time.Date(2024, time.September, 15, 23, 59, 59, 0, time.UTC), 0},
+		{start, 1},
+		{time.Date(2024, time.September, 16, 23, 59, 59, 0, time.UTC), 1},
+		{time.Date(2024, time.September, 17, 0, 0, 0, 0, time.UTC), 2},
+		// 01:00 on the 17th at UTC+3 is still the 16th in UTC.
+		{time.Date(2024, time.September, 17, 1, 0, 0, 0, plus3), 1},
+		{time.Date(2024, time.September, 17, 3, 0, 0, 0, plus3), 2},
+		{time.Date(2024, time.October, 16, 12, 0, 0, 0, time.UTC), 31},
+	} {
+		if got := activeDays(start, tc.at); got != tc.want {
+			t.Errorf("activeDays at %s = %d, want %d", tc.at.Format(time.RFC3339), got, tc.want)
+		}
+	}
+	// A start given in another zone still counts from its UTC day.
+	if got := activeDays(time.Date(2024, time.September, 16, 2, 0, 0, 0, plus3), start); got != 2 {
+		t.Errorf("activeDays from 02:00 UTC+3 = %d, want 2 counting from the 15th in UTC", got)
+	}
+}
+
+func TestParseSeasonStarts(t *testing.T) {
+	starts, err := parseSeasonStarts("2024-09-16", []int{2, 3})
+	if err != nil || len(starts) != 2 || starts[3].Format(time.DateOnly) != "2024-09-16" {
+		t.Errorf("a bare date should apply to every season: %v, %v", starts, err)
+	}
+	starts, err = parseSeasonStarts("2:2024-09-16, 3:2025-01-06", nil)
+	if err != nil || starts[3].Format(time.DateOnly) != "2025-01-06" {
+		t.Errorf("parseSeasonStarts = %v, %v", starts, err)
+	}
+	for _, bad := range []string{"2024-09-16T00:00:00Z", "x:2024-09-16", "2:16.09.2024"} {
+		if _, err := parseSeasonStarts(bad, []int{2}); err == nil {
+			t.Errorf("parseSeasonStarts(%q) should fail", bad)
+		}
+	}
+}
+
+func TestNormalizedOutput(t *testing.T) {
+	useNormalizeByDays(t)
+	// 10 UTC days into season 2.
+	lastUpdated := time.Date(2024, time.September, 25, 18, 0, 0, 0, time.UTC).Unix()
+	all := []seasonResults{{Results: []Result{
+		{Season: 2, Percentage: 0.01, LastUpdated: lastUpdated, Rank: 10, Points: 5000},
+		{Season: 2, Percentage: 0.05, LastUpdated: lastUpdated, Rank: 50, Skipped: true},
+	}}}
+
+	var out bytes.Buffer
+	writeText(&out, all[0].Results)
+	if want := "5000\t500.0/day\n-\n"; out.String() != want {
+		t.Errorf("text = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeCSV(&out, all); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), ",active_days,points_per_day\n") || !strings.Contains(out.String(), ",5000,false,10,500\n") {
+		t.Errorf("csv lacks the per-day columns:\n%s", out.String())
+	}
+
+	position := walletPosition{Rank: 10, TotalUsers: 1000, TotalScore: 2500, Percentile: "1.00%"}
+	position.normalize(2, lastUpdated)
+	out.Reset()
+	writeWalletPosition(&out, "text", position)
+	if !strings.HasPrefix(out.String(), "top 1.00% (rank 10 of 1,000): 2500 points, 250.0/day over 10 days\n") {
+		t.Errorf("position = %q", out.String())
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// discordContentLimit is the longest message Discord accepts.
+const discordContentLimit = 2000
+
+// notifications sends the cutoff changes of watch mode to its sinks; nil
+// without any.
+var notifications *watchNotifications
+
+// cutoffChange is how a cutoff's points moved, from the last notified value
+// to the latest one.
+type cutoffChange struct {
+	Season     int     `json:"season"`
+	Percentage float64 `json:"percentage"`
+	From       int     `json:"from"`
+	To         int     `json:"to"`
+	// TotalUsers and LastUpdated describe the leaderboard To was seen on.
+	TotalUsers  int   `json:"totalUsers,omitempty"`
+	LastUpdated int64 `json:"lastUpdated,omitempty"`
+}
+
+func (c cutoffChange) key() string {
+	return fmt.Sprintf("%d/%g", c.Season, c.Percentage)
+}
+
+// alert reports whether the change moved the cutoff by at least threshold
+// points; never with a threshold of 0.
+func (c cutoffChange) alert(threshold int) bool {
+	delta := c.To - c.From
+	return threshold > 0 && (delta >= threshold || -delta >= threshold)
+}
+
+func (c cutoffChange) String() string {
+	return fmt.Sprintf("s%d top %s: %s → %s (%+d)", c.Season, formatPercentage(c.Percentage),
+		formatThousands(c.From), formatThousands(c.To), c.To-c.From)
+}
+
+// notification is one message to the sinks. A digest covers the changes
+// held back from Since to Until, netted per cutoff.
+// Changes moving a cutoff by at least AlertThreshold points are alerts.
+type notification struct {
+	Changes        []cutoffChange
+	Digest         bool
+	Since, Until   time.Time
+	Location       *time.Location
+	AlertThreshold int
+}
+
+func (n notification) title() string {
+	if n.Digest {
+		return fmt.Sprintf("Taiko cutoffs, net change %s–%s",
+			n.Since.In(n.Location).Format("Jan 2 15:04"), n.Until.In(n.Location).Format("Jan 2 15:04 MST"))
+	}
+	return "Taiko cutoffs changed"
+}
+
+func (n notification) text() string {
+	var b strings.Builder
+	b.WriteString(n.title() + ":\n")
+	for _, change := range n.Changes {
+		bullet := "•"
+		if change.alert(n.AlertThreshold) {
+			bullet = "⚠"
+		}
+		fmt.Fprintf(&b, "%s %s\n", bullet, change)
+	}
+	return b.String()
+}
+
+// Notifier delivers watch-mode notifications somewhere people see them.
+type Notifier interface {
+	Notify(ctx context.Context, text string) error
+}
+
+// messageNotifier is a Notifier that lays a notification out itself
+// rather than sending its text.
+type messageNotifier interface {
+	Notifier
+	NotifyMessage(ctx context.Context, message notification) error
+}
+
+// discordNotifier posts to a Discord incoming webhook.
+type discordNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func newDiscordNotifier(url string) *discordNotifier {
+	return &discordNotifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (d *discordNotifier) Notify(ctx context.Context, text string) error {
+	if len(text) > discordContentLimit {
+		text = text[:discordContentLimit-len("…")] + "…"
+	}
+	body, err := json.Marshal(map[string]string{"content": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("discord: unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// quietHours is a daily window, in minutes since midnight, that may wrap
+// past midnight as in 23:00-07:00.
+type quietHours struct {
+	start, end int
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseQuietHours(s string) (*quietHours, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid quiet hours %q, want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("quiet hours %q are empty", s)
+	}
+	return &quietHours{start, end}, nil
+}
+
+// contains reports whether t, in its own location, is within the window.
+func (q *quietHours) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	return minute >= q.start || minute < q.end
+}
+
+// notifyState is what watch notifications keep in -state-file so a restart
+// neither renotifies old changes nor loses held ones.
+type notifyState struct {
+	// Points are the cutoffs of the last cycle, by cutoffChange.key.
+	Points map[string]int `json:"points"`
+	// Pending are the changes held back since WindowStart, one per cutoff
+	// with From as notified last and To as seen last.
+	Pending     []cutoffChange `json:"pending,omitempty"`
+	WindowStart time.Time      `json:"windowStart,omitzero"`
+	// HeldQuiet is set once quiet hours held back Pending, so the window
+	// is sent as a digest as soon as they end.
+	HeldQuiet bool `json:"heldQuiet,omitempty"`
+}
+
+// watchNotifications turns the cutoffs of each watch cycle into
+// notifications. Quiet hours take precedence over -digest: nothing is sent
+// during them, and the changes they held are sent as one digest at the
+// first cycle after they end, which also starts a new digest period.
+// Outside quiet hours, -digest holds changes until the oldest pending one
+// is that old; without it they are sent every cycle.
+type watchNotifications struct {
+	sinks     []Notifier
+	quiet     *quietHours
+	digest    time.Duration
+	location  *time.Location
+	statePath string
+	state     notifyState
+	// alertThreshold is -alert-threshold.
+	alertThreshold int
+}
+
+func newWatchNotifications(sinks []Notifier, quiet *quietHours, digest time.Duration, location *time.Location, statePath string) (*watchNotifications, error) {
+	n := &watchNotifications{sinks: sinks, quiet: quiet, digest: digest, location: location, statePath: statePath}
+	if statePath != "" {
+		data, err := os.ReadFile(statePath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read state file: %w", err)
+		default:
+			if err := json.Unmarshal(data, &n.state); err != nil {
+				return nil, fmt.Errorf("failed to parse state file %s: %w", statePath, err)
+			}
+		}
+	}
+	if n.state.Points == nil {
+		n.state.Points = make(map[string]int)
+	}
+	return n, nil
+}
+
+// observe records the cutoffs of a cycle at now and sends whatever is due.
+func (n *watchNotifications) observe(all []seasonResults, now time.Time) {
+	for _, season := range all {
+		for _, result := range season.Results {
+			if result.Skipped || result.Estimated {
+				continue
+			}
+			change := cutoffChange{Season: result.Season, Percentage: result.Percentage, To: result.Points,
+				TotalUsers: result.TotalUsers, LastUpdated: result.LastUpdated}
+			previous, seen := n.state.Points[change.key()]
+			n.state.Points[change.key()] = result.Points
+			if seen && previous != result.Points {
+				change.From = previous
+				n.hold(change, now)
+			}
+		}
+	}
+
+	if n.due(now) {
+		n.flush(now)
+	}
+	if err := n.save(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+func (n *watchNotifications) hold(change cutoffChange, now time.Time) {
+	if len(n.state.Pending) == 0 {
+		n.state.WindowStart = now
+	}
+	for i, pending := range n.state.Pending {
+		if pending.key() == change.key() {
+			change.From = pending.From
+			n.state.Pending[i] = change
+			return
+		}
+	}
+	n.state.Pending = append(n.state.Pending, change)
+}
+
+func (n *watchNotifications) due(now time.Time) bool {
+	if len(n.state.Pending) == 0 {
+		return false
+	}
+	if n.quiet != nil && n.quiet.contains(now.In(n.location)) {
+		n.state.HeldQuiet = true
+		return false
+	}
+	if n.state.HeldQuiet || n.digest <= 0 {
+		return true
+	}
+	return now.Sub(n.state.WindowStart) >= n.digest
+}
+
+// flush sends the net pending changes. They stay pending if no sink took
+// them, to be retried next cycle.
+func (n *watchNotifications) flush(now time.Time) {
+	message := notification{Digest: n.state.HeldQuiet || n.digest > 0, Since: n.state.WindowStart, Until: now,
+		Location: n.location, AlertThreshold: n.alertThreshold}
+	for _, change := range n.state.Pending {
+		if change.From != change.To {
+			message.Changes = append(message.Changes, change)
+		}
+	}
+
+	if len(message.Changes) > 0 {
+		text := message.text()
+		delivered := false
+		for _, sink := range n.sinks {
+			var err error
+			if rich, ok := sink.(messageNotifier); ok {
+				err = rich.NotifyMessage(context.Background(), message)
+			} else {
+				err = sink.Notify(context.Background(), text)
+			}
+			if err != nil {
+				log.Printf("Warning: notification failed: %v", err)
+				continue
+			}
+			delivered = true
+		}
+		if !delivered {
+			return
+		}
+	}
+	n.state.Pending, n.state.WindowStart, n.state.HeldQuiet = nil, time.Time{}, false
+}
+
+func (n *watchNotifications) save() error {
+	if n.statePath == "" {
+		return nil
+	}
+	tmp, err := writeTemp(filepath.Dir(n.statePath), func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(n.state)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, n.statePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingSink keeps every notification it is sent.
+type recordingSink struct {
+	sent []string
+}
+
+func (r *recordingSink) Notify(_ context.Context, text string) error {
+	r.sent = append(r.sent, text)
+	return nil
+}
+
+func cutoffCycle(points int) []seasonResults {
+	return []seasonResults{{Results: []Result{{Season: 2, Percentage: 0.01, Rank: 10, Points: points}}}}
+}
+
+// at is clock ("15:04") on the given day of June 2024, in UTC.
+func at(t *testing.T, day int, clock string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse("2006-01-02 15:04", fmt.Sprintf("2024-06-%02d %s", day, clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestQuietHoursContains(t *testing.T) {
+	overnight, err := parseQuietHours("23:00-07:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	daytime, err := parseQuietHours("09:30-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		quiet *quietHours
+		clock string
+		want  bool
+	}{
+		{overnight, "22:59", false},
+		{overnight, "23:00", true},
+		{overnight, "00:00", true},
+		{overnight, "06:59", true},
+		{overnight, "07:00", false},
+		{overnight, "12:00", false},
+		{daytime, "09:29", false},
+		{daytime, "09:30", true},
+		{daytime, "16:59", true},
+		{daytime, "17:00", false},
+		{daytime, "00:00", false},
+	} {
+		if got := tt.quiet.contains(at(t, 10, tt.clock)); got != tt.want {
+			t.Errorf("%+v contains %s = %v, want %v", *tt.quiet, tt.clock, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"23:00", "23:00-7", "25:00-07:00", "07:00-07:00"} {
+		if _, err := parseQuietHours(bad); err == nil {
+			t.Errorf("parseQuietHours(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestQuietHoursDigestAcrossMidnight(t *testing.T) {
+	sink := &recordingSink{}
+	quiet, _ := parseQuietHours("23:00-07:00")
+	n, _ := newWatchNotifications([]Notifier{sink}, quiet, 0, time.UTC, "")
+
+	n.observe(cutoffCycle(1000), at(t, 10, "22:00"))
+	n.observe(cutoffCycle(1100), at(t, 10, "22:30"))
+	if len(sink.sent) != 1 || !strings.Contains(sink.sent[0], "1,000 → 1,100 (+100)") {
+		t.Fatalf("outside quiet hours the change should be sent right away, got %q", sink.sent)
+	}
+
+	n.observe(cutoffCycle(1300), at(t, 10, "23:30"))
+	n.observe(cutoffCycle(1250), at(t, 11, "00:15"))
+	n.observe(cutoffCycle(1200), at(t, 11, "06:59"))
+	if len(sink.sent) != 1 {
+		t.Fatalf("changes were sent during quiet hours: %q", sink.sent[1:])
+	}
+
+	n.observe(cutoffCycle(1200), at(t, 11, "07:00"))
+	if len(sink.sent) != 2 {
+		t.Fatalf("want one digest when quiet hours end, got %q", sink.sent[1:])
+	}
+	digest := sink.sent[1]
+	if !strings.Contains(digest, "Jun 10 23:30–Jun 11 07:00") || !strings.Contains(digest, "1,100 → 1,200 (+100)") {
+		t.Errorf("digest should net the night's changes:\n%s", digest)
+	}
+
+	n.observe(cutoffCycle(1200), at(t, 11, "08:00"))
+	if len(sink.sent) != 2 {
+		t.Errorf("an unchanged cycle sent %q", sink.sent[2:])
+	}
+}
+
+func TestQuietHoursNetZeroSendsNothing(t *testing.T) {
+	sink := &recordingSink{}
+	quiet, _ := parseQuietHours("23:00-07:00")
+	n, _ := newWatchNotifications([]Notifier{sink}, quiet, 0, time.UTC, "")
+
+	n.observe(cutoffCycle(1000), at(t, 10, "22:00"))
+	n.observe(cutoffCycle(1200), at(t, 10, "23:55"))
+	n.observe(cutoffCycle(1000), at(t, 11, "00:05"))
+	n.observe(cutoffCycle(1000), at(t, 11, "07:30"))
+	if len(sink.sent) != 0 {
+		t.Errorf("a wiggle that came back should send nothing, got %q", sink.sent)
+	}
+	if len(n.state.Pending) != 0 {
+		t.Errorf("pending = %+v after quiet hours ended", n.state.Pending)
+	}
+}
+
+func TestDigestAndQuietHoursPrecedence(t *testing.T) {
+	sink := &recordingSink{}
+	quiet, _ := parseQuietHours("23:00-07:00")
+	n, _ := newWatchNotifications([]Notifier{sink}, quiet, 6*time.Hour, time.UTC, "")
+
+	n.observe(cutoffCycle(1000), at(t, 10, "12:00"))
+	n.observe(cutoffCycle(1100), at(t, 10, "14:00"))
+	n.observe(cutoffCycle(1150), at(t, 10, "19:59"))
+	if len(sink.sent) != 0 {
+		t.Fatalf("sent before the digest period was up: %q", sink.sent)
+	}
+	n.observe(cutoffCycle(1150), at(t, 10, "20:00"))
+	if len(sink.sent) != 1 || !strings.Contains(sink.sent[0], "1,000 → 1,150") {
+		t.Fatalf("want a digest 6h after the first change, got %q", sink.sent)
+	}
+
+	// A digest falling due during quiet hours waits for them to end, even
+	// though the next one would only be due at 04:30.
+	n.observe(cutoffCycle(1175), at(t, 10, "22:30"))
+	n.observe(cutoffCycle(1180), at(t, 11, "04:30"))
+	if len(sink.sent) != 1 {
+		t.Fatalf("a digest was sent during quiet hours: %q", sink.sent[1:])
+	}
+	n.observe(cutoffCycle(1180), at(t, 11, "07:05"))
+	if len(sink.sent) != 2 || !strings.Contains(sink.sent[1], "1,150 → 1,180") {
+		t.Fatalf("want the held digest when quiet hours end, got %q", sink.sent[1:])
+	}
+
+	// That digest started a new period rather than sending every cycle.
+	n.observe(cutoffCycle(1190), at(t, 11, "08:00"))
+	if len(sink.sent) != 2 {
+		t.Errorf("sent before the next digest period was up: %q", sink.sent[2:])
+	}
+}
+
+func TestNotifyStateSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	quiet, _ := parseQuietHours("23:00-07:00")
+	sink := &recordingSink{}
+	n, err := newWatchNotifications([]Notifier{sink}, quiet, 0, time.UTC, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.observe(cutoffCycle(1000), at(t, 10, "22:00"))
+	n.observe(cutoffCycle(1400), at(t, 11, "01:00"))
+
+	restarted, err := newWatchNotifications([]Notifier{sink}, quiet, 0, time.UTC, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.observe(cutoffCycle(1500), at(t, 11, "07:10"))
+	if len(sink.sent) != 1 || !strings.Contains(sink.sent[0], "1,000 → 1,500") {
+		t.Errorf("want the held change carried over the restart, got %q", sink.sent)
+	}
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := newDiscordNotifier(server.URL).Notify(context.Background(), strings.Repeat("x", 3000)); err != nil {
+		t.Fatal(err)
+	}
+	if content := got["content"]; len(content) > discordContentLimit || !strings.HasSuffix(content, "…") {
+		t.Errorf("content of %d bytes should be truncated to %d", len(content), discordContentLimit)
+	}
+}
+
+func TestAlertThreshold(t *testing.T) {
+	sink := &recordingSink{}
+	n, _ := newWatchNotifications([]Notifier{sink}, nil, 0, time.UTC, "")
+	n.alertThreshold = 200
+	n.observe(cutoffCycle(1000), at(t, 10, "10:00"))
+	n.observe(cutoffCycle(1100), at(t, 10, "11:00"))
+	n.observe(cutoffCycle(800), at(t, 10, "12:00"))
+	if len(sink.sent) != 2 || !strings.Contains(sink.sent[0], "• s2") || !strings.Contains(sink.sent[1], "⚠ s2 top 1%: 1,100 → 800 (-300)") {
+		t.Errorf("want only the drop of 300 marked as an alert, got %q", sink.sent)
+	}
+}
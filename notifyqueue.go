@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the flags pacing each sink.
+const (
+	defaultNotifyInterval = 2 * time.Second
+	defaultNotifyQueue    = 20
+	defaultNotifyCoalesce = 3
+)
+
+// notifyQueues are the sinks of watch mode, for /metrics.
+var notifyQueues []*notifyQueue
+
+// queuedNotification is a notification waiting for its sink: a message, or
+// the text of a plain Notify.
+type queuedNotification struct {
+	message *notification
+	text    string
+	// attempts is how many deliveries failed so far.
+	attempts int
+}
+
+func (q queuedNotification) String() string {
+	if q.message != nil {
+		return q.message.text()
+	}
+	return q.text
+}
+
+// notifyQueue paces the deliveries to a sink so a burst of changes, say
+// when the upstream updates after an outage, doesn't run into the
+// webhook's rate limit. Notifications are queued and delivered in order, at
+// most one per interval. Once more than coalesce are waiting, they are
+// combined into one, and past depth the oldest is dropped with a warning.
+// A failed delivery is retried at the head of the queue, up to retryLimit
+// times.
+//
+// Queued notifications are gone if the process exits before they are
+// delivered; -state-file only keeps what was never queued.
+type notifyQueue struct {
+	name     string
+	sink     Notifier
+	interval time.Duration
+	depth    int
+	coalesce int
+
+	mu    sync.Mutex
+	items []queuedNotification
+	wake  chan struct{}
+	// dropped and coalesced count the notifications lost to a full queue
+	// and merged into others, for /metrics.
+	dropped, coalesced int
+}
+
+func newNotifyQueue(name string, sink Notifier, interval time.Duration, depth, coalesce int) *notifyQueue {
+	return &notifyQueue{name: name, sink: sink, interval: interval, depth: max(depth, 1), coalesce: coalesce,
+		wake: make(chan struct{}, 1)}
+}
+
+func (q *notifyQueue) Notify(_ context.Context, text string) error {
+	q.push(queuedNotification{text: text})
+	return nil
+}
+
+func (q *notifyQueue) NotifyMessage(_ context.Context, message notification) error {
+	q.push(queuedNotification{message: &message})
+	return nil
+}
+
+func (q *notifyQueue) push(item queuedNotification) {
+	q.mu.Lock()
+	q.items = append(q.items, item)
+	if over := len(q.items) - q.depth; over > 0 {
+		q.items = q.items[over:]
+		q.dropped += over
+		log.Printf("Warning: the %s notification queue is full at %d, dropping the oldest", q.name, q.depth)
+	}
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take removes the next notification to deliver: the oldest, or all of
+// them combined once more than coalesce are waiting.
+func (q *notifyQueue) take() (queuedNotification, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return queuedNotification{}, false
+	}
+	if q.coalesce > 0 && len(q.items) > q.coalesce {
+		merged := coalesceNotifications(q.items)
+		q.coalesced += len(q.items) - 1
+		q.items = nil
+		return merged, true
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	return item, true
+}
+
+// requeue puts item back at the head of the queue after a failed delivery.
+func (q *notifyQueue) requeue(item queuedNotification) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append([]queuedNotification{item}, q.items...)
+}
+
+func (q *notifyQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// deliverNext delivers the next notification, if any, reporting whether
+// there was one.
+func (q *notifyQueue) deliverNext(ctx context.Context) bool {
+	item, ok := q.take()
+	if !ok {
+		return false
+	}
+	if item.message != nil && len(item.message.Changes) == 0 {
+		// Coalesced changes that cancelled out.
+		return true
+	}
+	var err error
+	if rich, ok := q.sink.(messageNotifier); ok && item.message != nil {
+		err = rich.NotifyMessage(ctx, *item.message)
+	} else {
+		err = q.sink.Notify(ctx, item.String())
+	}
+	if err != nil {
+		item.attempts++
+		if item.attempts >= retryLimit {
+			log.Printf("Warning: notification failed, giving up after %d attempts: %v", item.attempts, err)
+		} else {
+			log.Printf("Warning: notification failed, retrying: %v", err)
+			q.requeue(item)
+		}
+	}
+	return true
+}
+
+// run delivers the queue until ctx is done, waiting interval after each
+// delivery.
+func (q *notifyQueue) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		}
+		for q.deliverNext(ctx) {
+			if err := sleepCtx(ctx, q.interval); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// coalesceNotifications combines items into one, in order. Messages become
+// a digest of their net changes from the first Since to the last Until; if
+// any item is plain text, their texts are joined instead.
+func coalesceNotifications(items []queuedNotification) queuedNotification {
+	texts := make([]string, len(items))
+	allMessages := true
+	for i, item := range items {
+		texts[i] = item.String()
+		allMessages = allMessages && item.message != nil
+	}
+	if !allMessages {
+		return queuedNotification{text: strings.Join(texts, "\n")}
+	}
+
+	first, last := items[0].message, items[len(items)-1].message
+	merged := notification{Digest: true, Since: first.Since, Until: last.Until,
+		Location: last.Location, AlertThreshold: last.AlertThreshold}
+	net := make(map[string]int)
+	for _, item := range items {
+		for _, change := range item.message.Changes {
+			if i, ok := net[change.key()]; ok {
+				change.From = merged.Changes[i].From
+				merged.Changes[i] = change
+				continue
+			}
+			net[change.key()] = len(merged.Changes)
+			merged.Changes = append(merged.Changes, change)
+		}
+	}
+	merged.Changes = slices.DeleteFunc(merged.Changes, func(change cutoffChange) bool { return change.From == change.To })
+	return queuedNotification{message: &merged}
+}
+
+// writeNotifyMetrics writes the state of queues in the Prometheus text
+// format.
+func writeNotifyMetrics(w io.Writer, queues []*notifyQueue) {
+	for _, metric := range []struct {
+		name, help, kind string
+		value            func(*notifyQueue) int
+	}{
+		{"taiko_cutoffs_notify_queue_depth", "Notifications waiting for their sink.", "gauge",
+			func(q *notifyQueue) int { return len(q.items) }},
+		{"taiko_cutoffs_notify_dropped_total", "Notifications dropped from a full queue.", "counter",
+			func(q *notifyQueue) int { return q.dropped }},
+		{"taiko_cutoffs_notify_coalesced_total", "Notifications combined into others.", "counter",
+			func(q *notifyQueue) int { return q.coalesced }},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", metric.name, metric.kind)
+		for _, q := range queues {
+			q.mu.Lock()
+			value := metric.value(q)
+			q.mu.Unlock()
+			fmt.Fprintf(w, "%s{sink=%q} %d\n", metric.name, q.name, value)
+		}
+	}
+}
+
+// serveWatchMetrics serves the notification queues of watch mode at
+// /metrics on addr, in the background.
+func serveWatchMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeNotifyMetrics(w, notifyQueues)
+	})
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Printf("Warning: -metrics-addr: %v", err)
+		}
+	}()
+	return nil
+}
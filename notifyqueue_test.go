@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cutoffMoved is a notification of the top 1% cutoff of season 2 moving from
+// one value to another at minute past noon.
+func cutoffMoved(t *testing.T, from, to, minute int) notification {
+	t.Helper()
+	now := at(t, 10, "12:00").Add(time.Duration(minute) * time.Minute)
+	return notification{Changes: []cutoffChange{{Season: 2, Percentage: 0.01, From: from, To: to}},
+		Since: now.Add(-time.Minute), Until: now, Location: time.UTC}
+}
+
+// drain delivers everything queued, without pacing.
+func drain(q *notifyQueue) {
+	for q.deliverNext(context.Background()) {
+	}
+}
+
+func TestNotifyQueueCoalescesFloods(t *testing.T) {
+	sink := &recordingSink{}
+	q := newNotifyQueue("test", sink, 0, 20, 3)
+	for i := range 12 {
+		q.NotifyMessage(t.Context(), cutoffMoved(t, 1000+10*i, 1010+10*i, i))
+	}
+	drain(q)
+
+	if len(sink.sent) != 1 {
+		t.Fatalf("sent %d messages, want the 12 combined into one: %q", len(sink.sent), sink.sent)
+	}
+	want := "Taiko cutoffs, net change Jun 10 11:59–Jun 10 12:11 UTC:\n• s2 top 1%: 1,000 → 1,120 (+120)\n"
+	if sink.sent[0] != want {
+		t.Errorf("sent %q, want %q", sink.sent[0], want)
+	}
+	if q.coalesced != 11 || q.len() != 0 {
+		t.Errorf("coalesced %d, %d left, want 11 and none", q.coalesced, q.len())
+	}
+}
+
+func TestNotifyQueueKeepsOrder(t *testing.T) {
+	sink := &recordingSink{}
+	q := newNotifyQueue("test", sink, 0, 20, 3)
+	q.NotifyMessage(t.Context(), cutoffMoved(t, 1000, 1100, 0))
+	q.Notify(t.Context(), "plain")
+	q.NotifyMessage(t.Context(), cutoffMoved(t, 1100, 1050, 1))
+	drain(q)
+
+	if len(sink.sent) != 3 || !strings.Contains(sink.sent[0], "+100") || sink.sent[1] != "plain" || !strings.Contains(sink.sent[2], "-50") {
+		t.Errorf("sent %q, want the three in order", sink.sent)
+	}
+	if q.coalesced != 0 {
+		t.Errorf("coalesced %d of 3, want none at the limit", q.coalesced)
+	}
+}
+
+func TestNotifyQueueDropsOldest(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	sink := &recordingSink{}
+	q := newNotifyQueue("discord", sink, 0, 5, 0)
+	for _, text := range strings.Fields("a b c d e f g h") {
+		q.Notify(t.Context(), text)
+	}
+	drain(q)
+
+	if got := strings.Join(sink.sent, " "); got != "d e f g h" {
+		t.Errorf("sent %q, want the newest 5 in order", got)
+	}
+	if q.dropped != 3 || strings.Count(logs.String(), "discord notification queue is full") != 3 {
+		t.Errorf("dropped %d, logs %q, want 3 warned about", q.dropped, logs.String())
+	}
+}
+
+// flakySink fails its first failures deliveries.
+type flakySink struct {
+	recordingSink
+	failures int
+}
+
+func (f *flakySink) Notify(ctx context.Context, text string) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("webhook down")
+	}
+	return f.recordingSink.Notify(ctx, text)
+}
+
+func TestNotifyQueueRetriesAtHead(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	sink := &flakySink{failures: 1}
+	q := newNotifyQueue("test", sink, 0, 20, 0)
+	q.Notify(t.Context(), "first")
+	q.Notify(t.Context(), "second")
+	drain(q)
+	if got := strings.Join(sink.sent, " "); got != "first second" {
+		t.Errorf("sent %q, want the failed one retried before the next", got)
+	}
+
+	sink = &flakySink{failures: retryLimit}
+	q = newNotifyQueue("test", sink, 0, 20, 0)
+	q.Notify(t.Context(), "doomed")
+	q.Notify(t.Context(), "fine")
+	drain(q)
+	if got := strings.Join(sink.sent, " "); got != "fine" {
+		t.Errorf("sent %q, want the one failing every attempt given up", got)
+	}
+}
+
+func TestNotifyMetrics(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	q := newNotifyQueue("slack", &recordingSink{}, 0, 2, 0)
+	for range 3 {
+		q.Notify(t.Context(), "x")
+	}
+
+	var b strings.Builder
+	writeNotifyMetrics(&b, []*notifyQueue{q})
+	for _, want := range []string{
+		"# TYPE taiko_cutoffs_notify_queue_depth gauge\ntaiko_cutoffs_notify_queue_depth{sink=\"slack\"} 2\n",
+		"taiko_cutoffs_notify_dropped_total{sink=\"slack\"} 1\n",
+		"taiko_cutoffs_notify_coalesced_total{sink=\"slack\"} 0\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics = %q, want %q", b.String(), want)
+		}
+	}
+}
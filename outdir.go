@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// outDir, when set, makes a run write every format in outDirFormats to
+	// files in it instead of printing to stdout.
+	outDir        string
+	outDirLayout  = "flat"
+	outDirFormats stringList
+)
+
+var outDirLayouts = []string{"flat", "date"}
+
+// stringList is a flag that can be repeated or given a comma-separated list.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	for _, field := range strings.Split(value, ",") {
+		*l = append(*l, strings.TrimSpace(field))
+	}
+	return nil
+}
+
+func outputExtension(format string) string {
+	if format == "text" {
+		return "txt"
+	}
+	return format
+}
+
+// resolveOutDir returns the directory a run writes to, including the dated
+// subdirectory for -out-dir-layout date.
+func resolveOutDir(dir string, now time.Time) string {
+	if outDirLayout == "date" {
+		return filepath.Join(dir, now.UTC().Format(time.DateOnly))
+	}
+	return dir
+}
+
+// checkOutDir validates the -out-dir flags and makes sure the directory can
+// be written, so a run fails before it spends any requests.
+func checkOutDir(dir string) error {
+	if !slices.Contains(outDirLayouts, outDirLayout) {
+		return fmt.Errorf("unknown -out-dir-layout %q, want one of %s", outDirLayout, strings.Join(outDirLayouts, ", "))
+	}
+	if len(outDirFormats) == 0 {
+		outDirFormats = stringList{"json", "csv", "md"}
+	}
+	for _, format := range outDirFormats {
+		if !slices.Contains(outputFormats, format) {
+			return fmt.Errorf("unknown -output %q, want one of %s", format, strings.Join(outputFormats, ", "))
+		}
+	}
+
+	dir = resolveOutDir(dir, time.Now())
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("output directory is not writable: %w", err)
+	}
+	probe, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("output directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// writeOutDir renders all in every -output format from the one set of
+// results. Each file is written to a temporary file first and only renamed
+// into place once all of them were written, so a failed run never leaves a
+// half-written or mismatched set behind.
+func writeOutDir(dir string, all []seasonResults, now time.Time) error {
+	dir = resolveOutDir(dir, now)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	temps := make(map[string]string)
+	defer func() {
+		for _, temp := range temps {
+			os.Remove(temp)
+		}
+	}()
+
+	for _, format := range outDirFormats {
+		temp, err := writeTemp(dir, func(w io.Writer) error {
+			return writeOutput(w, func(w io.Writer) error { return renderCutoffs(w, format, all, now) })
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s output: %w", format, err)
+		}
+		temps[outputPath(filepath.Join(dir, "cutoffs."+outputExtension(format)))] = temp
+	}
+	for path, temp := range temps {
+		if err := os.Rename(temp, path); err != nil {
+			return fmt.Errorf("failed to move %s into place: %w", path, err)
+		}
+		delete(temps, path)
+	}
+	return nil
+}
+
+func writeTemp(dir string, write func(io.Writer) error) (string, error) {
+	file, err := os.CreateTemp(dir, ".cutoffs-*")
+	if err != nil {
+		return "", err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
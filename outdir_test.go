@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func useOutDir(t *testing.T, dir string, formats ...string) {
+	t.Helper()
+	oldDir, oldFormats, oldLayout, oldPercentages := outDir, outDirFormats, outDirLayout, defaultClient.Percentages
+	outDir, outDirFormats = dir, formats
+	defaultClient.Percentages = []float64{0.01, 0.1}
+	t.Cleanup(func() {
+		outDir, outDirFormats, outDirLayout, defaultClient.Percentages = oldDir, oldFormats, oldLayout, oldPercentages
+	})
+}
+
+func TestOutDirWritesOneSnapshot(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	dir := t.TempDir()
+	useOutDir(t, dir, "json", "csv", "md")
+
+	if err := checkOutDir(dir); err != nil {
+		t.Fatalf("checkOutDir: %v", err)
+	}
+	if err := printCutoffs(); err != nil {
+		t.Fatalf("printCutoffs: %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, " ") != "cutoffs.csv cutoffs.json cutoffs.md" {
+		t.Fatalf("directory holds %v, want exactly the three outputs", names)
+	}
+
+	const lastUpdated = 1718000000
+	data, _ := os.ReadFile(filepath.Join(dir, "cutoffs.json"))
+	var envelope snapshotEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if got := envelope.Seasons["2"].Results[0].LastUpdated; got != lastUpdated {
+		t.Errorf("cutoffs.json lastUpdated = %d", got)
+	}
+
+	file, _ := os.Open(filepath.Join(dir, "cutoffs.csv"))
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[1][3] != "1718000000" || rows[2][3] != "1718000000" {
+		t.Errorf("cutoffs.csv = %v", rows)
+	}
+
+	data, _ = os.ReadFile(filepath.Join(dir, "cutoffs.md"))
+	if want := time.Unix(lastUpdated, 0).UTC().Format(time.RFC3339); strings.Count(string(data), want) != 2 {
+		t.Errorf("cutoffs.md does not embed lastUpdated %s twice:\n%s", want, data)
+	}
+}
+
+func TestOutDirDateLayout(t *testing.T) {
+	dir := t.TempDir()
+	useOutDir(t, dir, "json")
+	outDirLayout = "date"
+
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	if err := writeOutDir(dir, []seasonResults{{}}, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-06-10", "cutoffs.json")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckOutDirRejectsUnwritable(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	os.WriteFile(file, nil, 0o644)
+	useOutDir(t, file)
+	if err := checkOutDir(file); err == nil {
+		t.Fatal("checkOutDir accepted a regular file")
+	}
+
+	useOutDir(t, t.TempDir(), "xml")
+	if err := checkOutDir(outDir); err == nil || !strings.Contains(err.Error(), `unknown -output "xml"`) {
+		t.Fatalf("checkOutDir = %v, want an unknown format error", err)
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+var outputFormats = []string{"text", "json", "ndjson", "csv", "md"}
+
+// decimalSep is the decimal separator used in text output. JSON always uses
+// a period.
+var decimalSep = "."
+
+type ndjsonRecord struct {
+	Result
+	Timestamp string `json:"timestamp"`
+}
+
+// basisPoints shows percentages as basis points (0.01 = 100 bps) with -bps.
+// Only the display changes; percentages are still computed as decimals.
+var basisPoints bool
+
+// breakdown shows the score and multiplier making up each cutoff's points
+// with -breakdown, to tell whether a cutoff is driven by score or by
+// multiplier.
+var breakdown bool
+
+// formatBreakdown is the score and multiplier of result, as "12,000 × 3".
+func formatBreakdown(result Result) string {
+	if result.Skipped {
+		return "-"
+	}
+	return fmt.Sprintf("%s × %d", formatThousands(int(result.Score)), result.Multiplier)
+}
+
+// toBasisPoints converts a decimal percentage to basis points, rounding away
+// float noise such as 0.07*10000 = 700.0000000000001.
+func toBasisPoints(percentage float64) float64 {
+	return math.Round(percentage*1e4*1e6) / 1e6
+}
+
+func formatPercentage(percentage float64) string {
+	if basisPoints {
+		return localizeDecimal(fmt.Sprintf("%.6g bps", toBasisPoints(percentage)))
+	}
+	return localizeDecimal(fmt.Sprintf("%.6g%%", percentage*100))
+}
+
+// withBasisPoints fills in Result.BasisPoints for JSON output with -bps,
+// leaving all untouched.
+func withBasisPoints(all []seasonResults) []seasonResults {
+	if !basisPoints {
+		return all
+	}
+	converted := make([]seasonResults, len(all))
+	for i, season := range all {
+		season.Results = slices.Clone(season.Results)
+		for j := range season.Results {
+			season.Results[j].BasisPoints = toBasisPoints(season.Results[j].Percentage)
+		}
+		converted[i] = season
+	}
+	return converted
+}
+
+// formatFloat formats v for text output with prec digits after the decimal
+// separator, or the fewest digits needed when prec is -1.
+func formatFloat(v float64, prec int) string {
+	return localizeDecimal(strconv.FormatFloat(v, 'f', prec, 64))
+}
+
+func localizeDecimal(s string) string {
+	if decimalSep == "." {
+		return s
+	}
+	return strings.Replace(s, ".", decimalSep, 1)
+}
+
+func explainResult(result Result) string {
+	points := "skipped"
+	if !result.Skipped {
+		points = fmt.Sprint(result.Points)
+	}
+	rank := fmt.Sprint(result.Rank)
+	if raw := rawRank(result.TotalUsers, result.Percentage); raw != int64(result.Rank) {
+		rank = fmt.Sprintf("%d, clamped to %d", raw, result.Rank)
+	}
+	return fmt.Sprintf("s%d: top %s = rank %s(totalUsers(%d) * %s) = %s, points = %s",
+		result.Season, formatPercentage(result.Percentage), rankRounding, result.TotalUsers, formatFloat(result.Percentage, -1), rank, points)
+}
+
+var (
+	// streamTo receives each result as it is computed with -stream.
+	streamTo io.Writer
+	streamMu sync.Mutex
+)
+
+// streamResult prints result to streamTo, if set. Results arrive from
+// concurrent lookups, so writes are serialized.
+func streamResult(result Result) {
+	if streamTo == nil {
+		return
+	}
+	points := "skipped"
+	if !result.Skipped {
+		points = formatPoints(result)
+	}
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	fmt.Fprintf(streamTo, "s%d top %s: rank %d, points %s\n", result.Season, formatPercentage(result.Percentage), result.Rank, points)
+}
+
+func printCutoffs() error {
+	if maxAge > 0 {
+		for _, season := range seasons {
+			if err := checkDataAge(season, maxAge, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+
+	estimate := len(seasons) * (1 + len(defaultClient.Percentages))
+	defaultClient.progress.AddTotal(estimate)
+	if defaultClient.MaxRequests > 0 && int64(estimate) > defaultClient.MaxRequests-defaultClient.requestCount() {
+		log.Printf("This run takes up to %d requests but -max-requests allows %d; fetching coarser pages or skipping cutoffs to fit",
+			estimate, defaultClient.MaxRequests-defaultClient.requestCount())
+	}
+
+	var (
+		all []seasonResults
+		err error
+	)
+	if len(seasons) == 1 {
+		var results []Result
+		results, err = calculatePointsForTopUsers(seasons[0])
+		all = []seasonResults{{Results: results}}
+	} else {
+		all, err = calculateSeasons(seasons)
+	}
+	if err == nil {
+		err = applyCriteria(all)
+	}
+	defaultClient.progress.Finish()
+	if err != nil && !errors.Is(err, ErrBudgetExhausted) {
+		return err
+	}
+	if err != nil {
+		markBudgetLimited(all)
+	}
+
+	if notifications != nil {
+		notifications.observe(all, time.Now())
+	}
+	if tracker != nil {
+		if trackErr := tracker.record(all, time.Now()); trackErr != nil {
+			log.Printf("Warning: -track: %v", trackErr)
+		}
+	}
+
+	if splitOutput != "" {
+		if splitErr := writeSplitOutput(splitOutput, all); splitErr != nil {
+			return splitErr
+		}
+	}
+
+	if outDir != "" {
+		if writeErr := writeOutDir(outDir, all, time.Now()); writeErr != nil {
+			return writeErr
+		}
+	}
+	if snapshotDir != "" {
+		if writeErr := writeSnapshotDir(snapshotDir, snapshotFormats(), snapshotKeep, all, time.Now()); writeErr != nil {
+			return writeErr
+		}
+	}
+	switch {
+	case hashResults:
+		fmt.Fprintln(stdout, resultsHash(all, hashTimestamps))
+	case dashboardView != nil:
+		dashboardView.update(all)
+	case outDir == "":
+		if renderErr := renderCutoffs(stdout, outputFormat, all, time.Now()); renderErr != nil {
+			return renderErr
+		}
+	}
+
+	if explain {
+		for _, season := range all {
+			for _, result := range season.Results {
+				fmt.Fprintln(os.Stderr, explainResult(result))
+			}
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w (partial results after %d requests; skipped %s)", err, defaultClient.requestCount(), strings.Join(skippedCutoffs(all), ", "))
+	}
+	return nil
+}
+
+// markBudgetLimited flags the seasons that lost cutoffs to the request
+// budget.
+func markBudgetLimited(all []seasonResults) {
+	for i := range all {
+		for _, result := range all[i].Results {
+			if result.Skipped {
+				all[i].BudgetLimited = true
+			}
+		}
+	}
+}
+
+// skippedCutoffs names the cutoffs without points, e.g. "s2 top 18%".
+func skippedCutoffs(all []seasonResults) []string {
+	var skipped []string
+	for _, season := range all {
+		for _, result := range season.Results {
+			if result.Skipped {
+				skipped = append(skipped, fmt.Sprintf("s%d top %s", result.Season, formatPercentage(result.Percentage)))
+			}
+		}
+	}
+	return skipped
+}
+
+// renderCutoffs writes all in format. now is the generation time embedded in
+// formats that record one.
+func renderCutoffs(w io.Writer, format string, all []seasonResults, now time.Time) error {
+	switch format {
+	case "json":
+		return writeJSON(w, all, now)
+	case "ndjson":
+		return writeNDJSON(w, all, now)
+	case "csv":
+		return writeCSV(w, all)
+	case "md":
+		return writeMarkdown(w, all)
+	}
+	if len(all) == 1 {
+		writeText(w, all[0].Results)
+	} else {
+		writeSeasonTable(w, all)
+	}
+	for i, season := range all {
+		if season.Criteria != nil {
+			fmt.Fprintln(w)
+			writeCriteria(w, seasons[i], season.Criteria)
+		}
+	}
+	return nil
+}
+
+// formatPoints prefixes points computed from a -fallback-total estimate
+// with "~".
+func formatPoints(result Result) string {
+	if result.Estimated {
+		return fmt.Sprintf("~%d", result.Points)
+	}
+	return fmt.Sprint(result.Points)
+}
+
+func writeText(w io.Writer, results []Result) {
+	for _, result := range results {
+		if result.Skipped {
+			fmt.Fprintln(w, "-")
+			continue
+		}
+		points := formatPoints(result)
+		if perDay := resultPerDay(result); perDay != "" {
+			points += "\t" + perDay
+		}
+		if ratio := formatBaselineRatio(results, result); ratio != "" {
+			points += "\t" + ratio
+		}
+		if breakdown {
+			points += "\t" + formatBreakdown(result)
+		}
+		if criteria != nil {
+			fmt.Fprintf(w, "%s\t%s\n", points, tierOrNone(result.Tier))
+			continue
+		}
+		fmt.Fprintln(w, points)
+	}
+}
+
+func writeJSON(w io.Writer, all []seasonResults, now time.Time) error {
+	all = withBaseline(withPerDay(withBasisPoints(all)))
+	envelope := snapshotEnvelope{
+		Version:      snapshotVersion,
+		GeneratedAt:  now.UTC(),
+		Seasons:      make(map[string]seasonResults, len(all)),
+		RankRounding: rankRounding,
+	}
+	for i, season := range seasons {
+		envelope.Seasons[strconv.Itoa(season)] = all[i]
+	}
+	if served := defaultClient.mirrors.servedBy(); served != "" {
+		envelope.Stats = &runStats{BaseURL: served, Requests: defaultClient.requestCount()}
+	}
+	return encodeJSON(w, envelope, true)
+}
+
+func writeNDJSON(w io.Writer, all []seasonResults, now time.Time) error {
+	timestamp := now.UTC().Format(time.RFC3339)
+	for _, season := range withBaseline(withPerDay(withBasisPoints(all))) {
+		for _, result := range season.Results {
+			if err := encodeJSON(w, ndjsonRecord{Result: result, Timestamp: timestamp}, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeSeasonTable(out io.Writer, all []seasonResults) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "top\t")
+	for i, season := range seasons {
+		fmt.Fprintf(w, "s%d rank\ts%d points\t", season, season)
+		if normalizeByDays {
+			fmt.Fprintf(w, "s%d /day\t", season)
+		}
+		if baseline != 0 {
+			fmt.Fprintf(w, "s%d /base\t", season)
+		}
+		if breakdown {
+			fmt.Fprintf(w, "s%d score\ts%d mult\t", season, season)
+		}
+		if i > 0 {
+			fmt.Fprintf(w, "s%d-s%d\t", season, seasons[i-1])
+		}
+	}
+	fmt.Fprintln(w)
+
+	for row, percentage := range defaultClient.Percentages {
+		fmt.Fprintf(w, "%s\t", formatPercentage(percentage))
+		for i := range seasons {
+			result, ok := seasonResult(all[i], row)
+			if ok {
+				fmt.Fprintf(w, "%d\t%s\t", result.Rank, formatPoints(result))
+			} else if all[i].Unavailable {
+				fmt.Fprint(w, "n/a\tn/a\t")
+			} else {
+				fmt.Fprint(w, "-\t-\t")
+			}
+			if normalizeByDays {
+				fmt.Fprintf(w, "%s\t", resultPerDay(result))
+			}
+			if baseline != 0 {
+				fmt.Fprintf(w, "%s\t", formatBaselineRatio(all[i].Results, result))
+			}
+			if breakdown {
+				if ok {
+					fmt.Fprintf(w, "%s\t%d\t", formatThousands(int(result.Score)), result.Multiplier)
+				} else {
+					fmt.Fprint(w, "-\t-\t")
+				}
+			}
+			if i > 0 {
+				previous, previousOK := seasonResult(all[i-1], row)
+				if ok && previousOK {
+					fmt.Fprintf(w, "%+d\t", result.Points-previous.Points)
+				} else {
+					fmt.Fprint(w, "\t")
+				}
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+func seasonResult(season seasonResults, row int) (Result, bool) {
+	if row >= len(season.Results) || season.Results[row].Skipped {
+		return Result{}, false
+	}
+	return season.Results[row], true
+}
+
+// writeCSV writes one row per cutoff. With a comma decimal separator the
+// fields are separated by semicolons, as spreadsheets in those locales expect.
+func writeCSV(out io.Writer, all []seasonResults) error {
+	w := csv.NewWriter(out)
+	if decimalSep == "," {
+		w.Comma = ';'
+	}
+	percentageHeader, percentage := "percentage", func(p float64) float64 { return p }
+	if basisPoints {
+		percentageHeader, percentage = "bps", toBasisPoints
+	}
+	header := []string{"season", percentageHeader, "total_users", "last_updated", "rank", "points", "estimated"}
+	if normalizeByDays {
+		header = append(header, "active_days", "points_per_day")
+	}
+	if baseline != 0 {
+		header = append(header, "baseline_ratio")
+	}
+	if breakdown {
+		header = append(header, "score", "multiplier")
+	}
+	w.Write(header)
+	for _, season := range withBaseline(withPerDay(all)) {
+		for _, result := range season.Results {
+			points := ""
+			if !result.Skipped {
+				points = strconv.Itoa(result.Points)
+			}
+			record := []string{
+				strconv.Itoa(result.Season),
+				formatFloat(percentage(result.Percentage), -1),
+				strconv.Itoa(result.TotalUsers),
+				strconv.FormatInt(result.LastUpdated, 10),
+				strconv.Itoa(result.Rank),
+				points,
+				strconv.FormatBool(result.Estimated),
+			}
+			if normalizeByDays {
+				activeDays, perDay := "", ""
+				if result.ActiveDays > 0 {
+					activeDays, perDay = strconv.Itoa(result.ActiveDays), formatFloat(result.PointsPerDay, -1)
+				}
+				record = append(record, activeDays, perDay)
+			}
+			if baseline != 0 {
+				ratio := ""
+				if result.BaselineRatio != 0 {
+					ratio = formatFloat(result.BaselineRatio, -1)
+				}
+				record = append(record, ratio)
+			}
+			if breakdown {
+				score, multiplier := "", ""
+				if !result.Skipped {
+					score, multiplier = formatFloat(result.Score, -1), strconv.Itoa(result.Multiplier)
+				}
+				record = append(record, score, multiplier)
+			}
+			w.Write(record)
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeMarkdown(w io.Writer, all []seasonResults) error {
+	header, align := "| Season | Top | Rank | Points |", "| ---: | ---: | ---: | ---: |"
+	extra := 0
+	if normalizeByDays {
+		header, align, extra = header+" Per day |", align+" ---: |", extra+1
+	}
+	if baseline != 0 {
+		header, align, extra = header+" Baseline |", align+" ---: |", extra+1
+	}
+	if breakdown {
+		header, align, extra = header+" Score | Multiplier |", align+" ---: | ---: |", extra+2
+	}
+	fmt.Fprintln(w, header+" Last updated |")
+	fmt.Fprintln(w, align+" --- |")
+	for i, season := range all {
+		if season.Unavailable {
+			fmt.Fprintf(w, "| %d | n/a | n/a | n/a |%s |\n", seasons[i], strings.Repeat(" n/a |", extra))
+			continue
+		}
+		for _, result := range season.Results {
+			points := "-"
+			if !result.Skipped {
+				points = formatPoints(result)
+			}
+			if normalizeByDays {
+				points += " | " + resultPerDay(result)
+			}
+			if baseline != 0 {
+				points += " | " + formatBaselineRatio(season.Results, result)
+			}
+			if breakdown {
+				if result.Skipped {
+					points += " | - | -"
+				} else {
+					points += fmt.Sprintf(" | %s | %d", formatThousands(int(result.Score)), result.Multiplier)
+				}
+			}
+			fmt.Fprintf(w, "| %d | %s | %d | %s | %s |\n", result.Season, formatPercentage(result.Percentage), result.Rank, points,
+				time.Unix(result.LastUpdated, 0).UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setSeasons(t *testing.T, list ...int) {
+	t.Helper()
+	old := seasons
+	seasons = list
+	t.Cleanup(func() { seasons = old })
+}
+
+func TestWriteJSONNestsUnderSeasonKeys(t *testing.T) {
+	results := []Result{{Season: 2, Percentage: 0.01, TotalUsers: 1000, Rank: 10, Points: 9910}}
+
+	for _, tc := range []struct {
+		name    string
+		seasons []int
+		all     []seasonResults
+	}{
+		{"single season", []int{2}, []seasonResults{{Results: results}}},
+		{"two seasons", []int{2, 3}, []seasonResults{{Results: results}, {Unavailable: true}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setSeasons(t, tc.seasons...)
+			var out bytes.Buffer
+			if err := writeJSON(&out, tc.all, time.Now()); err != nil {
+				t.Fatalf("writeJSON: %v", err)
+			}
+
+			var envelope snapshotEnvelope
+			if err := json.Unmarshal(out.Bytes(), &envelope); err != nil {
+				t.Fatalf("output is not a snapshot envelope: %v\n%s", err, out.String())
+			}
+			if envelope.Version != snapshotVersion {
+				t.Fatalf("version = %d, want %d", envelope.Version, snapshotVersion)
+			}
+			if envelope.RankRounding != "floor" {
+				t.Errorf("rankRounding = %q, want the default floor", envelope.RankRounding)
+			}
+			decoded := envelope.Seasons
+			if got := decoded["2"].Results; len(got) != 1 || got[0].Points != 9910 {
+				t.Fatalf("season 2 results = %+v", got)
+			}
+			if len(tc.seasons) > 1 && !decoded["3"].Unavailable {
+				t.Fatalf("season 3 not marked unavailable: %s", out.String())
+			}
+		})
+	}
+}
+
+func TestWriteNDJSONLinesParseIndependently(t *testing.T) {
+	all := []seasonResults{
+		{Results: []Result{
+			{Season: 2, Percentage: 0.01, TotalUsers: 1000, Rank: 10, Points: 9910},
+			{Season: 2, Percentage: 0.1, TotalUsers: 1000, Rank: 100, Points: 9010},
+		}},
+		{Unavailable: true},
+	}
+
+	var out bytes.Buffer
+	if err := writeNDJSON(&out, all, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("writeNDJSON: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&out)
+	lines := 0
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d is not standalone JSON: %v", lines+1, err)
+		}
+		for _, key := range []string{"percentage", "rank", "points", "totalUsers", "timestamp"} {
+			if _, ok := record[key]; !ok {
+				t.Fatalf("line %d is missing %q: %s", lines+1, key, scanner.Text())
+			}
+		}
+		if record["timestamp"] != "2024-06-01T12:00:00Z" {
+			t.Fatalf("timestamp = %v", record["timestamp"])
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Fatalf("got %d lines, want 2", lines)
+	}
+}
+
+func TestDecimalSeparator(t *testing.T) {
+	old := decimalSep
+	t.Cleanup(func() { decimalSep = old })
+
+	result := Result{Season: 2, Percentage: 0.005, TotalUsers: 1000, Rank: 5, Points: 9960}
+	decimalSep = ","
+	if got := formatPercentage(0.005); got != "0,5%" {
+		t.Fatalf("formatPercentage = %q, want 0,5%%", got)
+	}
+	if got := explainResult(result); !strings.Contains(got, "* 0,005)") || strings.Contains(got, ".") {
+		t.Fatalf("explainResult = %q, want comma decimals", got)
+	}
+
+	var out bytes.Buffer
+	if err := writeJSON(&out, []seasonResults{{Results: []Result{result}}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"percentage": 0.005`) {
+		t.Fatalf("JSON output must keep periods:\n%s", out.String())
+	}
+}
+
+func TestBasisPoints(t *testing.T) {
+	basisPoints = true
+	t.Cleanup(func() { basisPoints = false })
+
+	if got := formatPercentage(0.07); got != "700 bps" {
+		t.Errorf("formatPercentage(0.07) = %q, want 700 bps", got)
+	}
+	if got := formatPercentage(0.00005); got != "0.5 bps" {
+		t.Errorf("formatPercentage(0.00005) = %q, want 0.5 bps", got)
+	}
+
+	all := []seasonResults{{Results: []Result{{Season: 2, Percentage: 0.07, TotalUsers: 1000, Rank: 70, Points: 500}}}}
+	var out bytes.Buffer
+	if err := writeCSV(&out, all); err != nil {
+		t.Fatal(err)
+	}
+	if want := "season,bps,total_users,last_updated,rank,points,estimated\n2,700,"; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("CSV = %q, want prefix %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeJSON(&out, all, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"percentage": 0.07`) || !strings.Contains(out.String(), `"bps": 700`) {
+		t.Errorf("JSON should keep the decimal percentage and add bps:\n%s", out.String())
+	}
+	if all[0].Results[0].BasisPoints != 0 {
+		t.Error("writeJSON changed the results it was given")
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	baseline = 0.1
+	t.Cleanup(func() { baseline = 0 })
+
+	if err := checkBaseline([]float64{0.01, 0.05}); err == nil {
+		t.Error("want an error for a baseline that isn't computed")
+	}
+	results := []Result{
+		{Season: 2, Percentage: 0.01, Rank: 10, Points: 9000},
+		{Season: 2, Percentage: 0.05, Rank: 50, Skipped: true},
+		{Season: 2, Percentage: 0.1, Rank: 100, Points: 4000},
+	}
+	var out bytes.Buffer
+	writeText(&out, results)
+	if want := "9000\t2.25x\n-\n4000\t1.00x\n"; out.String() != want {
+		t.Errorf("text = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeJSON(&out, []seasonResults{{Results: results}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"baselineRatio": 2.25`) {
+		t.Errorf("JSON lacks the ratio:\n%s", out.String())
+	}
+
+	// Without the baseline's points there is nothing to compare against.
+	results[2].Skipped = true
+	if ratio, ok := baselineRatio(results, results[0]); ok {
+		t.Errorf("ratio = %v against a skipped baseline", ratio)
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	breakdown = true
+	t.Cleanup(func() { breakdown = false })
+	setSeasons(t, 2)
+
+	results := []Result{
+		{Season: 2, Percentage: 0.01, Rank: 10, Points: 36000, Score: 12000, Multiplier: 3},
+		{Season: 2, Percentage: 0.05, Rank: 50, Skipped: true},
+	}
+	var out bytes.Buffer
+	writeText(&out, results)
+	if want := "36000\t12,000 × 3\n-\n"; out.String() != want {
+		t.Errorf("text = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeCSV(&out, []seasonResults{{Results: results}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasSuffix(lines[0], ",score,multiplier") || !strings.HasSuffix(lines[1], ",36000,false,12000,3") || !strings.HasSuffix(lines[2], ",false,,") {
+		t.Errorf("CSV = %q, want score and multiplier columns", out.String())
+	}
+
+	useFakeUpstream(t, rankedUpstream(1000))
+	computed, err := calculatePointsForTopUsers(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range computed {
+		if result.Score != float64(result.Points) || result.Multiplier != 1 {
+			t.Errorf("%+v, want the score and multiplier of the user at rank %d", result, result.Rank)
+		}
+	}
+}
+
+func TestCompactJSON(t *testing.T) {
+	setSeasons(t, 2)
+	compactJSON = true
+	t.Cleanup(func() { compactJSON = false })
+
+	results := []Result{
+		{Season: 2, Percentage: 0.01, TotalUsers: 1000, LastUpdated: 1718000000, Rank: 10, Points: 9910},
+		{Season: 2, Percentage: 0.05, TotalUsers: 1000, LastUpdated: 1718000000, Rank: 50, Skipped: true},
+	}
+	var out bytes.Buffer
+	if err := writeJSON(&out, []seasonResults{{Results: results}}, time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	want := `"seasons":{"2":{"results":[` +
+		`{"lastUpdated":1718000000,"percentage":0.01,"points":9910,"rank":10,"season":2,"totalUsers":1000},` +
+		`{"lastUpdated":1718000000,"percentage":0.05,"rank":50,"season":2,"skipped":true,"totalUsers":1000}]}}`
+	if !strings.Contains(out.String(), want) || strings.Count(out.String(), "\n") != 1 {
+		t.Errorf("compact JSON =\n%s\nwant one line with\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	position := walletPosition{Address: "0xabc", Rank: 5, TotalUsers: 1000, TotalScore: 123456789012.5, Percentile: "0.5%"}
+	if err := writeWalletPosition(&out, "json", position); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"percentile":"0.5%","rank":5,"totalScore":123456789012.5,"totalUsers":1000}` + "\n"; out.String() != want {
+		t.Errorf("compact position = %s, want %s", out.String(), want)
+	}
+}
+
+func TestStreamResults(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	var out bytes.Buffer
+	streamTo = &out
+	t.Cleanup(func() { streamTo = nil })
+
+	results, err := calculatePointsForTopUsers(defaultSeason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(results) {
+		t.Fatalf("streamed %d lines for %d results:\n%s", len(lines), len(results), out.String())
+	}
+	for _, result := range results {
+		want := fmt.Sprintf("s%d top %s: rank %d, points %d", result.Season, formatPercentage(result.Percentage), result.Rank, result.Points)
+		if !slices.Contains(lines, want) {
+			t.Errorf("missing streamed line %q", want)
+		}
+	}
+}
+
+func TestResultsHash(t *testing.T) {
+	results := []Result{
+		{Season: 2, Percentage: 0.01, TotalUsers: 1000, LastUpdated: 100, Rank: 10, Points: 9910},
+		{Season: 2, Percentage: 0.001, TotalUsers: 1000, LastUpdated: 100, Rank: 1, Points: 9999},
+		{Season: 2, Percentage: 0.0001, TotalUsers: 1000, LastUpdated: 100, Rank: 1, Skipped: true},
+	}
+	base := resultsHash([]seasonResults{{Results: results}}, false)
+	if len(base) != 64 {
+		t.Fatalf("hash %q is not hex SHA-256", base)
+	}
+
+	reordered := []Result{results[2], results[0], results[1]}
+	if got := resultsHash([]seasonResults{{Results: reordered}}, false); got != base {
+		t.Error("hash depends on the order of the percentages")
+	}
+
+	moved := slices.Clone(results)
+	for i := range moved {
+		moved[i].LastUpdated, moved[i].TotalUsers, moved[i].Rank = 200, 1010, moved[i].Rank+1
+	}
+	if got := resultsHash([]seasonResults{{Results: moved}}, false); got != base {
+		t.Error("hash changed with only volatile fields")
+	}
+	if resultsHash([]seasonResults{{Results: moved}}, true) == resultsHash([]seasonResults{{Results: results}}, true) {
+		t.Error("-include-timestamp hash ignores lastUpdated")
+	}
+
+	changed := slices.Clone(results)
+	changed[0].Points++
+	if got := resultsHash([]seasonResults{{Results: changed}}, false); got == base {
+		t.Error("hash unchanged after points changed")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// calibration remembers when a Client's page convention was last detected
+// so that -calibrate-pages probes the API at most once per ttl.
+type calibration struct {
+	mu      sync.Mutex
+	enabled bool
+	ttl     time.Duration
+	at      time.Time
+	now     func() time.Time
+	// detected is the page base found by the last conclusive probe, or -1.
+	detected int
+}
+
+// pageBase is the index of the first leaderboard page: the detected one, or
+// PageBase.
+func (c *Client) pageBase() int {
+	c.calibration.mu.Lock()
+	defer c.calibration.mu.Unlock()
+	if c.calibration.at.IsZero() || c.calibration.detected < 0 {
+		return c.PageBase
+	}
+	return c.calibration.detected
+}
+
+// detectPageBase fetches the first user on pages 0 and 1 and works out from
+// their ranks whether pages are counted from 0 or 1. ok is false when the
+// answer is ambiguous, e.g. the rank field is missing or both pages claim
+// the same rank for different users.
+func (c *Client) detectPageBase(season int) (base int, ok bool, err error) {
+	ranks := make(map[int]int)
+	for _, page := range []int{0, 1} {
+		url := c.pageURL(season, page, 1)
+		response, err := c.fetchResponse(context.Background(), url, timeout)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to fetch page %d: %w", page, err)
+		}
+		if len(response.Data.Users) > 0 {
+			ranks[page] = c.fromAPIRank(response.Data.Users[0].Rank)
+		}
+	}
+
+	switch {
+	case ranks[0] == 1 && ranks[1] == 2:
+		return 0, true, nil
+	case ranks[1] == 1:
+		// Page 0 is either empty or treated as page 1.
+		return 1, true, nil
+	}
+	return 0, false, nil
+}
+
+// calibratePages runs detectPageBase for season when -calibrate-pages is set
+// and the last detection is older than its ttl, falling back to the
+// configured -page-base when the result is ambiguous or the probe fails.
+func (c *Client) calibratePages(season int) {
+	cal := c.calibration
+	cal.mu.Lock()
+	defer cal.mu.Unlock()
+
+	if !cal.enabled {
+		return
+	}
+	now := time.Now
+	if cal.now != nil {
+		now = cal.now
+	}
+	if !cal.at.IsZero() && now().Sub(cal.at) < cal.ttl {
+		return
+	}
+
+	base, ok, err := c.detectPageBase(season)
+	if err != nil {
+		log.Printf("Warning: failed to detect page indexing, assuming -page-base %d: %v", c.PageBase, err)
+		return
+	}
+	cal.at = now()
+	if !ok {
+		log.Printf("Warning: could not tell whether pages start at 0 or 1, assuming -page-base %d", c.PageBase)
+		cal.detected = -1
+		return
+	}
+	log.Printf("Detected %d-based page indexing", base)
+	cal.detected = base
+}
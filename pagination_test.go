@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func usePageCalibration(t *testing.T) {
+	t.Helper()
+	oldCalibration, oldBase := defaultClient.calibration, defaultClient.PageBase
+	defaultClient.calibration = &calibration{enabled: true, ttl: time.Hour}
+	t.Cleanup(func() { defaultClient.calibration, defaultClient.PageBase = oldCalibration, oldBase })
+}
+
+func TestDetectPageBase(t *testing.T) {
+	for _, tc := range []struct {
+		convention string
+		base       int
+		ok         bool
+	}{
+		{"zero_based", 0, true},
+		{"one_based", 1, true},
+		{"no_rank", 0, false},
+	} {
+		t.Run(tc.convention, func(t *testing.T) {
+			useFakeUpstream(t, fixtureUpstream(t, map[string]string{
+				"0/1": "page0_size1_" + tc.convention + ".json",
+				"1/1": "page1_size1_" + tc.convention + ".json",
+			}))
+			base, ok, err := defaultClient.detectPageBase(defaultSeason)
+			if err != nil {
+				t.Fatalf("detectPageBase: %v", err)
+			}
+			if base != tc.base || ok != tc.ok {
+				t.Fatalf("detectPageBase = %d, %v; want %d, %v", base, ok, tc.base, tc.ok)
+			}
+		})
+	}
+}
+
+func TestCalibratedZeroBasedPages(t *testing.T) {
+	requests := 0
+	fixtures := fixtureUpstream(t, map[string]string{
+		"0/1": "page0_size1_zero_based.json",
+		"1/1": "page1_size1_zero_based.json",
+		"2/3": "page2_size3_zero_based.json",
+	})
+	useFakeUpstream(t, countRequests(&requests, fixtures))
+	usePageCalibration(t)
+	setPageSize(t, 3)
+
+	defaultClient.calibratePages(defaultSeason)
+	if defaultClient.pageBase() != 0 {
+		t.Fatalf("pageBase = %d after calibrating against a 0-based API", defaultClient.pageBase())
+	}
+	user, err := defaultClient.UserAtRank(context.Background(), defaultSeason, 8)
+	if err != nil {
+		t.Fatalf("userAtRank(8): %v", err)
+	}
+	if user.Rank != 8 {
+		t.Fatalf("userAtRank(8) returned rank %d", user.Rank)
+	}
+
+	requests = 0
+	defaultClient.calibratePages(defaultSeason)
+	if requests != 0 {
+		t.Fatalf("calibration within the TTL made %d requests", requests)
+	}
+}
+
+func TestCalibrationFallsBackWhenAmbiguous(t *testing.T) {
+	useFakeUpstream(t, fixtureUpstream(t, map[string]string{
+		"0/1": "page0_size1_no_rank.json",
+		"1/1": "page1_size1_no_rank.json",
+	}))
+	usePageCalibration(t)
+	defaultClient.PageBase = 1
+
+	defaultClient.calibratePages(defaultSeason)
+	if defaultClient.pageBase() != 1 {
+		t.Fatalf("pageBase = %d, want the configured default 1", defaultClient.pageBase())
+	}
+}
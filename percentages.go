@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func parsePercentage(s string) (float64, error) {
+	percentage, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q: %w", s, err)
+	}
+	if percentage <= 0 || percentage > 1 {
+		return 0, fmt.Errorf("percentage %v out of range (0, 1]", percentage)
+	}
+	return percentage, nil
+}
+
+func parsePercentages(list string) ([]float64, error) {
+	var percentages []float64
+	for _, field := range strings.Split(list, ",") {
+		percentage, err := parsePercentage(field)
+		if err != nil {
+			return nil, err
+		}
+		percentages = append(percentages, percentage)
+	}
+	return percentages, nil
+}
+
+// stepPercentages returns every multiple of step percent up to 100%, so a
+// step of 5 gives 5%, 10%, ... 100%. A step that doesn't divide 100 stops
+// at the last multiple below it.
+func stepPercentages(step float64) ([]float64, error) {
+	if step <= 0 || step > 100 {
+		return nil, fmt.Errorf("step %v out of range (0, 100]", step)
+	}
+	var percentages []float64
+	for i := 1; ; i++ {
+		// Round away float noise such as 3*0.05 = 0.15000000000000002.
+		percentage := math.Round(float64(i)*step*1e7) / 1e9
+		if percentage > 1 {
+			break
+		}
+		percentages = append(percentages, percentage)
+	}
+	return percentages, nil
+}
+
+// densifyFactor is -densify: each gap between consecutive percentages is
+// split into this many equal parts.
+var densifyFactor = 1
+
+// densifyPercentages inserts factor-1 evenly spaced percentages between
+// each consecutive pair of percentages, so a factor of 2 adds every
+// midpoint. The result is sorted and free of duplicates; a factor of 1
+// leaves the set as it is.
+func densifyPercentages(percentages []float64, factor int) []float64 {
+	sorted := slices.Clone(percentages)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	if factor <= 1 {
+		return sorted
+	}
+	var dense []float64
+	for i, percentage := range sorted {
+		dense = append(dense, percentage)
+		if i == len(sorted)-1 {
+			break
+		}
+		step := (sorted[i+1] - percentage) / float64(factor)
+		for j := 1; j < factor; j++ {
+			// Round away float noise as stepPercentages does.
+			dense = append(dense, math.Round((percentage+float64(j)*step)*1e9)/1e9)
+		}
+	}
+	slices.Sort(dense)
+	return slices.Compact(dense)
+}
+
+func readPercentagesFile(path string) ([]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open percentages file: %w", err)
+	}
+	defer file.Close()
+
+	var percentages []float64
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		percentage, err := parsePercentage(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		percentages = append(percentages, percentage)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read percentages file: %w", err)
+	}
+	if len(percentages) == 0 {
+		return nil, fmt.Errorf("%s: no percentages found", path)
+	}
+	return percentages, nil
+}
+
+var rankRoundings = []string{"floor", "round", "ceil"}
+
+// rankRounding is how totalUsers*percentage becomes a whole rank. floor
+// is the original truncation except where float error left a whole product
+// just short of it: 100*0.29 was rank 28 and is now 29. No total up to
+// 300,000 gets a different rank for the default percentages.
+var rankRounding = "floor"
+
+// rankEpsilon absorbs float error in totalUsers*percentage, so that a
+// product meant to be whole is rounded as one: 100*0.07 =
+// 7.000000000000001 is rank 7 with ceil, not 8, and 100*0.29 =
+// 28.999999999999996 is rank 29 with floor, not 28.
+const rankEpsilon = 1e-9
+
+// maxRankFloat is 2^63, the first float64 past the int64 range; a product
+// at or above it can't be converted.
+const maxRankFloat = 1 << 63
+
+// rawRank is totalUsers*percentage rounded per -rank-rounding, before
+// clamping. A product exactly halfway between two ranks rounds up with
+// round, so the tie goes to the larger set of wallets. It is computed in
+// int64, saturating at math.MaxInt64, so the conversion from float64 is
+// defined however large totalUsers is.
+func rawRank(totalUsers int, percentage float64) int64 {
+	exact := float64(totalUsers) * percentage
+	var rounded float64
+	switch rankRounding {
+	case "round":
+		rounded = math.Floor(exact + 0.5 + rankEpsilon)
+	case "ceil":
+		rounded = math.Ceil(exact - rankEpsilon)
+	default:
+		rounded = math.Floor(exact + rankEpsilon)
+	}
+	switch {
+	case math.IsNaN(rounded) || rounded <= 0:
+		return 0
+	case rounded >= maxRankFloat:
+		return math.MaxInt64
+	}
+	return int64(rounded)
+}
+
+// clampInt converts n to int, saturating where int is narrower.
+func clampInt(n int64) int {
+	return int(min(max(n, math.MinInt), math.MaxInt))
+}
+
+// rankForPercentage is the rank of the cutoff for the top percentage of
+// totalUsers, never above rank 1 nor past the int range.
+func rankForPercentage(totalUsers int, percentage float64) int {
+	return max(clampInt(rawRank(totalUsers, percentage)), 1)
+}
+
+// cutoffRank is rankForPercentage for fetching: it fails with
+// ErrRankOutOfRange for a rank past the board, or on a page whose number
+// with pages of size users doesn't fit an int.
+func (c *Client) cutoffRank(totalUsers int, percentage float64, size int) (int, error) {
+	rank := rankForPercentage(totalUsers, percentage)
+	if totalUsers > 0 && rank > totalUsers {
+		return 0, fmt.Errorf("%w: top %s of %d wallets is rank %d", ErrRankOutOfRange, formatPercentage(percentage), totalUsers, rank)
+	}
+	if page := int64(rank-1)/int64(max(size, 1)) + int64(c.pageBase()); page > math.MaxInt {
+		return 0, fmt.Errorf("%w: rank %d is on page %d, past the largest page number %d", ErrRankOutOfRange, rank, page, math.MaxInt)
+	}
+	return rank, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestRankRounding(t *testing.T) {
+	t.Cleanup(func() { rankRounding = "floor" })
+
+	tests := []struct {
+		total      int
+		percentage float64
+		floor      int
+		round      int
+		ceil       int
+	}{
+		{12345, 0.000001, 1, 1, 1},
+		{12345, 0.0001, 1, 1, 2},
+		{12345, 0.005, 61, 62, 62},
+		{1000, 0.01, 10, 10, 10},
+		// 100*0.07 is 7.000000000000001 in floating point.
+		{100, 0.07, 7, 7, 7},
+		{208114, 0.0003, 62, 62, 63},
+	}
+	for _, tt := range tests {
+		for rounding, want := range map[string]int{"floor": tt.floor, "round": tt.round, "ceil": tt.ceil} {
+			rankRounding = rounding
+			if got := rankForPercentage(tt.total, tt.percentage); got != want {
+				t.Errorf("%s(%d * %v) = %d, want %d", rounding, tt.total, tt.percentage, got, want)
+			}
+		}
+	}
+}
+
+// TestFloorKeepsBaselineRanks pins floor to the truncation the tool always
+// did for the default percentages, and to the one rank it now differs by
+// where float error put a whole product just below it.
+func TestFloorKeepsBaselineRanks(t *testing.T) {
+	for total := 1; total <= 300000; total++ {
+		for _, percentage := range defaultPercentages {
+			if got, want := rawRank(total, percentage), int64(float64(total)*percentage); got != want {
+				t.Fatalf("floor(%d * %v) = %d, want the baseline %d", total, percentage, got, want)
+			}
+		}
+	}
+
+	tests := []struct {
+		total      int
+		percentage float64
+		baseline   int64
+		want       int64
+	}{
+		{100, 0.29, 28, 29},
+		{100, 0.57, 56, 57},
+		{1000, 0.29, 290, 290},
+	}
+	for _, tt := range tests {
+		if got := int64(float64(tt.total) * tt.percentage); got != tt.baseline {
+			t.Errorf("truncating %d * %v = %d, want %d", tt.total, tt.percentage, got, tt.baseline)
+		}
+		if got := rawRank(tt.total, tt.percentage); got != tt.want {
+			t.Errorf("floor(%d * %v) = %d, want %d", tt.total, tt.percentage, got, tt.want)
+		}
+	}
+}
+
+// TestRankRoundingConformance checks every mode against exact decimal
+// arithmetic over a matrix of totals and percentages, many of whose
+// products are whole numbers that float64 misses by a hair.
+func TestRankRoundingConformance(t *testing.T) {
+	t.Cleanup(func() { rankRounding = "floor" })
+
+	totals := []int{1, 3, 7, 10, 100, 333, 1000, 12345, 100000, 208114}
+	percentages := []string{"0.0001", "0.001", "0.005", "0.01", "0.07", "0.1", "0.15", "0.2", "0.25", "0.29", "0.3", "0.333", "0.5", "0.57", "0.7", "1"}
+	exact := func(total int, percentage, rounding string) int {
+		product, _ := new(big.Rat).SetString(percentage)
+		product.Mul(product, new(big.Rat).SetInt64(int64(total)))
+		switch rounding {
+		case "round":
+			product.Add(product, big.NewRat(1, 2))
+		case "ceil":
+			product.Add(product, big.NewRat(product.Denom().Int64()-1, product.Denom().Int64()))
+		}
+		whole := new(big.Int).Quo(product.Num(), product.Denom())
+		return max(int(whole.Int64()), 1)
+	}
+
+	integral := 0
+	for _, total := range totals {
+		for _, p := range percentages {
+			percentage, _ := strconv.ParseFloat(p, 64)
+			product, _ := new(big.Rat).SetString(p)
+			if product.Mul(product, new(big.Rat).SetInt64(int64(total))).IsInt() {
+				integral++
+			}
+			for _, rounding := range rankRoundings {
+				rankRounding = rounding
+				if got, want := rankForPercentage(total, percentage), exact(total, p, rounding); got != want {
+					t.Errorf("%s(%d * %s) = %d, want %d", rounding, total, p, got, want)
+				}
+			}
+		}
+	}
+	if integral < 50 {
+		t.Errorf("only %d products land on an integer; the matrix should exercise them", integral)
+	}
+}
+
+func TestRankAtIntBoundaries(t *testing.T) {
+	t.Cleanup(func() { rankRounding = "floor" })
+
+	for _, rounding := range rankRoundings {
+		rankRounding = rounding
+		// On 64-bit platforms float64(math.MaxInt) rounds up to 2^63, past
+		// the int64 range.
+		if got := rawRank(math.MaxInt, 1); got != math.MaxInt {
+			t.Errorf("%s: rawRank(MaxInt, 1) = %d, want MaxInt", rounding, got)
+		}
+		if got := rankForPercentage(math.MaxInt, 1); got != math.MaxInt {
+			t.Errorf("%s: rankForPercentage(MaxInt, 1) = %d, want MaxInt", rounding, got)
+		}
+		if got := rankForPercentage(math.MaxInt32, 1); got != math.MaxInt32 {
+			t.Errorf("%s: rankForPercentage(MaxInt32, 1) = %d, want MaxInt32", rounding, got)
+		}
+		if got := rankForPercentage(math.MaxInt32, 0.5); got < math.MaxInt32/2 || got > math.MaxInt32/2+1 {
+			t.Errorf("%s: rankForPercentage(MaxInt32, 0.5) = %d", rounding, got)
+		}
+		if got := rawRank(0, 0.5); got != 0 {
+			t.Errorf("%s: rawRank(0, 0.5) = %d, want 0", rounding, got)
+		}
+	}
+	if got := clampInt(math.MinInt64); got != math.MinInt {
+		t.Errorf("clampInt(MinInt64) = %d", got)
+	}
+
+	if rank, err := defaultClient.cutoffRank(math.MaxInt, 1, 1); err != nil || rank != math.MaxInt {
+		t.Errorf("cutoffRank(MaxInt, 1, 1) = %d, %v, want the last page to fit", rank, err)
+	}
+	if _, err := defaultClient.cutoffRank(100, 1.5, 1); !errors.Is(err, ErrRankOutOfRange) {
+		t.Errorf("cutoffRank past the board: err = %v, want ErrRankOutOfRange", err)
+	}
+}
+
+func TestStepPercentages(t *testing.T) {
+	got, err := stepPercentages(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 20 || got[0] != 0.05 || got[2] != 0.15 || got[19] != 1 {
+		t.Errorf("stepPercentages(5) = %v", got)
+	}
+
+	got, _ = stepPercentages(30)
+	if !slices.Equal(got, []float64{0.3, 0.6, 0.9}) {
+		t.Errorf("stepPercentages(30) = %v, want [0.3 0.6 0.9]", got)
+	}
+	got, _ = stepPercentages(0.1)
+	if len(got) != 1000 || got[2] != 0.003 || got[999] != 1 {
+		t.Errorf("stepPercentages(0.1) has %d entries, ending %v", len(got), got[len(got)-1])
+	}
+
+	for _, step := range []float64{0, -5, 101} {
+		if _, err := stepPercentages(step); err == nil {
+			t.Errorf("stepPercentages(%v) succeeded", step)
+		}
+	}
+}
+
+func TestDensifyPercentages(t *testing.T) {
+	tests := []struct {
+		percentages []float64
+		factor      int
+		want        []float64
+	}{
+		{[]float64{0.01, 0.1, 0.2}, 2, []float64{0.01, 0.055, 0.1, 0.15, 0.2}},
+		// Unsorted input with a duplicate comes back sorted and deduplicated.
+		{[]float64{0.3, 0.1, 0.3}, 4, []float64{0.1, 0.15, 0.2, 0.25, 0.3}},
+		// 0.1 + 0.1/3 is 0.13333333333333333; rounding keeps it stable.
+		{[]float64{0.1, 0.2}, 3, []float64{0.1, 0.133333333, 0.166666667, 0.2}},
+		{[]float64{0.2, 0.1}, 1, []float64{0.1, 0.2}},
+		{[]float64{0.5}, 2, []float64{0.5}},
+	}
+	for _, tt := range tests {
+		if got := densifyPercentages(tt.percentages, tt.factor); !slices.Equal(got, tt.want) {
+			t.Errorf("densifyPercentages(%v, %d) = %v, want %v", tt.percentages, tt.factor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// planOutcome describes how a user's trajectory relates to the cutoff's.
+type planOutcome string
+
+const (
+	planAlreadyAbove planOutcome = "already-above"
+	planReaches      planOutcome = "reaches"
+	planNever        planOutcome = "never"
+)
+
+type planResult struct {
+	Percentage   float64     `json:"percentage"`
+	Rank         int         `json:"rank"`
+	TotalUsers   int         `json:"totalUsers"`
+	CurrentScore float64     `json:"currentScore"`
+	DailyPoints  float64     `json:"dailyPoints"`
+	Cutoff       float64     `json:"cutoff"`
+	CutoffGrowth float64     `json:"cutoffGrowth"`
+	GrowthSource string      `json:"growthSource"`
+	Outcome      planOutcome `json:"outcome"`
+	// Days is how long until the user reaches the cutoff, or for a user
+	// already above it, how long until the cutoff catches up (0 if never).
+	Days float64   `json:"days,omitempty"`
+	Date time.Time `json:"date,omitzero"`
+	// CutoffPerDay and DatePerDay are the cutoff per active day of the
+	// season now and on Date, with -normalize-by-days.
+	CutoffPerDay float64 `json:"cutoffPerDay,omitempty"`
+	DatePerDay   float64 `json:"datePerDay,omitempty"`
+}
+
+// normalize fills in the per-day cutoffs of plan in season at now.
+func (p *planResult) normalize(season int, now time.Time) {
+	p.CutoffPerDay, _, _ = perDay(season, now, p.Cutoff)
+	if !p.Date.IsZero() {
+		p.DatePerDay, _, _ = perDay(season, p.Date, p.Cutoff+p.CutoffGrowth*p.Days)
+	}
+}
+
+// solvePlan intersects the user's trajectory score + daily*t with the
+// cutoff's cutoff + growth*t.
+func solvePlan(score, daily, cutoff, growth float64, now time.Time) planResult {
+	plan := planResult{CurrentScore: score, DailyPoints: daily, Cutoff: cutoff, CutoffGrowth: growth}
+	closing := daily - growth
+
+	switch {
+	case score >= cutoff:
+		plan.Outcome = planAlreadyAbove
+		if closing < 0 {
+			plan.Days = (score - cutoff) / -closing
+		}
+	case closing <= 0:
+		plan.Outcome = planNever
+	default:
+		plan.Outcome = planReaches
+		plan.Days = (cutoff - score) / closing
+	}
+	if plan.Days > 0 {
+		plan.Date = now.Add(time.Duration(plan.Days * float64(24*time.Hour))).UTC()
+	}
+	return plan
+}
+
+// historicalGrowth returns the cutoff's growth in points per day for
+// percentage between the oldest and newest stored snapshots. Snapshots
+// synthesized by history -fill are ignored: they'd only echo the real ones.
+func historicalGrowth(snapshots []Snapshot, percentage float64) (growth float64, n int, ok bool) {
+	var first, last Result
+	for _, snapshot := range snapshots {
+		if snapshot.Synthesized {
+			continue
+		}
+		for _, result := range snapshot.Results {
+			if result.Percentage != percentage || result.Skipped {
+				continue
+			}
+			if n == 0 {
+				first = result
+			}
+			last = result
+			n++
+		}
+	}
+	days := float64(last.LastUpdated-first.LastUpdated) / (24 * 60 * 60)
+	if n < 2 || days <= 0 {
+		return 0, n, false
+	}
+	return float64(last.Points-first.Points) / days, n, true
+}
+
+func writePlan(w io.Writer, plan planResult) {
+	fmt.Fprintf(w, "cutoff for top %s: %.0f points (rank %d of %d)\n",
+		formatPercentage(plan.Percentage), plan.Cutoff, plan.Rank, plan.TotalUsers)
+	fmt.Fprintf(w, "cutoff growth: %s points/day (%s)\n", formatFloat(plan.CutoffGrowth, 1), plan.GrowthSource)
+	fmt.Fprintf(w, "your score:  %.0f + %s*t\n", plan.CurrentScore, formatFloat(plan.DailyPoints, 1))
+	fmt.Fprintf(w, "cutoff:      %.0f + %s*t\n", plan.Cutoff, formatFloat(plan.CutoffGrowth, 1))
+	if plan.CutoffPerDay > 0 {
+		fmt.Fprintf(w, "cutoff per active day: %s now", formatPerDay(plan.CutoffPerDay))
+		if plan.DatePerDay > 0 {
+			fmt.Fprintf(w, ", %s on %s", formatPerDay(plan.DatePerDay), plan.Date.Format(time.DateOnly))
+		}
+		fmt.Fprintln(w)
+	}
+
+	switch plan.Outcome {
+	case planAlreadyAbove:
+		fmt.Fprintf(w, "you are already %.0f points above the cutoff", plan.CurrentScore-plan.Cutoff)
+		if plan.Days > 0 {
+			fmt.Fprintf(w, ", but it grows faster and catches up in t = %.0f / (%s - %s) = %s days, around %s\n",
+				plan.CurrentScore-plan.Cutoff, formatFloat(plan.CutoffGrowth, 1), formatFloat(plan.DailyPoints, 1),
+				formatFloat(plan.Days, 1), plan.Date.Format(time.DateOnly))
+		} else {
+			fmt.Fprintln(w, " and stay above it at this rate")
+		}
+	case planReaches:
+		fmt.Fprintf(w, "t = (%.0f - %.0f) / (%s - %s) = %s days: you reach the cutoff around %s\n",
+			plan.Cutoff, plan.CurrentScore, formatFloat(plan.DailyPoints, 1), formatFloat(plan.CutoffGrowth, 1),
+			formatFloat(plan.Days, 1), plan.Date.Format(time.DateOnly))
+	case planNever:
+		if plan.CutoffGrowth <= 0 && plan.DailyPoints <= 0 {
+			fmt.Fprintln(w, "neither score is growing: the gap stays the same")
+		} else if plan.DailyPoints == plan.CutoffGrowth {
+			fmt.Fprintf(w, "the cutoff grows as fast as you do: the gap stays at %.0f points\n", plan.Cutoff-plan.CurrentScore)
+		} else {
+			fmt.Fprintln(w, "the cutoff grows faster than you do: the gap widens and you never reach it at this rate")
+		}
+	}
+}
+
+func runPlan(config Config, args []string) error {
+	flags := newCommandFlags("plan")
+	score := flags.Float64("current-score", 0, "your current total score")
+	daily := flags.Float64("daily-points", 0, "points you expect to earn per day")
+	tier := flags.String("tier", "", "target tier as a top percentage, e.g. 0.1")
+	growthFlag := flags.Float64("cutoff-growth", math.NaN(), "cutoff growth in points per day")
+	flags.Lookup("cutoff-growth").DefValue = "from the history store"
+	if err := parseCommandFlags(flags, args, "season", "base-url", "format", "verbose"); err != nil {
+		return err
+	}
+	if *tier == "" {
+		return errors.New("usage: plan -tier <percentage> -current-score N -daily-points N")
+	}
+	percentage, err := parsePercentage(*tier)
+	if err != nil {
+		return fmt.Errorf("-tier: %w", err)
+	}
+	if *score < 0 || *daily < 0 {
+		return errors.New("-current-score and -daily-points must not be negative")
+	}
+
+	season := seasons[0]
+	totalUsers, err := getTotalWallets(season)
+	if err != nil {
+		return err
+	}
+	rank := rankForPercentage(totalUsers, percentage)
+	user, err := defaultClient.UserAtRank(context.Background(), season, rank)
+	if err != nil {
+		return fmt.Errorf("failed to get the cutoff at rank %d: %w", rank, err)
+	}
+
+	growth, source := *growthFlag, "from -cutoff-growth"
+	if math.IsNaN(growth) {
+		store, err := openSnapshotStore(config)
+		if err != nil {
+			return err
+		}
+		snapshots, err := store.List(season)
+		if err != nil {
+			return err
+		}
+		var n int
+		var ok bool
+		growth, n, ok = historicalGrowth(snapshots, percentage)
+		if !ok {
+			return fmt.Errorf("history has %d snapshots for top %s, need at least 2 over time: pass -cutoff-growth", n, formatPercentage(percentage))
+		}
+		source = fmt.Sprintf("from %d history snapshots", n)
+	}
+
+	now := time.Now()
+	plan := solvePlan(*score, *daily, user.TotalScore, growth, now)
+	plan.Percentage, plan.Rank, plan.TotalUsers, plan.GrowthSource = percentage, rank, totalUsers, source
+	if normalizeByDays {
+		plan.normalize(season, now)
+	}
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	}
+	writePlan(stdout, plan)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSolvePlan(t *testing.T) {
+	now := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name                         string
+		score, daily, cutoff, growth float64
+		outcome                      planOutcome
+		days                         float64
+	}{
+		{"reaches", 1000, 5000, 21000, 1000, planReaches, 5},
+		{"reaches a shrinking cutoff", 1000, 0, 2000, -100, planReaches, 10},
+		{"gap widens", 1000, 500, 21000, 1000, planNever, 0},
+		{"gap constant", 1000, 1000, 21000, 1000, planNever, 0},
+		{"nobody grows", 1000, 0, 21000, 0, planNever, 0},
+		{"already above and staying", 30000, 1000, 21000, 1000, planAlreadyAbove, 0},
+		{"already above but caught", 30000, 0, 21000, 1500, planAlreadyAbove, 6},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := solvePlan(tc.score, tc.daily, tc.cutoff, tc.growth, now)
+			if plan.Outcome != tc.outcome || !closeTo(plan.Days, tc.days) {
+				t.Fatalf("solvePlan = %s in %v days, want %s in %v", plan.Outcome, plan.Days, tc.outcome, tc.days)
+			}
+			if tc.days > 0 && !plan.Date.Equal(now.AddDate(0, 0, int(tc.days))) {
+				t.Fatalf("date = %s", plan.Date)
+			}
+
+			var out bytes.Buffer
+			writePlan(&out, plan)
+			if !strings.Contains(out.String(), "cutoff:") || !strings.Contains(out.String(), "your score:") {
+				t.Fatalf("plan output does not show both trajectories:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestHistoricalGrowth(t *testing.T) {
+	day := int64(24 * 60 * 60)
+	snapshots := []Snapshot{
+		{Results: []Result{{Percentage: 0.1, LastUpdated: 1714000000, Points: 9000}, {Percentage: 0.01, LastUpdated: 1714000000, Points: 50000}}},
+		{Results: []Result{{Percentage: 0.1, LastUpdated: 1714000000 + day, Skipped: true}}},
+		{Results: []Result{{Percentage: 0.1, LastUpdated: 1714000000 + 4*day, Points: 10000}}},
+	}
+	growth, n, ok := historicalGrowth(snapshots, 0.1)
+	if !ok || n != 2 || !closeTo(growth, 250) {
+		t.Fatalf("historicalGrowth = %v, %d, %v; want 250/day from 2 snapshots", growth, n, ok)
+	}
+	if _, _, ok := historicalGrowth(snapshots, 0.01); ok {
+		t.Fatal("a single snapshot should not give a growth rate")
+	}
+
+	synthesized := Snapshot{Synthesized: true, Results: []Result{{Percentage: 0.1, LastUpdated: 1714000000 + 8*day, Points: 20000}}}
+	growth, n, ok = historicalGrowth(append(snapshots, synthesized), 0.1)
+	if !ok || n != 2 || !closeTo(growth, 250) {
+		t.Fatalf("historicalGrowth with a synthesized snapshot = %v, %d, %v; want it ignored", growth, n, ok)
+	}
+}
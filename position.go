@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// percentilePrecision is the number of decimals shown for a wallet's
+// percentile.
+var percentilePrecision = 2
+
+const maxPercentilePrecision = 6
+
+// walletPosition is where a wallet stands on the leaderboard. Percentile is
+// the displayed "top N%", rounded up at the chosen precision so a wallet is
+// never shown better placed than it is; Fraction is the exact rank/total.
+type walletPosition struct {
+	Address    string  `json:"address"`
+	Rank       int     `json:"rank"`
+	TotalUsers int     `json:"totalUsers"`
+	TotalScore float64 `json:"totalScore"`
+	Fraction   float64 `json:"fraction"`
+	Percentile string  `json:"percentile"`
+	// ClimbToImprove is how many ranks the wallet must climb for the
+	// displayed percentile to drop by one step, or 0 if it can't.
+	ClimbToImprove int `json:"climbToImprove"`
+	// PointsPerDay is TotalScore divided by ActiveDays, the UTC days of
+	// the season begun by the leaderboard update, with -normalize-by-days.
+	PointsPerDay float64 `json:"pointsPerDay,omitempty"`
+	ActiveDays   int     `json:"activeDays,omitempty"`
+	// addressActivity is set with -enrich-rpc.
+	*addressActivity
+}
+
+// percentScale is 100% in steps of 10^-precision percent.
+func percentScale(precision int) int64 {
+	scale := int64(100)
+	for range precision {
+		scale *= 10
+	}
+	return scale
+}
+
+// percentileSteps returns rank/total as a percentage in steps of
+// 10^-precision percent, rounded up.
+func percentileSteps(rank, total, precision int) int64 {
+	return (int64(rank)*percentScale(precision) + int64(total) - 1) / int64(total)
+}
+
+// climbToImprove returns how many ranks above rank the displayed percentile
+// first drops by one step.
+func climbToImprove(rank, total, precision int) int {
+	steps := percentileSteps(rank, total, precision)
+	if steps <= 1 {
+		return 0
+	}
+	target := (steps - 1) * int64(total) / percentScale(precision)
+	if target < 1 {
+		return 0
+	}
+	return rank - int(target)
+}
+
+func newWalletPosition(user User, total, precision int) walletPosition {
+	steps := percentileSteps(user.Rank, total, precision)
+	percentile := strconv.FormatInt(steps, 10)
+	if precision > 0 {
+		percentile = fmt.Sprintf("%0*s", precision+1, percentile)
+		percentile = percentile[:len(percentile)-precision] + "." + percentile[len(percentile)-precision:]
+	}
+	return walletPosition{
+		Address:        user.Address,
+		Rank:           user.Rank,
+		TotalUsers:     total,
+		TotalScore:     user.TotalScore,
+		Fraction:       topPercentile(user.Rank, total),
+		Percentile:     percentile + "%",
+		ClimbToImprove: climbToImprove(user.Rank, total, precision),
+	}
+}
+
+// normalize fills in PointsPerDay for a position on the leaderboard of
+// season updated at lastUpdated.
+func (p *walletPosition) normalize(season int, lastUpdated int64) {
+	p.PointsPerDay, p.ActiveDays, _ = perDay(season, time.Unix(lastUpdated, 0), p.TotalScore)
+}
+
+// formatThousands groups the digits of n in threes, with a period when the
+// decimal separator is a comma.
+func formatThousands(n int) string {
+	sep := ","
+	if decimalSep == "," {
+		sep = "."
+	}
+	digits := strconv.Itoa(n)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+func writeWalletPosition(w io.Writer, format string, position walletPosition) error {
+	position.Address = redact.address(position.Address)
+	if format == "json" {
+		return encodeJSON(w, position, true)
+	}
+	fmt.Fprintf(w, "top %s (rank %s of %s): %.0f points", localizeDecimal(position.Percentile),
+		formatThousands(position.Rank), formatThousands(position.TotalUsers), position.TotalScore)
+	if position.ActiveDays > 0 {
+		fmt.Fprintf(w, ", %s over %d days", formatPerDay(position.PointsPerDay), position.ActiveDays)
+	}
+	fmt.Fprintln(w)
+	if position.ClimbToImprove > 0 {
+		fmt.Fprintf(w, "climb %s ranks to improve by one step\n", formatThousands(position.ClimbToImprove))
+	}
+	if activity := position.addressActivity; activity != nil {
+		fmt.Fprintf(w, "contract: %s, transactions: %s\n", activity.contractText(), activity.txCountText())
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWalletPositionRoundsTowardWorse(t *testing.T) {
+	tests := []struct {
+		rank, total, precision int
+		percentile             string
+		climb                  int
+	}{
+		{14203, 208114, 2, "6.83%", 10},
+		// Exact boundaries are not rounded.
+		{1, 100, 0, "1%", 0},
+		{2, 100, 0, "2%", 1},
+		{100, 10000, 2, "1.00%", 1},
+		// Just past a boundary rounds up to the next step.
+		{10001, 1000000, 2, "1.01%", 1},
+		{10000, 1000000, 2, "1.00%", 100},
+		{1, 1000000, 2, "0.01%", 0},
+		{1, 3, 1, "33.4%", 0},
+		{3, 3, 1, "100.0%", 1},
+		{5, 1000000, 4, "0.0005%", 1},
+	}
+	for _, tt := range tests {
+		position := newWalletPosition(User{Rank: tt.rank}, tt.total, tt.precision)
+		if position.Percentile != tt.percentile || position.ClimbToImprove != tt.climb {
+			t.Errorf("rank %d of %d at precision %d = %s, climb %d; want %s, climb %d",
+				tt.rank, tt.total, tt.precision, position.Percentile, position.ClimbToImprove, tt.percentile, tt.climb)
+		}
+	}
+}
+
+func TestClimbReachesNextStep(t *testing.T) {
+	const total, precision = 208114, 2
+	for _, rank := range []int{30, 99, 1000, 14203, 100000, total} {
+		climb := climbToImprove(rank, total, precision)
+		steps := percentileSteps(rank, total, precision)
+		if got := percentileSteps(rank-climb, total, precision); got != steps-1 {
+			t.Errorf("rank %d: climbing %d reaches %d steps, want %d", rank, climb, got, steps-1)
+		}
+		if got := percentileSteps(rank-climb+1, total, precision); got != steps {
+			t.Errorf("rank %d: climbing %d ranks already improves the percentile", rank, climb-1)
+		}
+	}
+}
+
+func TestWriteWalletPosition(t *testing.T) {
+	position := newWalletPosition(User{Rank: 14203, Address: "0xabc", TotalScore: 1234}, 208114, 2)
+
+	var text bytes.Buffer
+	writeWalletPosition(&text, "text", position)
+	want := "top 6.83% (rank 14,203 of 208,114): 1234 points\nclimb 10 ranks to improve by one step\n"
+	if text.String() != want {
+		t.Errorf("text output = %q, want %q", text.String(), want)
+	}
+
+	var out bytes.Buffer
+	writeWalletPosition(&out, "json", position)
+	var decoded walletPosition
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Fraction != 14203.0/208114 || decoded.Percentile != "6.83%" {
+		t.Errorf("json output = %+v", decoded)
+	}
+}
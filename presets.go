@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+// defaultPercentages are the cutoffs computed when no other list is given.
+var defaultPercentages = []float64{
+	0.0001, 0.001, 0.005, 0.01, 0.03, 0.04, 0.06, 0.08, 0.1, 0.18, 0.26,
+}
+
+type percentagePreset struct {
+	name        string
+	description string
+	percentages []float64
+}
+
+// percentagePresets are the lists selectable with -preset, in the order
+// -list-presets shows them. Each is sorted, free of duplicates and within
+// (0, 1].
+var percentagePresets = []percentagePreset{
+	{"default", "the list used without -preset (alias og)", defaultPercentages},
+	{"coarse", "a handful of round cutoffs", []float64{0.01, 0.05, 0.1, 0.25, 0.5}},
+	{"fine", "25 log-spaced cutoffs from 0.01% to 50%", logGrid(0.0001, 0.5, 25)},
+	{"airdrop-speculation", "tiers the community commonly quotes for airdrops", []float64{0.001, 0.005, 0.01, 0.02, 0.05, 0.1, 0.2}},
+}
+
+var presetAliases = map[string]string{"og": "default"}
+
+// logGrid returns n points spaced evenly on a log scale from lo to hi,
+// rounded to three significant digits.
+func logGrid(lo, hi float64, n int) []float64 {
+	grid := make([]float64, n)
+	for i := range grid {
+		v := lo * math.Pow(hi/lo, float64(i)/float64(n-1))
+		scale := math.Pow(10, 2-math.Floor(math.Log10(v)))
+		grid[i] = math.Round(v*scale) / scale
+	}
+	return grid
+}
+
+func findPreset(name string) ([]float64, error) {
+	if alias, ok := presetAliases[name]; ok {
+		name = alias
+	}
+	for _, preset := range percentagePresets {
+		if preset.name == name {
+			return slices.Clone(preset.percentages), nil
+		}
+	}
+	names := make([]string, len(percentagePresets))
+	for i, preset := range percentagePresets {
+		names[i] = preset.name
+	}
+	return nil, fmt.Errorf("unknown preset %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+// unionPercentages merges a and b into one sorted list without duplicates.
+func unionPercentages(a, b []float64) []float64 {
+	union := slices.Concat(a, b)
+	slices.Sort(union)
+	return slices.Compact(union)
+}
+
+func writePresets(w io.Writer) {
+	for i, preset := range percentagePresets {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s: %s\n", preset.name, preset.description)
+		values := make([]string, len(preset.percentages))
+		for j, percentage := range preset.percentages {
+			values[j] = formatPercentage(percentage)
+		}
+		fmt.Fprintf(w, "  %s\n", strings.Join(values, ", "))
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPercentagePresets(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, preset := range percentagePresets {
+		if seen[preset.name] {
+			t.Errorf("preset %s defined twice", preset.name)
+		}
+		seen[preset.name] = true
+		if len(preset.percentages) == 0 {
+			t.Errorf("preset %s is empty", preset.name)
+		}
+		for i, percentage := range preset.percentages {
+			if percentage <= 0 || percentage > 1 {
+				t.Errorf("preset %s: %v out of range (0, 1]", preset.name, percentage)
+			}
+			if i > 0 && percentage <= preset.percentages[i-1] {
+				t.Errorf("preset %s: %v after %v, want sorted without duplicates", preset.name, percentage, preset.percentages[i-1])
+			}
+		}
+	}
+	for alias, name := range presetAliases {
+		if !seen[name] {
+			t.Errorf("alias %s points at unknown preset %s", alias, name)
+		}
+	}
+
+	fine, _ := findPreset("fine")
+	if len(fine) != 25 || fine[0] != 0.0001 || fine[24] != 0.5 {
+		t.Errorf("fine = %v, want 25 points from 0.0001 to 0.5", fine)
+	}
+	if og, _ := findPreset("og"); !slices.Equal(og, defaultPercentages) {
+		t.Errorf("og = %v, want the default list", og)
+	}
+	if _, err := findPreset("medium"); err == nil {
+		t.Error("unknown preset accepted")
+	}
+}
+
+func TestUnionPercentages(t *testing.T) {
+	got := unionPercentages([]float64{0.5, 0.02, 0.01}, []float64{0.01, 0.05, 0.1})
+	if want := []float64{0.01, 0.02, 0.05, 0.1, 0.5}; !slices.Equal(got, want) {
+		t.Errorf("union = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// record-fixtures is a command for maintainers, left out of the usage: it
+// captures a few live responses into testdata for the tests to serve from
+// a fake upstream, so the fixtures track what the API really sends.
+
+const (
+	// fixturesDir is where record-fixtures writes by default, and where the
+	// tests look for what it recorded.
+	fixturesDir  = "testdata/recorded"
+	fixturesMeta = "fixtures.json"
+	// fixtureTimestamp replaces every lastUpdated recorded, so tests don't
+	// depend on when the fixtures were captured.
+	fixtureTimestamp = 1718000000
+)
+
+// fixtureHeaders are the response headers kept in the metadata as hints of
+// the API's version.
+var fixtureHeaders = []string{"Server", "Via", "X-Powered-By", "Api-Version", "X-Api-Version"}
+
+// fixtureRequest is a recorded response and the query it answered, with
+// addresses sanitized.
+type fixtureRequest struct {
+	File  string `json:"file"`
+	Query string `json:"query"`
+}
+
+// fixturesMetadata describes a recording, in fixturesMeta next to it.
+type fixturesMetadata struct {
+	CapturedAt time.Time        `json:"capturedAt"`
+	Season     int              `json:"season"`
+	Tool       string           `json:"tool"`
+	Requests   []fixtureRequest `json:"requests"`
+	// Headers and Keys hint at the API version recorded: the headers of
+	// fixtureHeaders it sent and the JSON paths its responses had.
+	Headers map[string]string `json:"headers,omitempty"`
+	Keys    []string          `json:"keys"`
+}
+
+// fixtureSanitizer replaces the addresses of recorded responses with
+// placeholders, the same one for an address wherever it appears, and
+// lastUpdated with fixtureTimestamp.
+type fixtureSanitizer struct {
+	addresses map[string]string
+	keys      map[string]bool
+}
+
+func newFixtureSanitizer() *fixtureSanitizer {
+	return &fixtureSanitizer{addresses: make(map[string]string), keys: make(map[string]bool)}
+}
+
+func (s *fixtureSanitizer) address(address string) string {
+	key := strings.ToLower(address)
+	if placeholder, ok := s.addresses[key]; ok {
+		return placeholder
+	}
+	placeholder := fmt.Sprintf("0x%040x", len(s.addresses)+1)
+	s.addresses[key] = placeholder
+	return placeholder
+}
+
+// sanitize rewrites the decoded JSON value v found at path, noting the
+// paths it sees in the style of responseSchema.
+func (s *fixtureSanitizer) sanitize(v any, path string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			s.keys[childPath] = true
+			switch address, isString := child.(string); {
+			case key == "address" && isString:
+				v[key] = s.address(address)
+			case key == "lastUpdated":
+				v[key] = json.Number(strconv.Itoa(fixtureTimestamp))
+			default:
+				v[key] = s.sanitize(child, childPath)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = s.sanitize(child, path+"[]")
+		}
+	}
+	return v
+}
+
+// fetchFixture gets url as-is, without the cache, mirrors or retries, and
+// returns its body sanitized.
+func (s *fixtureSanitizer) fetchFixture(ctx context.Context, url string) ([]byte, http.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, _, err := newAPIRequest(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := defaultClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, nil, &StatusError{StatusCode: resp.StatusCode, Body: body}
+	}
+	decoder := json.NewDecoder(&limitedReader{r: resp.Body, n: maxResponseBytes})
+	decoder.UseNumber()
+	var body any
+	if err := decoder.Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	data, err := json.MarshalIndent(s.sanitize(body, ""), "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(data, '\n'), resp.Header, nil
+}
+
+// recordFixtures records page 1 of size 1, the page of size 1 holding the
+// middle rank, and the lookup of the address at that rank in season, into
+// dir.
+func recordFixtures(ctx context.Context, dir string, season int) (fixturesMetadata, error) {
+	meta := fixturesMetadata{CapturedAt: time.Now().UTC(), Season: season, Tool: defaultUserAgent(), Headers: make(map[string]string)}
+	s := newFixtureSanitizer()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return meta, err
+	}
+	record := func(file, query string) (Response, error) {
+		data, header, err := s.fetchFixture(ctx, defaultClient.leaderboardURL(season)+"?"+query)
+		if err != nil {
+			return Response{}, fmt.Errorf("%s: %w", query, err)
+		}
+		for _, name := range fixtureHeaders {
+			if value := header.Get(name); value != "" {
+				meta.Headers[name] = value
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+			return Response{}, err
+		}
+		var response Response
+		err = json.Unmarshal(data, &response)
+		return response, err
+	}
+
+	first, err := record("page1_size1.json", "page=1&size=1")
+	if err != nil {
+		return meta, err
+	}
+	meta.Requests = append(meta.Requests, fixtureRequest{File: "page1_size1.json", Query: "page=1&size=1"})
+	if first.Data.Total < 1 {
+		return meta, errors.New("the leaderboard is empty")
+	}
+	mid := (first.Data.Total + 1) / 2
+	query := fmt.Sprintf("page=%d&size=1", mid)
+	middle, err := record("mid_rank.json", query)
+	if err != nil {
+		return meta, err
+	}
+	meta.Requests = append(meta.Requests, fixtureRequest{File: "mid_rank.json", Query: query})
+	if len(middle.Data.Users) == 0 {
+		return meta, fmt.Errorf("no user at rank %d to look up", mid)
+	}
+
+	// The lookup is of the real address; what's recorded is its
+	// placeholder, which the mid-rank page already maps it to.
+	var real string
+	for address, placeholder := range s.addresses {
+		if placeholder == middle.Data.Users[0].Address {
+			real = address
+		}
+	}
+	if _, err := record("address.json", "address="+neturl.QueryEscape(real)); err != nil {
+		return meta, err
+	}
+	meta.Requests = append(meta.Requests, fixtureRequest{File: "address.json", Query: "address=" + middle.Data.Users[0].Address})
+
+	for key := range s.keys {
+		meta.Keys = append(meta.Keys, key)
+	}
+	slices.Sort(meta.Keys)
+	return meta, writeJSONFile(filepath.Join(dir, fixturesMeta), meta)
+}
+
+func runRecordFixtures(args []string) error {
+	flags := newCommandFlags("record-fixtures")
+	dir := flags.String("dir", fixturesDir, "directory to write the fixtures to")
+	if err := parseCommandFlags(flags, args, "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+	meta, err := recordFixtures(context.Background(), *dir, seasons[0])
+	if err != nil {
+		return fmt.Errorf("record-fixtures: %w", err)
+	}
+	log.Printf("Recorded %d responses of season %d into %s", len(meta.Requests), meta.Season, *dir)
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtureStaleAfter is how old recorded fixtures get before the tests warn
+// that they should be recorded again.
+const fixtureStaleAfter = 90 * 24 * time.Hour
+
+// recordedUpstream serves the fixtures recorded into dir, answering each
+// query recorded with its response. The board without a query, fetched for
+// the total, is answered with page 1.
+func recordedUpstream(t *testing.T, dir string) (http.Handler, fixturesMetadata) {
+	t.Helper()
+	var meta fixturesMetadata
+	data, err := os.ReadFile(filepath.Join(dir, fixturesMeta))
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skipf("no fixtures recorded in %s; run the record-fixtures command to record them", dir)
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &meta)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	bodies := make(map[string][]byte)
+	for _, request := range meta.Requests {
+		body, err := os.ReadFile(filepath.Join(dir, request.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies[request.Query] = body
+	}
+	bodies[""] = bodies["page=1&size=1"]
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.RawQuery]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}), meta
+}
+
+// checkRecordedFixtures runs the lookups the fixtures in dir were recorded
+// for against them.
+func checkRecordedFixtures(t *testing.T, dir string) fixturesMetadata {
+	t.Helper()
+	upstream, meta := recordedUpstream(t, dir)
+	useFakeUpstream(t, upstream)
+
+	total, err := getTotalWallets(meta.Season)
+	if err != nil || total < 1 {
+		t.Fatalf("getTotalWallets = %d, %v", total, err)
+	}
+	mid, err := defaultClient.UserAtRank(context.Background(), meta.Season, (total+1)/2)
+	if err != nil {
+		t.Fatalf("userAtRank(middle): %v", err)
+	}
+	user, _, found, err := userByAddress(t.Context(), meta.Season, mid.Address)
+	if err != nil || !found || user.Rank != mid.Rank || user.TotalScore != mid.TotalScore {
+		t.Errorf("userByAddress(t.Context(), %s) = %+v, %v, %v, want %+v", mid.Address, user, found, err, mid)
+	}
+	return meta
+}
+
+func TestRecordFixtures(t *testing.T) {
+	var live bytes.Buffer
+	upstream := rankedUpstream(1001)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := httptest.NewRecorder()
+		upstream.ServeHTTP(recorder, r)
+		body := strings.ReplaceAll(recorder.Body.String(), "1718000000", "1760000000")
+		live.WriteString(body)
+		w.Header().Set("Server", "taiko-api/2.1")
+		w.Write([]byte(body))
+	}))
+	dir := t.TempDir()
+	meta, err := recordFixtures(t.Context(), dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(meta.Requests) != 3 || meta.Requests[1].Query != "page=501&size=1" || meta.Headers["Server"] != "taiko-api/2.1" {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if !strings.Contains(strings.Join(meta.Keys, " "), "data.items[].multiplier") {
+		t.Errorf("keys = %v, want the paths seen", meta.Keys)
+	}
+	// Rank 501 has address ...1f5; its placeholder is the second one.
+	address, err := os.ReadFile(filepath.Join(dir, "address.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(live.String(), "1f5") || strings.Contains(string(address), "1f5") || !strings.Contains(string(address), `"0x0000000000000000000000000000000000000002"`) {
+		t.Errorf("address.json = %s, want the address sanitized like on the mid-rank page", address)
+	}
+	if strings.Contains(string(address), "1760000000") || !strings.Contains(string(address), `"lastUpdated": 1718000000`) {
+		t.Errorf("address.json = %s, want lastUpdated normalized", address)
+	}
+
+	checkRecordedFixtures(t, dir)
+}
+
+func TestRecordedFixtures(t *testing.T) {
+	meta := checkRecordedFixtures(t, fixturesDir)
+	if age := time.Since(meta.CapturedAt); age > fixtureStaleAfter {
+		log.Printf("Warning: the fixtures in %s were recorded %d days ago; run record-fixtures to refresh them", fixturesDir, int(age.Hours()/24))
+	}
+}
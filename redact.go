@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// redactor replaces wallet addresses in rendered output with a short
+// pseudonym: the first 6 characters of the address followed by a keyed hash,
+// so a wallet maps to the same pseudonym within a report without being
+// reversible. A nil *redactor leaves addresses unchanged.
+type redactor struct {
+	key []byte
+}
+
+// newRedactor keys pseudonyms with secret, or with a random per-run key when
+// secret is empty.
+func newRedactor(secret string) *redactor {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &redactor{key: key}
+}
+
+func (r *redactor) address(address string) string {
+	if r == nil || address == "" {
+		return address
+	}
+	address = strings.ToLower(address)
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(address))
+	return address[:min(6, len(address))] + "-" + hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+func (r *redactor) user(user User) User {
+	user.Address = r.address(user.Address)
+	return user
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+var fullAddress = regexp.MustCompile(`0x[0-9a-fA-F]{40}`)
+
+func useRedactor(t *testing.T, secret string) {
+	t.Helper()
+	old := redact
+	redact = newRedactor(secret)
+	t.Cleanup(func() { redact = old })
+}
+
+func TestRedactorIsStable(t *testing.T) {
+	a := newRedactor("secret")
+	address := "0x000000000000000000000000000000000000000A"
+	got := a.address(address)
+	if got != a.address(address) || got != a.address("0x000000000000000000000000000000000000000a") {
+		t.Fatal("the same wallet should map to the same pseudonym")
+	}
+	if got == a.address("0x000000000000000000000000000000000000000b") {
+		t.Fatal("different wallets should map to different pseudonyms")
+	}
+	if got != newRedactor("secret").address(address) {
+		t.Fatal("the same secret should give the same pseudonym")
+	}
+	if got == newRedactor("other").address(address) {
+		t.Fatal("a different secret should give a different pseudonym")
+	}
+	var none *redactor
+	if none.address(address) != address {
+		t.Fatal("a nil redactor should leave addresses unchanged")
+	}
+}
+
+func TestRedactedOutputsHaveNoAddresses(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	useRedactor(t, "")
+
+	watchlist, err := readWatchlist("testdata/watchlist.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallets, groups, err := buildWatchlistReport(defaultSeason, watchlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report bytes.Buffer
+	writeWatchlistReport(&report, wallets, groups)
+	if match := fullAddress.FindString(report.String()); match != "" {
+		t.Errorf("watchlist report contains %s:\n%s", match, report.String())
+	}
+
+	handler := newHTTPHandler(&snapshotStore{}, defaultSeason)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/user?address=0x000000000000000000000000000000000000000a", nil))
+	if rec.Code != 200 {
+		t.Fatalf("GET /user = %d: %s", rec.Code, rec.Body.String())
+	}
+	if match := fullAddress.FindString(rec.Body.String()); match != "" {
+		t.Errorf("/user response contains %s: %s", match, rec.Body.String())
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// reloader re-reads the config file for serve and -watch; nil until main
+// has loaded the config.
+var reloader *configReloader
+
+// configReloader re-reads the config file on SIGHUP or POST /-/reload. A new
+// config is validated in full before anything changes; on any error the old
+// one stays in force. Valid changes are applied by the refresh loop just
+// before its next refresh, so a refresh never sees half of a reload, and
+// the cached snapshot and history store are left alone.
+type configReloader struct {
+	path     string
+	explicit bool
+	// percentagesFromFlags is set when -percentages, -percentages-file or
+	// -percentile-step was given, which take precedence over the config.
+	percentagesFromFlags bool
+
+	mu      sync.Mutex
+	current Config
+	pending *Config
+}
+
+// reload loads and validates the config file and queues it for the next
+// refresh. It returns the changes it found.
+func (r *configReloader) reload() ([]string, error) {
+	config, err := loadConfig(r.path, r.explicit)
+	if err != nil {
+		return nil, err
+	}
+	if config.Watchlist != "" {
+		if _, err := readWatchlist(config.Watchlist); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	base := r.current
+	if r.pending != nil {
+		base = *r.pending
+	}
+	changes := diffConfig(base, config, r.percentagesFromFlags)
+	r.pending = &config
+	return changes, nil
+}
+
+// apply puts a pending reload into effect. Only the goroutine that runs
+// refreshes may call it.
+func (r *configReloader) apply() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil {
+		return
+	}
+	if !r.percentagesFromFlags && len(r.pending.Percentages) > 0 {
+		defaultClient.Percentages = r.pending.Percentages
+		if densifyFactor > 1 {
+			defaultClient.Percentages = densifyPercentages(defaultClient.Percentages, densifyFactor)
+		}
+	}
+	r.current = *r.pending
+	r.pending = nil
+}
+
+// diffConfig describes what changed between old and new. Only percentages
+// take effect on reload; the rest is read once at startup.
+func diffConfig(old, new Config, percentagesFromFlags bool) []string {
+	var changes []string
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := range oldValue.NumField() {
+		field := oldValue.Type().Field(i)
+		a, b := oldValue.Field(i).Interface(), newValue.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		change := fmt.Sprintf("%s: %v -> %v", name, a, b)
+		switch {
+		case field.Name != "Percentages":
+			change += " (needs a restart)"
+		case percentagesFromFlags:
+			change += " (overridden by -percentages)"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func (r *configReloader) logReload() ([]string, error) {
+	changes, err := r.reload()
+	if err != nil {
+		log.Printf("Config reload rejected, keeping the current config: %v", err)
+		return nil, err
+	}
+	if len(changes) == 0 {
+		log.Printf("Config reloaded: no changes")
+	}
+	for _, change := range changes {
+		log.Printf("Config reloaded: %s", change)
+	}
+	return changes, nil
+}
+
+// watchSignals reloads the config on every SIGHUP until the returned stop
+// function is called.
+func (r *configReloader) watchSignals() (stop func()) {
+	if r == nil {
+		return func() {}
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-hup:
+				r.logReload()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		close(done)
+	}
+}
+
+// handleReload serves POST /-/reload for callers presenting token as a
+// bearer token.
+func handleReload(r *configReloader, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		presented, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "invalid reload token")
+			return
+		}
+		changes, err := r.logReload()
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if changes == nil {
+			changes = []string{}
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]any{"status": "reloaded", "changes": changes})
+	}
+}
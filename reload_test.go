@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func newTestReloader(t *testing.T, config string) (*configReloader, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	current, err := loadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := defaultClient.Percentages
+	defaultClient.Percentages = current.Percentages
+	t.Cleanup(func() { defaultClient.Percentages = old })
+	log.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &configReloader{path: path, explicit: true, current: current}, path
+}
+
+func TestConfigReload(t *testing.T) {
+	r, path := newTestReloader(t, "percentages: [0.01, 0.1]\nuserAgent: a\n")
+
+	os.WriteFile(path, []byte("percentages: [0.05]\nuserAgent: b\n"), 0o644)
+	changes, err := r.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"userAgent: a -> b (needs a restart)", "percentages: [0.01 0.1] -> [0.05]"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %q, want %q", changes, want)
+	}
+	// Nothing changes until the refresh loop applies it.
+	if !reflect.DeepEqual(defaultClient.Percentages, []float64{0.01, 0.1}) {
+		t.Fatalf("percentages changed before apply: %v", defaultClient.Percentages)
+	}
+	r.apply()
+	if !reflect.DeepEqual(defaultClient.Percentages, []float64{0.05}) {
+		t.Errorf("percentages after apply = %v, want [0.05]", defaultClient.Percentages)
+	}
+
+	for name, config := range map[string]string{
+		"bad yaml":          "percentages: [0.05\n",
+		"bad percentage":    "percentages: [0.05, 2]\n",
+		"bad base URL":      "percentages: [0.2]\nbaseURLs: [ftp://x]\n",
+		"missing watchlist": "percentages: [0.2]\nwatchlist: nowhere.yaml\n",
+	} {
+		os.WriteFile(path, []byte(config), 0o644)
+		if _, err := r.reload(); err == nil {
+			t.Errorf("%s: reload succeeded", name)
+		}
+		r.apply()
+		if !reflect.DeepEqual(defaultClient.Percentages, []float64{0.05}) {
+			t.Errorf("%s: percentages = %v, want the old [0.05] kept", name, defaultClient.Percentages)
+		}
+	}
+}
+
+func TestReloadEndpoint(t *testing.T) {
+	r, path := newTestReloader(t, "percentages: [0.01]\n")
+	store := &snapshotStore{}
+	store.set(testSnapshot(100, 9910))
+
+	mux := http.NewServeMux()
+	mux.Handle("/", newHTTPHandler(store, defaultSeason))
+	mux.HandleFunc("POST /-/reload", handleReload(r, "secret"))
+
+	post := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/-/reload", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	os.WriteFile(path, []byte("percentages: [0.02]\n"), 0o644)
+	for _, token := range []string{"", "wrong"} {
+		if rec := post(token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, rec.Code)
+		}
+	}
+	rec := post("secret")
+	var body struct{ Changes []string }
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusOK || !slices.Equal(body.Changes, []string{"percentages: [0.01] -> [0.02]"}) {
+		t.Errorf("reload: status %d, changes %q", rec.Code, body.Changes)
+	}
+
+	os.WriteFile(path, []byte("percentages: [7]\n"), 0o644)
+	if rec := post("secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid config: status %d, want 400", rec.Code)
+	}
+	r.apply()
+	if !reflect.DeepEqual(defaultClient.Percentages, []float64{0.02}) {
+		t.Errorf("percentages = %v, want the last valid [0.02]", defaultClient.Percentages)
+	}
+	if _, ok := store.get(); !ok {
+		t.Error("reload dropped the cached snapshot")
+	}
+}
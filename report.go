@@ -0,0 +1,336 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// churnPercentages are the tiers whose entries and exits the weekly report
+// counts.
+var churnPercentages = []float64{0.01, 0.1}
+
+// tierChange is how a cutoff moved over the report window.
+type tierChange struct {
+	Percentage float64
+	From, To   int
+}
+
+func (c tierChange) Delta() int { return c.To - c.From }
+
+// Relative is the change as a fraction of the starting cutoff, or 0 if it
+// started at 0.
+func (c tierChange) Relative() float64 {
+	if c.From == 0 {
+		return 0
+	}
+	return float64(c.Delta()) / float64(c.From)
+}
+
+type churnRow struct {
+	Percentage float64 `json:"percentage"`
+	Entered    int     `json:"entered"`
+	Exited     int     `json:"left"`
+}
+
+// checkCoverage fails unless the exports before and after hold the same
+// prefix of the board, or allowPartial is set.
+func checkCoverage(before, after leaderboardDump, allowPartial bool) error {
+	if before.matches(after.dumpCoverage) || allowPartial {
+		return nil
+	}
+	return fmt.Errorf("the first export holds %s but the second %s; pass -allow-partial to compare them anyway", before.dumpCoverage, after.dumpCoverage)
+}
+
+// churnBetween counts the wallets entering and leaving the top of each of
+// percentages from before to after. The tiers either export doesn't hold
+// whole are left out, with a note saying so.
+func churnBetween(before, after leaderboardDump, percentages []float64) (churn []churnRow, notes []string) {
+	for _, percentage := range percentages {
+		if !before.covers(before.TotalUsers, percentage) || !after.covers(after.TotalUsers, percentage) {
+			notes = append(notes, fmt.Sprintf("Churn of the top %s is left out: the exports hold %s and %s.",
+				formatPercentage(percentage), before.dumpCoverage, after.dumpCoverage))
+			continue
+		}
+		was, is := topAddresses(before, percentage), topAddresses(after, percentage)
+		row := churnRow{Percentage: percentage}
+		for address := range is {
+			if !was[address] {
+				row.Entered++
+			}
+		}
+		for address := range was {
+			if !is[address] {
+				row.Exited++
+			}
+		}
+		churn = append(churn, row)
+	}
+	return churn, notes
+}
+
+// weeklyReport is what report weekly found between From and To. Sections
+// without data are nil, and Notes says why.
+type weeklyReport struct {
+	Season   int
+	From, To time.Time
+	// Start and End are the leaderboard updates compared.
+	Start, End         time.Time
+	Tiers              []tierChange
+	Fastest            *tierChange
+	UsersFrom, UsersTo int
+	Churn              []churnRow
+	Notes              []string
+}
+
+func (r weeklyReport) UserDelta() int { return r.UsersTo - r.UsersFrom }
+
+// UserGrowth is the relative change in wallets.
+func (r weeklyReport) UserGrowth() float64 {
+	if r.UsersFrom == 0 {
+		return 0
+	}
+	return float64(r.UserDelta()) / float64(r.UsersFrom)
+}
+
+func readDump(path string) (leaderboardDump, error) {
+	var dump leaderboardDump
+	file, err := os.Open(path)
+	if err != nil {
+		return dump, fmt.Errorf("failed to read export: %w", err)
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return dump, fmt.Errorf("failed to read export %s: %w", path, err)
+		}
+		r = gz
+	}
+	if err := json.NewDecoder(r).Decode(&dump); err != nil {
+		return dump, fmt.Errorf("failed to parse export %s: %w", path, err)
+	}
+	return dump, nil
+}
+
+// topAddresses is the set of addresses in the top percentage of dump.
+func topAddresses(dump leaderboardDump, percentage float64) map[string]bool {
+	cutoff := rankForPercentage(dump.TotalUsers, percentage)
+	top := make(map[string]bool)
+	for _, user := range dump.Users {
+		if user.Rank <= cutoff {
+			top[strings.ToLower(user.Address)] = true
+		}
+	}
+	return top
+}
+
+// buildWeeklyReport compares the first and last snapshots of season updated
+// between from and to, and the two exports if given.
+func buildWeeklyReport(season int, snapshots []Snapshot, from, to time.Time, before, after *leaderboardDump) weeklyReport {
+	report := weeklyReport{Season: season, From: from, To: to}
+
+	var window []Snapshot
+	for _, snapshot := range snapshotsSince(snapshots, from) {
+		if !time.Unix(snapshot.LastUpdated, 0).After(to) {
+			window = append(window, snapshot)
+		}
+	}
+	if len(window) < 2 {
+		report.Notes = append(report.Notes, fmt.Sprintf("Cutoffs and wallet growth are left out: the history has %d snapshots in this window, and comparing needs 2.", len(window)))
+	} else {
+		start, end := window[0], window[len(window)-1]
+		report.Start, report.End = time.Unix(start.LastUpdated, 0), time.Unix(end.LastUpdated, 0)
+		report.UsersFrom, report.UsersTo = start.TotalUsers, end.TotalUsers
+		for _, first := range start.Results {
+			for _, last := range end.Results {
+				if first.Percentage == last.Percentage && !first.Skipped && !last.Skipped {
+					report.Tiers = append(report.Tiers, tierChange{Percentage: first.Percentage, From: first.Points, To: last.Points})
+				}
+			}
+		}
+		for i, tier := range report.Tiers {
+			if report.Fastest == nil || math.Abs(tier.Relative()) > math.Abs(report.Fastest.Relative()) {
+				report.Fastest = &report.Tiers[i]
+			}
+		}
+		if len(report.Tiers) == 0 {
+			report.Notes = append(report.Notes, "Cutoffs are left out: the first and last snapshots share no computed percentages.")
+		}
+	}
+
+	if before == nil || after == nil {
+		report.Notes = append(report.Notes, "Churn is left out: pass -before and -after exports to count wallets entering and leaving the top tiers.")
+		return report
+	}
+	churn, notes := churnBetween(*before, *after, churnPercentages)
+	report.Churn = churn
+	report.Notes = append(report.Notes, notes...)
+	return report
+}
+
+func formatRelative(f float64) string {
+	return localizeDecimal(fmt.Sprintf("%+.1f%%", f*100))
+}
+
+func reportDate(t time.Time) string {
+	return t.UTC().Format("Jan 2 2006")
+}
+
+func writeReportMarkdown(w io.Writer, r weeklyReport) error {
+	fmt.Fprintf(w, "# Taiko season %d weekly report: %s – %s\n", r.Season, reportDate(r.From), reportDate(r.To))
+	if len(r.Tiers) > 0 {
+		fmt.Fprintf(w, "\n## Cutoffs\n\nFrom the leaderboard of %s to that of %s.\n\n", reportDate(r.Start), reportDate(r.End))
+		fmt.Fprintln(w, "| Top | Start | End | Change |")
+		fmt.Fprintln(w, "| ---: | ---: | ---: | ---: |")
+		for _, tier := range r.Tiers {
+			fmt.Fprintf(w, "| %s | %s | %s | %+d (%s) |\n", formatPercentage(tier.Percentage), formatThousands(tier.From),
+				formatThousands(tier.To), tier.Delta(), formatRelative(tier.Relative()))
+		}
+		fmt.Fprintf(w, "\nFastest-moving tier: top %s, %s.\n", formatPercentage(r.Fastest.Percentage), formatRelative(r.Fastest.Relative()))
+	}
+	if r.UsersTo > 0 {
+		fmt.Fprintf(w, "\n## Wallets\n\n%s → %s (%+d, %s)\n", formatThousands(r.UsersFrom), formatThousands(r.UsersTo),
+			r.UserDelta(), formatRelative(r.UserGrowth()))
+	}
+	if len(r.Churn) > 0 {
+		fmt.Fprintln(w, "\n## Churn\n\n| Top | Entered | Left |\n| ---: | ---: | ---: |")
+		for _, row := range r.Churn {
+			fmt.Fprintf(w, "| %s | %d | %d |\n", formatPercentage(row.Percentage), row.Entered, row.Exited)
+		}
+	}
+	if len(r.Notes) > 0 {
+		fmt.Fprintln(w, "\n## Notes")
+		fmt.Fprintln(w)
+		for _, note := range r.Notes {
+			fmt.Fprintf(w, "- %s\n", note)
+		}
+	}
+	return nil
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date":       reportDate,
+	"percentage": formatPercentage,
+	"thousands":  formatThousands,
+	"relative":   formatRelative,
+	"signed":     func(n int) string { return fmt.Sprintf("%+d", n) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Taiko season {{.Season}} weekly report</title></head>
+<body>
+<h1>Taiko season {{.Season}} weekly report: {{date .From}} – {{date .To}}</h1>
+{{- if .Tiers}}
+<h2>Cutoffs</h2>
+<p>From the leaderboard of {{date .Start}} to that of {{date .End}}.</p>
+<table>
+<tr><th>Top</th><th>Start</th><th>End</th><th>Change</th></tr>
+{{- range .Tiers}}
+<tr><td>{{percentage .Percentage}}</td><td>{{thousands .From}}</td><td>{{thousands .To}}</td><td>{{signed .Delta}} ({{relative .Relative}})</td></tr>
+{{- end}}
+</table>
+<p>Fastest-moving tier: top {{percentage .Fastest.Percentage}}, {{relative .Fastest.Relative}}.</p>
+{{- end}}
+{{- if .UsersTo}}
+<h2>Wallets</h2>
+<p>{{thousands .UsersFrom}} → {{thousands .UsersTo}} ({{signed .UserDelta}}, {{relative .UserGrowth}})</p>
+{{- end}}
+{{- if .Churn}}
+<h2>Churn</h2>
+<table>
+<tr><th>Top</th><th>Entered</th><th>Left</th></tr>
+{{- range .Churn}}
+<tr><td>{{percentage .Percentage}}</td><td>{{.Entered}}</td><td>{{.Exited}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Notes}}
+<h2>Notes</h2>
+<ul>
+{{- range .Notes}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
+
+func runReport(config Config, args []string) error {
+	if len(args) == 0 || args[0] != "weekly" {
+		return errors.New("usage: report weekly [-from time] [-to time] [-format md|html] [-before export -after export [-allow-partial]]")
+	}
+	flags := newCommandFlags("report weekly")
+	fromFlag := flags.String("from", "", "start of the report, as a duration back from now or an RFC3339 time or date (default 7 days before -to)")
+	toFlag := flags.String("to", "", "end of the report, like -from (default now)")
+	format := flags.String("format", "md", "md or html")
+	beforePath := flags.String("before", "", "JSON export (export -format json or -dump-json) from the start of the week, for churn")
+	afterPath := flags.String("after", "", "JSON export from the end of the week, for churn")
+	allowPartial := flags.Bool("allow-partial", false, "compare -before and -after exports even if they cover different prefixes of the board")
+	if err := parseCommandFlags(flags, args[1:], "season"); err != nil {
+		return err
+	}
+	if *format != "md" && *format != "html" {
+		return fmt.Errorf("report weekly: unknown -format %q, want md or html", *format)
+	}
+	if (*beforePath == "") != (*afterPath == "") {
+		return errors.New("report weekly: -before and -after go together")
+	}
+
+	now := time.Now()
+	to := now
+	if *toFlag != "" {
+		var err error
+		if to, err = parseSince("-to", *toFlag, now); err != nil {
+			return err
+		}
+	}
+	from := to.Add(-7 * 24 * time.Hour)
+	if *fromFlag != "" {
+		var err error
+		if from, err = parseSince("-from", *fromFlag, now); err != nil {
+			return err
+		}
+	}
+	if !from.Before(to) {
+		return errors.New("report weekly: -from must be before -to")
+	}
+
+	var before, after *leaderboardDump
+	if *beforePath != "" {
+		b, err := readDump(*beforePath)
+		if err != nil {
+			return err
+		}
+		a, err := readDump(*afterPath)
+		if err != nil {
+			return err
+		}
+		if err := checkCoverage(b, a, *allowPartial); err != nil {
+			return fmt.Errorf("report weekly: %w", err)
+		}
+		before, after = &b, &a
+	}
+
+	store, err := openSnapshotStore(config)
+	if err != nil {
+		return err
+	}
+	snapshots, err := store.List(seasons[0])
+	if err != nil {
+		return err
+	}
+	report := buildWeeklyReport(seasons[0], snapshots, from, to, before, after)
+	if *format == "html" {
+		return reportHTML.Execute(stdout, report)
+	}
+	return writeReportMarkdown(stdout, report)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func reportSnapshot(lastUpdated int64, totalUsers, top1, top10 int) Snapshot {
+	return Snapshot{Season: 2, LastUpdated: lastUpdated, TotalUsers: totalUsers, Results: []Result{
+		{Season: 2, Percentage: 0.01, Points: top1},
+		{Season: 2, Percentage: 0.1, Points: top10},
+	}}
+}
+
+// reportDump is a board of total users where the users listed by address
+// hold ranks 1, 2, ...
+func reportDump(total int, addresses ...string) *leaderboardDump {
+	dump := &leaderboardDump{TotalUsers: total}
+	for i, address := range addresses {
+		dump.Users = append(dump.Users, User{Rank: i + 1, Address: address})
+	}
+	return dump
+}
+
+func TestWeeklyReport(t *testing.T) {
+	from := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	to := from.Add(7 * 24 * time.Hour)
+	snapshots := []Snapshot{
+		reportSnapshot(from.Add(-time.Hour).Unix(), 900, 1, 1),
+		reportSnapshot(from.Add(time.Hour).Unix(), 1000, 2000, 500),
+		reportSnapshot(from.Add(72*time.Hour).Unix(), 1100, 2100, 520),
+		reportSnapshot(to.Add(-time.Hour).Unix(), 1200, 2200, 600),
+		reportSnapshot(to.Add(time.Hour).Unix(), 1300, 9999, 9999),
+	}
+	var addresses []string
+	for i := range 200 {
+		addresses = append(addresses, fmt.Sprintf("0x%040x", i))
+	}
+	// The wallets at ranks 11-15 climb into the top 1% (top 10 of 1,000),
+	// pushing 5 out of it; the top 10% (top 100) keeps the same wallets.
+	after := append(append(append([]string{}, addresses[10:15]...), addresses[:10]...), addresses[15:]...)
+
+	report := buildWeeklyReport(2, snapshots, from, to, reportDump(1000, addresses...), reportDump(1000, after...))
+	var out bytes.Buffer
+	if err := writeReportMarkdown(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"# Taiko season 2 weekly report: Jun 3 2024 – Jun 10 2024",
+		"| 1% | 2,000 | 2,200 | +200 (+10.0%) |",
+		"| 10% | 500 | 600 | +100 (+20.0%) |",
+		"Fastest-moving tier: top 10%, +20.0%.",
+		"1,000 → 1,200 (+200, +20.0%)",
+		"| 1% | 5 | 5 |",
+		"| 10% | 0 | 0 |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "## Notes") {
+		t.Errorf("a complete report has no notes:\n%s", got)
+	}
+
+	out.Reset()
+	if err := reportHTML.Execute(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "<tr><td>10%</td><td>500</td><td>600</td>") {
+		t.Errorf("HTML report is missing the 10%% row:\n%s", out.String())
+	}
+}
+
+func TestWeeklyReportOmitsMissingSections(t *testing.T) {
+	from := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	report := buildWeeklyReport(2, []Snapshot{reportSnapshot(from.Add(time.Hour).Unix(), 1000, 2000, 500)},
+		from, from.Add(7*24*time.Hour), nil, nil)
+
+	var out bytes.Buffer
+	if err := writeReportMarkdown(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, section := range []string{"## Cutoffs", "## Wallets", "## Churn"} {
+		if strings.Contains(got, section) {
+			t.Errorf("section %q should be left out:\n%s", section, got)
+		}
+	}
+	if !strings.Contains(got, "the history has 1 snapshots in this window") || !strings.Contains(got, "Churn is left out") {
+		t.Errorf("want notes on the missing sections:\n%s", got)
+	}
+
+	out.Reset()
+	if err := reportHTML.Execute(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "<table>") {
+		t.Errorf("HTML report without data has tables:\n%s", out.String())
+	}
+}
+
+func TestReadDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	var out bytes.Buffer
+	board := Response{LastUpdated: 1718000000}
+	board.Data.Total = 2
+	writeDump(&out, board, dumpCoverage{}, []User{{Rank: 1, Address: "0xa"}, {Rank: 2, Address: "0xb"}}, true)
+	os.WriteFile(path, out.Bytes(), 0o644)
+
+	dump, err := readDump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.TotalUsers != 2 || len(dump.Users) != 2 || dump.Users[1].Address != "0xb" {
+		t.Errorf("dump = %+v", dump)
+	}
+}
+
+func TestWeeklyReportPartialExports(t *testing.T) {
+	from := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	before, after := reportDump(1000, "0xa", "0xb", "0xc"), reportDump(1000, "0xb", "0xd", "0xa")
+	before.TopRanks, after.TopRanks = 20, 20
+	report := buildWeeklyReport(2, nil, from, from.Add(7*24*time.Hour), before, after)
+	if len(report.Churn) != 1 || report.Churn[0].Percentage != 0.01 || report.Churn[0].Entered != 1 {
+		t.Errorf("churn = %+v, want only the top 1%% the exports cover", report.Churn)
+	}
+	if note := strings.Join(report.Notes, " "); !strings.Contains(note, "Churn of the top 10% is left out: the exports hold the top 20 ranks") {
+		t.Errorf("notes = %q", report.Notes)
+	}
+
+	dir := t.TempDir()
+	write := func(name string, coverage dumpCoverage) string {
+		var out bytes.Buffer
+		board := Response{LastUpdated: 1718000000}
+		board.Data.Total = 1000
+		writeDump(&out, board, coverage, []User{{Rank: 1, Address: "0xa"}}, false)
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, out.Bytes(), 0o644)
+		return path
+	}
+	full, top := write("full.json", dumpCoverage{}), write("top.json", dumpCoverage{TopPercent: 0.02, TopRanks: 20})
+	err := runReport(Config{}, []string{"weekly", "-before", full, "-after", top})
+	if err == nil || !strings.Contains(err.Error(), "the first export holds the whole board but the second the top 2% (20 ranks)") {
+		t.Errorf("mismatched exports: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+)
+
+// scanPoint is the score at one scanned rank.
+type scanPoint struct {
+	Rank       int     `json:"rank"`
+	TotalScore float64 `json:"totalScore"`
+}
+
+// scanRanks is every step-th rank from from to to, always ending with to.
+func scanRanks(from, to, step int) []int {
+	var ranks []int
+	for rank := from; rank <= to; rank += step {
+		ranks = append(ranks, rank)
+	}
+	if ranks[len(ranks)-1] != to {
+		ranks = append(ranks, to)
+	}
+	return ranks
+}
+
+// planScan picks the page size that fetches ranks in the fewest requests,
+// preferring the smaller page on a tie, and fits it into budget like
+// planPages.
+func planScan(ranks []int, budget int) pagePlan {
+	best := defaultClient.PageSize
+	for _, size := range budgetPageSizes {
+		if pagesFor(ranks, size) < pagesFor(ranks, best) {
+			best = size
+		}
+	}
+	return planPages(ranks, best, budget)
+}
+
+// scan fetches the score at each rank the plan doesn't skip, in rank order
+// so that every page is fetched once. On an error it returns the points so
+// far.
+func scan(ctx context.Context, season int, ranks []int, plan pagePlan) ([]scanPoint, error) {
+	pages := &pageMemo{}
+	var points []scanPoint
+	for i, rank := range ranks {
+		if plan.skip[i] {
+			continue
+		}
+		user, err := defaultClient.userAtRankPaged(ctx, season, rank, plan.size, pages)
+		if err != nil {
+			return points, fmt.Errorf("failed to scan rank %d: %w", rank, err)
+		}
+		points = append(points, scanPoint{Rank: rank, TotalScore: user.TotalScore})
+	}
+	return points, nil
+}
+
+func writeScan(out io.Writer, format string, points []scanPoint) error {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(points)
+	}
+	w := csv.NewWriter(out)
+	if decimalSep == "," {
+		w.Comma = ';'
+	}
+	w.Write([]string{"rank", "total_score"})
+	for _, point := range points {
+		w.Write([]string{strconv.Itoa(point.Rank), formatFloat(point.TotalScore, -1)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func runScan(args []string) error {
+	flags := newCommandFlags("scan")
+	from := flags.Int("from-rank", 0, "first rank to scan")
+	to := flags.Int("to-rank", 0, "last rank to scan")
+	step := flags.Int("step", 100, "scan every this many ranks")
+	format := flags.String("format", "csv", "csv or json")
+	maxPoints := flags.Int("max-points", 500, "ask for -yes before scanning more ranks than this")
+	yes := flags.Bool("yes", false, "scan past -max-points")
+	if err := parseCommandFlags(flags, args, "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+	if *from < 1 || *to < *from {
+		return errors.New("scan: want 1 <= -from-rank <= -to-rank")
+	}
+	if *step < 1 {
+		return errors.New("scan: -step must be at least 1")
+	}
+	if !slices.Contains([]string{"csv", "json"}, *format) {
+		return fmt.Errorf("scan: unknown -format %q, want csv or json", *format)
+	}
+
+	season := seasons[0]
+	defaultClient.calibratePages(season)
+	board, err := defaultClient.FetchBoard(context.Background(), season)
+	if err != nil {
+		return err
+	}
+	if *from > board.Data.Total {
+		return fmt.Errorf("scan: -from-rank %d is past the last rank %d", *from, board.Data.Total)
+	}
+	if *to > board.Data.Total {
+		log.Printf("Season %d has %d users; scanning up to rank %d", season, board.Data.Total, board.Data.Total)
+		*to = board.Data.Total
+	}
+
+	ranks := scanRanks(*from, *to, *step)
+	plan := planScan(ranks, defaultClient.requestsLeft(1))
+	if len(ranks) > *maxPoints && !*yes {
+		return fmt.Errorf("scan: %s ranks would take an estimated %s requests with pages of %d; pass -yes to go ahead",
+			formatThousands(len(ranks)), formatThousands(plan.requests), plan.size)
+	}
+	plan.log(season)
+
+	for _, skip := range plan.skip {
+		if !skip {
+			defaultClient.progress.AddTotal(1)
+		}
+	}
+	points, err := scan(context.Background(), season, ranks, plan)
+	defaultClient.progress.Finish()
+	if writeErr := writeScan(stdout, *format, points); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScanRanks(t *testing.T) {
+	if got, want := scanRanks(10000, 10250, 100), []int{10000, 10100, 10200, 10250}; !slices.Equal(got, want) {
+		t.Errorf("scanRanks = %v, want %v", got, want)
+	}
+	if got := scanRanks(5, 5, 100); !slices.Equal(got, []int{5}) {
+		t.Errorf("scanRanks of one rank = %v", got)
+	}
+}
+
+func TestPlanScan(t *testing.T) {
+	setPageSize(t, 1)
+	// Ranks 100 apart never share a page, so small pages are as good.
+	if plan := planScan(scanRanks(10000, 20000, 100), -1); plan.size != 1 || plan.requests != 101 {
+		t.Errorf("step 100: pages of %d in %d requests, want pages of 1 in 101", plan.size, plan.requests)
+	}
+	// Ranks 5 apart share pages of 100.
+	if plan := planScan(scanRanks(1, 1000, 5), -1); plan.size != dumpPageSize || plan.requests != 10 {
+		t.Errorf("step 5: pages of %d in %d requests, want pages of %d in 10", plan.size, plan.requests, dumpPageSize)
+	}
+	plan := planScan(scanRanks(1, 1000, 5), 4)
+	if plan.requests != 4 || !plan.skip[len(plan.skip)-1] {
+		t.Errorf("a budget of 4 should skip the ranks past the 4th page: %+v", plan)
+	}
+}
+
+func TestScan(t *testing.T) {
+	requests := 0
+	useFakeUpstream(t, countRequests(&requests, rankedUpstream(1000)))
+	setPageSize(t, 1)
+
+	ranks := scanRanks(1, 1000, 50)
+	points, err := scan(context.Background(), defaultSeason, ranks, planScan(ranks, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 21 || points[1] != (scanPoint{Rank: 51, TotalScore: 9500}) || points[20].Rank != 1000 {
+		t.Fatalf("points = %+v", points)
+	}
+	if requests != 10 {
+		t.Errorf("made %d requests, want one per page of %d", requests, dumpPageSize)
+	}
+
+	var out bytes.Buffer
+	if err := writeScan(&out, "csv", points[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "rank,total_score\n1,10000\n51,9500\n"; out.String() != want {
+		t.Errorf("csv = %q, want %q", out.String(), want)
+	}
+}
+
+func TestScanNeedsConfirmation(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	err := runScan([]string{"-from-rank", "1", "-to-rank", "1000", "-step", "1"})
+	if err == nil || !strings.Contains(err.Error(), "1,000 ranks would take an estimated 10 requests with pages of 100; pass -yes") {
+		t.Errorf("err = %v, want a request estimate asking for -yes", err)
+	}
+}
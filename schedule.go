@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// watchClock is what the watch scheduler tells time and waits with, so
+// tests can run it without sleeping.
+type watchClock interface {
+	// Now is the wall-clock time.
+	Now() time.Time
+	// Monotonic is a reading of a clock that only moves forward at a
+	// steady rate, whatever NTP does to the wall clock. On Linux it
+	// stands still while the host is suspended.
+	Monotonic() time.Duration
+	// Sleep waits for d of monotonic time, or until ctx is done.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// processStart anchors realClock's monotonic readings.
+var processStart = time.Now()
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Monotonic() time.Duration { return time.Since(processStart) }
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error { return sleepCtx(ctx, d) }
+
+// watchScheduler paces watch cycles every interval on the monotonic clock,
+// counting from when each cycle was due rather than when the previous one
+// finished, so the cadence doesn't drift by the time the cycles take. With
+// align, cycles are due on the wall-clock multiples of interval instead,
+// e.g. on the hour for -watch 1h, for tidy history timestamps.
+//
+// A cycle that starts an interval or more late, because the host was
+// suspended or a cycle overran, is run once to catch up, and the cadence
+// resumes from it instead of running every cycle missed in between.
+type watchScheduler struct {
+	clock    watchClock
+	interval time.Duration
+	align    bool
+
+	started bool
+	// due is the monotonic reading the next cycle is due at, and boundary
+	// the wall-clock time it was aligned to.
+	due      time.Duration
+	boundary time.Time
+}
+
+func newWatchScheduler(clock watchClock, interval time.Duration, align bool) *watchScheduler {
+	return &watchScheduler{clock: clock, interval: interval, align: align}
+}
+
+// upcoming is the wall-clock time the next cycle is due at.
+func (s *watchScheduler) upcoming() time.Time {
+	now := s.clock.Now()
+	if s.align {
+		return now.Round(0).Truncate(s.interval).Add(s.interval)
+	}
+	if !s.started {
+		return now
+	}
+	return now.Add(s.due + s.interval - s.clock.Monotonic())
+}
+
+// wait returns when the next cycle is due; the first one is due at once.
+func (s *watchScheduler) wait(ctx context.Context) error {
+	if !s.started {
+		s.started = true
+		s.due = s.clock.Monotonic()
+		return nil
+	}
+
+	wallBefore, monoBefore := s.clock.Now(), s.clock.Monotonic()
+	if s.align {
+		// Never the same boundary twice, should the wall clock lag the
+		// monotonic one we slept on.
+		boundary := s.upcoming()
+		if !boundary.After(s.boundary) {
+			boundary = s.boundary.Add(s.interval)
+		}
+		s.boundary = boundary
+		s.due = monoBefore + boundary.Sub(wallBefore.Round(0))
+	} else {
+		s.due += s.interval
+	}
+	wait := s.due - monoBefore
+	if wait > 0 {
+		if err := s.clock.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+
+	// The wall clock covers a suspend the monotonic clock skips; the
+	// monotonic one covers overruns and hosts whose wall clock jumped
+	// back.
+	monoNow := s.clock.Monotonic()
+	wallLate := s.clock.Now().Round(0).Sub(wallBefore.Round(0)) - max(wait, 0)
+	late := max(monoNow-s.due, wallLate)
+	if late >= s.interval {
+		log.Printf("Watch: the refresh due %s ago was missed, likely because the host was suspended; refreshing once to catch up and then every %s",
+			late.Round(time.Second), s.interval)
+		s.due = monoNow
+	}
+	return nil
+}
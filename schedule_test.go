@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a watchClock whose sleeps return at once, advancing both
+// clocks by what was asked.
+type fakeClock struct {
+	wall time.Time
+	mono time.Duration
+	// slept is every duration Sleep was asked for.
+	slept []time.Duration
+	// midSleep, if set, runs halfway through the next sleep, e.g. to
+	// suspend the host there.
+	midSleep func()
+}
+
+func (c *fakeClock) Now() time.Time { return c.wall }
+
+func (c *fakeClock) Monotonic() time.Duration { return c.mono }
+
+func (c *fakeClock) Sleep(_ context.Context, d time.Duration) error {
+	c.slept = append(c.slept, d)
+	if hook := c.midSleep; hook != nil {
+		c.midSleep = nil
+		c.advance(d / 2)
+		hook()
+		d -= d / 2
+	}
+	c.advance(d)
+	return nil
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.wall = c.wall.Add(d)
+	c.mono += d
+}
+
+// cycleTimes runs cycles watch cycles of s, each taking work, and returns
+// when each started.
+func cycleTimes(t *testing.T, clock *fakeClock, s *watchScheduler, cycles int, work time.Duration) []string {
+	t.Helper()
+	var starts []string
+	for range cycles {
+		if err := s.wait(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+		starts = append(starts, clock.wall.Format("15:04:05"))
+		clock.advance(work)
+	}
+	return starts
+}
+
+func TestWatchSchedulerKeepsCadence(t *testing.T) {
+	clock := &fakeClock{wall: at(t, 10, "10:03").Add(20 * time.Second)}
+	s := newWatchScheduler(clock, 15*time.Minute, false)
+
+	got := cycleTimes(t, clock, s, 4, 40*time.Second)
+	want := []string{"10:03:20", "10:18:20", "10:33:20", "10:48:20"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("cycles at %v, want %v without the 40s each cycle takes adding up", got, want)
+	}
+	if next := s.upcoming().Format("15:04:05"); next != "11:03:20" {
+		t.Errorf("upcoming = %s, want 11:03:20", next)
+	}
+}
+
+func TestWatchSchedulerCatchesUpAfterSuspend(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	clock := &fakeClock{wall: at(t, 10, "10:00")}
+	s := newWatchScheduler(clock, 15*time.Minute, false)
+	cycleTimes(t, clock, s, 2, 0)
+
+	// Suspended for 2 hours while waiting for 10:30.
+	clock.midSleep = func() { clock.wall = clock.wall.Add(2 * time.Hour) }
+	got := cycleTimes(t, clock, s, 3, 0)
+	want := []string{"12:30:00", "12:45:00", "13:00:00"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("cycles at %v, want one catch-up then every 15m from it", got)
+	}
+	if strings.Count(logs.String(), "was missed") != 1 || !strings.Contains(logs.String(), "due 2h0m0s ago") {
+		t.Errorf("logs = %q, want the missed refresh noted once", logs.String())
+	}
+}
+
+func TestWatchSchedulerOverrun(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	clock := &fakeClock{wall: at(t, 10, "10:00")}
+	s := newWatchScheduler(clock, time.Minute, false)
+	s.wait(t.Context())
+	clock.advance(3 * time.Minute)
+	s.wait(t.Context())
+	if clock.wall.Format("15:04") != "10:03" || len(clock.slept) != 0 {
+		t.Fatalf("at %s after sleeping %v, want to refresh right after the overrun", clock.wall.Format("15:04"), clock.slept)
+	}
+	s.wait(t.Context())
+	if clock.wall.Format("15:04") != "10:04" {
+		t.Errorf("at %s, want a minute after the catch-up", clock.wall.Format("15:04"))
+	}
+}
+
+func TestWatchSchedulerAlign(t *testing.T) {
+	clock := &fakeClock{wall: at(t, 10, "10:03").Add(20 * time.Second)}
+	s := newWatchScheduler(clock, 15*time.Minute, true)
+
+	got := cycleTimes(t, clock, s, 4, 40*time.Second)
+	want := []string{"10:03:20", "10:15:00", "10:30:00", "10:45:00"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("cycles at %v, want %v", got, want)
+	}
+	if next := s.upcoming().Format("15:04:05"); next != "11:00:00" {
+		t.Errorf("upcoming = %s, want 11:00:00", next)
+	}
+
+	// A wall clock that NTP stepped back still doesn't run a boundary twice.
+	clock.wall = clock.wall.Add(-time.Minute)
+	s.wait(t.Context())
+	if got := clock.wall.Format("15:04:05"); got != "11:00:00" {
+		t.Errorf("after stepping back, cycle at %s, want 11:00:00", got)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// responseSchemaVersion numbers responseSchema. Bump it with every change to
+// the expected shape, so that a shape change upstream is a deliberate update
+// here rather than something decoded into wrong numbers.
+const responseSchemaVersion = 1
+
+// responseSchema lists the fields of each object in a leaderboard response
+// by path, with "[]" for the elements of an array. Every field is required,
+// and any other field is unknown.
+var responseSchema = map[string][]string{
+	"":             {"data", "lastUpdated"},
+	"data":         {"items", "page", "size", "total", "total_pages"},
+	"data.items[]": {"rank", "address", "score", "multiplier", "totalScore"},
+}
+
+var (
+	// schemaViolations counts the violations let through without
+	// -strict-schema, for /metrics.
+	schemaViolations atomic.Int64
+)
+
+// schemaViolation is one way a response differs from responseSchema.
+type schemaViolation struct {
+	Path    string
+	Problem string
+}
+
+func (v schemaViolation) String() string {
+	return v.Path + ": " + v.Problem
+}
+
+// checkShape compares the decoded JSON value at path with the schema entry
+// key, reporting missing and unknown fields and values of the wrong kind.
+func checkShape(path, key string, value any) []schemaViolation {
+	fields, ok := responseSchema[key]
+	if !ok {
+		return nil
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return []schemaViolation{{displayPath(path), fmt.Sprintf("want an object, got %s", jsonKind(value))}}
+	}
+
+	var violations []schemaViolation
+	for _, field := range fields {
+		fieldPath, fieldKey := joinPath(path, field), joinPath(key, field)
+		child, present := object[field]
+		if !present {
+			violations = append(violations, schemaViolation{fieldPath, "missing"})
+			continue
+		}
+		if _, isObject := responseSchema[fieldKey]; isObject {
+			violations = append(violations, checkShape(fieldPath, fieldKey, child)...)
+		}
+		if _, isArray := responseSchema[fieldKey+"[]"]; isArray {
+			elements, ok := child.([]any)
+			if !ok {
+				violations = append(violations, schemaViolation{fieldPath, fmt.Sprintf("want an array, got %s", jsonKind(child))})
+				continue
+			}
+			for i, element := range elements {
+				violations = append(violations, checkShape(fmt.Sprintf("%s[%d]", fieldPath, i), fieldKey+"[]", element)...)
+			}
+		}
+	}
+	var unknown []string
+	for field := range object {
+		if !slices.Contains(fields, field) {
+			unknown = append(unknown, field)
+		}
+	}
+	slices.Sort(unknown)
+	for _, field := range unknown {
+		violations = append(violations, schemaViolation{joinPath(path, field), "unknown field"})
+	}
+	return violations
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+func jsonKind(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// checkUser checks the values of one leaderboard row.
+func (c *Client) checkUser(path string, user User) []schemaViolation {
+	var violations []schemaViolation
+	if user.Rank < c.RankBase {
+		violations = append(violations, schemaViolation{path + ".rank", fmt.Sprintf("must be at least %d, got %d", c.RankBase, user.Rank)})
+	}
+	if user.Score < 0 {
+		violations = append(violations, schemaViolation{path + ".score", fmt.Sprintf("must not be negative, got %v", user.Score)})
+	}
+	if user.TotalScore < 0 {
+		violations = append(violations, schemaViolation{path + ".totalScore", fmt.Sprintf("must not be negative, got %v", user.TotalScore)})
+	}
+	return violations
+}
+
+// checkValues checks that the values of a decoded response make sense
+// together: a page holds at most size users, all within total, ranked one
+// after another.
+func (c *Client) checkValues(response Response) []schemaViolation {
+	data := response.Data
+	var violations []schemaViolation
+	if data.Page < 0 {
+		violations = append(violations, schemaViolation{"data.page", fmt.Sprintf("must not be negative, got %d", data.Page)})
+	}
+	if data.Size < 0 {
+		violations = append(violations, schemaViolation{"data.size", fmt.Sprintf("must not be negative, got %d", data.Size)})
+	}
+	if data.Total < 0 {
+		violations = append(violations, schemaViolation{"data.total", fmt.Sprintf("must not be negative, got %d", data.Total)})
+	}
+	if len(data.Users) > data.Size {
+		violations = append(violations, schemaViolation{"data.items", fmt.Sprintf("%d items on a page of size %d", len(data.Users), data.Size)})
+	}
+	if len(data.Users) > data.Total {
+		violations = append(violations, schemaViolation{"data.items", fmt.Sprintf("%d items of a total of %d", len(data.Users), data.Total)})
+	}
+	for i, user := range data.Users {
+		path := fmt.Sprintf("data.items[%d]", i)
+		violations = append(violations, c.checkUser(path, user)...)
+		if last := data.Total - 1 + c.RankBase; user.Rank > last {
+			violations = append(violations, schemaViolation{path + ".rank", fmt.Sprintf("%d is past the last rank %d of a total of %d", user.Rank, last, data.Total)})
+		}
+		if i > 0 && user.Rank != data.Users[i-1].Rank+1 {
+			violations = append(violations, schemaViolation{path + ".rank", fmt.Sprintf("%d follows rank %d", user.Rank, data.Users[i-1].Rank)})
+		}
+	}
+	return violations
+}
+
+// reportSchemaViolations fails with the violations under -strict-schema,
+// and otherwise logs them as warnings and counts them.
+func (c *Client) reportSchemaViolations(violations []schemaViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	if c.StrictSchema {
+		lines := make([]string, len(violations))
+		for i, v := range violations {
+			lines[i] = v.String()
+		}
+		return fmt.Errorf("%w v%d: %s", ErrSchemaViolation, responseSchemaVersion, strings.Join(lines, "; "))
+	}
+	schemaViolations.Add(int64(len(violations)))
+	for _, v := range violations {
+		log.Printf("Warning: response doesn't match schema v%d at %s", responseSchemaVersion, v)
+	}
+	return nil
+}
+
+// schemaCheck reports keys of responses that don't map to any field of
+// Response with -schema-check; nil without it.
+var schemaCheck *unmappedKeys
+
+// unmappedKeys logs each unmapped key the first time a response has it.
+type unmappedKeys struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newUnmappedKeys() *unmappedKeys {
+	return &unmappedKeys{seen: make(map[string]bool)}
+}
+
+func (u *unmappedKeys) check(generic any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, path := range unmappedFields("", generic, reflect.TypeFor[Response]()) {
+		if !u.seen[path] {
+			u.seen[path] = true
+			log.Printf("Schema check: the response has %s, which isn't mapped to any field", path)
+		}
+	}
+}
+
+// unmappedFields lists the paths of the object keys in value that
+// encoding/json wouldn't decode into a field of typ, with "[]" for array
+// elements.
+func unmappedFields(path string, value any, typ reflect.Type) []string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		if typ.Kind() != reflect.Struct {
+			return nil
+		}
+		var unmapped []string
+		for key, child := range v {
+			field, ok := jsonField(typ, key)
+			if !ok {
+				unmapped = append(unmapped, joinPath(path, key))
+				continue
+			}
+			unmapped = append(unmapped, unmappedFields(joinPath(path, key), child, field.Type)...)
+		}
+		slices.Sort(unmapped)
+		return unmapped
+	case []any:
+		if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+			return nil
+		}
+		var unmapped []string
+		for _, element := range v {
+			unmapped = append(unmapped, unmappedFields(path+"[]", element, typ.Elem())...)
+		}
+		slices.Sort(unmapped)
+		return slices.Compact(unmapped)
+	}
+	return nil
+}
+
+// jsonField is the field of the struct type typ that encoding/json decodes
+// key into: by its tag or name, preferring an exact match to a
+// case-insensitive one.
+func jsonField(typ reflect.Type, key string) (reflect.StructField, bool) {
+	var folded reflect.StructField
+	found := false
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if name == key {
+			return field, true
+		}
+		if !found && strings.EqualFold(name, key) {
+			folded, found = field, true
+		}
+	}
+	return folded, found
+}
+
+// parseJSONResponse decodes a leaderboard response and checks it against
+// responseSchema; see reportSchemaViolations.
+func (c *Client) parseJSONResponse(body io.Reader, response *Response) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	var violations []schemaViolation
+	var generic any
+	if json.Unmarshal(data, &generic) == nil {
+		if schemaCheck != nil {
+			schemaCheck.check(generic)
+		}
+		violations = checkShape("", "", generic)
+		if c.StrictSchema && len(violations) > 0 {
+			return c.reportSchemaViolations(violations)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if c.StrictSchema {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(response); err != nil {
+		return err
+	}
+	return c.reportSchemaViolations(append(violations, c.checkValues(*response)...))
+}
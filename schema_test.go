@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func useStrictSchema(t *testing.T, enabled bool) {
+	t.Helper()
+	old := defaultClient.StrictSchema
+	defaultClient.StrictSchema = enabled
+	t.Cleanup(func() { defaultClient.StrictSchema = old })
+}
+
+func TestSchemaAcceptsFixtures(t *testing.T) {
+	useStrictSchema(t, true)
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		name := filepath.Base(fixture)
+		// no_rank is deliberately malformed for page detection, and
+		// criteria.json isn't a response.
+		if strings.Contains(name, "no_rank") || name == "criteria.json" {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var response Response
+			if err := defaultClient.parseJSONResponse(bytes.NewReader(data), &response); err != nil {
+				t.Errorf("schema v%d rejects %s: %v", responseSchemaVersion, name, err)
+			}
+		})
+	}
+}
+
+func TestSchemaRejectsMutations(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "page2_size3_one_based.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := string(data)
+	if !strings.Contains(fixture, `"rank": 4,`) || !strings.Contains(fixture, `"score": 960`) {
+		t.Fatalf("the fixture changed; update the mutations:\n%s", fixture)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"missing field", `"rank": 4, `, ``, "data.items[0].rank: missing"},
+		{"renamed field", `"totalScore"`, `"total_score"`, "data.items[0].total_score: unknown field"},
+		{"unknown field", `"lastUpdated"`, `"updatedAt": 0, "lastUpdated"`, "updatedAt: unknown field"},
+		{"wrong kind", `"items": [`, `"items": null, "rows": [`, "data.items: want an array, got null"},
+		{"zero rank", `"rank": 4,`, `"rank": 0,`, "data.items[0].rank: must be at least 1, got 0"},
+		{"negative score", `"score": 960`, `"score": -960`, "data.items[0].score: must not be negative, got -960"},
+		{"oversized page", `"size": 3`, `"size": 2`, "data.items: 3 items on a page of size 2"},
+		{"rank past total", `"total": 100`, `"total": 5`, "data.items[2].rank: 6 is past the last rank 5 of a total of 5"},
+		{"rank gap", `"rank": 5,`, `"rank": 7,`, "data.items[1].rank: 7 follows rank 4"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mutated := strings.Replace(fixture, tc.old, tc.new, 1)
+
+			useStrictSchema(t, true)
+			var response Response
+			err := defaultClient.parseJSONResponse(strings.NewReader(mutated), &response)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("strict err = %v, want one naming %q", err, tc.want)
+			}
+
+			defaultClient.StrictSchema = false
+			before := schemaViolations.Load()
+			if err := defaultClient.parseJSONResponse(strings.NewReader(mutated), &response); err != nil {
+				t.Fatalf("without -strict-schema the response should decode with a warning: %v", err)
+			}
+			if schemaViolations.Load() == before {
+				t.Error("the violation wasn't counted")
+			}
+		})
+	}
+}
+
+func TestUnmappedFields(t *testing.T) {
+	var generic any
+	body := `{"data":{"items":[{"rank":1,"Address":"0x1","badge":"gold"},{"rank":2,"badge":"","streak":3}],` +
+		`"page":1,"size":2,"total":2,"total_pages":1,"cursor":"abc"},"lastUpdated":1,"season":{"id":2}}`
+	if err := json.Unmarshal([]byte(body), &generic); err != nil {
+		t.Fatal(err)
+	}
+	got := unmappedFields("", generic, reflect.TypeFor[Response]())
+	want := []string{"data.cursor", "data.items[].badge", "data.items[].streak", "season"}
+	if !slices.Equal(got, want) {
+		t.Errorf("unmapped = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// Where a score falls relative to the leaderboard.
+const (
+	scoreRanked     = "ranked"
+	scoreAboveTop   = "above top"
+	scoreBelowBoard = "below board"
+)
+
+// scoreLookup is where a score from -scores falls on the leaderboard: the
+// last rank with at least that many points, unless nobody has that many or
+// everybody has more.
+type scoreLookup struct {
+	Score      int     `json:"score"`
+	Position   string  `json:"position"`
+	Rank       int     `json:"rank,omitempty"`
+	Points     int     `json:"points,omitempty"`
+	Percentile float64 `json:"percentile,omitempty"`
+	// TopPercentage is the smallest of the computed percentages whose
+	// cutoff Rank makes, and Tier the official tier of Score with
+	// -criteria-file.
+	TopPercentage float64 `json:"topPercentage,omitempty"`
+	Tier          string  `json:"tier,omitempty"`
+}
+
+// parseScores parses the comma-separated -scores list.
+func parseScores(list string) ([]int, error) {
+	var scores []int
+	for _, field := range strings.Split(list, ",") {
+		score, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || score < 0 {
+			return nil, fmt.Errorf("invalid score %q", field)
+		}
+		scores = append(scores, score)
+	}
+	return scores, nil
+}
+
+// scoreProbes are the points seen at each probed rank, shared by the
+// searches of a batch.
+type scoreProbes struct {
+	mu     sync.Mutex
+	points map[int]float64
+}
+
+// bounds is the last probed rank with at least score points and the first
+// with fewer, or 0 and total+1 where none is probed yet.
+func (p *scoreProbes) bounds(score, total int) (lo, hi int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lo, hi = 0, total+1
+	for rank, points := range p.points {
+		if points >= float64(score) {
+			lo = max(lo, rank)
+		} else {
+			hi = min(hi, rank)
+		}
+	}
+	return lo, hi
+}
+
+// probe fetches the points at each of ranks at once, leaving the pacing to
+// the rate limiter and sharing pages through pages.
+func (p *scoreProbes) probe(ctx context.Context, season int, ranks []int, pages *pageMemo) error {
+	errs := make([]error, len(ranks))
+	var wg sync.WaitGroup
+	for i, rank := range ranks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := defaultClient.userAtRankPaged(ctx, season, rank, defaultClient.PageSize, pages)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to get the points at rank %d: %w", rank, err)
+				return
+			}
+			p.mu.Lock()
+			p.points[rank] = user.TotalScore
+			p.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupScores binary-searches season for every score at once. The searches
+// advance in rounds, fetching the midpoints of all of them together, and
+// every probe narrows every search it falls within, so scores close to each
+// other cost little more than one.
+func lookupScores(ctx context.Context, season int, scores []int) ([]scoreLookup, error) {
+	total, err := getTotalWallets(season)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total wallets: %w", err)
+	}
+	if total < 1 {
+		return nil, fmt.Errorf("leaderboard is empty")
+	}
+	probes := &scoreProbes{points: make(map[int]float64)}
+	pages := &pageMemo{}
+	if err := probes.probe(ctx, season, slices.Compact([]int{1, total}), pages); err != nil {
+		return nil, err
+	}
+
+	for round := 0; ; round++ {
+		var mids []int
+		for _, score := range scores {
+			lo, hi := probes.bounds(score, total)
+			if lo > 0 && hi-lo > 1 {
+				mids = append(mids, lo+(hi-lo)/2)
+			}
+		}
+		if len(mids) == 0 {
+			break
+		}
+		if round >= maxProbes {
+			return nil, fmt.Errorf("no boundary found for every score after %d rounds", round)
+		}
+		slices.Sort(mids)
+		if err := probes.probe(ctx, season, slices.Compact(mids), pages); err != nil {
+			return nil, err
+		}
+	}
+
+	lookups := make([]scoreLookup, len(scores))
+	for i, score := range scores {
+		lookup := scoreLookup{Score: score, Position: scoreRanked}
+		lo, _ := probes.bounds(score, total)
+		switch {
+		case lo == 0:
+			lookup.Position = scoreAboveTop
+		case probes.points[total] > float64(score):
+			lookup.Position = scoreBelowBoard
+		default:
+			lookup.Rank, lookup.Points = lo, int(probes.points[lo])
+			lookup.Percentile = topPercentile(lo, total)
+			lookup.TopPercentage = smallestTopPercentage(lo, total)
+			lookup.Tier = tierForPoints(score)
+		}
+		lookups[i] = lookup
+	}
+	return lookups, nil
+}
+
+// smallestTopPercentage is the smallest computed percentage whose cutoff
+// rank is at or after rank, or 0 if rank makes none of them.
+func smallestTopPercentage(rank, total int) float64 {
+	best := 0.0
+	for _, percentage := range defaultClient.Percentages {
+		if rank <= rankForPercentage(total, percentage) && (best == 0 || percentage < best) {
+			best = percentage
+		}
+	}
+	return best
+}
+
+func writeScores(w io.Writer, format string, lookups []scoreLookup) error {
+	if format == "json" {
+		return encodeJSON(w, lookups, true)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "score\trank\tpoints\ttop\tmakes"
+	if criteria != nil {
+		header += "\ttier"
+	}
+	fmt.Fprintln(tw, header)
+	for _, lookup := range lookups {
+		if lookup.Position != scoreRanked {
+			fmt.Fprintf(tw, "%s\t%s\n", formatThousands(lookup.Score), lookup.Position)
+			continue
+		}
+		makes := "-"
+		if lookup.TopPercentage > 0 {
+			makes = "top " + formatPercentage(lookup.TopPercentage)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s", formatThousands(lookup.Score), formatThousands(lookup.Rank),
+			formatThousands(lookup.Points), formatPercentage(lookup.Percentile), makes)
+		if criteria != nil {
+			fmt.Fprintf(tw, "\t%s", tierOrNone(lookup.Tier))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func printScores(scores []int) error {
+	lookups, err := lookupScores(context.Background(), seasons[0], scores)
+	if err != nil {
+		return err
+	}
+	return writeScores(stdout, outputFormat, lookups)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLookupScores(t *testing.T) {
+	var requests atomic.Int32
+	upstream := rankedUpstream(1000)
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		upstream.ServeHTTP(w, r)
+	}))
+
+	lookups, err := lookupScores(context.Background(), defaultSeason, []int{20000, 10000, 9000, 9005, 10, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []scoreLookup{
+		{Score: 20000, Position: scoreAboveTop},
+		{Score: 10000, Position: scoreRanked, Rank: 1, Points: 10000, Percentile: 0.001, TopPercentage: 0.0001},
+		{Score: 9000, Position: scoreRanked, Rank: 101, Points: 9000, Percentile: 0.101, TopPercentage: 0.18},
+		{Score: 9005, Position: scoreRanked, Rank: 100, Points: 9010, Percentile: 0.1, TopPercentage: 0.1},
+		{Score: 10, Position: scoreRanked, Rank: 1000, Points: 10, Percentile: 1},
+		{Score: 5, Position: scoreBelowBoard},
+	}
+	for i := range want {
+		if lookups[i] != want[i] {
+			t.Errorf("score %d: got %+v, want %+v", want[i].Score, lookups[i], want[i])
+		}
+	}
+
+	// Alone, each of the three searches takes the total, both ends and
+	// about 10 probes; together they share the first three, and 9000 and
+	// 9005 all but their last probe.
+	if n := requests.Load(); n > 25 {
+		t.Errorf("%d requests, want the searches to share probes", n)
+	}
+
+	var out bytes.Buffer
+	if err := writeScores(&out, "text", lookups); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"20,000  above top", "9,000   101", "top 18%", "5       below board"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("table lacks %q:\n%s", line, out.String())
+		}
+	}
+}
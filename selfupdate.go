@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// checksumsAsset lists the SHA-256 of every release asset, in the
+	// "<hex>  <name>" format of sha256sum.
+	checksumsAsset = "checksums.txt"
+	// maxAssetBytes bounds a downloaded binary.
+	maxAssetBytes = 200 << 20
+)
+
+// githubAPIURL is the GitHub REST API; tests point it at a fake.
+var githubAPIURL = "https://api.github.com"
+
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+func (r release) asset(name string) (releaseAsset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return releaseAsset{}, false
+}
+
+// platformAsset is the name of the release binary for this OS and
+// architecture.
+func platformAsset() string {
+	name := fmt.Sprintf("taikoPointsByLevel_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// compareVersions compares two vMAJOR.MINOR.PATCH[-pre] versions. A
+// prerelease sorts before the release it precedes.
+func compareVersions(a, b string) (int, error) {
+	parse := func(v string) ([3]int, string, error) {
+		var parts [3]int
+		core, pre, _ := strings.Cut(strings.TrimPrefix(v, "v"), "-")
+		fields := strings.Split(core, ".")
+		if len(fields) != 3 {
+			return parts, "", fmt.Errorf("invalid version %q", v)
+		}
+		for i, field := range fields {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 {
+				return parts, "", fmt.Errorf("invalid version %q", v)
+			}
+			parts[i] = n
+		}
+		return parts, pre, nil
+	}
+	pa, preA, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, preB, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0, nil
+	case preA == "":
+		return 1, nil
+	case preB == "":
+		return -1, nil
+	}
+	return strings.Compare(preA, preB), nil
+}
+
+// download fetches url through client, which honours HTTP(S)_PROXY.
+func download(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := defaultClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: body}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: %s is over %d bytes", ErrResponseTooLarge, url, limit)
+	}
+	return body, nil
+}
+
+func latestRelease(ctx context.Context) (release, error) {
+	var latest release
+	body, err := download(ctx, githubAPIURL+"/repos/HeuDeaI/taikoPointsByLevel/releases/latest", maxResponseBytes)
+	if err != nil {
+		return latest, fmt.Errorf("failed to check for releases: %w", err)
+	}
+	if err := json.Unmarshal(body, &latest); err != nil {
+		return latest, fmt.Errorf("failed to parse release: %w", err)
+	}
+	return latest, nil
+}
+
+// checksumFor finds name in a sha256sum-style checksums file.
+func checksumFor(checksums []byte, name string) ([]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			sum, err := hex.DecodeString(fields[0])
+			if err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("invalid checksum for %s", name)
+			}
+			return sum, nil
+		}
+	}
+	return nil, fmt.Errorf("no checksum for %s in %s", name, checksumsAsset)
+}
+
+// verifyMinisign checks a minisign signature of message against a public
+// key in minisign's base64 format. Both the legacy (Ed) and prehashed (ED)
+// signature algorithms are accepted, and the trusted comment is verified
+// too.
+func verifyMinisign(publicKey string, message, signature []byte) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != 2+8+ed25519.PublicKeySize || string(key[:2]) != "Ed" {
+		return errors.New("invalid minisign public key")
+	}
+	keyID, pub := key[2:10], ed25519.PublicKey(key[10:])
+
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) < 4 {
+		return errors.New("invalid minisign signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return errors.New("invalid minisign signature")
+	}
+	if !bytes.Equal(sig[2:10], keyID) {
+		return errors.New("minisign signature was made with a different key")
+	}
+
+	signed := message
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(message)
+		signed = sum[:]
+	default:
+		return fmt.Errorf("unsupported minisign algorithm %q", sig[:2])
+	}
+	if !ed25519.Verify(pub, signed, sig[10:]) {
+		return errors.New("minisign signature does not match")
+	}
+
+	comment, ok := strings.CutPrefix(strings.TrimSpace(lines[2]), "trusted comment: ")
+	if !ok {
+		return errors.New("invalid minisign trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	globalSigned := append(bytes.Clone(sig[10:]), comment...)
+	if err != nil || !ed25519.Verify(pub, globalSigned, global) {
+		return errors.New("minisign trusted comment signature does not match")
+	}
+	return nil
+}
+
+// replaceFile atomically replaces path with data: data goes to a temp file
+// next to path that is renamed over it, so a failure never leaves a partly
+// written binary behind.
+func replaceFile(path string, data []byte) error {
+	mode := os.FileMode(0o755)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		// A running executable can be renamed but not overwritten.
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+type updateOptions struct {
+	checkOnly bool
+	publicKey string
+	target    string
+	out       io.Writer
+	// timeout bounds the whole update, release check and downloads
+	// included (0 = none).
+	timeout time.Duration
+}
+
+// selfUpdate replaces opts.target with the latest release's binary for this
+// platform after verifying its checksum, and the checksums file's minisign
+// signature when a public key is given.
+func selfUpdate(ctx context.Context, current string, opts updateOptions) error {
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+	latest, err := latestRelease(ctx)
+	if err != nil {
+		return err
+	}
+	cmp, err := compareVersions(current, latest.TagName)
+	if err != nil {
+		return fmt.Errorf("%w; self-update needs a release build (-ldflags \"-X main.version=vX.Y.Z\")", err)
+	}
+	if cmp >= 0 {
+		fmt.Fprintf(opts.out, "%s is up to date\n", current)
+		return nil
+	}
+	if opts.checkOnly {
+		fmt.Fprintf(opts.out, "%s is available (you have %s)\n", latest.TagName, current)
+		return nil
+	}
+
+	name := platformAsset()
+	binary, ok := latest.asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", latest.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	sums, ok := latest.asset(checksumsAsset)
+	if !ok {
+		return fmt.Errorf("release %s has no %s", latest.TagName, checksumsAsset)
+	}
+	checksums, err := download(ctx, sums.URL, maxResponseBytes)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", checksumsAsset, err)
+	}
+	if opts.publicKey != "" {
+		sig, ok := latest.asset(checksumsAsset + ".minisig")
+		if !ok {
+			return fmt.Errorf("release %s has no signature for %s", latest.TagName, checksumsAsset)
+		}
+		signature, err := download(ctx, sig.URL, maxResponseBytes)
+		if err != nil {
+			return fmt.Errorf("failed to download signature: %w", err)
+		}
+		if err := verifyMinisign(opts.publicKey, checksums, signature); err != nil {
+			return err
+		}
+	}
+	want, err := checksumFor(checksums, name)
+	if err != nil {
+		return err
+	}
+
+	data, err := download(ctx, binary.URL, maxAssetBytes)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if got := sha256.Sum256(data); !bytes.Equal(got[:], want) {
+		return fmt.Errorf("checksum mismatch for %s: got %x, want %x", name, got, want)
+	}
+	if err := replaceFile(opts.target, data); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", opts.target, err)
+	}
+	fmt.Fprintf(opts.out, "updated %s to %s\n", current, latest.TagName)
+	return nil
+}
+
+func runSelfUpdate(args []string) error {
+	flags := newCommandFlags("self-update")
+	checkOnly := flags.Bool("check-only", false, "only report whether a newer release is available")
+	updateTimeout := flags.Duration("timeout", 5*time.Minute, "give up on the update if it takes longer than this, downloads included (0 = never)")
+	publicKey := flags.String("public-key", "", "minisign public key; when set, the release checksums must carry a valid signature")
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+
+	target, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if target, err = filepath.EvalSymlinks(target); err != nil {
+		return err
+	}
+	return selfUpdate(context.Background(), buildVersion(), updateOptions{
+		checkOnly: *checkOnly,
+		publicKey: *publicKey,
+		target:    target,
+		out:       os.Stdout,
+		timeout:   *updateTimeout,
+	})
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// fakeReleases serves a latest release with the given assets.
+func fakeReleases(t *testing.T, tag string, assets map[string][]byte) {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	latest := release{TagName: tag}
+	for name, data := range assets {
+		latest.Assets = append(latest.Assets, releaseAsset{Name: name, URL: server.URL + "/download/" + name})
+		mux.HandleFunc("GET /download/"+name, func(w http.ResponseWriter, r *http.Request) { w.Write(data) })
+	}
+	mux.HandleFunc("GET /repos/HeuDeaI/taikoPointsByLevel/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(latest)
+	})
+	old := githubAPIURL
+	githubAPIURL = server.URL
+	t.Cleanup(func() {
+		server.Close()
+		githubAPIURL = old
+	})
+}
+
+// minisign signs message the way minisign -S does with a prehashed key.
+func minisign(t *testing.T, message []byte) (publicKey string, signature []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte("testkey1")
+	hash := blake2b.Sum512(message)
+	sig := append(append([]byte("ED"), keyID...), ed25519.Sign(priv, hash[:])...)
+	comment := "timestamp:1718000000"
+	global := ed25519.Sign(priv, append(bytes.Clone(sig[10:]), comment...))
+	signature = fmt.Appendf(nil, "untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(sig), comment, base64.StdEncoding.EncodeToString(global))
+	return base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...)), signature
+}
+
+func releaseAssets(binary []byte) map[string][]byte {
+	sum := sha256.Sum256(binary)
+	return map[string][]byte{
+		platformAsset(): binary,
+		checksumsAsset:  fmt.Appendf(nil, "%x  %s\n%x  other_asset\n", sum, platformAsset(), sha256.Sum256(nil)),
+	}
+}
+
+func TestSelfUpdate(t *testing.T) {
+	binary := []byte("new binary")
+	assets := releaseAssets(binary)
+	publicKey, signature := minisign(t, assets[checksumsAsset])
+	assets[checksumsAsset+".minisig"] = signature
+	fakeReleases(t, "v1.3.0", assets)
+
+	target := filepath.Join(t.TempDir(), "taikoPointsByLevel")
+	os.WriteFile(target, []byte("old binary"), 0o755)
+	var out bytes.Buffer
+
+	if err := selfUpdate(context.Background(), "v1.2.9", updateOptions{checkOnly: true, target: target, out: &out}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "old binary" || !strings.Contains(out.String(), "v1.3.0 is available") {
+		t.Fatalf("-check-only replaced the binary or printed %q", out.String())
+	}
+
+	if err := selfUpdate(context.Background(), "v1.2.9", updateOptions{publicKey: publicKey, target: target, out: &out}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "new binary" {
+		t.Errorf("binary = %q after update", got)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+
+	out.Reset()
+	if err := selfUpdate(context.Background(), "v1.3.0", updateOptions{target: target, out: &out}); err != nil || !strings.Contains(out.String(), "up to date") {
+		t.Errorf("same version: %v, %q", err, out.String())
+	}
+}
+
+func TestSelfUpdateFailsSafely(t *testing.T) {
+	assets := releaseAssets([]byte("new binary"))
+	assets[platformAsset()] = []byte("tampered binary")
+	otherKey, _ := minisign(t, nil)
+	_, signature := minisign(t, assets[checksumsAsset])
+	assets[checksumsAsset+".minisig"] = signature
+	fakeReleases(t, "v2.0.0", assets)
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "taikoPointsByLevel")
+	os.WriteFile(target, []byte("old binary"), 0o755)
+
+	for name, opts := range map[string]updateOptions{
+		"checksum mismatch": {target: target, out: &bytes.Buffer{}},
+		"wrong signer":      {target: target, publicKey: otherKey, out: &bytes.Buffer{}},
+	} {
+		if err := selfUpdate(context.Background(), "v1.0.0", opts); err == nil {
+			t.Errorf("%s: update succeeded", name)
+		}
+		if got, _ := os.ReadFile(target); string(got) != "old binary" {
+			t.Errorf("%s: binary = %q, want it untouched", name, got)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("left %d files behind, want only the binary", len(entries))
+	}
+
+	if err := selfUpdate(context.Background(), "dev", updateOptions{target: target, out: &bytes.Buffer{}}); err == nil {
+		t.Error("unversioned build updated")
+	}
+}
+
+func TestSelfUpdateTimesOut(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	old := githubAPIURL
+	githubAPIURL = server.URL
+	t.Cleanup(func() {
+		server.Close()
+		githubAPIURL = old
+	})
+
+	start := time.Now()
+	err := selfUpdate(context.Background(), "v1.0.0", updateOptions{target: filepath.Join(t.TempDir(), "taikoPointsByLevel"), out: &bytes.Buffer{}, timeout: 50 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up after %v", elapsed)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.3", "v1.10.0", -1},
+		{"1.2.3", "v1.2.2", 1},
+		{"v2.0.0-rc1", "v2.0.0", -1},
+		{"v2.0.0-rc2", "v2.0.0-rc1", 1},
+	} {
+		if got, err := compareVersions(tt.a, tt.b); err != nil || got != tt.want {
+			t.Errorf("compareVersions(%s, %s) = %d, %v; want %d", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Snapshot struct {
+	Season      int       `json:"season"`
+	TotalUsers  int       `json:"totalUsers"`
+	LastUpdated int64     `json:"lastUpdated"`
+	RefreshedAt time.Time `json:"refreshedAt"`
+	Results     []Result  `json:"results"`
+	// Tracked lists the -track addresses looked up when the snapshot was
+	// recorded, and Users holds the rows of those that were ranked.
+	Tracked []string `json:"tracked,omitempty"`
+	Users   []User   `json:"users,omitempty"`
+	// Synthesized marks a snapshot made up by history -fill for a missing
+	// interval; the store never holds one.
+	Synthesized bool `json:"synthesized,omitempty"`
+}
+
+// snapshotStore holds the latest snapshot computed by the background
+// refresher and fans changes out to subscribers. Subscribers only ever see
+// the newest snapshot; a slow one skips intermediate versions.
+type snapshotStore struct {
+	mu          sync.RWMutex
+	snapshot    *Snapshot
+	subscribers map[chan Snapshot]struct{}
+
+	// Refresh health; see health.
+	lastError     error
+	lastErrorAt   time.Time
+	failures      int
+	failuresTotal int64
+	// maxStale is how old a snapshot may get before /cutoffs stops serving
+	// it (0 = forever).
+	maxStale time.Duration
+}
+
+func (s *snapshotStore) get() (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.snapshot == nil {
+		return Snapshot{}, false
+	}
+	return *s.snapshot, true
+}
+
+func (s *snapshotStore) set(snapshot Snapshot) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := s.snapshot == nil ||
+		s.snapshot.LastUpdated != snapshot.LastUpdated ||
+		s.snapshot.TotalUsers != snapshot.TotalUsers ||
+		!slices.Equal(s.snapshot.Results, snapshot.Results)
+	s.snapshot = &snapshot
+	if !changed {
+		return false
+	}
+
+	for ch := range s.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot
+	}
+	return true
+}
+
+func (s *snapshotStore) subscribe() (<-chan Snapshot, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan Snapshot, 1)
+	if s.subscribers == nil {
+		s.subscribers = make(map[chan Snapshot]struct{})
+	}
+	s.subscribers[ch] = struct{}{}
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, ch)
+	}
+}
+
+func buildSnapshot(season int) (Snapshot, error) {
+	results, err := calculatePointsForTopUsers(season)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot := Snapshot{Season: season, RefreshedAt: time.Now(), Results: results}
+	if len(results) > 0 {
+		snapshot.TotalUsers = results[0].TotalUsers
+		snapshot.LastUpdated = results[0].LastUpdated
+	}
+	return snapshot, nil
+}
+
+// refreshSnapshots recomputes the snapshot every interval. With a lock, only
+// the leader replica refreshes from upstream; the lock is held for two
+// intervals so a leader that misses one cycle keeps it.
+func refreshSnapshots(ctx context.Context, store *snapshotStore, season int, interval time.Duration, lock refreshLock) {
+	if lock != nil {
+		defer lock.Release(context.Background())
+	}
+	for {
+		reloader.apply()
+		refreshOnce(ctx, store, season, lock, 2*interval)
+		logDedup.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// refreshOnce runs one refresh and records its outcome in the store's
+// health. A failed refresh leaves the last good snapshot in place.
+func refreshOnce(ctx context.Context, store *snapshotStore, season int, lock refreshLock, ttl time.Duration) {
+	var err error
+	if lock != nil {
+		err = refreshCoordinated(ctx, store, season, lock, ttl)
+	} else {
+		err = refreshLocal(store, season)
+	}
+	if err != nil {
+		log.Printf("Refresh failed: %v", err)
+		store.refreshFailed(err)
+		return
+	}
+	store.refreshSucceeded()
+}
+
+func newHTTPHandler(store *snapshotStore, season int) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /cutoffs", handleCutoffs(store))
+	mux.HandleFunc("GET /status", handleStatus(store))
+	mux.HandleFunc("GET /metrics", handleMetrics(store))
+	mux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			writeJSONError(w, http.StatusBadRequest, "missing address")
+			return
+		}
+		user, _, found, err := userByAddress(r.Context(), season, address)
+		if errors.Is(err, ErrInvalidAddress) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		if !found {
+			writeJSONError(w, http.StatusNotFound, "address not ranked")
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, redact.user(user))
+	})
+	mux.HandleFunc("GET /widget", handleWidget(store))
+	mux.HandleFunc("GET /badge/{file}", handleBadge(store))
+	return mux
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSONResponse(w, status, map[string]string{"error": message})
+}
+
+func runServe(args []string) error {
+	flags := newCommandFlags("serve")
+	addr := flags.String("addr", ":8080", "HTTP listen address (empty to disable)")
+	grpcAddr := flags.String("grpc", "", "gRPC listen address, e.g. :9090 (empty to disable)")
+	interval := flags.Duration("refresh", 5*time.Minute, "how often the cutoffs are recomputed")
+	reloadToken := flags.String("reload-token", os.Getenv("TAIKO_RELOAD_TOKEN"), "bearer token for POST /-/reload (default $TAIKO_RELOAD_TOKEN; empty disables the endpoint)")
+	maxStale := flags.Duration("max-stale", time.Hour, "stop serving /cutoffs with a 503 once the last good snapshot is this old (0 = serve it forever)")
+	lockURL := flags.String("lock", "", "coordinate refreshes across replicas through this store, e.g. redis://localhost:6379/0")
+	if err := parseCommandFlags(flags, args, "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+	if *addr == "" && *grpcAddr == "" {
+		return errors.New("serve: nothing to serve, both -addr and -grpc are empty")
+	}
+
+	season := seasons[0]
+	var lock refreshLock
+	if *lockURL != "" {
+		var err error
+		if lock, err = openRefreshLock(*lockURL, season); err != nil {
+			return err
+		}
+		defer lock.Close()
+	}
+
+	store := &snapshotStore{maxStale: *maxStale}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go refreshSnapshots(ctx, store, season, *interval, lock)
+	stop := reloader.watchSignals()
+	defer stop()
+
+	handler := newHTTPHandler(store, season)
+	if *reloadToken != "" && reloader != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/", handler)
+		mux.HandleFunc("POST /-/reload", handleReload(reloader, *reloadToken))
+		handler = mux
+	}
+
+	errs := make(chan error, 2)
+	if *addr != "" {
+		log.Printf("Serving HTTP on %s", *addr)
+		go func() { errs <- http.ListenAndServe(*addr, handler) }()
+	}
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return err
+		}
+		log.Printf("Serving gRPC on %s", *grpcAddr)
+		go func() { errs <- newGRPCServer(store, season).Serve(listener) }()
+	}
+	return <-errs
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	neturl "net/url"
+	"sync"
+)
+
+// showURLs prints the API URLs requested with -show-urls.
+var showURLs bool
+
+// dryRun plans the cutoffs without fetching them with -dry-run.
+var dryRun bool
+
+// shownURLs are the URLs -show-urls printed, so that retries and pages
+// shared between cutoffs are printed once.
+var shownURLs sync.Map
+
+// showURL prints url to stderr with -show-urls the first time it is
+// requested, redacted.
+func showURL(url string) {
+	if !showURLs {
+		return
+	}
+	if _, shown := shownURLs.LoadOrStore(url, true); !shown {
+		log.Print(redactURL(url))
+	}
+}
+
+// redactURL is raw with its user information and the values of its secret
+// query parameters replaced by REDACTED. Unlike stripURLSecrets it keeps
+// their names, so the URL still shows what was sent.
+func redactURL(raw string) string {
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if u.User != nil {
+		u.User = neturl.User("REDACTED")
+	}
+	query := u.Query()
+	redacted := false
+	for name, values := range query {
+		if secretKey.MatchString(name) {
+			for i := range values {
+				values[i] = "REDACTED"
+			}
+			redacted = true
+		}
+	}
+	if redacted {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// plannedURLs are the pages the cutoffs of seasons would be fetched from,
+// in order and without duplicates. Only the totals are fetched to plan
+// them.
+func plannedURLs(ctx context.Context, seasons []int) ([]string, error) {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, season := range seasons {
+		defaultClient.calibratePages(season)
+		board, _, err := defaultClient.fetchBoardOrEstimate(ctx, season)
+		if err != nil {
+			return nil, fmt.Errorf("season %d: failed to get total wallets: %w", season, err)
+		}
+		ranks, plan, err := defaultClient.planCutoffs(board.Data.Total, len(seasons))
+		if err != nil {
+			return nil, fmt.Errorf("season %d: %w", season, err)
+		}
+		plan.log(season)
+		for i, rank := range ranks {
+			url := defaultClient.pageURL(season, (rank-1)/plan.size+defaultClient.pageBase(), plan.size)
+			if !plan.skip[i] && !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// slackRetryLimit is how many times a post Slack rate limits is
+	// retried, waiting as long as its Retry-After asks.
+	slackRetryLimit = 3
+	// slackAlertColor marks the attachment holding alerts.
+	slackAlertColor = "#d40e0d"
+)
+
+// slackNotifier posts to a Slack incoming webhook as Block Kit messages.
+type slackNotifier struct {
+	url string
+	// channel overrides the webhook's channel, which only legacy
+	// webhooks allow; others ignore it.
+	channel string
+	client  *http.Client
+}
+
+func newSlackNotifier(url, channel string) *slackNotifier {
+	return &slackNotifier{url: url, channel: channel, client: &http.Client{Timeout: timeout}}
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Text        string            `json:"text"`
+	Blocks      []slackBlock      `json:"blocks,omitempty"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+// changeBlocks are a section for change with a context line on the data
+// it was seen in, as of now.
+func changeBlocks(change cutoffChange, now time.Time) []slackBlock {
+	section := slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: fmt.Sprintf("*s%d top %s*\n%s → %s (%+d)",
+		change.Season, formatPercentage(change.Percentage), formatThousands(change.From), formatThousands(change.To), change.To-change.From)}}
+	context := fmt.Sprintf("%s wallets", formatThousands(change.TotalUsers))
+	if change.LastUpdated > 0 {
+		age := now.Sub(time.Unix(change.LastUpdated, 0)).Round(time.Minute)
+		context = fmt.Sprintf("data from %s ago · %s", strings.TrimSuffix(age.String(), "0s"), context)
+	}
+	return []slackBlock{section, {Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: context}}}}
+}
+
+// slackBlocks lays out message with a section per changed cutoff. Alerts go
+// into an attachment in slackAlertColor, after the other changes.
+func (s *slackNotifier) slackBlocks(message notification) slackMessage {
+	out := slackMessage{
+		Channel: s.channel,
+		Text:    message.text(),
+		Blocks:  []slackBlock{{Type: "header", Text: &slackText{Type: "plain_text", Text: message.title()}}},
+	}
+	var alerts []slackBlock
+	for _, change := range message.Changes {
+		if change.alert(message.AlertThreshold) {
+			alerts = append(alerts, changeBlocks(change, message.Until)...)
+			continue
+		}
+		out.Blocks = append(out.Blocks, changeBlocks(change, message.Until)...)
+	}
+	if len(alerts) > 0 {
+		out.Attachments = []slackAttachment{{Color: slackAlertColor, Blocks: alerts}}
+	}
+	return out
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, text string) error {
+	return s.post(ctx, slackMessage{Channel: s.channel, Text: text})
+}
+
+func (s *slackNotifier) NotifyMessage(ctx context.Context, message notification) error {
+	return s.post(ctx, s.slackBlocks(message))
+}
+
+// post sends message, retrying when Slack rate limits the webhook.
+func (s *slackNotifier) post(ctx context.Context, message slackMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("slack: %w", err)
+		}
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < slackRetryLimit:
+			wait := time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+			log.Printf("Slack rate limited the webhook, retrying in %s", wait)
+			if err := sleepCtx(ctx, wait); err != nil {
+				return fmt.Errorf("slack: %w", err)
+			}
+		case resp.StatusCode/100 != 2:
+			return fmt.Errorf("slack: unexpected status code: %d: %s", resp.StatusCode, bytes.TrimSpace(reply))
+		default:
+			return nil
+		}
+	}
+}
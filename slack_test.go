@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	var posts atomic.Int32
+	var got slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if posts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "rate_limited", http.StatusTooManyRequests)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("body is not JSON: %v", err)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	n, _ := newWatchNotifications([]Notifier{newSlackNotifier(server.URL, "#cutoffs")}, nil, 0, time.UTC, "")
+	n.alertThreshold = 500
+	now := at(t, 10, "12:00")
+	cycle := func(first, second int) []seasonResults {
+		return []seasonResults{{Results: []Result{
+			{Season: 2, Percentage: 0.01, TotalUsers: 208114, LastUpdated: now.Add(-15 * time.Minute).Unix(), Points: first},
+			{Season: 2, Percentage: 0.05, TotalUsers: 208114, LastUpdated: now.Add(-15 * time.Minute).Unix(), Points: second},
+		}}}
+	}
+	n.observe(cycle(10000, 2000), now.Add(-time.Hour))
+	n.observe(cycle(10100, 2600), now)
+
+	if posts.Load() != 2 {
+		t.Fatalf("%d posts, want a retry after the 429", posts.Load())
+	}
+	if got.Channel != "#cutoffs" || !strings.Contains(got.Text, "10,000 → 10,100") {
+		t.Errorf("channel %q, fallback text %q", got.Channel, got.Text)
+	}
+	if len(got.Blocks) != 3 || got.Blocks[0].Type != "header" || got.Blocks[0].Text.Text != "Taiko cutoffs changed" {
+		t.Fatalf("blocks = %+v, want a header and one change", got.Blocks)
+	}
+	section, context := got.Blocks[1], got.Blocks[2]
+	if section.Type != "section" || section.Text.Type != "mrkdwn" || section.Text.Text != "*s2 top 1%*\n10,000 → 10,100 (+100)" {
+		t.Errorf("section = %+v", section)
+	}
+	if context.Type != "context" || len(context.Elements) != 1 || context.Elements[0].Text != "data from 15m ago · 208,114 wallets" {
+		t.Errorf("context = %+v", context)
+	}
+
+	if len(got.Attachments) != 1 || got.Attachments[0].Color != slackAlertColor {
+		t.Fatalf("attachments = %+v, want one red one for the alert", got.Attachments)
+	}
+	alert := got.Attachments[0].Blocks
+	if len(alert) != 2 || alert[0].Text.Text != "*s2 top 5%*\n2,000 → 2,600 (+600)" || alert[1].Type != "context" {
+		t.Errorf("alert blocks = %+v", alert)
+	}
+}
+
+func TestSlackNotifierGivesUp(t *testing.T) {
+	var posts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+		if r.URL.Path == "/limited" {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "rate_limited", http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := newSlackNotifier(server.URL+"/limited", "").Notify(t.Context(), "hi")
+	if err == nil || posts.Load() != slackRetryLimit+1 {
+		t.Errorf("err = %v after %d posts, want an error after %d", err, posts.Load(), slackRetryLimit+1)
+	}
+	err = newSlackNotifier(server.URL+"/bad", "").Notify(t.Context(), "hi")
+	if err == nil || !strings.Contains(err.Error(), "invalid_payload") {
+		t.Errorf("err = %v, want Slack's reason", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// snapshotDir, when set, makes every run or -watch cycle add a
+	// timestamped file per format to it, keeping the newest snapshotKeep.
+	snapshotDir  string
+	snapshotKeep int
+)
+
+// snapshotTimeLayout is ISO 8601 basic format in UTC: it sorts by time as a
+// string and has no colons, which some filesystems reject.
+const snapshotTimeLayout = "20060102T150405Z"
+
+const snapshotPrefix = "cutoffs-"
+
+// snapshotFormats are the formats written to -snapshot-dir: those given
+// with -output, or JSON and CSV.
+func snapshotFormats() []string {
+	if flagSet("output") {
+		return outDirFormats
+	}
+	return []string{"json", "csv"}
+}
+
+// writeSnapshotDir writes all to timestamped files in dir, then removes all
+// but the newest keep snapshots (0 keeps them all).
+func writeSnapshotDir(dir string, formats []string, keep int, all []seasonResults, now time.Time) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	stamp := now.UTC().Format(snapshotTimeLayout)
+	for _, format := range formats {
+		path := outputPath(filepath.Join(dir, snapshotPrefix+stamp+"."+outputExtension(format)))
+		temp, err := writeTemp(dir, func(w io.Writer) error {
+			return writeOutput(w, func(w io.Writer) error { return renderCutoffs(w, format, all, now) })
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s snapshot: %w", format, err)
+		}
+		if err := os.Rename(temp, path); err != nil {
+			os.Remove(temp)
+			return fmt.Errorf("failed to move %s into place: %w", path, err)
+		}
+	}
+	if keep > 0 {
+		return pruneSnapshots(dir, keep)
+	}
+	return nil
+}
+
+// pruneSnapshots removes the files of all but the newest keep snapshots in
+// dir. A snapshot is every file sharing one timestamp, whatever its format.
+func pruneSnapshots(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	byStamp := make(map[string][]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		rest, ok := strings.CutPrefix(name, snapshotPrefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, _, _ := strings.Cut(rest, ".")
+		if _, err := time.Parse(snapshotTimeLayout, stamp); err != nil {
+			continue
+		}
+		byStamp[stamp] = append(byStamp[stamp], name)
+	}
+
+	stamps := make([]string, 0, len(byStamp))
+	for stamp := range byStamp {
+		stamps = append(stamps, stamp)
+	}
+	slices.Sort(stamps)
+	for _, stamp := range stamps[:max(len(stamps)-keep, 0)] {
+		for _, name := range byStamp[stamp] {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return fmt.Errorf("failed to prune old snapshot: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDirRotates(t *testing.T) {
+	dir := t.TempDir()
+	all := []seasonResults{{Results: []Result{{Season: defaultSeason, Percentage: 0.01, TotalUsers: 1000, Rank: 10, Points: 9910}}}}
+	// A file that only looks like a snapshot is left alone.
+	os.WriteFile(filepath.Join(dir, "cutoffs-latest.json"), nil, 0o644)
+
+	start := time.Date(2024, 6, 10, 23, 59, 58, 0, time.UTC)
+	for i := range 4 {
+		if err := writeSnapshotDir(dir, []string{"json", "csv"}, 2, all, start.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("cycle %d: %v", i, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{
+		"cutoffs-20240611T000000Z.csv",
+		"cutoffs-20240611T000000Z.json",
+		"cutoffs-20240611T000001Z.csv",
+		"cutoffs-20240611T000001Z.json",
+		"cutoffs-latest.json",
+	}
+	if !slices.Equal(names, want) {
+		t.Fatalf("files = %q, want %q", names, want)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, want[3]))
+	var envelope snapshotEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil || !envelope.GeneratedAt.Equal(start.Add(3*time.Second)) {
+		t.Errorf("newest snapshot generatedAt = %v, err %v", envelope.GeneratedAt, err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var (
+	// splitOutput, when set, is a directory that gets one JSON file per
+	// percentage in addition to the normal output.
+	splitOutput    string
+	splitNeighbors int
+)
+
+type splitRecord struct {
+	Result
+	Neighbors []User `json:"neighbors,omitempty"`
+}
+
+// neighbors returns the users up to n ranks above and below rank.
+func neighbors(season, rank, totalUsers, n int) ([]User, error) {
+	var users []User
+	for r := max(rank-n, 1); r <= min(rank+n, totalUsers); r++ {
+		if r == rank {
+			continue
+		}
+		user, err := defaultClient.UserAtRank(context.Background(), season, r)
+		if err != nil {
+			return users, fmt.Errorf("failed to get neighbor at rank %d: %w", r, err)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func splitFileName(percentage float64) string {
+	return strconv.FormatFloat(percentage, 'f', -1, 64) + ".json"
+}
+
+// writeSplitOutput writes every result of all to its own file named after
+// its percentage, in a per-season subdirectory when there are several
+// seasons.
+func writeSplitOutput(dir string, all []seasonResults) error {
+	for i, season := range all {
+		seasonDir := dir
+		if len(all) > 1 {
+			seasonDir = filepath.Join(dir, fmt.Sprintf("s%d", seasons[i]))
+		}
+		if err := os.MkdirAll(seasonDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create split output directory: %w", err)
+		}
+
+		for _, result := range season.Results {
+			record := splitRecord{Result: result}
+			if splitNeighbors > 0 && !result.Skipped {
+				users, err := neighbors(result.Season, result.Rank, result.TotalUsers, splitNeighbors)
+				if err != nil {
+					return err
+				}
+				record.Neighbors = redactUsers(users)
+			}
+
+			path := outputPath(filepath.Join(seasonDir, splitFileName(result.Percentage)))
+			temp, err := writeTemp(seasonDir, func(w io.Writer) error {
+				return writeOutput(w, func(w io.Writer) error {
+					encoder := json.NewEncoder(w)
+					encoder.SetIndent("", "  ")
+					return encoder.Encode(record)
+				})
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			if err := os.Rename(temp, path); err != nil {
+				os.Remove(temp)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func redactUsers(users []User) []User {
+	for i := range users {
+		users[i] = redact.user(users[i])
+	}
+	return users
+}
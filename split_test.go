@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSplitOutput(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	dir := filepath.Join(t.TempDir(), "split")
+	splitNeighbors = 2
+	t.Cleanup(func() { splitNeighbors = 0 })
+
+	all := []seasonResults{{Results: []Result{
+		{Season: 2, Percentage: 0.001, TotalUsers: 1000, Rank: 1, Points: 10000},
+		{Season: 2, Percentage: 0.01, TotalUsers: 1000, Rank: 10, Points: 9910},
+	}}}
+	if err := writeSplitOutput(dir, all); err != nil {
+		t.Fatalf("writeSplitOutput: %v", err)
+	}
+
+	for name, wantNeighbors := range map[string][]int{"0.001.json": {2, 3}, "0.01.json": {8, 9, 11, 12}} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var record splitRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			t.Fatal(err)
+		}
+		if len(record.Neighbors) != len(wantNeighbors) {
+			t.Fatalf("%s has %d neighbors, want %v", name, len(record.Neighbors), wantNeighbors)
+		}
+		for i, user := range record.Neighbors {
+			if user.Rank != wantNeighbors[i] {
+				t.Errorf("%s neighbor %d has rank %d, want %d", name, i, user.Rank, wantNeighbors[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stateBundleVersion is the layout of the archive written by state export.
+const stateBundleVersion = 1
+
+const stateManifestName = "manifest.json"
+
+// stateSchemas are the schema levels this build reads and writes for each
+// part of the state. Bump one when its file changes incompatibly, and add
+// the migration from the previous level to stateMigrations.
+var stateSchemas = map[string]int{
+	"config":      1,
+	"history":     1,
+	"watchlist":   1,
+	"notifyState": 1,
+}
+
+// stateMigrations upgrade a part of the state by one level: the function
+// at stateMigrations[part][n] turns a level n file into a level n+1 one.
+// state import -force-migrate runs them in order.
+var stateMigrations = map[string]map[int]func([]byte) ([]byte, error){}
+
+// stateFiles are the names the parts of the state have inside a bundle and
+// next to the config file once imported.
+var stateFiles = map[string]string{
+	"config":      "config.yaml",
+	"history":     "history.jsonl",
+	"watchlist":   "watchlist.yaml",
+	"notifyState": "notify-state.json",
+}
+
+// stateManifest describes a bundle. The page cache only lives in memory and
+// -cache-ttl answers from history, so there is no separate cache to bundle.
+type stateManifest struct {
+	Version     int       `json:"version"`
+	ToolVersion string    `json:"toolVersion"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Schemas holds the schema level of every part in the bundle.
+	Schemas map[string]int `json:"schemas"`
+	// Secrets is whether the config was bundled with its secrets.
+	Secrets bool `json:"secrets"`
+}
+
+// secretKey matches config keys whose values are secret.
+var secretKey = regexp.MustCompile(`(?i)webhook|token|secret|password|passwd|api_?key`)
+
+// stripSecrets removes secret keys from the YAML node, and credentials and
+// secret query parameters from URLs in it, returning where it removed
+// something.
+func stripSecrets(node *yaml.Node, path string) []string {
+	var stripped []string
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := path
+			if node.Kind == yaml.SequenceNode {
+				childPath = fmt.Sprintf("%s[%d]", path, i)
+			}
+			stripped = append(stripped, stripSecrets(child, childPath)...)
+		}
+	case yaml.MappingNode:
+		var kept []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := strings.TrimPrefix(path+"."+key.Value, ".")
+			if secretKey.MatchString(key.Value) {
+				stripped = append(stripped, keyPath)
+				continue
+			}
+			stripped = append(stripped, stripSecrets(value, keyPath)...)
+			kept = append(kept, key, value)
+		}
+		node.Content = kept
+	case yaml.ScalarNode:
+		if value, changed := stripURLSecrets(node.Value); changed {
+			node.Value = value
+			stripped = append(stripped, path)
+		}
+	}
+	return stripped
+}
+
+// stripURLSecrets removes the credentials and secret query parameters from
+// raw if it is a URL, reporting whether there were any.
+func stripURLSecrets(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw, false
+	}
+	changed := u.User != nil
+	u.User = nil
+	query := u.Query()
+	for name := range query {
+		if secretKey.MatchString(name) {
+			query.Del(name)
+			changed = true
+		}
+	}
+	if !changed {
+		return raw, false
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), true
+}
+
+// setConfigKey sets the top-level key of the config document to value.
+func setConfigKey(doc *yaml.Node, key, value string) {
+	mapping := doc.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
+
+// bundleConfig is the config at configPath as it goes into a bundle, with
+// the history and watchlist pointing at their bundled copies.
+func bundleConfig(configPath string, hasWatchlist, includeSecrets bool) ([]byte, error) {
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	data, err := os.ReadFile(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", configPath, err)
+		}
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config %s is not a YAML mapping", configPath)
+	}
+
+	if !includeSecrets {
+		for _, path := range stripSecrets(doc, "") {
+			log.Printf("Leaving the secret in %s out of the bundle; pass -include-secrets to keep it", path)
+		}
+	}
+	setConfigKey(doc, "history", stateFiles["history"])
+	if hasWatchlist {
+		setConfigKey(doc, "watchlist", stateFiles["watchlist"])
+	}
+	return yaml.Marshal(doc)
+}
+
+// exportState writes a bundle of the config at configPath, the history and
+// watchlist it points at, and the notification state at stateFile if
+// given. Secrets in the config are left out unless includeSecrets.
+func exportState(w io.Writer, configPath, stateFile string, includeSecrets bool) (stateManifest, error) {
+	manifest := stateManifest{
+		Version:     stateBundleVersion,
+		ToolVersion: buildVersion(),
+		CreatedAt:   time.Now().UTC(),
+		Schemas:     map[string]int{},
+		Secrets:     includeSecrets,
+	}
+	config, err := loadConfig(configPath, false)
+	if err != nil {
+		return manifest, err
+	}
+	historyPath := config.History
+	if historyPath == "" {
+		historyPath = defaultHistoryPath()
+	}
+
+	files := map[string][]byte{}
+	for part, path := range map[string]string{"history": historyPath, "watchlist": config.Watchlist, "notifyState": stateFile} {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) && part == "history" {
+			data, err = nil, nil
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("failed to read %s: %w", part, err)
+		}
+		files[part] = data
+	}
+	_, hasWatchlist := files["watchlist"]
+	files["config"], err = bundleConfig(configPath, hasWatchlist, includeSecrets)
+	if err != nil {
+		return manifest, err
+	}
+	for part := range files {
+		manifest.Schemas[part] = stateSchemas[part]
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := archive.Write(data)
+		return err
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err := add(stateManifestName, append(manifestData, '\n')); err != nil {
+		return manifest, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	parts := make([]string, 0, len(files))
+	for part := range files {
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	for _, part := range parts {
+		if err := add(stateFiles[part], files[part]); err != nil {
+			return manifest, fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return manifest, gz.Close()
+}
+
+// readStateBundle reads the manifest and the files of a bundle, keyed by
+// part.
+func readStateBundle(r io.Reader) (stateManifest, map[string][]byte, error) {
+	var manifest stateManifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("not a state bundle: %w", err)
+	}
+	parts := map[string]string{}
+	for part, name := range stateFiles {
+		parts[name] = part
+	}
+
+	files := map[string][]byte{}
+	haveManifest := false
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return manifest, nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Name == stateManifestName {
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return manifest, nil, fmt.Errorf("invalid bundle manifest: %w", err)
+			}
+			haveManifest = true
+			continue
+		}
+		part, ok := parts[header.Name]
+		if !ok {
+			return manifest, nil, fmt.Errorf("unexpected file %q in bundle", header.Name)
+		}
+		files[part] = data
+	}
+	if !haveManifest {
+		return manifest, nil, errors.New("bundle has no manifest")
+	}
+	if manifest.Version < 1 || manifest.Version > stateBundleVersion {
+		return manifest, nil, fmt.Errorf("unsupported bundle version %d (this build reads up to %d)", manifest.Version, stateBundleVersion)
+	}
+	for part := range files {
+		if _, ok := manifest.Schemas[part]; !ok {
+			return manifest, nil, fmt.Errorf("bundle manifest has no schema level for %s", part)
+		}
+	}
+	return manifest, files, nil
+}
+
+// migrateState brings every part of the bundle up to this build's schema
+// levels. A part at an older level is only migrated with forceMigrate; one
+// at a newer level is never imported.
+func migrateState(manifest stateManifest, files map[string][]byte, forceMigrate bool) error {
+	for part, data := range files {
+		from, to := manifest.Schemas[part], stateSchemas[part]
+		if from > to {
+			return fmt.Errorf("the bundle's %s is at schema level %d, newer than this build's %d; import with %s or later",
+				part, from, to, manifest.ToolVersion)
+		}
+		if from == to {
+			continue
+		}
+		if !forceMigrate {
+			return fmt.Errorf("the bundle's %s is at schema level %d and this build is at %d; pass -force-migrate to migrate it", part, from, to)
+		}
+		for level := from; level < to; level++ {
+			migrate, ok := stateMigrations[part][level]
+			if !ok {
+				return fmt.Errorf("no migration for %s from schema level %d", part, level)
+			}
+			var err error
+			if data, err = migrate(data); err != nil {
+				return fmt.Errorf("failed to migrate %s from schema level %d: %w", part, level, err)
+			}
+		}
+		files[part] = data
+	}
+	return nil
+}
+
+// checkStateFiles makes sure the bundled files are what this build reads.
+func checkStateFiles(files map[string][]byte) error {
+	var config Config
+	if err := yaml.Unmarshal(files["config"], &config); err != nil {
+		return fmt.Errorf("bundled config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return fmt.Errorf("bundled config: %w", err)
+	}
+	for line, data := range bytes.Split(bytes.TrimSpace(files["history"]), []byte("\n")) {
+		var snapshot Snapshot
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &snapshot); err != nil {
+				return fmt.Errorf("bundled history, line %d: %w", line+1, err)
+			}
+		}
+	}
+	if data, ok := files["watchlist"]; ok {
+		var watchlist Watchlist
+		if err := yaml.Unmarshal(data, &watchlist); err != nil {
+			return fmt.Errorf("bundled watchlist: %w", err)
+		}
+	}
+	if data, ok := files["notifyState"]; ok {
+		var state notifyState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("bundled notification state: %w", err)
+		}
+	}
+	return nil
+}
+
+// importState restores a bundle, writing the config to configPath and the
+// rest next to it. It refuses to overwrite existing files unless overwrite,
+// and returns the paths written by part.
+func importState(r io.Reader, configPath string, forceMigrate, overwrite bool) (map[string]string, error) {
+	manifest, files, err := readStateBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := files["config"]; !ok {
+		return nil, errors.New("bundle has no config")
+	}
+	if err := migrateState(manifest, files, forceMigrate); err != nil {
+		return nil, err
+	}
+	if err := checkStateFiles(files); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(configPath)
+	paths := map[string]string{}
+	for part := range files {
+		paths[part] = filepath.Join(dir, stateFiles[part])
+		if part == "config" {
+			paths[part] = configPath
+		}
+		if _, err := os.Stat(paths[part]); err == nil && !overwrite {
+			return nil, fmt.Errorf("%s already exists; pass -overwrite to replace it", paths[part])
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for part, path := range paths {
+		temp, err := writeTemp(dir, func(w io.Writer) error {
+			_, err := w.Write(files[part])
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if err := os.Rename(temp, path); err != nil {
+			os.Remove(temp)
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return paths, nil
+}
+
+func runState(configPath string, args []string) error {
+	const usage = "usage: state export [-state-file path] [-include-secrets] <bundle.tar.gz> | state import [-force-migrate] [-overwrite] <bundle.tar.gz>"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	if configPath == "" {
+		return errors.New("state: no config path; pass -config")
+	}
+
+	switch args[0] {
+	case "export":
+		flags := newCommandFlags("state export")
+		stateFile := flags.String("state-file", "", "watch notification -state-file to include")
+		includeSecrets := flags.Bool("include-secrets", false, "keep webhook URLs, tokens and URL credentials in the bundled config")
+		if err := parseCommandFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New(usage)
+		}
+		temp, err := writeTemp(filepath.Dir(flags.Arg(0)), func(w io.Writer) error {
+			_, err := exportState(w, configPath, *stateFile, *includeSecrets)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("state export: %w", err)
+		}
+		if err := os.Rename(temp, flags.Arg(0)); err != nil {
+			os.Remove(temp)
+			return fmt.Errorf("state export: %w", err)
+		}
+		fmt.Fprintf(stdout, "Wrote %s\n", flags.Arg(0))
+		return nil
+	case "import":
+		flags := newCommandFlags("state import")
+		forceMigrate := flags.Bool("force-migrate", false, "migrate a bundle written at an older schema level")
+		overwrite := flags.Bool("overwrite", false, "replace existing files")
+		if err := parseCommandFlags(flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New(usage)
+		}
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("state import: %w", err)
+		}
+		defer file.Close()
+		paths, err := importState(file, configPath, *forceMigrate, *overwrite)
+		if err != nil {
+			return fmt.Errorf("state import: %w", err)
+		}
+		parts := make([]string, 0, len(paths))
+		for part := range paths {
+			parts = append(parts, part)
+		}
+		sort.Strings(parts)
+		for _, part := range parts {
+			fmt.Fprintf(stdout, "%s: %s\n", part, paths[part])
+		}
+		if path, ok := paths["notifyState"]; ok {
+			fmt.Fprintf(stdout, "Pass -state-file %s to -watch to pick up the notification state.\n", path)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown state command %q", args[0])
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// scoreStats summarizes the TotalScore of a leaderboard. Variance is the
+// population variance, as the board is every user rather than a sample;
+// all but Count are 0 for an empty board.
+type scoreStats struct {
+	Count    int     `json:"count"`
+	Sum      float64 `json:"sum"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	StdDev   float64 `json:"stdDev"`
+}
+
+// statsAccumulator computes scoreStats one score at a time with Welford's
+// algorithm, which stays accurate where summing squares would cancel out
+// for large scores that differ little.
+type statsAccumulator struct {
+	count    int
+	sum      float64
+	min, max float64
+	mean, m2 float64
+}
+
+func (a *statsAccumulator) add(score float64) {
+	a.count++
+	a.sum += score
+	if a.count == 1 || score < a.min {
+		a.min = score
+	}
+	if a.count == 1 || score > a.max {
+		a.max = score
+	}
+	delta := score - a.mean
+	a.mean += delta / float64(a.count)
+	a.m2 += delta * (score - a.mean)
+}
+
+func (a *statsAccumulator) stats() scoreStats {
+	if a.count == 0 {
+		return scoreStats{}
+	}
+	variance := a.m2 / float64(a.count)
+	return scoreStats{
+		Count:    a.count,
+		Sum:      a.sum,
+		Min:      a.min,
+		Max:      a.max,
+		Mean:     a.mean,
+		Variance: variance,
+		StdDev:   math.Sqrt(variance),
+	}
+}
+
+// computeStats summarizes the TotalScore of users.
+func computeStats(users []User) scoreStats {
+	var a statsAccumulator
+	for _, user := range users {
+		a.add(user.TotalScore)
+	}
+	return a.stats()
+}
+
+func writeStats(w io.Writer, format string, stats scoreStats) error {
+	if format == "json" {
+		return encodeJSON(w, stats, true)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "users\t%s\n", formatThousands(stats.Count))
+	for _, row := range []struct {
+		name  string
+		value float64
+	}{
+		{"total points", stats.Sum},
+		{"min", stats.Min},
+		{"max", stats.Max},
+		{"mean", stats.Mean},
+		{"variance", stats.Variance},
+		{"std dev", stats.StdDev},
+	} {
+		fmt.Fprintf(tw, "%s\t%s\n", row.name, formatFloat(row.value, 1))
+	}
+	return tw.Flush()
+}
+
+// printStats streams every user of the season through the accumulator, so
+// the board is never held in memory.
+func printStats() error {
+	var a statsAccumulator
+	_, err := walkUsers(seasons[0], func(_ Response, page []User) error {
+		for _, user := range page {
+			a.add(user.TotalScore)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeStats(stdout, outputFormat, a.stats())
+}
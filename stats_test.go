@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestComputeStats(t *testing.T) {
+	if got := computeStats(nil); got != (scoreStats{}) {
+		t.Errorf("empty board = %+v, want all zero", got)
+	}
+	if got := computeStats([]User{{TotalScore: 42}}); got != (scoreStats{Count: 1, Sum: 42, Min: 42, Max: 42, Mean: 42}) {
+		t.Errorf("one user = %+v, want no spread", got)
+	}
+
+	got := computeStats([]User{{TotalScore: 9}, {TotalScore: 7}, {TotalScore: 5}, {TotalScore: 5}, {TotalScore: 4}, {TotalScore: 4}, {TotalScore: 4}, {TotalScore: 2}})
+	if got.Count != 8 || got.Sum != 40 || got.Min != 2 || got.Max != 9 || !closeTo(got.Mean, 5) || !closeTo(got.Variance, 4) || !closeTo(got.StdDev, 2) {
+		t.Errorf("stats = %+v, want mean 5, variance 4, std dev 2", got)
+	}
+
+	// Squaring scores this large loses the spread entirely; Welford's
+	// algorithm keeps it.
+	var users []User
+	for _, offset := range []float64{4, 7, 13, 16} {
+		users = append(users, User{TotalScore: 1e9 + offset})
+	}
+	if got := computeStats(users); math.Abs(got.Variance-22.5) > 1e-6 {
+		t.Errorf("variance of large scores = %v, want 22.5", got.Variance)
+	}
+}
+
+func TestStatsOfWalkedBoard(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(25))
+	var out bytes.Buffer
+	var a statsAccumulator
+	if _, err := walkUsers(defaultSeason, func(_ Response, page []User) error {
+		for _, user := range page {
+			a.add(user.TotalScore)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	stats := a.stats()
+	if stats.Count != 25 || stats.Mean != 130 || stats.Max != 250 {
+		t.Errorf("streamed stats = %+v", stats)
+	}
+	if err := writeStats(&out, "text", stats); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "std dev") || !strings.Contains(out.String(), "mean          130.0") {
+		t.Errorf("table:\n%s", out.String())
+	}
+}
@@ -0,0 +1,437 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: taikopoints/v1/cutoffs.proto
+
+package taikopointspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CutoffsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CutoffsRequest) Reset() {
+	*x = CutoffsRequest{}
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CutoffsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CutoffsRequest) ProtoMessage() {}
+
+func (x *CutoffsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CutoffsRequest.ProtoReflect.Descriptor instead.
+func (*CutoffsRequest) Descriptor() ([]byte, []int) {
+	return file_taikopoints_v1_cutoffs_proto_rawDescGZIP(), []int{0}
+}
+
+type Cutoff struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Percentage    float64                `protobuf:"fixed64,1,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	Rank          int64                  `protobuf:"varint,2,opt,name=rank,proto3" json:"rank,omitempty"`
+	Points        int64                  `protobuf:"varint,3,opt,name=points,proto3" json:"points,omitempty"`
+	Skipped       bool                   `protobuf:"varint,4,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cutoff) Reset() {
+	*x = Cutoff{}
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cutoff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cutoff) ProtoMessage() {}
+
+func (x *Cutoff) ProtoReflect() protoreflect.Message {
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cutoff.ProtoReflect.Descriptor instead.
+func (*Cutoff) Descriptor() ([]byte, []int) {
+	return file_taikopoints_v1_cutoffs_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Cutoff) GetPercentage() float64 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
+func (x *Cutoff) GetRank() int64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *Cutoff) GetPoints() int64 {
+	if x != nil {
+		return x.Points
+	}
+	return 0
+}
+
+func (x *Cutoff) GetSkipped() bool {
+	if x != nil {
+		return x.Skipped
+	}
+	return false
+}
+
+type CutoffsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Season        int32                  `protobuf:"varint,1,opt,name=season,proto3" json:"season,omitempty"`
+	TotalUsers    int64                  `protobuf:"varint,2,opt,name=total_users,json=totalUsers,proto3" json:"total_users,omitempty"`
+	LastUpdated   int64                  `protobuf:"varint,3,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
+	RefreshedAt   int64                  `protobuf:"varint,4,opt,name=refreshed_at,json=refreshedAt,proto3" json:"refreshed_at,omitempty"`
+	Cutoffs       []*Cutoff              `protobuf:"bytes,5,rep,name=cutoffs,proto3" json:"cutoffs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CutoffsResponse) Reset() {
+	*x = CutoffsResponse{}
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CutoffsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CutoffsResponse) ProtoMessage() {}
+
+func (x *CutoffsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CutoffsResponse.ProtoReflect.Descriptor instead.
+func (*CutoffsResponse) Descriptor() ([]byte, []int) {
+	return file_taikopoints_v1_cutoffs_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CutoffsResponse) GetSeason() int32 {
+	if x != nil {
+		return x.Season
+	}
+	return 0
+}
+
+func (x *CutoffsResponse) GetTotalUsers() int64 {
+	if x != nil {
+		return x.TotalUsers
+	}
+	return 0
+}
+
+func (x *CutoffsResponse) GetLastUpdated() int64 {
+	if x != nil {
+		return x.LastUpdated
+	}
+	return 0
+}
+
+func (x *CutoffsResponse) GetRefreshedAt() int64 {
+	if x != nil {
+		return x.RefreshedAt
+	}
+	return 0
+}
+
+func (x *CutoffsResponse) GetCutoffs() []*Cutoff {
+	if x != nil {
+		return x.Cutoffs
+	}
+	return nil
+}
+
+type UserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserRequest) Reset() {
+	*x = UserRequest{}
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserRequest) ProtoMessage() {}
+
+func (x *UserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserRequest.ProtoReflect.Descriptor instead.
+func (*UserRequest) Descriptor() ([]byte, []int) {
+	return file_taikopoints_v1_cutoffs_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UserRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type UserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Rank          int64                  `protobuf:"varint,2,opt,name=rank,proto3" json:"rank,omitempty"`
+	Address       string                 `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Score         float64                `protobuf:"fixed64,4,opt,name=score,proto3" json:"score,omitempty"`
+	Multiplier    int32                  `protobuf:"varint,5,opt,name=multiplier,proto3" json:"multiplier,omitempty"`
+	TotalScore    float64                `protobuf:"fixed64,6,opt,name=total_score,json=totalScore,proto3" json:"total_score,omitempty"`
+	TotalUsers    int64                  `protobuf:"varint,7,opt,name=total_users,json=totalUsers,proto3" json:"total_users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserResponse) Reset() {
+	*x = UserResponse{}
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserResponse) ProtoMessage() {}
+
+func (x *UserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_taikopoints_v1_cutoffs_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserResponse.ProtoReflect.Descriptor instead.
+func (*UserResponse) Descriptor() ([]byte, []int) {
+	return file_taikopoints_v1_cutoffs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UserResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *UserResponse) GetRank() int64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *UserResponse) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *UserResponse) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *UserResponse) GetMultiplier() int32 {
+	if x != nil {
+		return x.Multiplier
+	}
+	return 0
+}
+
+func (x *UserResponse) GetTotalScore() float64 {
+	if x != nil {
+		return x.TotalScore
+	}
+	return 0
+}
+
+func (x *UserResponse) GetTotalUsers() int64 {
+	if x != nil {
+		return x.TotalUsers
+	}
+	return 0
+}
+
+var File_taikopoints_v1_cutoffs_proto protoreflect.FileDescriptor
+
+const file_taikopoints_v1_cutoffs_proto_rawDesc = "" +
+	"\n" +
+	"\x1ctaikopoints/v1/cutoffs.proto\x12\x0etaikopoints.v1\"\x10\n" +
+	"\x0eCutoffsRequest\"n\n" +
+	"\x06Cutoff\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\x01 \x01(\x01R\n" +
+	"percentage\x12\x12\n" +
+	"\x04rank\x18\x02 \x01(\x03R\x04rank\x12\x16\n" +
+	"\x06points\x18\x03 \x01(\x03R\x06points\x12\x18\n" +
+	"\askipped\x18\x04 \x01(\bR\askipped\"\xc2\x01\n" +
+	"\x0fCutoffsResponse\x12\x16\n" +
+	"\x06season\x18\x01 \x01(\x05R\x06season\x12\x1f\n" +
+	"\vtotal_users\x18\x02 \x01(\x03R\n" +
+	"totalUsers\x12!\n" +
+	"\flast_updated\x18\x03 \x01(\x03R\vlastUpdated\x12!\n" +
+	"\frefreshed_at\x18\x04 \x01(\x03R\vrefreshedAt\x120\n" +
+	"\acutoffs\x18\x05 \x03(\v2\x16.taikopoints.v1.CutoffR\acutoffs\"'\n" +
+	"\vUserRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"\xca\x01\n" +
+	"\fUserResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x12\n" +
+	"\x04rank\x18\x02 \x01(\x03R\x04rank\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05score\x18\x04 \x01(\x01R\x05score\x12\x1e\n" +
+	"\n" +
+	"multiplier\x18\x05 \x01(\x05R\n" +
+	"multiplier\x12\x1f\n" +
+	"\vtotal_score\x18\x06 \x01(\x01R\n" +
+	"totalScore\x12\x1f\n" +
+	"\vtotal_users\x18\a \x01(\x03R\n" +
+	"totalUsers2\xf1\x01\n" +
+	"\aCutoffs\x12M\n" +
+	"\n" +
+	"GetCutoffs\x12\x1e.taikopoints.v1.CutoffsRequest\x1a\x1f.taikopoints.v1.CutoffsResponse\x12D\n" +
+	"\aGetUser\x12\x1b.taikopoints.v1.UserRequest\x1a\x1c.taikopoints.v1.UserResponse\x12Q\n" +
+	"\fWatchCutoffs\x12\x1e.taikopoints.v1.CutoffsRequest\x1a\x1f.taikopoints.v1.CutoffsResponse0\x01B5Z3github.com/HeuDeaI/taikoPointsByLevel/taikopointspbb\x06proto3"
+
+var (
+	file_taikopoints_v1_cutoffs_proto_rawDescOnce sync.Once
+	file_taikopoints_v1_cutoffs_proto_rawDescData []byte
+)
+
+func file_taikopoints_v1_cutoffs_proto_rawDescGZIP() []byte {
+	file_taikopoints_v1_cutoffs_proto_rawDescOnce.Do(func() {
+		file_taikopoints_v1_cutoffs_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_taikopoints_v1_cutoffs_proto_rawDesc), len(file_taikopoints_v1_cutoffs_proto_rawDesc)))
+	})
+	return file_taikopoints_v1_cutoffs_proto_rawDescData
+}
+
+var file_taikopoints_v1_cutoffs_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_taikopoints_v1_cutoffs_proto_goTypes = []any{
+	(*CutoffsRequest)(nil),  // 0: taikopoints.v1.CutoffsRequest
+	(*Cutoff)(nil),          // 1: taikopoints.v1.Cutoff
+	(*CutoffsResponse)(nil), // 2: taikopoints.v1.CutoffsResponse
+	(*UserRequest)(nil),     // 3: taikopoints.v1.UserRequest
+	(*UserResponse)(nil),    // 4: taikopoints.v1.UserResponse
+}
+var file_taikopoints_v1_cutoffs_proto_depIdxs = []int32{
+	1, // 0: taikopoints.v1.CutoffsResponse.cutoffs:type_name -> taikopoints.v1.Cutoff
+	0, // 1: taikopoints.v1.Cutoffs.GetCutoffs:input_type -> taikopoints.v1.CutoffsRequest
+	3, // 2: taikopoints.v1.Cutoffs.GetUser:input_type -> taikopoints.v1.UserRequest
+	0, // 3: taikopoints.v1.Cutoffs.WatchCutoffs:input_type -> taikopoints.v1.CutoffsRequest
+	2, // 4: taikopoints.v1.Cutoffs.GetCutoffs:output_type -> taikopoints.v1.CutoffsResponse
+	4, // 5: taikopoints.v1.Cutoffs.GetUser:output_type -> taikopoints.v1.UserResponse
+	2, // 6: taikopoints.v1.Cutoffs.WatchCutoffs:output_type -> taikopoints.v1.CutoffsResponse
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_taikopoints_v1_cutoffs_proto_init() }
+func file_taikopoints_v1_cutoffs_proto_init() {
+	if File_taikopoints_v1_cutoffs_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_taikopoints_v1_cutoffs_proto_rawDesc), len(file_taikopoints_v1_cutoffs_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_taikopoints_v1_cutoffs_proto_goTypes,
+		DependencyIndexes: file_taikopoints_v1_cutoffs_proto_depIdxs,
+		MessageInfos:      file_taikopoints_v1_cutoffs_proto_msgTypes,
+	}.Build()
+	File_taikopoints_v1_cutoffs_proto = out.File
+	file_taikopoints_v1_cutoffs_proto_goTypes = nil
+	file_taikopoints_v1_cutoffs_proto_depIdxs = nil
+}
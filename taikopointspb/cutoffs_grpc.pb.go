@@ -0,0 +1,215 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: taikopoints/v1/cutoffs.proto
+
+package taikopointspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Cutoffs_GetCutoffs_FullMethodName   = "/taikopoints.v1.Cutoffs/GetCutoffs"
+	Cutoffs_GetUser_FullMethodName      = "/taikopoints.v1.Cutoffs/GetUser"
+	Cutoffs_WatchCutoffs_FullMethodName = "/taikopoints.v1.Cutoffs/WatchCutoffs"
+)
+
+// CutoffsClient is the client API for Cutoffs service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Cutoffs serves the tier cutoffs computed by the background refresher and
+// wallet lookups against the leaderboard.
+type CutoffsClient interface {
+	// GetCutoffs returns the latest cached cutoff snapshot.
+	GetCutoffs(ctx context.Context, in *CutoffsRequest, opts ...grpc.CallOption) (*CutoffsResponse, error)
+	// GetUser looks up a single wallet by address.
+	GetUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	// WatchCutoffs sends the current snapshot and then a new message every
+	// time the refresher detects a change.
+	WatchCutoffs(ctx context.Context, in *CutoffsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CutoffsResponse], error)
+}
+
+type cutoffsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCutoffsClient(cc grpc.ClientConnInterface) CutoffsClient {
+	return &cutoffsClient{cc}
+}
+
+func (c *cutoffsClient) GetCutoffs(ctx context.Context, in *CutoffsRequest, opts ...grpc.CallOption) (*CutoffsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CutoffsResponse)
+	err := c.cc.Invoke(ctx, Cutoffs_GetCutoffs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cutoffsClient) GetUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, Cutoffs_GetUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cutoffsClient) WatchCutoffs(ctx context.Context, in *CutoffsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CutoffsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Cutoffs_ServiceDesc.Streams[0], Cutoffs_WatchCutoffs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CutoffsRequest, CutoffsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Cutoffs_WatchCutoffsClient = grpc.ServerStreamingClient[CutoffsResponse]
+
+// CutoffsServer is the server API for Cutoffs service.
+// All implementations must embed UnimplementedCutoffsServer
+// for forward compatibility.
+//
+// Cutoffs serves the tier cutoffs computed by the background refresher and
+// wallet lookups against the leaderboard.
+type CutoffsServer interface {
+	// GetCutoffs returns the latest cached cutoff snapshot.
+	GetCutoffs(context.Context, *CutoffsRequest) (*CutoffsResponse, error)
+	// GetUser looks up a single wallet by address.
+	GetUser(context.Context, *UserRequest) (*UserResponse, error)
+	// WatchCutoffs sends the current snapshot and then a new message every
+	// time the refresher detects a change.
+	WatchCutoffs(*CutoffsRequest, grpc.ServerStreamingServer[CutoffsResponse]) error
+	mustEmbedUnimplementedCutoffsServer()
+}
+
+// UnimplementedCutoffsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCutoffsServer struct{}
+
+func (UnimplementedCutoffsServer) GetCutoffs(context.Context, *CutoffsRequest) (*CutoffsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCutoffs not implemented")
+}
+func (UnimplementedCutoffsServer) GetUser(context.Context, *UserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedCutoffsServer) WatchCutoffs(*CutoffsRequest, grpc.ServerStreamingServer[CutoffsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCutoffs not implemented")
+}
+func (UnimplementedCutoffsServer) mustEmbedUnimplementedCutoffsServer() {}
+func (UnimplementedCutoffsServer) testEmbeddedByValue()                 {}
+
+// UnsafeCutoffsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CutoffsServer will
+// result in compilation errors.
+type UnsafeCutoffsServer interface {
+	mustEmbedUnimplementedCutoffsServer()
+}
+
+func RegisterCutoffsServer(s grpc.ServiceRegistrar, srv CutoffsServer) {
+	// If the following call pancis, it indicates UnimplementedCutoffsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Cutoffs_ServiceDesc, srv)
+}
+
+func _Cutoffs_GetCutoffs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CutoffsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CutoffsServer).GetCutoffs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cutoffs_GetCutoffs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CutoffsServer).GetCutoffs(ctx, req.(*CutoffsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cutoffs_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CutoffsServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cutoffs_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CutoffsServer).GetUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cutoffs_WatchCutoffs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CutoffsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CutoffsServer).WatchCutoffs(m, &grpc.GenericServerStream[CutoffsRequest, CutoffsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Cutoffs_WatchCutoffsServer = grpc.ServerStreamingServer[CutoffsResponse]
+
+// Cutoffs_ServiceDesc is the grpc.ServiceDesc for Cutoffs service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Cutoffs_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "taikopoints.v1.Cutoffs",
+	HandlerType: (*CutoffsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCutoffs",
+			Handler:    _Cutoffs_GetCutoffs_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _Cutoffs_GetUser_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchCutoffs",
+			Handler:       _Cutoffs_WatchCutoffs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "taikopoints/v1/cutoffs.proto",
+}
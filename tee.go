@@ -0,0 +1,23 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// stdout is where results are printed: os.Stdout, and with -tee a file as
+// well.
+var stdout io.Writer = os.Stdout
+
+// openTee creates path for -tee, truncating what an earlier run left, and
+// makes stdout write to it as well as os.Stdout. The file gets exactly what
+// is printed, whatever the -format, so it's readable by whoever can read
+// the directory, like a shell redirection would make it.
+func openTee(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	stdout = io.MultiWriter(os.Stdout, file)
+	return file, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.txt")
+	if err := os.WriteFile(path, []byte("from an earlier run, longer than this one\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	file, err := openTee(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStats(stdout, "json", computeStats([]User{{TotalScore: 10}, {TotalScore: 30}})); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(got), "{") || !strings.Contains(string(got), `"mean": 20`) || strings.Contains(string(got), "earlier run") {
+		t.Errorf("file = %q, want just the JSON printed", got)
+	}
+
+	if _, err := openTee(filepath.Join(t.TempDir(), "missing", "x")); err == nil {
+		t.Error("openTee in a missing directory succeeded")
+	}
+}
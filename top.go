@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var topFormats = []string{"text", "json", "csv"}
+
+// fetchTop returns the first n users of the leaderboard, paging through it in
+// pages of at most dumpPageSize. n is clamped to the size of the board.
+func fetchTop(season, n int) ([]User, error) {
+	defaultClient.calibratePages(season)
+	board, err := defaultClient.FetchBoard(context.Background(), season)
+	if err != nil {
+		return nil, err
+	}
+	if n > board.Data.Total {
+		log.Printf("Season %d has only %d users, showing all of them", season, board.Data.Total)
+		n = board.Data.Total
+	}
+
+	size := min(n, dumpPageSize)
+	defaultClient.progress.AddTotal((n + size - 1) / max(size, 1))
+	defer defaultClient.progress.Finish()
+
+	users := make([]User, 0, n)
+	for page := defaultClient.pageBase(); len(users) < n; page++ {
+		response, err := defaultClient.fetchResponse(context.Background(), defaultClient.pageURL(season, page, size), timeoutForRank(len(users)+1))
+		if err != nil {
+			return users, fmt.Errorf("failed to fetch page %d: %w", page, err)
+		}
+		checkPageFill(season, page, size, response.Data.Size, len(response.Data.Users), len(users)+len(response.Data.Users) >= board.Data.Total)
+		if len(response.Data.Users) == 0 {
+			return users, fmt.Errorf("page %d is empty after %d of %d users", page, len(users), n)
+		}
+		for _, user := range response.Data.Users {
+			checkLeaderboardAddress(user)
+			user.Rank = defaultClient.fromAPIRank(user.Rank)
+			if user.Rank != len(users)+1 {
+				return users, fmt.Errorf("%w: expected rank %d on page %d but API returned rank %d", ErrRankMismatch, len(users)+1, page, user.Rank)
+			}
+			users = append(users, user)
+			if len(users) == n {
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+// checksumAddress returns address in EIP-55 mixed-case checksum form.
+// Anything that isn't a well-formed address is returned unchanged.
+func checksumAddress(address string) string {
+	if !addressPattern.MatchString(address) {
+		return address
+	}
+	lower := strings.ToLower(address[2:])
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(lower))
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	checksummed := []byte(lower)
+	for i, c := range checksummed {
+		if c >= 'a' && digest[i] >= '8' {
+			checksummed[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(checksummed)
+}
+
+// displayAddress is the checksummed address, or its pseudonym with
+// -redact-addresses.
+func displayAddress(address string) string {
+	if redact != nil {
+		return redact.address(address)
+	}
+	return checksumAddress(address)
+}
+
+// enrichedUser is a user in JSON output with -enrich-rpc.
+type enrichedUser struct {
+	User
+	addressActivity
+}
+
+// writeTop writes users in format. activity, keyed by lowercase address,
+// adds the -enrich-rpc columns; nil leaves them out.
+func writeTop(out io.Writer, format string, users []User, activity map[string]addressActivity) error {
+	display := make([]User, len(users))
+	byRank := make(map[int]addressActivity, len(users))
+	for i, user := range users {
+		byRank[user.Rank] = activity[strings.ToLower(user.Address)]
+		user.Address = displayAddress(user.Address)
+		display[i] = user
+	}
+	columns := selectedUserColumns()
+	if activity != nil {
+		columns = append(slices.Clip(columns), activityColumns(byRank)...)
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if activity == nil {
+			return encoder.Encode(display)
+		}
+		enriched := make([]enrichedUser, len(display))
+		for i, user := range display {
+			enriched[i] = enrichedUser{user, byRank[user.Rank]}
+		}
+		return encoder.Encode(enriched)
+	case "csv":
+		return writeUsersCSV(out, display, columns)
+	}
+	return writeUsersTable(out, display, columns)
+}
+
+// writeUsersCSV writes columns of users.
+func writeUsersCSV(out io.Writer, users []User, columns []userColumn) error {
+	w := csv.NewWriter(out)
+	if decimalSep == "," {
+		w.Comma = ';'
+	}
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.csvHeader
+	}
+	w.Write(record)
+	for _, user := range users {
+		for i, column := range columns {
+			record[i] = column.csv(user)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeUsersTable writes columns of users as an aligned table. Numbers are
+// right-aligned; addresses are padded to a common width so they read
+// left-aligned whatever their length.
+func writeUsersTable(out io.Writer, users []User, columns []userColumn) error {
+	width := len("address")
+	for _, user := range users {
+		width = max(width, len(user.Address))
+	}
+	cell := func(column userColumn, value string) string {
+		if column.field == "address" {
+			return fmt.Sprintf("%-*s", width, value)
+		}
+		return value
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, column := range columns {
+		fmt.Fprintf(w, "%s\t", cell(column, column.tableHeader))
+	}
+	fmt.Fprintln(w)
+	for _, user := range users {
+		for _, column := range columns {
+			fmt.Fprintf(w, "%s\t", cell(column, column.table(user)))
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+func runTop(args []string) error {
+	flags := newCommandFlags("top")
+	n := flags.Int("n", 20, "number of users to show")
+	format := flags.String("output", "text", fmt.Sprintf("output format: %s", strings.Join(topFormats, ", ")))
+	if err := parseCommandFlags(flags, args, "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return errors.New("top: -n must be at least 1")
+	}
+	if !slices.Contains(topFormats, *format) {
+		return fmt.Errorf("top: unknown -output %q, want one of %s", *format, strings.Join(topFormats, ", "))
+	}
+
+	users, err := fetchTop(seasons[0], *n)
+	if err != nil {
+		return err
+	}
+	var activity map[string]addressActivity
+	if enricher != nil {
+		addresses := make([]string, len(users))
+		for i, user := range users {
+			addresses[i] = user.Address
+		}
+		activity = enricher.enrich(addresses)
+	}
+	return writeTop(stdout, *format, users, activity)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// usersUpstream serves users as a 1-based leaderboard.
+func usersUpstream(users []User) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		page, size = max(page, 1), max(size, 1)
+		items := []User{}
+		if from := (page - 1) * size; from < len(users) {
+			items = users[from:min(from+size, len(users))]
+		}
+		data, _ := json.Marshal(items)
+		fmt.Fprintf(w, `{"data":{"items":%s,"page":%d,"size":%d,"total":%d,"total_pages":1},"lastUpdated":1718000000}`,
+			data, page, size, len(users))
+	})
+}
+
+func TestChecksumAddress(t *testing.T) {
+	for _, want := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		if got := checksumAddress(strings.ToLower(want)); got != want {
+			t.Errorf("checksumAddress = %s, want %s", got, want)
+		}
+	}
+	if got := checksumAddress("0xabc"); got != "0xabc" {
+		t.Errorf("malformed address changed to %s", got)
+	}
+}
+
+func TestTopTableGolden(t *testing.T) {
+	useFakeUpstream(t, usersUpstream([]User{
+		{Rank: 1, Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", Score: 1234567.8, Multiplier: 12, TotalScore: 14814813.6},
+		{Rank: 2, Address: "0xabc", Score: 99, Multiplier: 1, TotalScore: 99},
+		{Rank: 3, Address: "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359", Score: 7, Multiplier: 3, TotalScore: 21},
+	}))
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	users, err := fetchTop(defaultSeason, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 || !strings.Contains(logs.String(), "only 3 users") {
+		t.Errorf("got %d users and notice %q, want 3 and a clamping notice", len(users), logs.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeTop(&out, "text", users, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "top", out.Bytes())
+}
+
+func TestFetchTopPaginates(t *testing.T) {
+	var requests int
+	useFakeUpstream(t, countRequests(&requests, rankedUpstream(250)))
+
+	users, err := fetchTop(defaultSeason, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 150 || users[149].Rank != 150 {
+		t.Fatalf("got %d users ending at rank %d, want 150", len(users), users[len(users)-1].Rank)
+	}
+	// The board and two pages of 100.
+	if requests != 3 {
+		t.Errorf("made %d requests, want 3", requests)
+	}
+
+	var out bytes.Buffer
+	writeTop(&out, "csv", users[:1], nil)
+	if want := "rank,address,score,multiplier,total_score\n1,0x0000000000000000000000000000000000000001,2500,1,2500\n"; out.String() != want {
+		t.Errorf("csv = %q, want %q", out.String(), want)
+	}
+}
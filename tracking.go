@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// tracker records -track addresses to the history store each run; nil
+// without -track.
+var tracker *historyTracker
+
+type historyTracker struct {
+	store     SnapshotStore
+	addresses []string
+}
+
+// record adds a snapshot of every season in all, with the rows of the
+// tracked addresses, unless the store already has one for that leaderboard
+// update. A snapshot is only recorded once every address was looked up, so
+// a missing row always means the address wasn't ranked.
+func (t *historyTracker) record(all []seasonResults, now time.Time) error {
+	for _, season := range all {
+		if season.Unavailable || len(season.Results) == 0 || season.Results[0].Estimated {
+			continue
+		}
+		snapshot, err := snapshotFromResults(season.Results, now)
+		if err != nil {
+			return err
+		}
+		exists, err := t.store.Has(snapshot.Season, snapshot.LastUpdated)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		snapshot.Tracked = t.addresses
+		for _, address := range t.addresses {
+			user, _, found, err := userByAddress(context.Background(), snapshot.Season, address)
+			if err != nil {
+				return err
+			}
+			if found {
+				user.Address = strings.ToLower(user.Address)
+				snapshot.Users = append(snapshot.Users, user)
+			}
+		}
+		if err := t.store.Add(snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addressPoint is where an address stood in one snapshot.
+type addressPoint struct {
+	LastUpdated int64
+	TotalUsers  int
+	// Tracked is false for snapshots recorded without the address, which
+	// say nothing about it.
+	Tracked bool
+	Ranked  bool
+	User    User
+	// Synthesized is true for a point history -fill made up.
+	Synthesized bool
+}
+
+// addressHistory follows address through snapshots, oldest first.
+func addressHistory(snapshots []Snapshot, address string) []addressPoint {
+	points := make([]addressPoint, len(snapshots))
+	for i, snapshot := range snapshots {
+		points[i] = addressPoint{
+			LastUpdated: snapshot.LastUpdated,
+			TotalUsers:  snapshot.TotalUsers,
+			Tracked:     slices.Contains(snapshot.Tracked, address),
+			Synthesized: snapshot.Synthesized,
+		}
+		if j := slices.IndexFunc(snapshot.Users, func(u User) bool { return u.Address == address }); j >= 0 {
+			points[i].Ranked, points[i].User = true, snapshot.Users[j]
+		}
+	}
+	return points
+}
+
+// writeAddressHistory writes the trend of address in season. Runs of
+// snapshots that didn't track it are collapsed into one line, so sparse
+// history stays readable.
+func writeAddressHistory(out io.Writer, season int, address string, points []addressPoint) error {
+	first := slices.IndexFunc(points, func(p addressPoint) bool { return p.Tracked })
+	if first < 0 {
+		return fmt.Errorf("no snapshot recorded %s; record it with -track", address)
+	}
+	points = points[first:]
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "lastUpdated\trank\ttotal score\tchange\ttop"
+	if normalizeByDays {
+		header += "\tper day"
+	}
+	fmt.Fprintln(w, header)
+	var previous, oldest, latest *addressPoint
+	untracked, synthesized := 0, false
+	for i := range points {
+		point := &points[i]
+		if !point.Tracked {
+			untracked++
+			continue
+		}
+		if untracked > 0 {
+			fmt.Fprintf(w, "…\t(%d untracked)\t\t\t\n", untracked)
+			untracked = 0
+		}
+		when := time.Unix(point.LastUpdated, 0).UTC().Format(time.RFC3339)
+		if point.Synthesized {
+			when += "*"
+			synthesized = true
+		}
+		if !point.Ranked {
+			fmt.Fprintf(w, "%s\tnot ranked\t\t\t\n", when)
+			previous = nil
+			continue
+		}
+		change := ""
+		if previous != nil {
+			change = fmt.Sprintf("%+.0f", point.User.TotalScore-previous.User.TotalScore)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.0f\t%s\t%s", when, formatThousands(point.User.Rank), point.User.TotalScore, change,
+			formatPercentage(topPercentile(point.User.Rank, point.TotalUsers)))
+		if normalizeByDays {
+			if value, _, ok := perDay(season, time.Unix(point.LastUpdated, 0), point.User.TotalScore); ok {
+				fmt.Fprintf(w, "\t%s", formatPerDay(value))
+			}
+		}
+		fmt.Fprintln(w)
+		previous = point
+		if point.Synthesized {
+			continue
+		}
+		latest = point
+		if oldest == nil {
+			oldest = point
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if synthesized {
+		fmt.Fprintln(out, "\n* synthesized by -fill, not recorded")
+	}
+
+	if oldest == nil {
+		_, err := fmt.Fprintf(out, "\n%s was not ranked in any recorded snapshot\n", address)
+		return err
+	}
+	if oldest == latest {
+		_, err := fmt.Fprintf(out, "\nonly one snapshot ranks %s; record more with -track to see a trend\n", address)
+		return err
+	}
+	climb := fmt.Sprintf("up %d", oldest.User.Rank-latest.User.Rank)
+	if latest.User.Rank > oldest.User.Rank {
+		climb = fmt.Sprintf("down %d", latest.User.Rank-oldest.User.Rank)
+	}
+	_, err := fmt.Fprintf(out, "\nrank %s → %s (%s), total score %.0f → %.0f (%+.0f)\n",
+		formatThousands(oldest.User.Rank), formatThousands(latest.User.Rank), climb,
+		oldest.User.TotalScore, latest.User.TotalScore, latest.User.TotalScore-oldest.User.TotalScore)
+	return err
+}
+
+func runAddressHistory(store SnapshotStore, address string, since string, fill historyFill, out io.Writer) error {
+	normalized, err := normalizeAddress(address)
+	if err != nil {
+		return err
+	}
+	snapshots, err := queryHistory(store, since, fill)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		return errors.New("no snapshots in the history")
+	}
+	return writeAddressHistory(out, seasons[0], address, addressHistory(snapshots, normalized))
+}
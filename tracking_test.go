@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackerRecordsAddressRows(t *testing.T) {
+	ranked := "0x00000000000000000000000000000000000000aa"
+	unranked := "0x00000000000000000000000000000000000000bb"
+	useFakeUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items := "[]"
+		if r.URL.Query().Get("address") == ranked {
+			items = fmt.Sprintf(`[{"rank":42,"address":"0x%s","score":100,"multiplier":2,"totalScore":200}]`, strings.ToUpper(ranked[2:]))
+		}
+		fmt.Fprintf(w, `{"data":{"items":%s,"page":1,"size":1,"total":1000,"total_pages":1},"lastUpdated":1718000000}`, items)
+	}))
+	store, err := openSnapshotStore(Config{History: filepath.Join(t.TempDir(), "history.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracking := &historyTracker{store: store, addresses: []string{ranked, unranked}}
+	all := []seasonResults{{Results: []Result{{Season: 2, Percentage: 0.01, TotalUsers: 1000, LastUpdated: 1718000000, Rank: 10, Points: 500}}}}
+
+	for range 2 {
+		if err := tracking.record(all, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshots, err := store.List(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("recorded %d snapshots of one leaderboard update, want 1", len(snapshots))
+	}
+	if len(snapshots[0].Users) != 1 || snapshots[0].Users[0].Address != ranked || snapshots[0].Users[0].TotalScore != 200 {
+		t.Errorf("users = %+v, want only %s", snapshots[0].Users, ranked)
+	}
+
+	points := addressHistory(snapshots, unranked)
+	if !points[0].Tracked || points[0].Ranked {
+		t.Errorf("%s should be tracked but not ranked: %+v", unranked, points[0])
+	}
+}
+
+func TestAddressHistorySparse(t *testing.T) {
+	address := "0x00000000000000000000000000000000000000aa"
+	row := func(rank int, totalScore float64) []User {
+		return []User{{Rank: rank, Address: address, TotalScore: totalScore}}
+	}
+	tracked := []string{address}
+	snapshots := []Snapshot{
+		{LastUpdated: 1717000000, TotalUsers: 1000},
+		{LastUpdated: 1717100000, TotalUsers: 1000, Tracked: tracked, Users: row(120, 1000)},
+		{LastUpdated: 1717200000, TotalUsers: 1000},
+		{LastUpdated: 1717300000, TotalUsers: 1000},
+		{LastUpdated: 1717400000, TotalUsers: 1000, Tracked: tracked},
+		{LastUpdated: 1717500000, TotalUsers: 1000, Tracked: tracked, Users: row(100, 1500)},
+		{LastUpdated: 1717600000, TotalUsers: 1000, Tracked: tracked, Users: row(95, 1600)},
+	}
+
+	var out bytes.Buffer
+	if err := writeAddressHistory(&out, defaultSeason, address, addressHistory(snapshots, address)); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"…                     (2 untracked)",
+		"2024-06-03T07:33:20Z  not ranked ",
+		"+100",
+		"rank 120 → 95 (up 25), total score 1000 → 1600 (+600)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "2024-05-29T16:26:40Z") || strings.Contains(got, "+500") {
+		t.Errorf("untracked leading snapshots or a change across a gap in ranking were shown:\n%s", got)
+	}
+
+	if err := writeAddressHistory(&out, defaultSeason, address, addressHistory(snapshots[:1], address)); err == nil {
+		t.Error("want an error for an address no snapshot recorded")
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HeuDeaI/taikoPointsByLevel/internal/progress"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// dashboardLogLines is how many of the latest log lines the dashboard keeps
+// below the cutoffs.
+const dashboardLogLines = 5
+
+// dashboardView is the -tui dashboard, or nil when watch mode prints plainly.
+var dashboardView *dashboard
+
+type cutoffKey struct {
+	season     int
+	percentage float64
+}
+
+// dashboard is the -tui view of watch mode, drawn with tview: the current
+// cutoffs in a table with how they moved since the previous cycle and how
+// old the data is, a status line above it and the latest log lines below,
+// so they don't scroll the cutoffs away. q or Ctrl-C quits.
+type dashboard struct {
+	mu  sync.Mutex
+	now func() time.Time
+	// fallback takes the log output while the dashboard isn't on screen.
+	fallback io.Writer
+
+	all         []seasonResults
+	points      map[cutoffKey]int
+	deltas      map[cutoffKey]int
+	refreshedAt time.Time
+	next        time.Time
+	lastErr     error
+	logs        []string
+
+	app    *tview.Application
+	status *tview.TextView
+	table  *tview.Table
+	footer *tview.TextView
+	// changed asks run to redraw; running is set while it does.
+	changed chan struct{}
+	running atomic.Bool
+}
+
+// openDashboard is the -tui dashboard for out, or nil when out isn't a
+// terminal and watch mode should print plainly instead. tview draws on the
+// controlling terminal, the one out is then connected to.
+func openDashboard(out *os.File) *dashboard {
+	if !progress.IsTerminal(out) {
+		return nil
+	}
+	return newDashboard(nil, os.Stderr, time.Now)
+}
+
+// newDashboard lays out a dashboard on screen, or on the terminal with a
+// nil screen. Log lines written to it go to fallback until run is called.
+func newDashboard(screen tcell.Screen, fallback io.Writer, now func() time.Time) *dashboard {
+	d := &dashboard{
+		now:      now,
+		fallback: fallback,
+		points:   make(map[cutoffKey]int),
+		status:   tview.NewTextView().SetDynamicColors(true),
+		table:    tview.NewTable().SetFixed(1, 0).SetSeparator(' '),
+		footer:   tview.NewTextView().SetDynamicColors(true),
+		changed:  make(chan struct{}, 1),
+	}
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(d.status, 2, 0, false).
+		AddItem(d.table, 0, 1, false).
+		AddItem(d.footer, dashboardLogLines+2, 0, false)
+	d.app = tview.NewApplication().SetRoot(layout, true)
+	d.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'q' {
+			d.app.Stop()
+			return nil
+		}
+		return event
+	})
+	if screen != nil {
+		d.app.SetScreen(screen)
+	}
+	d.populate()
+	return d
+}
+
+// run shows the dashboard until q or Ctrl-C is pressed.
+func (d *dashboard) run() error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-d.changed:
+				d.app.QueueUpdateDraw(d.populate)
+			case <-done:
+				return
+			}
+		}
+	}()
+	d.running.Store(true)
+	defer d.running.Store(false)
+	d.redraw()
+	return d.app.Run()
+}
+
+// redraw has run draw the dashboard again, without waiting for it.
+func (d *dashboard) redraw() {
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
+
+// update shows all, with deltas against the points of the last cycle that
+// had them.
+func (d *dashboard) update(all []seasonResults) {
+	d.mu.Lock()
+	d.all, d.refreshedAt = all, d.now()
+	d.deltas = make(map[cutoffKey]int)
+	for _, season := range all {
+		for _, result := range season.Results {
+			if result.Skipped {
+				continue
+			}
+			key := cutoffKey{result.Season, result.Percentage}
+			if previous, ok := d.points[key]; ok {
+				d.deltas[key] = result.Points - previous
+			}
+			d.points[key] = result.Points
+		}
+	}
+	d.mu.Unlock()
+	d.redraw()
+}
+
+// cycleDone records how the cycle ended and when the next one starts.
+func (d *dashboard) cycleDone(err error, next time.Time) {
+	d.mu.Lock()
+	d.lastErr, d.next = err, next
+	d.mu.Unlock()
+	d.redraw()
+}
+
+// Write takes log output, keeping the latest dashboardLogLines lines while
+// the dashboard is on screen and passing it to the fallback otherwise.
+func (d *dashboard) Write(p []byte) (int, error) {
+	if !d.running.Load() {
+		return d.fallback.Write(p)
+	}
+	d.mu.Lock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		d.logs = append(d.logs, line)
+	}
+	if len(d.logs) > dashboardLogLines {
+		d.logs = d.logs[len(d.logs)-dashboardLogLines:]
+	}
+	d.mu.Unlock()
+	d.redraw()
+	return len(p), nil
+}
+
+// dashboardClock is the watch scheduler's clock with -tui: it redraws the
+// dashboard every second while sleeping, so the ages and countdown stay
+// current.
+type dashboardClock struct {
+	realClock
+	view *dashboard
+}
+
+func (c dashboardClock) Sleep(ctx context.Context, d time.Duration) error {
+	start := c.Monotonic()
+	for {
+		c.view.redraw()
+		remaining := d - (c.Monotonic() - start)
+		if remaining <= 0 {
+			return nil
+		}
+		if err := sleepCtx(ctx, min(remaining, time.Second)); err != nil {
+			return err
+		}
+	}
+}
+
+func formatAge(d time.Duration) string {
+	return max(d, 0).Round(time.Second).String()
+}
+
+// populate fills the widgets in as of d.now().
+func (d *dashboard) populate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+
+	status := "waiting for the first cycle"
+	if !d.refreshedAt.IsZero() {
+		status = "refreshed " + d.refreshedAt.Format("15:04:05")
+	}
+	if !d.next.IsZero() {
+		status += ", next in " + formatAge(d.next.Sub(now))
+	}
+	d.status.SetText(fmt.Sprintf("[::b]Taiko cutoffs[::-]  %s  [gray](q to quit)[-]", status))
+
+	d.table.Clear()
+	for column, title := range []string{"season", "top", "rank", "points", "change", "data age"} {
+		d.table.SetCell(0, column, tview.NewTableCell(title).SetAlign(tview.AlignRight).SetAttributes(tcell.AttrBold).SetSelectable(false))
+	}
+	row := 1
+	for _, season := range d.all {
+		for _, result := range season.Results {
+			key := cutoffKey{result.Season, result.Percentage}
+			points := "skipped"
+			if !result.Skipped {
+				points = formatPoints(result)
+			}
+			change := tview.NewTableCell("")
+			if delta, ok := d.deltas[key]; ok {
+				change.SetText(fmt.Sprintf("%+d", delta))
+				switch {
+				case delta > 0:
+					change.SetTextColor(tcell.ColorGreen)
+				case delta < 0:
+					change.SetTextColor(tcell.ColorRed)
+				}
+			}
+			for column, cell := range []*tview.TableCell{
+				tview.NewTableCell(fmt.Sprint(result.Season)),
+				tview.NewTableCell(formatPercentage(result.Percentage)),
+				tview.NewTableCell(formatThousands(result.Rank)),
+				tview.NewTableCell(points),
+				change,
+				tview.NewTableCell(formatAge(now.Sub(time.Unix(result.LastUpdated, 0)))),
+			} {
+				d.table.SetCell(row, column, cell.SetAlign(tview.AlignRight))
+			}
+			row++
+		}
+	}
+
+	var footer strings.Builder
+	if d.lastErr != nil {
+		fmt.Fprintf(&footer, "[red]last cycle failed: %s[-]\n", tview.Escape(d.lastErr.Error()))
+	}
+	for _, line := range d.logs {
+		footer.WriteString(tview.Escape(line) + "\n")
+	}
+	d.footer.SetText(footer.String())
+}
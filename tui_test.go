@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// tableRows is the dashboard's table as text, a line per row.
+func tableRows(d *dashboard) []string {
+	var rows []string
+	for row := range d.table.GetRowCount() {
+		var cells []string
+		for column := range d.table.GetColumnCount() {
+			cells = append(cells, d.table.GetCell(row, column).Text)
+		}
+		rows = append(rows, strings.Join(cells, "|"))
+	}
+	return rows
+}
+
+func TestDashboard(t *testing.T) {
+	now := time.Unix(1718000090, 0)
+	d := newDashboard(tcell.NewSimulationScreen("UTF-8"), &bytes.Buffer{}, func() time.Time { return now })
+	d.running.Store(true)
+
+	cycle := func(top1, top10 int, skipTop10 bool) []seasonResults {
+		return []seasonResults{{Results: []Result{
+			{Season: 2, Percentage: 0.01, Rank: 1, Points: top1, LastUpdated: 1718000000},
+			{Season: 2, Percentage: 0.1, Rank: 10, Points: top10, LastUpdated: 1718000000, Skipped: skipTop10},
+		}}}
+	}
+
+	d.update(cycle(1000, 100, false))
+	d.populate()
+	if rows := tableRows(d); len(rows) != 3 || rows[0] != "season|top|rank|points|change|data age" || !strings.HasSuffix(rows[1], "||1m30s") {
+		t.Errorf("first cycle should show no change and a 1m30s data age:\n%s", strings.Join(rows, "\n"))
+	}
+
+	now = now.Add(time.Minute)
+	d.update(cycle(1012, 0, true))
+	d.update(cycle(1005, 90, false))
+	d.cycleDone(errors.New("upstream down [503]"), now.Add(30*time.Second))
+	d.populate()
+	rows := strings.Join(tableRows(d), "\n")
+	for _, want := range []string{"|-7|2m30s", "|-10|2m30s"} {
+		if !strings.Contains(rows, want) {
+			t.Errorf("table is missing %q:\n%s", want, rows)
+		}
+	}
+	if status := d.status.GetText(true); !strings.Contains(status, "next in 30s") {
+		t.Errorf("status = %q, want the countdown", status)
+	}
+	if footer := d.footer.GetText(true); !strings.Contains(footer, "last cycle failed: upstream down [503]") {
+		t.Errorf("footer = %q, want the error as logged", footer)
+	}
+
+	for i := range dashboardLogLines + 2 {
+		fmt.Fprintf(d, "log line %d\n", i)
+	}
+	d.populate()
+	footer := d.footer.GetText(true)
+	if strings.Contains(footer, "log line 1\n") || !strings.Contains(footer, fmt.Sprintf("log line %d\n", dashboardLogLines+1)) {
+		t.Errorf("want only the last %d log lines:\n%s", dashboardLogLines, footer)
+	}
+}
+
+func TestDashboardRunsUntilQuit(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	var fallback bytes.Buffer
+	d := newDashboard(screen, &fallback, time.Now)
+	screen.SetSize(80, 20)
+
+	done := make(chan error)
+	go func() { done <- d.run() }()
+	d.update([]seasonResults{{Results: []Result{{Season: 2, Percentage: 0.01, Rank: 1, Points: 4321, LastUpdated: time.Now().Unix()}}}})
+
+	// The screen is read on the dashboard's goroutine, which draws it.
+	shown := func() (text string) {
+		d.app.QueueUpdate(func() { text = screenText(screen) })
+		return text
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for text := shown(); !strings.Contains(text, "4321"); text = shown() {
+		if time.Now().After(deadline) {
+			t.Fatalf("the cutoff never showed up on screen:\n%s", text)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("q didn't quit the dashboard")
+	}
+	fmt.Fprintln(d, "after quitting")
+	if fallback.String() != "after quitting\n" {
+		t.Errorf("log output after quitting went to %q, want the fallback", fallback.String())
+	}
+}
+
+// screenText is what screen shows, a line per row.
+func screenText(screen tcell.SimulationScreen) string {
+	cells, width, _ := screen.GetContents()
+	var b strings.Builder
+	for i, cell := range cells {
+		b.WriteString(string(cell.Runes))
+		if (i+1)%width == 0 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func TestOpenDashboardFallsBackWhenNotATerminal(t *testing.T) {
+	out, err := os.Create(t.TempDir() + "/out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if d := openDashboard(out); d != nil {
+		t.Error("opened the dashboard on a file, want plain output")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"runtime/debug"
+)
+
+const repoURL = "https://github.com/HeuDeaI/taikoPointsByLevel"
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3". When
+// it isn't, the module version from the build info is used.
+var version string
+
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+func defaultUserAgent() string {
+	return fmt.Sprintf("taikoPointsByLevel/%s (+%s)", buildVersion(), repoURL)
+}
+
+// newRequestID returns a random (version 4) UUID.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+type WatchlistEntry struct {
+	Address string `yaml:"address"`
+	Label   string `yaml:"label"`
+	Group   string `yaml:"group"`
+}
+
+type Watchlist struct {
+	Wallets []WatchlistEntry `yaml:"wallets"`
+}
+
+type walletReport struct {
+	Entry  WatchlistEntry
+	Ranked bool
+	User   User
+	Total  int
+}
+
+type groupReport struct {
+	Group          string
+	Wallets        int
+	Ranked         int
+	TotalScore     float64
+	BestRank       int
+	AvgPercentile  float64
+	percentileSums float64
+}
+
+func readWatchlist(path string) (Watchlist, error) {
+	var watchlist Watchlist
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return watchlist, fmt.Errorf("failed to read watchlist: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &watchlist); err != nil {
+		return watchlist, fmt.Errorf("failed to parse watchlist %s: %w", path, err)
+	}
+	if err := watchlist.validate(); err != nil {
+		return watchlist, fmt.Errorf("invalid watchlist %s: %w", path, err)
+	}
+	return watchlist, nil
+}
+
+func (w Watchlist) validate() error {
+	addresses := make(map[string]WatchlistEntry)
+	labels := make(map[string]bool)
+	for i, entry := range w.Wallets {
+		if entry.Address == "" {
+			return fmt.Errorf("entry %d: missing address", i+1)
+		}
+		if entry.Label == "" {
+			return fmt.Errorf("entry %d (%s): missing label", i+1, entry.Address)
+		}
+		if entry.Group == "" {
+			return fmt.Errorf("entry %d (%s): missing group", i+1, entry.Label)
+		}
+
+		address, err := normalizeAddress(entry.Address)
+		if err != nil {
+			return fmt.Errorf("entry %d (%s): %w", i+1, entry.Label, err)
+		}
+		if previous, ok := addresses[address]; ok {
+			return fmt.Errorf("address %s is listed twice: %q in group %q and %q in group %q",
+				entry.Address, previous.Label, previous.Group, entry.Label, entry.Group)
+		}
+		addresses[address] = entry
+
+		if labels[entry.Label] {
+			return fmt.Errorf("label %q is used more than once", entry.Label)
+		}
+		labels[entry.Label] = true
+	}
+	return nil
+}
+
+// resolve returns the address for ref, which may be a watchlist label or an
+// address.
+func (w Watchlist) resolve(ref string) string {
+	for _, entry := range w.Wallets {
+		if entry.Label == ref {
+			return entry.Address
+		}
+	}
+	return ref
+}
+
+func buildWatchlistReport(season int, watchlist Watchlist) ([]walletReport, []groupReport, error) {
+	var wg sync.WaitGroup
+	wallets := make([]walletReport, len(watchlist.Wallets))
+	errs := make([]error, len(watchlist.Wallets))
+
+	for i, entry := range watchlist.Wallets {
+		wg.Add(1)
+		go func(i int, entry WatchlistEntry) {
+			defer wg.Done()
+			user, total, found, err := userByAddress(context.Background(), season, entry.Address)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to look up %s (%s): %w", entry.Label, entry.Address, err)
+				return
+			}
+			wallets[i] = walletReport{Entry: entry, Ranked: found, User: user, Total: total}
+		}(i, entry)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return wallets, summarizeGroups(wallets), nil
+}
+
+func summarizeGroups(wallets []walletReport) []groupReport {
+	byGroup := make(map[string]*groupReport)
+	var groups []*groupReport
+	for _, wallet := range wallets {
+		group, ok := byGroup[wallet.Entry.Group]
+		if !ok {
+			group = &groupReport{Group: wallet.Entry.Group}
+			byGroup[wallet.Entry.Group] = group
+			groups = append(groups, group)
+		}
+
+		group.Wallets++
+		if !wallet.Ranked {
+			continue
+		}
+		group.Ranked++
+		group.TotalScore += wallet.User.TotalScore
+		if group.BestRank == 0 || wallet.User.Rank < group.BestRank {
+			group.BestRank = wallet.User.Rank
+		}
+		group.percentileSums += topPercentile(wallet.User.Rank, wallet.Total)
+	}
+
+	reports := make([]groupReport, len(groups))
+	for i, group := range groups {
+		if group.Ranked > 0 {
+			group.AvgPercentile = group.percentileSums / float64(group.Ranked)
+		}
+		reports[i] = *group
+	}
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].Group < reports[j].Group })
+	return reports
+}
+
+func topPercentile(rank, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(rank) / float64(total)
+}
+
+func writeWatchlistReport(out io.Writer, wallets []walletReport, groups []groupReport) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "group\tlabel\taddress\trank\ttotal score\ttop")
+	for _, group := range groups {
+		for _, wallet := range wallets {
+			if wallet.Entry.Group != group.Group {
+				continue
+			}
+			if !wallet.Ranked {
+				fmt.Fprintf(w, "%s\t%s\t%s\tunranked\t0\t-\n", group.Group, wallet.Entry.Label, redact.address(wallet.Entry.Address))
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.0f\t%s\n", group.Group, wallet.Entry.Label, redact.address(wallet.Entry.Address),
+				wallet.User.Rank, wallet.User.TotalScore, formatPercentage(topPercentile(wallet.User.Rank, wallet.Total)))
+		}
+	}
+	w.Flush()
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "group\twallets\tranked\ttotal score\tbest rank\tavg top")
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.0f\t%d\t%s\n", group.Group, group.Wallets, group.Ranked,
+			group.TotalScore, group.BestRank, formatPercentage(group.AvgPercentile))
+	}
+	w.Flush()
+}
+
+func runWatchlist(config Config, args []string) error {
+	if len(args) == 0 || args[0] != "report" {
+		return fmt.Errorf("usage: watchlist report [-watchlist path]")
+	}
+
+	flags := newCommandFlags("watchlist report")
+	path := flags.String("watchlist", config.Watchlist, "watchlist YAML file (defaults to the config file's watchlist)")
+	if err := parseCommandFlags(flags, args[1:], "season", "base-url", "verbose"); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("no watchlist configured: set watchlist in the config file or pass -watchlist")
+	}
+
+	watchlist, err := readWatchlist(*path)
+	if err != nil {
+		return err
+	}
+	defaultClient.progress.AddTotal(len(watchlist.Wallets))
+	wallets, groups, err := buildWatchlistReport(seasons[0], watchlist)
+	defaultClient.progress.Finish()
+	if err != nil {
+		return err
+	}
+	writeWatchlistReport(stdout, wallets, groups)
+	return nil
+}
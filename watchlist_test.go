@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadWatchlist(t *testing.T) {
+	watchlist, err := readWatchlist("testdata/watchlist.yaml")
+	if err != nil {
+		t.Fatalf("readWatchlist: %v", err)
+	}
+	if len(watchlist.Wallets) != 4 {
+		t.Fatalf("got %d wallets, want 4", len(watchlist.Wallets))
+	}
+	if got := watchlist.resolve("carol"); got != "0x0000000000000000000000000000000000000032" {
+		t.Fatalf("resolve(carol) = %s", got)
+	}
+	if got := watchlist.resolve("0xabc"); got != "0xabc" {
+		t.Fatalf("resolve of a plain address = %s, want it unchanged", got)
+	}
+}
+
+func TestWatchlistRejectsDuplicateAddressAcrossGroups(t *testing.T) {
+	watchlist := Watchlist{Wallets: []WatchlistEntry{
+		{Address: "0xAbC0000000000000000000000000000000000001", Label: "alice", Group: "main"},
+		{Address: "0xabc0000000000000000000000000000000000001", Label: "alice-alt", Group: "friends"},
+	}}
+	err := watchlist.validate()
+	if err == nil || !strings.Contains(err.Error(), "listed twice") {
+		t.Fatalf("validate() = %v, want duplicate address error", err)
+	}
+}
+
+func TestWatchlistReport(t *testing.T) {
+	useFakeUpstream(t, rankedUpstream(1000))
+	watchlist, err := readWatchlist("testdata/watchlist.yaml")
+	if err != nil {
+		t.Fatalf("readWatchlist: %v", err)
+	}
+
+	wallets, groups, err := buildWatchlistReport(defaultSeason, watchlist)
+	if err != nil {
+		t.Fatalf("buildWatchlistReport: %v", err)
+	}
+
+	want := map[string]groupReport{
+		"friends": {Group: "friends", Wallets: 1, Ranked: 1, TotalScore: 9510, BestRank: 50, AvgPercentile: 0.05},
+		"main":    {Group: "main", Wallets: 2, Ranked: 2, TotalScore: 9990 + 9910, BestRank: 2, AvgPercentile: 0.006},
+		"team":    {Group: "team", Wallets: 1},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for _, group := range groups {
+		group.percentileSums = 0
+		w := want[group.Group]
+		if group.Wallets != w.Wallets || group.Ranked != w.Ranked || group.TotalScore != w.TotalScore ||
+			group.BestRank != w.BestRank || !closeTo(group.AvgPercentile, w.AvgPercentile) {
+			t.Errorf("group %s = %+v, want %+v", group.Group, group, w)
+		}
+	}
+
+	var out bytes.Buffer
+	writeWatchlistReport(&out, wallets, groups)
+	if !strings.Contains(out.String(), "dave") || !strings.Contains(out.String(), "unranked") {
+		t.Fatalf("report is missing the unranked wallet:\n%s", out.String())
+	}
+}
+
+func closeTo(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+var widgetTemplate = template.Must(template.New("widget").Parse(`<div class="tpbl tpbl-{{.Theme}}{{if .Compact}} tpbl-compact{{end}}">
+<style>
+.tpbl{font:13px/1.4 system-ui,sans-serif;display:inline-block;padding:8px;border-radius:6px}
+.tpbl-light{background:#fff;color:#1f2328;border:1px solid #d0d7de}
+.tpbl-dark{background:#0d1117;color:#e6edf3;border:1px solid #30363d}
+.tpbl table{border-collapse:collapse}
+.tpbl th,.tpbl td{padding:2px 8px;text-align:right}
+.tpbl caption,.tpbl p{margin:0 0 4px;font-weight:600;text-align:left}
+.tpbl p{margin:4px 0 0;font-weight:400;opacity:.7}
+.tpbl-compact{font-size:11px;padding:4px}
+</style>
+<table>
+{{- if not .Compact}}
+<caption>Season {{.Season}} cutoffs</caption>
+<thead><tr><th>Top</th><th>Rank</th><th>Points</th></tr></thead>
+{{- end}}
+<tbody>
+{{- range .Rows}}
+<tr><td>{{.Top}}</td>{{if not $.Compact}}<td>{{.Rank}}</td>{{end}}<td>{{.Points}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- if not .Compact}}
+<p>Updated {{.Updated}}</p>
+{{- end}}
+</div>
+`))
+
+type widgetRow struct {
+	Top    string
+	Rank   int
+	Points string
+}
+
+type widgetData struct {
+	Season  int
+	Theme   string
+	Compact bool
+	Updated string
+	Rows    []widgetRow
+}
+
+// snapshotETag identifies a snapshot by the leaderboard's lastUpdated so
+// embedding pages can revalidate cheaply.
+func snapshotETag(snapshot Snapshot) string {
+	return fmt.Sprintf(`"%d"`, snapshot.LastUpdated)
+}
+
+// writeCacheable sets the CORS and ETag headers for an embeddable response and
+// reports whether the client's copy is still current, in which case a 304 has
+// already been written.
+func writeCacheable(w http.ResponseWriter, r *http.Request, snapshot Snapshot) bool {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("ETag", snapshotETag(snapshot))
+	if r.Header.Get("If-None-Match") == snapshotETag(snapshot) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+func handleWidget(store *snapshotStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshot, ok := store.get()
+		if !ok {
+			http.Error(w, "no snapshot yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		query := r.URL.Query()
+		data := widgetData{
+			Season:  snapshot.Season,
+			Theme:   "light",
+			Compact: query.Get("compact") == "1" || query.Get("compact") == "true",
+			Updated: time.Unix(snapshot.LastUpdated, 0).UTC().Format("2006-01-02 15:04 UTC"),
+		}
+		switch theme := query.Get("theme"); theme {
+		case "", "light":
+		case "dark":
+			data.Theme = theme
+		default:
+			http.Error(w, fmt.Sprintf("unknown theme %q", theme), http.StatusBadRequest)
+			return
+		}
+
+		var tiers []float64
+		if list := query.Get("tiers"); list != "" {
+			var err error
+			if tiers, err = parsePercentages(list); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		for _, result := range snapshot.Results {
+			if tiers != nil && !slices.Contains(tiers, result.Percentage) {
+				continue
+			}
+			row := widgetRow{Top: formatPercentage(result.Percentage), Rank: result.Rank, Points: "-"}
+			if !result.Skipped {
+				row.Points = formatPoints(result)
+			}
+			data.Rows = append(data.Rows, row)
+		}
+
+		if writeCacheable(w, r, snapshot) {
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := widgetTemplate.Execute(w, data); err != nil {
+			log.Printf("Failed to write widget: %v", err)
+		}
+	}
+}
+
+// badgeSVG renders a shields.io-style flat badge. Widths are estimated at a
+// fixed width per character, which is close enough for short labels.
+func badgeSVG(label, value string) string {
+	const charWidth, padding = 7, 10
+	labelWidth := len(label)*charWidth + padding
+	valueWidth := len(value)*charWidth + padding
+	width := labelWidth + valueWidth
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+"\n", width, label, value)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`+"\n", label, value)
+	fmt.Fprintf(&b, `<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+"\n")
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+"\n", width)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="#e81899"/><rect width="%d" height="20" fill="url(#s)"/></g>`+"\n",
+		labelWidth, labelWidth, valueWidth, width)
+	fmt.Fprintf(&b, `<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%d" y="14">%s</text><text x="%d" y="14">%s</text></g>`+"\n", labelWidth/2, label, labelWidth+valueWidth/2, value)
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+func handleBadge(store *snapshotStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutSuffix(r.PathValue("file"), ".svg")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		percentage, err := parsePercentage(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		snapshot, ok := store.get()
+		if !ok {
+			http.Error(w, "no snapshot yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		i := slices.IndexFunc(snapshot.Results, func(result Result) bool { return result.Percentage == percentage })
+		if i < 0 {
+			http.Error(w, fmt.Sprintf("tier %s is not computed", name), http.StatusNotFound)
+			return
+		}
+		value := "n/a"
+		if result := snapshot.Results[i]; !result.Skipped {
+			value = formatPoints(result) + " pts"
+		}
+
+		if writeCacheable(w, r, snapshot) {
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		fmt.Fprint(w, badgeSVG("top "+formatPercentage(percentage), value))
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func widgetStore() *snapshotStore {
+	store := &snapshotStore{}
+	store.set(Snapshot{
+		Season:      2,
+		TotalUsers:  1000,
+		LastUpdated: 1718000000,
+		RefreshedAt: time.Unix(1718000100, 0),
+		Results: []Result{
+			{Season: 2, Percentage: 0.001, TotalUsers: 1000, LastUpdated: 1718000000, Rank: 1, Points: 10000},
+			{Season: 2, Percentage: 0.01, TotalUsers: 1000, LastUpdated: 1718000000, Rank: 10, Points: 9910},
+			{Season: 2, Percentage: 0.1, TotalUsers: 1000, LastUpdated: 1718000000, Rank: 100, Skipped: true},
+		},
+	})
+	return store
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := "testdata/" + name + ".golden"
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs from %s:\n%s", name, path, got)
+	}
+}
+
+func TestWidgetGolden(t *testing.T) {
+	handler := newHTTPHandler(widgetStore(), defaultSeason)
+	for _, tc := range []struct{ name, url string }{
+		{"widget_light", "/widget"},
+		{"widget_dark_tiers", "/widget?theme=dark&tiers=0.01,0.1"},
+		{"widget_compact", "/widget?compact=1"},
+		{"badge_1pct", "/badge/0.01.svg"},
+		{"badge_skipped", "/badge/0.1.svg"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", tc.url, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET %s = %d: %s", tc.url, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+				t.Errorf("Access-Control-Allow-Origin = %q", got)
+			}
+			checkGolden(t, tc.name, rec.Body.Bytes())
+		})
+	}
+}
+
+func TestWidgetETag(t *testing.T) {
+	handler := newHTTPHandler(widgetStore(), defaultSeason)
+	for _, url := range []string{"/widget", "/badge/0.01.svg"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		etag := rec.Header().Get("ETag")
+		if etag != `"1718000000"` {
+			t.Fatalf("GET %s ETag = %q", url, etag)
+		}
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("If-None-Match", etag)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Fatalf("revalidating %s = %d with %d bytes, want an empty 304", url, rec.Code, rec.Body.Len())
+		}
+	}
+}
+
+func TestBadgeUnknownTier(t *testing.T) {
+	handler := newHTTPHandler(widgetStore(), defaultSeason)
+	for url, want := range map[string]int{
+		"/badge/0.05.svg": http.StatusNotFound,
+		"/badge/abc.svg":  http.StatusBadRequest,
+		"/badge/0.01.png": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", url, rec.Code, want)
+		}
+	}
+}